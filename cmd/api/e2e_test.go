@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/cmd/api/handlers"
+	"github.com/JhonatanRSantos/review-chatbot/config"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/fakegemini"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
+	"github.com/fasthttp/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	e2eAPIKey       = "e2e-api-key"
+	e2eReplyTimeout = 5 * time.Second
+)
+
+// e2eAPI is the API running offline: a SQLite database and the fake Gemini API
+type e2eAPI struct {
+	url      string
+	gemini   *fakegemini.Server
+	database godb.DB
+}
+
+// e2eChat is a chat saved by the API
+type e2eChat struct {
+	ID         string     `db:"id"`
+	Status     string     `db:"status"`
+	Product    string     `db:"product"`
+	ReviewedAt *time.Time `db:"reviewed_at"`
+}
+
+// e2eMessage is a chat message saved by the API
+type e2eMessage struct {
+	Author  string `db:"author"`
+	Message string `db:"message"`
+}
+
+// startE2EAPI starts the API with the routes, services and workers of main. It's stopped when
+// the test ends.
+func startE2EAPI(t *testing.T) *e2eAPI {
+	if !fakegemini.StreamsSupported() {
+		t.Skip("the genai client can't read the API streams with this Go toolchain, run with GOEXPERIMENT=nojsonv2")
+	}
+
+	ctx := gocontext.FromContext(context.Background())
+	dir := t.TempDir()
+
+	gemini := fakegemini.NewServer()
+	t.Cleanup(gemini.Close)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := fmt.Sprint(listener.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, listener.Close())
+
+	flagSet := flag.NewFlagSet("e2e", flag.ContinueOnError)
+	loader := config.NewLoader(flagSet)
+	require.NoError(t, flagSet.Parse([]string{
+		"-server_port", port,
+		"-gen_ai_api_key", fakegemini.APIKey,
+		"-gen_ai_endpoint", gemini.URL(),
+		"-static_files_path", filepath.Join("..", "..", "static"),
+		"-auth.signing_keys", "e2e:e2e-signing-secret",
+		"-auth.api_keys", e2eAPIKey,
+		"-export.directory", filepath.Join(dir, "exports"),
+		"-tracing.exporter", "none",
+	}))
+
+	configs, err := loader.Load()
+	require.NoError(t, err)
+
+	database, err := godb.NewDB(godb.DBConfig{
+		User:             "e2e",
+		Password:         "e2e",
+		Database:         filepath.Join(dir, "review-chatbot"),
+		DatabaseType:     godb.SQLiteDB,
+		ConnectTimeout:   100 * time.Millisecond,
+		ConnectionParams: godb.DBConnectionParams{"_busy_timeout": "5000"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	require.NoError(t, migrations.NewMigrator(database).Migrate(ctx))
+
+	server := newAPI(ctx, configs, database)
+	t.Cleanup(func() { server.chatbot.Close() })
+
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	t.Cleanup(stopWorkers)
+	server.startWorkers(workersCtx)
+
+	go server.ws.Listen("127.0.0.1:" + port)
+	t.Cleanup(func() {
+		stopWorkers()
+		server.shutdown(ctx, e2eReplyTimeout)
+	})
+
+	api := &e2eAPI{url: "http://127.0.0.1:" + port, gemini: gemini, database: database}
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(api.url + "/healthz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 20*time.Millisecond)
+
+	return api
+}
+
+// post sends the body as JSON with the API key and decodes the response into out, if not nil
+func (e *e2eAPI) post(t *testing.T, path string, body any, out any) int {
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, e.url+path, bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(handlers.APIKeyHeader, e2eAPIKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+// connect creates the customer and opens the web chat with a token issued for the email
+func (e *e2eAPI) connect(t *testing.T, firstName string, email string) *websocket.Conn {
+	status := e.post(t, "/api/user", datatypes.CreateUserRequest{FirstName: firstName, LastName: "Doe", Email: email}, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	var token datatypes.AuthToken
+	require.Equal(t, http.StatusCreated, e.post(t, "/api/auth/token", datatypes.IssueTokenRequest{Email: email}, &token))
+
+	chatURL := strings.Replace(e.url, "http", "ws", 1) + "/api/ws/" + url.PathEscape(email) + "?token=" + token.Token
+	conn, _, err := websocket.DefaultDialer.Dial(chatURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// startReview asks the API to start the review of the product with the connected customer. The
+// session is registered after the websocket upgrade, so the review is retried while the API
+// doesn't find it (404, the email invitations are disabled).
+func (e *e2eAPI) startReview(t *testing.T, name string, email string, product string) {
+	req := datatypes.CreateReviewRequest{User: datatypes.CreateReviewUser{Name: name, Email: email}, Product: product}
+	require.Eventually(t, func() bool {
+		status := e.post(t, "/api/review", req, nil)
+		require.Contains(t, []int{http.StatusOK, http.StatusNotFound}, status)
+		return status == http.StatusOK
+	}, e2eReplyTimeout, 10*time.Millisecond)
+}
+
+// chats returns the chats saved so far
+func (e *e2eAPI) chats(t *testing.T) []e2eChat {
+	chats := []e2eChat{}
+	require.NoError(t, e.database.SelectContext(context.Background(), &chats,
+		"SELECT id, status, product, reviewed_at FROM chats ORDER BY created_at"))
+	return chats
+}
+
+// messages returns the messages of the chat in the order they were saved
+func (e *e2eAPI) messages(t *testing.T, chatID string) []e2eMessage {
+	messages := []e2eMessage{}
+	require.NoError(t, e.database.SelectContext(context.Background(), &messages,
+		"SELECT author, message FROM messages WHERE chat_id = ? ORDER BY created_at", chatID))
+	return messages
+}
+
+// send sends the customer message and returns the reply of the chat
+func send(t *testing.T, conn *websocket.Conn, message string) string {
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+	return read(t, conn)
+}
+
+// read waits for the next message of the chat
+func read(t *testing.T, conn *websocket.Conn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(e2eReplyTimeout)))
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	return string(message)
+}
+
+func TestE2EReview(t *testing.T) {
+	t.Run("should run a full review and complete it when the customer leaves", func(t *testing.T) {
+		api := startE2EAPI(t)
+		api.gemini.Script(
+			fakegemini.Text("Hi Mary! How do you like your new Smart TV?"),
+			fakegemini.Text("Great to hear that! Would you recommend it?"),
+			fakegemini.Text("Thank you for your review!"),
+		)
+
+		conn := api.connect(t, "Mary", "mary@continental.com")
+		api.startReview(t, "Mary", "mary@continental.com", "Smart TV")
+		assert.Equal(t, "Hi Mary! How do you like your new Smart TV?", read(t, conn))
+
+		assert.Equal(t, "Great to hear that! Would you recommend it?", send(t, conn, "It works great, send the bill to mary@continental.com"))
+		assert.Equal(t, "Thank you for your review!", send(t, conn, "Yes, 5 stars"))
+		require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+
+		requests := api.gemini.Requests()
+		require.Len(t, requests, 3)
+		assert.Equal(t, "Start a new review with Mary, who just bought a new Smart TV", requests[0].LastMessage())
+		assert.Contains(t, requests[0].SystemInstruction, "AI Tech Shop")
+		assert.Equal(t, []string{chatbot.EscalationFunctionName}, requests[0].Tools)
+		assert.NotContains(t, requests[1].LastMessage(), "mary@continental.com")
+		assert.Len(t, requests[2].Messages, 5)
+		assert.Equal(t, "Yes, 5 stars", requests[2].LastMessage())
+
+		var chat e2eChat
+		require.Eventually(t, func() bool {
+			chats := api.chats(t)
+			if len(chats) != 1 {
+				return false
+			}
+			chat = chats[0]
+			return chat.ReviewedAt != nil
+		}, e2eReplyTimeout, 10*time.Millisecond)
+		assert.Equal(t, datatypes.ChatStatusOpen, chat.Status)
+		assert.Equal(t, "Smart TV", chat.Product)
+
+		assert.Equal(t, []e2eMessage{
+			{Author: datatypes.AuthorChatbot, Message: "Hi Mary! How do you like your new Smart TV?"},
+			{Author: datatypes.AuthorUser, Message: "It works great, send the bill to mary@continental.com"},
+			{Author: datatypes.AuthorChatbot, Message: "Great to hear that! Would you recommend it?"},
+			{Author: datatypes.AuthorUser, Message: "Yes, 5 stars"},
+			{Author: datatypes.AuthorChatbot, Message: "Thank you for your review!"},
+		}, api.messages(t, chat.ID))
+	})
+
+	t.Run("should hand the chat over to an agent when the model asks for one", func(t *testing.T) {
+		api := startE2EAPI(t)
+		api.gemini.Script(
+			fakegemini.Text("Hi John! How do you like your new headphones?"),
+			fakegemini.Call(chatbot.EscalationFunctionName, map[string]any{"reason": "broken headphones"}),
+		)
+
+		conn := api.connect(t, "John", "john@continental.com")
+		api.startReview(t, "John", "john@continental.com", "Headphones")
+		read(t, conn)
+
+		reply := send(t, conn, "They broke after a day, I want to talk to a person")
+		assert.Contains(t, reply, "human agents")
+
+		require.Eventually(t, func() bool {
+			return api.chats(t)[0].Status == datatypes.ChatStatusAgent
+		}, e2eReplyTimeout, 10*time.Millisecond)
+
+		// the model doesn't answer the chats with an agent
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("Hello?")))
+		require.Eventually(t, func() bool {
+			return len(api.messages(t, api.chats(t)[0].ID)) == 4
+		}, e2eReplyTimeout, 10*time.Millisecond)
+		assert.Len(t, api.gemini.Requests(), 2)
+	})
+
+	t.Run("should apologize when the model fails, blocks the message or is too slow", func(t *testing.T) {
+		api := startE2EAPI(t)
+		api.gemini.Script(
+			fakegemini.Text("Hi Ana! How do you like your new phone?"),
+			fakegemini.Error(http.StatusTooManyRequests, "quota exceeded"),
+			fakegemini.BlockedPrompt("SAFETY"),
+			fakegemini.BlockedReply(),
+			fakegemini.Text("Sorry for the wait, thanks for your review!").After(200*time.Millisecond),
+		)
+
+		conn := api.connect(t, "Ana", "ana@continental.com")
+		api.startReview(t, "Ana", "ana@continental.com", "Phone")
+		read(t, conn)
+
+		apology := "I'm sorry but can't help you right now. Can you please try later."
+		assert.Equal(t, apology, send(t, conn, "It's ok"))
+		assert.Equal(t, apology, send(t, conn, "It's ok, I said"))
+		assert.Equal(t, apology, send(t, conn, "Hello?"))
+		assert.Equal(t, "Sorry for the wait, thanks for your review!", send(t, conn, "Are you there?"))
+		assert.Equal(t, 0, api.gemini.Pending())
+	})
+}
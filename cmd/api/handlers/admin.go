@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/admin"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+)
+
+type adminService interface {
+	ListUsers(ctx context.Context, actor datatypes.Admin, search string, page datatypes.Page) ([]datatypes.User, error)
+	ListChats(ctx context.Context, actor datatypes.Admin, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error)
+	GetTranscript(ctx context.Context, actor datatypes.Admin, chatID string) (datatypes.Transcript, error)
+	CloseChat(ctx context.Context, actor datatypes.Admin, chatID string) error
+	JoinChat(ctx context.Context, actor datatypes.Admin, chatID string) (datatypes.Chat, error)
+	DeleteChat(ctx context.Context, actor datatypes.Admin, chatID string) error
+	DeleteUser(ctx context.Context, actor datatypes.Admin, userID string) (datatypes.User, error)
+	ListAuditLog(ctx context.Context, actor datatypes.Admin, page datatypes.Page) ([]datatypes.AuditEntry, error)
+	ExportChats(ctx context.Context, actor datatypes.Admin, resourceID string, details string) error
+	ViewAnalytics(ctx context.Context, actor datatypes.Admin, report string, details string) error
+	ViewBotSettings(ctx context.Context, actor datatypes.Admin) error
+	ReloadBotSettings(ctx context.Context, actor datatypes.Admin) error
+	CreateTenant(ctx context.Context, actor datatypes.Admin, domain string) error
+	ListTenants(ctx context.Context, actor datatypes.Admin) error
+}
+
+type chatDisconnector interface {
+	DisconnectChat(chatID string) bool
+	DisconnectUser(ctx context.Context, email string)
+}
+
+type AdminHandlers struct {
+	adminService     adminService
+	chatDisconnector chatDisconnector
+}
+
+// NewAdminHandlers
+func NewAdminHandlers(adminService adminService, chatDisconnector chatDisconnector) *AdminHandlers {
+	return &AdminHandlers{
+		adminService:     adminService,
+		chatDisconnector: chatDisconnector,
+	}
+}
+
+// ListUsers lists users filtered by the search query param
+func (ah *AdminHandlers) ListUsers(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	users, err := ah.adminService.ListUsers(ctx, adminFromContext(fc), fc.Query("search"), pageFromQuery(fc))
+	if err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.JSON(users)
+}
+
+// ListChats lists chats filtered by the userId, email and status query params
+func (ah *AdminHandlers) ListChats(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	filter := datatypes.ChatFilter{
+		UserID: fc.Query("userId"),
+		Email:  fc.Query("email"),
+		Status: fc.Query("status"),
+	}
+
+	chats, err := ah.adminService.ListChats(ctx, adminFromContext(fc), filter, pageFromQuery(fc))
+	if err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.JSON(chats)
+}
+
+// GetTranscript returns the chat with all its messages
+func (ah *AdminHandlers) GetTranscript(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	transcript, err := ah.adminService.GetTranscript(ctx, adminFromContext(fc), fc.Params("id"))
+	if err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.JSON(transcript)
+}
+
+// CloseChat closes the chat and disconnects the customer
+func (ah *AdminHandlers) CloseChat(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	if err := ah.adminService.CloseChat(ctx, adminFromContext(fc), fc.Params("id")); err != nil {
+		return adminError(ctx, fc, err)
+	}
+
+	ah.chatDisconnector.DisconnectChat(fc.Params("id"))
+	return fc.SendStatus(fiber.StatusNoContent)
+}
+
+// AuthorizeChatTakeover only lets staff allowed to join the :chatId chat open the agent websocket
+func (ah *AdminHandlers) AuthorizeChatTakeover(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	if _, err := ah.adminService.JoinChat(ctx, adminFromContext(fc), fc.Params("chatId")); err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.Next()
+}
+
+// AuthorizeExport only lets staff allowed to export transcripts reach the export endpoints
+func (ah *AdminHandlers) AuthorizeExport(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	details := string(fc.Request().URI().QueryString())
+
+	if err := ah.adminService.ExportChats(ctx, adminFromContext(fc), fc.Params("id"), details); err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.Next()
+}
+
+// AuthorizeAnalytics only lets staff allowed to read reports reach the analytics endpoints
+func (ah *AdminHandlers) AuthorizeAnalytics(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	report := path.Base(fc.Path())
+	details := string(fc.Request().URI().QueryString())
+
+	if err := ah.adminService.ViewAnalytics(ctx, adminFromContext(fc), report, details); err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.Next()
+}
+
+// AuthorizeBotSettings only lets staff allowed to read or reload the bot settings reach
+// the bot settings endpoints
+func (ah *AdminHandlers) AuthorizeBotSettings(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	authorize := ah.adminService.ViewBotSettings
+	if fc.Method() != fiber.MethodGet {
+		authorize = ah.adminService.ReloadBotSettings
+	}
+
+	if err := authorize(ctx, adminFromContext(fc)); err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.Next()
+}
+
+// AuthorizeTenants only lets staff allowed to list or create tenants reach the tenant endpoints
+func (ah *AdminHandlers) AuthorizeTenants(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	var err error
+	if fc.Method() == fiber.MethodGet {
+		err = ah.adminService.ListTenants(ctx, adminFromContext(fc))
+	} else {
+		// the body is validated by the tenant handlers, here it only tells the audit log the domain
+		var req datatypes.CreateTenantRequest
+		_ = fc.BodyParser(&req)
+		err = ah.adminService.CreateTenant(ctx, adminFromContext(fc), req.Domain)
+	}
+
+	if err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.Next()
+}
+
+// DeleteChat deletes the chat with its messages and their analyses
+func (ah *AdminHandlers) DeleteChat(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	if err := ah.adminService.DeleteChat(ctx, adminFromContext(fc), fc.Params("id")); err != nil {
+		return adminError(ctx, fc, err)
+	}
+
+	ah.chatDisconnector.DisconnectChat(fc.Params("id"))
+	return fc.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteUser erases the user like the customer erasure requests and disconnects its web chat
+func (ah *AdminHandlers) DeleteUser(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	erased, err := ah.adminService.DeleteUser(ctx, adminFromContext(fc), fc.Params("id"))
+	if err != nil {
+		return adminError(ctx, fc, err)
+	}
+
+	ah.chatDisconnector.DisconnectUser(ctx, erased.Email)
+	return fc.SendStatus(fiber.StatusNoContent)
+}
+
+// ListAuditLog lists the latest admin actions
+func (ah *AdminHandlers) ListAuditLog(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	entries, err := ah.adminService.ListAuditLog(ctx, adminFromContext(fc), pageFromQuery(fc))
+	if err != nil {
+		return adminError(ctx, fc, err)
+	}
+	return fc.JSON(entries)
+}
+
+// adminFromContext returns the admin authenticated by AuthHandlers.RequireAdmin
+func adminFromContext(fc *fiber.Ctx) datatypes.Admin {
+	actor, _ := fc.Locals(AdminLocal).(datatypes.Admin)
+	return actor
+}
+
+// pageFromQuery reads the limit and offset query params
+func pageFromQuery(fc *fiber.Ctx) datatypes.Page {
+	return datatypes.Page{
+		Limit:  fc.QueryInt("limit"),
+		Offset: fc.QueryInt("offset"),
+	}
+}
+
+// adminError maps the admin service errors to status codes
+func adminError(ctx context.Context, fc *fiber.Ctx, err error) error {
+	golog.Log().Error(ctx, err.Error())
+
+	switch {
+	case errors.Is(err, admin.ErrForbidden):
+		return fc.SendStatus(fiber.StatusForbidden)
+	case errors.Is(err, admin.ErrChatNotFound), errors.Is(err, admin.ErrUserNotFound):
+		return fc.SendStatus(fiber.StatusNotFound)
+	case errors.Is(err, admin.ErrChatAlreadyClosed):
+		return fc.SendStatus(fiber.StatusConflict)
+	}
+	return fc.SendStatus(fiber.StatusInternalServerError)
+}
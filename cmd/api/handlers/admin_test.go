@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/admin"
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type adminServiceMock struct {
+	Error              error
+	CallbackCloseChat  func(ctx context.Context, actor datatypes.Admin, chatID string) error
+	CallbackDeleteUser func(ctx context.Context, actor datatypes.Admin, userID string) (datatypes.User, error)
+}
+
+func (asm *adminServiceMock) ListUsers(ctx context.Context, actor datatypes.Admin, search string, page datatypes.Page) ([]datatypes.User, error) {
+	return []datatypes.User{}, asm.Error
+}
+
+func (asm *adminServiceMock) ListChats(ctx context.Context, actor datatypes.Admin, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error) {
+	return []datatypes.Chat{}, asm.Error
+}
+
+func (asm *adminServiceMock) GetTranscript(ctx context.Context, actor datatypes.Admin, chatID string) (datatypes.Transcript, error) {
+	return datatypes.Transcript{}, asm.Error
+}
+
+func (asm *adminServiceMock) CloseChat(ctx context.Context, actor datatypes.Admin, chatID string) error {
+	if asm.CallbackCloseChat != nil {
+		return asm.CallbackCloseChat(ctx, actor, chatID)
+	}
+	return asm.Error
+}
+
+func (asm *adminServiceMock) JoinChat(ctx context.Context, actor datatypes.Admin, chatID string) (datatypes.Chat, error) {
+	return datatypes.Chat{ID: chatID}, asm.Error
+}
+
+func (asm *adminServiceMock) DeleteChat(ctx context.Context, actor datatypes.Admin, chatID string) error {
+	return asm.Error
+}
+
+func (asm *adminServiceMock) DeleteUser(ctx context.Context, actor datatypes.Admin, userID string) (datatypes.User, error) {
+	if asm.CallbackDeleteUser != nil {
+		return asm.CallbackDeleteUser(ctx, actor, userID)
+	}
+	return datatypes.User{}, asm.Error
+}
+
+func (asm *adminServiceMock) ListAuditLog(ctx context.Context, actor datatypes.Admin, page datatypes.Page) ([]datatypes.AuditEntry, error) {
+	return []datatypes.AuditEntry{}, asm.Error
+}
+
+func (asm *adminServiceMock) ExportChats(ctx context.Context, actor datatypes.Admin, resourceID string, details string) error {
+	return asm.Error
+}
+
+func (asm *adminServiceMock) ViewAnalytics(ctx context.Context, actor datatypes.Admin, report string, details string) error {
+	return asm.Error
+}
+
+func (asm *adminServiceMock) ViewBotSettings(ctx context.Context, actor datatypes.Admin) error {
+	return asm.Error
+}
+
+func (asm *adminServiceMock) ReloadBotSettings(ctx context.Context, actor datatypes.Admin) error {
+	return asm.Error
+}
+
+func (asm *adminServiceMock) CreateTenant(ctx context.Context, actor datatypes.Admin, domain string) error {
+	return asm.Error
+}
+
+func (asm *adminServiceMock) ListTenants(ctx context.Context, actor datatypes.Admin) error {
+	return asm.Error
+}
+
+type chatDisconnectorMock struct {
+	Disconnected      []string
+	DisconnectedUsers []string
+}
+
+func (cdm *chatDisconnectorMock) DisconnectChat(chatID string) bool {
+	cdm.Disconnected = append(cdm.Disconnected, chatID)
+	return true
+}
+
+func (cdm *chatDisconnectorMock) DisconnectUser(ctx context.Context, email string) {
+	cdm.DisconnectedUsers = append(cdm.DisconnectedUsers, email)
+}
+
+func TestHandlerAdminCloseChat(t *testing.T) {
+	path := "/api/admin/chats/:id/close"
+	supportAgent := datatypes.Admin{Name: "bob", Role: auth.RoleSupportAgent}
+
+	t.Run("should close the chat and disconnect the customer", func(t *testing.T) {
+		disconnector := &chatDisconnectorMock{}
+		handlers := NewAdminHandlers(&adminServiceMock{
+			CallbackCloseChat: func(ctx context.Context, actor datatypes.Admin, chatID string) error {
+				require.Equal(t, supportAgent, actor)
+				return nil
+			},
+		}, disconnector)
+		authHandlers := NewAuthHandlers(&authServiceMock{Admin: supportAgent}, &tenantServiceMock{})
+
+		app := fiber.New()
+		app.Post(path, authHandlers.RequireAdmin, handlers.CloseChat)
+
+		req, err := http.NewRequest("POST", "/api/admin/chats/qwerty/close", nil)
+		require.NoError(t, err)
+		req.Header.Add(AdminKeyHeader, "admin-key")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNoContent, result.StatusCode)
+		require.Equal(t, []string{"qwerty"}, disconnector.Disconnected)
+	})
+
+	t.Run("should reject requests without admin credentials", func(t *testing.T) {
+		handlers := NewAdminHandlers(&adminServiceMock{}, &chatDisconnectorMock{})
+		authHandlers := NewAuthHandlers(&authServiceMock{Error: auth.ErrInvalidAPIKey}, &tenantServiceMock{})
+
+		app := fiber.New()
+		app.Post(path, authHandlers.RequireAdmin, handlers.CloseChat)
+
+		req, err := http.NewRequest("POST", "/api/admin/chats/qwerty/close", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusUnauthorized, result.StatusCode)
+	})
+
+	t.Run("should map the admin errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			admin.ErrForbidden:         fiber.StatusForbidden,
+			admin.ErrChatNotFound:      fiber.StatusNotFound,
+			admin.ErrChatAlreadyClosed: fiber.StatusConflict,
+		} {
+			disconnector := &chatDisconnectorMock{}
+			handlers := NewAdminHandlers(&adminServiceMock{Error: err}, disconnector)
+
+			app := fiber.New()
+			app.Post(path, handlers.CloseChat)
+
+			req, reqErr := http.NewRequest("POST", "/api/admin/chats/qwerty/close", nil)
+			require.NoError(t, reqErr)
+
+			result, reqErr := app.Test(req)
+			require.NoError(t, reqErr)
+			require.Equal(t, status, result.StatusCode)
+			require.Empty(t, disconnector.Disconnected)
+		}
+	})
+}
+
+func TestHandlerAdminDeleteUser(t *testing.T) {
+	path := "/api/admin/users/:id"
+
+	t.Run("should erase the user and disconnect its web chat", func(t *testing.T) {
+		disconnector := &chatDisconnectorMock{}
+		handlers := NewAdminHandlers(&adminServiceMock{
+			CallbackDeleteUser: func(ctx context.Context, actor datatypes.Admin, userID string) (datatypes.User, error) {
+				require.Equal(t, "qwerty", userID)
+				return datatypes.User{ID: userID, Email: "john@wick.com"}, nil
+			},
+		}, disconnector)
+
+		app := fiber.New()
+		app.Delete(path, handlers.DeleteUser)
+
+		req, err := http.NewRequest("DELETE", "/api/admin/users/qwerty", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNoContent, result.StatusCode)
+		require.Equal(t, []string{"john@wick.com"}, disconnector.DisconnectedUsers)
+	})
+
+	t.Run("should not disconnect when the user does not exist", func(t *testing.T) {
+		disconnector := &chatDisconnectorMock{}
+		handlers := NewAdminHandlers(&adminServiceMock{Error: admin.ErrUserNotFound}, disconnector)
+
+		app := fiber.New()
+		app.Delete(path, handlers.DeleteUser)
+
+		req, err := http.NewRequest("DELETE", "/api/admin/users/qwerty", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNotFound, result.StatusCode)
+		require.Empty(t, disconnector.DisconnectedUsers)
+	})
+}
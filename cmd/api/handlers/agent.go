@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	agentRequestedMessage = "I'm transferring you to one of our human agents. Please wait, they will join this chat soon."
+	agentJoinedMessage    = "%s from our support team joined the chat."
+	agentLeftMessage      = "The agent left the chat. Another agent will join soon."
+	customerLeftMessage   = "The customer left the chat."
+)
+
+type agentConnection struct {
+	conn       *websocket.Conn
+	writeMutex *sync.Mutex
+	name       string
+}
+
+// write sends a message to the agent
+func (ac *agentConnection) write(message string) error {
+	ac.writeMutex.Lock()
+	defer ac.writeMutex.Unlock()
+	return ac.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+// HandleAgentWebsocketConnection lets a staff member take over a chat and reply to the customer.
+// The customer messages are forwarded to the agent while the chatbot stays quiet.
+func (h *Handlers) HandleAgentWebsocketConnection() func(*fiber.Ctx) error {
+	return websocket.New(func(conn *websocket.Conn) {
+		ctx := gocontext.FromContext(context.Background())
+		chatID := conn.Params("chatId")
+		actor, _ := conn.Locals(AdminLocal).(datatypes.Admin)
+
+		if !h.beginConnection() {
+			rejectConnection(conn)
+			return
+		}
+		defer h.connections.Done()
+
+		agent := &agentConnection{
+			conn:       conn,
+			writeMutex: &sync.Mutex{},
+			name:       actor.Name,
+		}
+
+		if err := h.joinChat(ctx, chatID, agent); err != nil {
+			golog.Log().Error(ctx, err.Error())
+			agent.write(err.Error())
+			conn.Close()
+			return
+		}
+		defer h.leaveChat(ctx, chatID, agent)
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				h.logError(ctx, fmt.Sprintf("failed to read agent message. Cause: %s", err))
+				break
+			}
+
+			if !h.beginTurn() {
+				continue
+			}
+
+			err = h.sendAgentMessage(ctx, chatID, string(message))
+			h.turns.Done()
+
+			if err != nil {
+				h.logError(ctx, err.Error())
+				agent.write(customerLeftMessage)
+				break
+			}
+		}
+	})
+}
+
+// joinChat attaches the agent to the customer session and hands the chat over if the chatbot was still answering
+func (h *Handlers) joinChat(ctx context.Context, chatID string, agent *agentConnection) error {
+	h.sessionMutex.Lock()
+	key, session, ok := h.findSessionByChatID(chatID)
+	if !ok {
+		h.sessionMutex.Unlock()
+		return ErrSessionNotFound
+	}
+
+	if session.agent != nil {
+		h.sessionMutex.Unlock()
+		return ErrChatAlreadyTaken
+	}
+
+	wasAgentMode := session.agentMode
+	session.agentMode = true
+	session.agent = agent
+	h.sessions[key] = session
+	h.sessionMutex.Unlock()
+
+	if !wasAgentMode {
+		if err := h.chatService.EscalateChat(tenant.WithTenant(ctx, session.tenant), chatID, fmt.Sprintf("taken over by %s", agent.name)); err != nil {
+			h.leaveChat(ctx, chatID, agent)
+			return err
+		}
+	}
+
+	return h.sendAgentMessage(ctx, chatID, fmt.Sprintf(agentJoinedMessage, agent.name))
+}
+
+// leaveChat detaches the agent from the customer session
+func (h *Handlers) leaveChat(ctx context.Context, chatID string, agent *agentConnection) {
+	h.sessionMutex.Lock()
+	key, session, ok := h.findSessionByChatID(chatID)
+	if !ok || session.agent != agent {
+		h.sessionMutex.Unlock()
+		return
+	}
+
+	session.agent = nil
+	h.sessions[key] = session
+	h.sessionMutex.Unlock()
+
+	if err := session.write(agentLeftMessage); err != nil {
+		golog.Log().Error(ctx, fmt.Sprintf("failed to write message. Cause: %s", err))
+	}
+}
+
+// sendAgentMessage saves the agent message and sends it to the customer
+func (h *Handlers) sendAgentMessage(ctx context.Context, chatID string, message string) error {
+	h.sessionMutex.RLock()
+	_, session, ok := h.findSessionByChatID(chatID)
+	h.sessionMutex.RUnlock()
+
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	// the agents aren't bound to a tenant, the message belongs to the tenant of the customer
+	if _, err := h.chatService.CreateMessage(tenant.WithTenant(ctx, session.tenant), chatID, datatypes.AuthorAgent, message); err != nil {
+		return err
+	}
+
+	if err := session.write(message); err != nil {
+		return fmt.Errorf("failed to write message. Cause: %w", err)
+	}
+	return nil
+}
+
+// findSessionByChatID must be called holding the session mutex
+func (h *Handlers) findSessionByChatID(chatID string) (string, connection, bool) {
+	for key, session := range h.sessions {
+		if session.chatID == chatID {
+			return key, session, true
+		}
+	}
+	return "", connection{}, false
+}
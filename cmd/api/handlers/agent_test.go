@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerCustomerMessageWithAgent(t *testing.T) {
+	t.Run("should save the message without calling the chatbot", func(t *testing.T) {
+		var authors []string
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{
+				CallbackCreateRedactedMessage: func(ctx context.Context, chatID, author, message, pii string) (datatypes.Message, error) {
+					authors = append(authors, author)
+					return datatypes.Message{}, nil
+				},
+			},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		// the chatbot session is nil, so calling the model would panic
+		handlers.sessions[sessionKey(context.Background(), "mary@continental.com")] = connection{chatID: "qwerty", agentMode: true, conversation: handlers.conversations.NewConversation("qwerty", nil)}
+
+		err := handlers.handleCustomerMessage(context.Background(), sessionKey(context.Background(), "mary@continental.com"), "qwerty", "my phone is broken")
+		require.NoError(t, err)
+		require.Equal(t, []string{datatypes.AuthorUser}, authors)
+	})
+
+	t.Run("should analyze the user messages", func(t *testing.T) {
+		analyzed := make(chan datatypes.Message, 1)
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{
+				CallbackCreateRedactedMessage: func(ctx context.Context, chatID, author, message, pii string) (datatypes.Message, error) {
+					return datatypes.Message{ID: "message-1", ChatID: chatID, Author: author, Message: message}, nil
+				},
+			},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{
+				CallbackAnalyzeMessage: func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+					analyzed <- message
+					return datatypes.MessageAnalysis{}, nil
+				},
+			},
+			testRedactor,
+		)
+		handlers.sessions[sessionKey(context.Background(), "mary@continental.com")] = connection{chatID: "qwerty", agentMode: true, conversation: handlers.conversations.NewConversation("qwerty", nil)}
+
+		err := handlers.handleCustomerMessage(context.Background(), sessionKey(context.Background(), "mary@continental.com"), "qwerty", "my phone is broken")
+		require.NoError(t, err)
+
+		select {
+		case message := <-analyzed:
+			require.Equal(t, "message-1", message.ID)
+		case <-time.After(time.Second):
+			t.Fatal("message was not analyzed")
+		}
+
+		// chats already with an agent are not escalated again
+		require.NoError(t, handlers.OnNegativeSentiment(context.Background(), datatypes.MessageAnalysis{ChatID: "qwerty", Sentiment: -1}))
+	})
+
+	t.Run("should redact the personal data before the analysis", func(t *testing.T) {
+		var stored string
+		analyzed := make(chan datatypes.Message, 1)
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{
+				CallbackCreateRedactedMessage: func(ctx context.Context, chatID, author, message, pii string) (datatypes.Message, error) {
+					stored = message
+					return datatypes.Message{ID: "message-1", ChatID: chatID, Author: author, Message: message}, nil
+				},
+			},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{
+				CallbackAnalyzeMessage: func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+					analyzed <- message
+					return datatypes.MessageAnalysis{}, nil
+				},
+			},
+			testRedactor,
+		)
+		handlers.sessions[sessionKey(context.Background(), "mary@continental.com")] = connection{chatID: "qwerty", agentMode: true, conversation: handlers.conversations.NewConversation("qwerty", nil)}
+
+		err := handlers.handleCustomerMessage(context.Background(), sessionKey(context.Background(), "mary@continental.com"), "qwerty", "call me at (212) 555-0199")
+		require.NoError(t, err)
+		require.Equal(t, "call me at (212) 555-0199", stored)
+
+		select {
+		case message := <-analyzed:
+			require.Equal(t, "call me at [PHONE_1]", message.Message)
+		case <-time.After(time.Second):
+			t.Fatal("message was not analyzed")
+		}
+	})
+
+	t.Run("should not start a review after the handoff", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+		handlers.sessions[sessionKey(context.Background(), "mary@continental.com")] = connection{chatID: "qwerty", agentMode: true, conversation: handlers.conversations.NewConversation("qwerty", nil)}
+
+		app := fiber.New()
+		path := "/api/review"
+		app.Post(path, handlers.CreateReview)
+
+		body := `{"user":{"name":"Mary Ann","email":"mary@continental.com"},"product":"Galaxy S24"}`
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusConflict, result.StatusCode)
+	})
+
+	t.Run("should fail to join a chat that is not connected", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		err := handlers.joinChat(context.Background(), "qwerty", &agentConnection{name: "bob"})
+		require.ErrorIs(t, err, ErrSessionNotFound)
+	})
+
+	t.Run("should not let two agents join the same chat", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+		handlers.sessions[sessionKey(context.Background(), "mary@continental.com")] = connection{
+			chatID:    "qwerty",
+			agentMode: true,
+			agent:     &agentConnection{name: "alice"},
+		}
+
+		err := handlers.joinChat(context.Background(), "qwerty", &agentConnection{name: "bob"})
+		require.ErrorIs(t, err, ErrChatAlreadyTaken)
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/analytics"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	ReportFormatJSON = "json"
+	ReportFormatCSV  = "csv"
+)
+
+type analyticsService interface {
+	Summary(ctx context.Context, filter datatypes.AnalyticsFilter) (datatypes.AnalyticsSummary, error)
+	Volume(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error)
+	Ratings(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.ProductRatings, error)
+	Intents(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.IntentCount, error)
+}
+
+type AnalyticsHandlers struct {
+	analyticsService analyticsService
+}
+
+// NewAnalyticsHandlers
+func NewAnalyticsHandlers(analyticsService analyticsService) *AnalyticsHandlers {
+	return &AnalyticsHandlers{
+		analyticsService: analyticsService,
+	}
+}
+
+// Summary returns the chat volume, completion, duration, turns and ratings totals
+func (ah *AnalyticsHandlers) Summary(fc *fiber.Ctx) error {
+	return ah.report(fc, "summary", func(ctx context.Context, filter datatypes.AnalyticsFilter) (interface{}, error) {
+		return ah.analyticsService.Summary(ctx, filter)
+	})
+}
+
+// Volume returns the number of chats, users and messages per day
+func (ah *AnalyticsHandlers) Volume(fc *fiber.Ctx) error {
+	return ah.report(fc, "volume", func(ctx context.Context, filter datatypes.AnalyticsFilter) (interface{}, error) {
+		return ah.analyticsService.Volume(ctx, filter)
+	})
+}
+
+// Ratings returns the satisfaction ratings per product
+func (ah *AnalyticsHandlers) Ratings(fc *fiber.Ctx) error {
+	return ah.report(fc, "ratings", func(ctx context.Context, filter datatypes.AnalyticsFilter) (interface{}, error) {
+		return ah.analyticsService.Ratings(ctx, filter)
+	})
+}
+
+// Intents returns the number of messages per intent (complaints, returns, questions...)
+func (ah *AnalyticsHandlers) Intents(fc *fiber.Ctx) error {
+	return ah.report(fc, "intents", func(ctx context.Context, filter datatypes.AnalyticsFilter) (interface{}, error) {
+		return ah.analyticsService.Intents(ctx, filter)
+	})
+}
+
+// report reads the from, to, product and format query params and writes the report as JSON or CSV
+func (ah *AnalyticsHandlers) report(
+	fc *fiber.Ctx,
+	name string,
+	find func(ctx context.Context, filter datatypes.AnalyticsFilter) (interface{}, error),
+) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	format := fc.Query("format", ReportFormatJSON)
+	if format != ReportFormatJSON && format != ReportFormatCSV {
+		return fc.Status(fiber.StatusBadRequest).SendString(analytics.ErrUnsupportedFormat.Error())
+	}
+
+	filter, err := analytics.NewFilter(fc.Query("from"), fc.Query("to"), fc.Query("product"))
+	if err != nil {
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	report, err := find(ctx, filter)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	if format == ReportFormatJSON {
+		return fc.JSON(report)
+	}
+
+	var body bytes.Buffer
+	if err := analytics.WriteCSV(&body, report); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	fc.Attachment(name + ".csv")
+	fc.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	return fc.Send(body.Bytes())
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type analyticsServiceMock struct {
+	Error          error
+	CallbackVolume func(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error)
+}
+
+func (asm *analyticsServiceMock) Summary(ctx context.Context, filter datatypes.AnalyticsFilter) (datatypes.AnalyticsSummary, error) {
+	return datatypes.AnalyticsSummary{}, asm.Error
+}
+
+func (asm *analyticsServiceMock) Volume(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error) {
+	if asm.CallbackVolume != nil {
+		return asm.CallbackVolume(ctx, filter)
+	}
+	return []datatypes.VolumeEntry{}, asm.Error
+}
+
+func (asm *analyticsServiceMock) Ratings(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.ProductRatings, error) {
+	return []datatypes.ProductRatings{}, asm.Error
+}
+
+func (asm *analyticsServiceMock) Intents(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.IntentCount, error) {
+	return []datatypes.IntentCount{}, asm.Error
+}
+
+func TestHandlerAnalyticsVolume(t *testing.T) {
+	path := "/api/analytics/volume"
+
+	t.Run("should write the report as csv with the filter", func(t *testing.T) {
+		var filter datatypes.AnalyticsFilter
+		handlers := NewAnalyticsHandlers(&analyticsServiceMock{
+			CallbackVolume: func(ctx context.Context, f datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error) {
+				filter = f
+				return []datatypes.VolumeEntry{{Date: "2024-05-01", Chats: 2, Users: 1, Messages: 10}}, nil
+			},
+		})
+
+		app := fiber.New()
+		app.Get(path, handlers.Volume)
+
+		req, err := http.NewRequest("GET", path+"?from=2024-05-01&to=2024-05-31&product=Galaxy%20S24&format=csv", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+		require.Contains(t, result.Header.Get(fiber.HeaderContentType), "text/csv")
+
+		body, err := io.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.Equal(t, "date,chats,users,messages\n2024-05-01,2,1,10\n", string(body))
+
+		require.Equal(t, "Galaxy S24", filter.Product)
+		require.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), filter.To)
+	})
+
+	t.Run("should fail when the date range is invalid", func(t *testing.T) {
+		app := fiber.New()
+		app.Get(path, NewAnalyticsHandlers(&analyticsServiceMock{}).Volume)
+
+		for _, query := range []string{"?from=yesterday", "?from=2024-06-01&to=2024-05-01", "?format=xml"} {
+			req, err := http.NewRequest("GET", path+query, nil)
+			require.NoError(t, err)
+
+			result, err := app.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, fiber.StatusBadRequest, result.StatusCode, query)
+		}
+	})
+}
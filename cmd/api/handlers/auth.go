@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// APIKeyHeader is the header used by server-to-server calls
+	APIKeyHeader = "X-API-Key"
+	// AdminKeyHeader is the header used by internal staff on the admin api
+	AdminKeyHeader = "X-Admin-Key"
+	// AdminLocal is the fiber local holding the authenticated datatypes.Admin
+	AdminLocal = "admin"
+	// CustomerLocal is the fiber local holding the authenticated customer email
+	CustomerLocal = "customer"
+)
+
+type authService interface {
+	IssueToken(tenantID string, subject string) (datatypes.AuthToken, error)
+	VerifyToken(token string) (auth.Claims, error)
+	VerifyAPIKey(key string) error
+	AuthenticateAdmin(key string) (datatypes.Admin, error)
+}
+
+type tenantFinder interface {
+	FindByID(id string) (datatypes.Tenant, error)
+	FindByAPIKey(key string) (datatypes.Tenant, error)
+}
+
+type AuthHandlers struct {
+	authService authService
+	tenants     tenantFinder
+}
+
+// NewAuthHandlers
+func NewAuthHandlers(authService authService, tenants tenantFinder) *AuthHandlers {
+	return &AuthHandlers{
+		authService: authService,
+		tenants:     tenants,
+	}
+}
+
+// RequireAPIKey only allows requests with a valid api key. The global keys keep the tenant of
+// the host, the tenant keys switch the request to their tenant.
+func (ah *AuthHandlers) RequireAPIKey(fc *fiber.Ctx) error {
+	key := fc.Get(APIKeyHeader)
+
+	err := ah.authService.VerifyAPIKey(key)
+	if err == nil {
+		return fc.Next()
+	}
+
+	keyTenant, tenantErr := ah.tenants.FindByAPIKey(key)
+	if tenantErr != nil {
+		golog.Log().Error(gocontext.FromContext(fc.UserContext()), err.Error())
+		return fc.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	setTenant(fc, keyTenant)
+	return fc.Next()
+}
+
+// RequireAdmin only allows requests with valid admin credentials. Role checks are done by the admin service.
+func (ah *AuthHandlers) RequireAdmin(fc *fiber.Ctx) error {
+	admin, err := ah.authService.AuthenticateAdmin(fc.Get(AdminKeyHeader))
+	if err != nil {
+		golog.Log().Error(gocontext.FromContext(fc.UserContext()), err.Error())
+		return fc.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	fc.Locals(AdminLocal, admin)
+	return fc.Next()
+}
+
+// RequireCustomerToken only allows requests with a valid customer token for the :email param and
+// switches the request to the tenant of the token. Browsers can't set headers on websockets, so
+// the token is also read from the query string.
+func (ah *AuthHandlers) RequireCustomerToken(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	token := strings.TrimPrefix(fc.Get(fiber.HeaderAuthorization), "Bearer ")
+	if token == "" {
+		token = fc.Query("token")
+	}
+
+	claims, err := ah.authService.VerifyToken(token)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	if validation.LookupEmail(claims.Subject) != validation.LookupEmail(fc.Params("email")) {
+		return fc.SendStatus(fiber.StatusForbidden)
+	}
+
+	// the tokens issued before the tenants belong to the default tenant
+	tenantID := claims.Tenant
+	if tenantID == "" {
+		tenantID = tenant.DefaultID
+	}
+
+	tokenTenant, err := ah.tenants.FindByID(tenantID)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	setTenant(fc, tokenTenant)
+	fc.Locals(CustomerLocal, claims.Subject)
+	return fc.Next()
+}
+
+// IssueToken issues a customer token for the web chat
+func (ah *AuthHandlers) IssueToken(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.IssueTokenRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	token, err := ah.authService.IssueToken(tenant.IDFromContext(ctx), req.Email)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return fc.Status(fiber.StatusCreated).JSON(token)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type authServiceMock struct {
+	Error               error
+	CallbackIssueToken  func(tenantID string, subject string) (datatypes.AuthToken, error)
+	CallbackVerifyToken func(token string) (auth.Claims, error)
+	Admin               datatypes.Admin
+}
+
+func (asm *authServiceMock) IssueToken(tenantID string, subject string) (datatypes.AuthToken, error) {
+	if asm.CallbackIssueToken != nil {
+		return asm.CallbackIssueToken(tenantID, subject)
+	}
+	return datatypes.AuthToken{}, asm.Error
+}
+
+func (asm *authServiceMock) VerifyToken(token string) (auth.Claims, error) {
+	if asm.CallbackVerifyToken != nil {
+		return asm.CallbackVerifyToken(token)
+	}
+	return auth.Claims{}, asm.Error
+}
+
+func (asm *authServiceMock) VerifyAPIKey(key string) error {
+	return asm.Error
+}
+
+func (asm *authServiceMock) AuthenticateAdmin(key string) (datatypes.Admin, error) {
+	return asm.Admin, asm.Error
+}
+
+func TestHandlerRequireAPIKey(t *testing.T) {
+	t.Run("should reject requests with an invalid api key", func(t *testing.T) {
+		handlers := NewAuthHandlers(&authServiceMock{Error: auth.ErrInvalidAPIKey}, &tenantServiceMock{})
+
+		app := fiber.New()
+		path := "/api/review"
+		app.Post(path, handlers.RequireAPIKey, func(fc *fiber.Ctx) error { return fc.SendStatus(fiber.StatusOK) })
+
+		req, err := http.NewRequest("POST", path, nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusUnauthorized, result.StatusCode)
+	})
+
+	t.Run("should allow requests with a valid api key", func(t *testing.T) {
+		handlers := NewAuthHandlers(&authServiceMock{}, &tenantServiceMock{})
+
+		app := fiber.New()
+		path := "/api/review"
+		app.Post(path, handlers.RequireAPIKey, func(fc *fiber.Ctx) error { return fc.SendStatus(fiber.StatusOK) })
+
+		req, err := http.NewRequest("POST", path, nil)
+		require.NoError(t, err)
+		req.Header.Add(APIKeyHeader, "qwerty")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+	})
+}
+
+func TestHandlerRequireCustomerToken(t *testing.T) {
+	handlers := NewAuthHandlers(&authServiceMock{
+		CallbackVerifyToken: func(token string) (auth.Claims, error) {
+			if token != "valid-token" {
+				return auth.Claims{}, auth.ErrInvalidToken
+			}
+			return auth.Claims{Subject: "mary@continental.com"}, nil
+		},
+	}, &tenantServiceMock{})
+
+	app := fiber.New()
+	app.Get("/api/ws/:email", handlers.RequireCustomerToken, func(fc *fiber.Ctx) error {
+		return fc.SendString(fc.Locals(CustomerLocal).(string))
+	})
+
+	t.Run("should allow the token owner", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/api/ws/mary@continental.com?token=valid-token", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+	})
+
+	t.Run("should read the token from the authorization header", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/api/ws/mary@continental.com", nil)
+		require.NoError(t, err)
+		req.Header.Add(fiber.HeaderAuthorization, "Bearer valid-token")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+	})
+
+	t.Run("should reject an invalid token", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/api/ws/mary@continental.com?token=invalid-token", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusUnauthorized, result.StatusCode)
+	})
+
+	t.Run("should reject a token issued to another customer", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "/api/ws/john@continental.com?token=valid-token", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusForbidden, result.StatusCode)
+	})
+}
+
+func TestHandlerIssueToken(t *testing.T) {
+	t.Run("should issue a customer token", func(t *testing.T) {
+		handlers := NewAuthHandlers(&authServiceMock{
+			CallbackIssueToken: func(tenantID string, subject string) (datatypes.AuthToken, error) {
+				return datatypes.AuthToken{Token: subject, ExpiresAt: time.Now()}, nil
+			},
+		}, &tenantServiceMock{})
+
+		app := fiber.New()
+		path := "/api/auth/token"
+		app.Post(path, handlers.IssueToken)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(`{"email":"mary@continental.com"}`))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusCreated, result.StatusCode)
+	})
+}
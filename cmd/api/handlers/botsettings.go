@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/botsettings"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+type settingsService interface {
+	Current() chatbot.Settings
+	Reload(ctx context.Context) (chatbot.Settings, error)
+}
+
+type BotSettingsHandlers struct {
+	settingsService settingsService
+}
+
+// NewBotSettingsHandlers
+func NewBotSettingsHandlers(settingsService settingsService) *BotSettingsHandlers {
+	return &BotSettingsHandlers{
+		settingsService: settingsService,
+	}
+}
+
+// GetSettings returns the bot settings of the new chat sessions
+func (bsh *BotSettingsHandlers) GetSettings(fc *fiber.Ctx) error {
+	return fc.JSON(bsh.settingsService.Current())
+}
+
+// ReloadSettings applies the settings file to the new chat sessions. The chats already
+// started keep their settings.
+func (bsh *BotSettingsHandlers) ReloadSettings(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	settings, err := bsh.settingsService.Reload(ctx)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+
+		var errs validation.Errors
+		switch {
+		case errors.Is(err, botsettings.ErrNoSettingsFile):
+			return fc.Status(fiber.StatusConflict).SendString(err.Error())
+		case errors.As(err, &errs):
+			return fc.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"errors": errs})
+		case errors.Is(err, botsettings.ErrInvalidSettingsFile):
+			return fc.Status(fiber.StatusUnprocessableEntity).SendString(err.Error())
+		}
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return fc.JSON(settings)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/botsettings"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type settingsServiceMock struct {
+	Error    error
+	Settings chatbot.Settings
+}
+
+func (ssm *settingsServiceMock) Current() chatbot.Settings {
+	return ssm.Settings
+}
+
+func (ssm *settingsServiceMock) Reload(ctx context.Context) (chatbot.Settings, error) {
+	return ssm.Settings, ssm.Error
+}
+
+func TestHandlerReloadSettings(t *testing.T) {
+	reload := func(t *testing.T, service *settingsServiceMock) *http.Response {
+		app := fiber.New()
+		app.Post("/api/admin/bot/settings/reload", NewBotSettingsHandlers(service).ReloadSettings)
+
+		req, err := http.NewRequest("POST", "/api/admin/bot/settings/reload", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("should return the new settings", func(t *testing.T) {
+		result := reload(t, &settingsServiceMock{Settings: chatbot.DefaultSettings("You are Max")})
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+
+		var settings chatbot.Settings
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&settings))
+		assert.Equal(t, "You are Max", settings.SystemInstruction)
+	})
+
+	t.Run("should list the invalid settings", func(t *testing.T) {
+		result := reload(t, &settingsServiceMock{
+			Error: fmt.Errorf("failed to reload the bot settings. Cause: %w", validation.Errors{"top_p": "must be between 0 and 1"}),
+		})
+		require.Equal(t, fiber.StatusUnprocessableEntity, result.StatusCode)
+
+		var body struct {
+			Errors map[string]string `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&body))
+		assert.Equal(t, "must be between 0 and 1", body.Errors["top_p"])
+	})
+
+	t.Run("should return conflict without a settings file", func(t *testing.T) {
+		result := reload(t, &settingsServiceMock{Error: botsettings.ErrNoSettingsFile})
+		require.Equal(t, fiber.StatusConflict, result.StatusCode)
+	})
+}
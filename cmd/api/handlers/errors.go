@@ -0,0 +1,10 @@
+package handlers
+
+import "errors"
+
+var (
+	ErrSessionNotFound  = errors.New("failed to find chat session. Cause: the user is not connected")
+	ErrChatWithAgent    = errors.New("the chat was handed over to a human agent")
+	ErrChatAlreadyTaken = errors.New("another agent already joined the chat")
+	ErrShuttingDown     = errors.New("the server is shutting down")
+)
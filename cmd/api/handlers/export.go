@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/export"
+	"github.com/gofiber/fiber/v2"
+)
+
+type exportService interface {
+	ExportChat(ctx context.Context, w io.Writer, chatID string, format string) error
+	ExportChats(ctx context.Context, w io.Writer, filter datatypes.ExportFilter, format string) (int, error)
+	CreateJob(ctx context.Context, requestedBy string, filter datatypes.ExportFilter, format string) (datatypes.ExportJob, error)
+	FindJob(ctx context.Context, id string) (datatypes.ExportJob, error)
+	OpenJobFile(ctx context.Context, id string) (datatypes.ExportJob, *os.File, error)
+}
+
+type ExportHandlers struct {
+	exportService exportService
+}
+
+// NewExportHandlers
+func NewExportHandlers(exportService exportService) *ExportHandlers {
+	return &ExportHandlers{
+		exportService: exportService,
+	}
+}
+
+// ExportChat writes the chat transcript in the format query param (json, csv or markdown)
+func (eh *ExportHandlers) ExportChat(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	format := fc.Query("format", export.FormatJSON)
+	if !export.IsValidFormat(format) {
+		return fc.Status(fiber.StatusBadRequest).SendString(export.ErrUnsupportedFormat.Error())
+	}
+
+	var body bytes.Buffer
+	if err := eh.exportService.ExportChat(ctx, &body, fc.Params("id"), format); err != nil {
+		return exportError(ctx, fc, err)
+	}
+
+	fc.Attachment(export.FileName(fmt.Sprintf("chat-%s", fc.Params("id")), format))
+	fc.Set(fiber.HeaderContentType, export.ContentType(format))
+	return fc.Send(body.Bytes())
+}
+
+// ExportChats streams the transcripts of the chats filtered by the userId, email, from and to query params
+func (eh *ExportHandlers) ExportChats(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	format := fc.Query("format", export.FormatJSON)
+	if !export.IsValidFormat(format) {
+		return fc.Status(fiber.StatusBadRequest).SendString(export.ErrUnsupportedFormat.Error())
+	}
+
+	req := datatypes.CreateExportJobRequest{
+		UserID: fc.Query("userId"),
+		Email:  fc.Query("email"),
+		From:   fc.Query("from"),
+		To:     fc.Query("to"),
+	}
+
+	filter, err := req.Filter()
+	if err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	fc.Attachment(export.FileName("chats", format))
+	fc.Set(fiber.HeaderContentType, export.ContentType(format))
+
+	// the stream is written after the handler returns, so the request context can't be used
+	ctx = gocontext.FromContext(context.Background())
+	fc.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if _, err := eh.exportService.ExportChats(ctx, w, filter, format); err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to stream chats export. Cause: %s", err))
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// CreateExportJob schedules a bulk export for large date ranges
+func (eh *ExportHandlers) CreateExportJob(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.CreateExportJobRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	filter, err := req.Filter()
+	if err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	if req.Format == "" {
+		req.Format = export.FormatJSON
+	}
+
+	job, err := eh.exportService.CreateJob(ctx, adminFromContext(fc).Name, filter, req.Format)
+	if err != nil {
+		return exportError(ctx, fc, err)
+	}
+	return fc.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetExportJob returns the export job status
+func (eh *ExportHandlers) GetExportJob(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	job, err := eh.exportService.FindJob(ctx, fc.Params("id"))
+	if err != nil {
+		return exportError(ctx, fc, err)
+	}
+	return fc.JSON(job)
+}
+
+// DownloadExportJob sends the file of a completed export job
+func (eh *ExportHandlers) DownloadExportJob(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	job, file, err := eh.exportService.OpenJobFile(ctx, fc.Params("id"))
+	if err != nil {
+		return exportError(ctx, fc, err)
+	}
+
+	fc.Attachment(export.FileName(fmt.Sprintf("export-%s", job.ID), job.Format))
+	fc.Set(fiber.HeaderContentType, export.ContentType(job.Format))
+	// fiber closes the file after sending it
+	return fc.SendStream(file)
+}
+
+// exportError maps the export service errors to status codes
+func exportError(ctx context.Context, fc *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, export.ErrUnsupportedFormat):
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	case errors.Is(err, export.ErrChatNotFound), errors.Is(err, export.ErrJobNotFound):
+		return fc.SendStatus(fiber.StatusNotFound)
+	case errors.Is(err, export.ErrJobNotCompleted):
+		return fc.Status(fiber.StatusConflict).SendString(err.Error())
+	}
+
+	golog.Log().Error(ctx, err.Error())
+	return fc.SendStatus(fiber.StatusInternalServerError)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/export"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type exportServiceMock struct {
+	Error             error
+	CallbackCreateJob func(ctx context.Context, requestedBy string, filter datatypes.ExportFilter, format string) (datatypes.ExportJob, error)
+}
+
+func (esm *exportServiceMock) ExportChat(ctx context.Context, w io.Writer, chatID string, format string) error {
+	if esm.Error != nil {
+		return esm.Error
+	}
+	_, err := io.WriteString(w, "# Chat "+chatID)
+	return err
+}
+
+func (esm *exportServiceMock) ExportChats(ctx context.Context, w io.Writer, filter datatypes.ExportFilter, format string) (int, error) {
+	return 0, esm.Error
+}
+
+func (esm *exportServiceMock) CreateJob(ctx context.Context, requestedBy string, filter datatypes.ExportFilter, format string) (datatypes.ExportJob, error) {
+	if esm.CallbackCreateJob != nil {
+		return esm.CallbackCreateJob(ctx, requestedBy, filter, format)
+	}
+	return datatypes.ExportJob{}, esm.Error
+}
+
+func (esm *exportServiceMock) FindJob(ctx context.Context, id string) (datatypes.ExportJob, error) {
+	return datatypes.ExportJob{ID: id}, esm.Error
+}
+
+func (esm *exportServiceMock) OpenJobFile(ctx context.Context, id string) (datatypes.ExportJob, *os.File, error) {
+	return datatypes.ExportJob{}, nil, esm.Error
+}
+
+func TestHandlerExportChat(t *testing.T) {
+	path := "/api/chats/:id/export"
+
+	t.Run("should send the transcript as an attachment", func(t *testing.T) {
+		app := fiber.New()
+		app.Get(path, NewExportHandlers(&exportServiceMock{}).ExportChat)
+
+		req, err := http.NewRequest("GET", "/api/chats/qwerty/export?format=markdown", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+		require.Contains(t, result.Header.Get(fiber.HeaderContentType), "text/markdown")
+		require.Contains(t, result.Header.Get(fiber.HeaderContentDisposition), "chat-qwerty.md")
+
+		body, err := io.ReadAll(result.Body)
+		require.NoError(t, err)
+		require.Equal(t, "# Chat qwerty", string(body))
+	})
+
+	t.Run("should return not found when the chat does not exist", func(t *testing.T) {
+		app := fiber.New()
+		app.Get(path, NewExportHandlers(&exportServiceMock{Error: export.ErrChatNotFound}).ExportChat)
+
+		req, err := http.NewRequest("GET", "/api/chats/qwerty/export", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNotFound, result.StatusCode)
+	})
+}
+
+func TestHandlerCreateExportJob(t *testing.T) {
+	path := "/api/exports"
+
+	t.Run("should schedule the job for the admin", func(t *testing.T) {
+		var requestedBy string
+		app := fiber.New()
+		app.Post(path, func(fc *fiber.Ctx) error {
+			fc.Locals(AdminLocal, datatypes.Admin{Name: "alice"})
+			return fc.Next()
+		}, NewExportHandlers(&exportServiceMock{
+			CallbackCreateJob: func(ctx context.Context, by string, filter datatypes.ExportFilter, format string) (datatypes.ExportJob, error) {
+				requestedBy = by
+				return datatypes.ExportJob{ID: "job-1", Format: format, Status: export.JobStatusPending}, nil
+			},
+		}).CreateExportJob)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(`{"from":"2024-01-01","to":"2024-03-31","format":"csv"}`))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusAccepted, result.StatusCode)
+		require.Equal(t, "alice", requestedBy)
+	})
+
+	t.Run("should fail when the date range is invalid", func(t *testing.T) {
+		app := fiber.New()
+		app.Post(path, NewExportHandlers(&exportServiceMock{}).CreateExportJob)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(`{"from":"2024-03-31","to":"2024-01-01"}`))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusBadRequest, result.StatusCode)
+	})
+}
@@ -2,43 +2,117 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
 	"github.com/JhonatanRSantos/gocore/pkg/golog"
 	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/conversation"
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/notification"
+	"github.com/JhonatanRSantos/review-chatbot/internal/redaction"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// RequestAgentCommand is the message a customer sends to talk to a human agent
+const RequestAgentCommand = "/agent"
+
 type connection struct {
-	conn        *websocket.Conn
-	chatID      string
-	chatSession *chatbot.ChatbotServiceSession
+	conn          *websocket.Conn
+	writeMutex    *sync.Mutex
+	tenant        datatypes.Tenant
+	chatID        string
+	conversation  *conversation.Conversation
+	reviewProduct string
+	// agentMode is true after the chat was handed over to a human. The chatbot doesn't answer anymore.
+	agentMode bool
+	agent     *agentConnection
+}
+
+// write sends a message to the customer. Websocket connections don't support concurrent writers.
+func (c connection) write(message string) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return c.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+// writeTraced sends a message to the customer in a child span of the context
+func (c connection) writeTraced(ctx context.Context, message string) error {
+	_, span := tracing.Start(ctx, "websocket.WriteMessage", attribute.String("chat.id", c.chatID))
+	err := c.write(message)
+	tracing.End(span, err)
+	return err
 }
 
 type userService interface {
 	Create(ctx context.Context, firstName string, lastName string, email string) (datatypes.User, error)
 	FindByEmail(ctx context.Context, email string) (datatypes.User, error)
+	FindByID(ctx context.Context, id string) (datatypes.User, error)
+	List(ctx context.Context, search string, includeDeleted bool, page datatypes.Page) ([]datatypes.User, error)
+	Update(ctx context.Context, id string, req datatypes.UpdateUserRequest) (datatypes.User, error)
+	Deactivate(ctx context.Context, id string) error
+	ExportData(ctx context.Context, email string) (datatypes.UserDataExport, error)
+	Forget(ctx context.Context, email string) error
 }
 
 type chatService interface {
 	CreateChat(ctx context.Context, user datatypes.User) (string, error)
 	CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error)
+	CreateRedactedMessage(ctx context.Context, chatID string, author string, message string, pii string) (datatypes.Message, error)
+	StartReview(ctx context.Context, chatID string, product string) error
+	CompleteReview(ctx context.Context, chatID string, user datatypes.User, product string) error
+	EscalateChat(ctx context.Context, chatID string, reason string) error
 }
 
 type chatbotService interface {
-	StartChat() *chatbot.ChatbotServiceSession
+	StartChat(ctx context.Context) *chatbot.ChatbotServiceSession
+}
+
+type notificationService interface {
+	SendReviewInvitation(ctx context.Context, name string, email string, product string) (datatypes.ReviewInvitation, error)
+	OpenInvitation(ctx context.Context, id string, expires string, signature string) (datatypes.ReviewInvitation, error)
+	UseInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) error
+}
+
+type messageAnalyzer interface {
+	AnalyzeMessage(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error)
+}
+
+type piiRedactor interface {
+	NewSession() *redaction.Session
+	RedactText(text string) string
+	StoredMessage(original string, redacted redaction.Redacted) (string, string, error)
+}
+
+type tokenIssuer interface {
+	IssueToken(tenantID string, subject string) (datatypes.AuthToken, error)
 }
 
 type Handlers struct {
-	sessions       map[string]connection
-	sessionMutex   *sync.RWMutex
-	userService    userService
-	chatService    chatService
-	chatbotService chatbotService
+	// sessions are keyed by the tenant and the email of the customer, see sessionKey
+	sessions            map[string]connection
+	sessionMutex        *sync.RWMutex
+	userService         userService
+	chatService         chatService
+	chatbotService      chatbotService
+	notificationService notificationService
+	tokenIssuer         tokenIssuer
+	redactor            piiRedactor
+	conversations       *conversation.ConversationService
+	// draining is true while the server shuts down, guarded by the sessionMutex
+	draining    bool
+	turns       *sync.WaitGroup
+	connections *sync.WaitGroup
 }
 
 // NewHandlers
@@ -46,19 +120,29 @@ func NewHandlers(
 	userService userService,
 	chatService chatService,
 	chatbotService chatbotService,
+	notificationService notificationService,
+	tokenIssuer tokenIssuer,
+	messageAnalyzer messageAnalyzer,
+	redactor piiRedactor,
 ) *Handlers {
 	return &Handlers{
-		sessions:       make(map[string]connection),
-		sessionMutex:   &sync.RWMutex{},
-		userService:    userService,
-		chatService:    chatService,
-		chatbotService: chatbotService,
+		sessions:            make(map[string]connection),
+		sessionMutex:        &sync.RWMutex{},
+		userService:         userService,
+		chatService:         chatService,
+		chatbotService:      chatbotService,
+		notificationService: notificationService,
+		tokenIssuer:         tokenIssuer,
+		redactor:            redactor,
+		conversations:       conversation.NewConversationService(chatService, messageAnalyzer, redactor),
+		turns:               &sync.WaitGroup{},
+		connections:         &sync.WaitGroup{},
 	}
 }
 
 // CreateUser
 func (h *Handlers) CreateUser(fc *fiber.Ctx) error {
-	ctx := gocontext.FromContext(fc.Context())
+	ctx := gocontext.FromContext(fc.UserContext())
 
 	var req datatypes.CreateUserRequest
 
@@ -68,22 +152,55 @@ func (h *Handlers) CreateUser(fc *fiber.Ctx) error {
 	}
 
 	if err := req.Validate(); err != nil {
-		golog.Log().Error(ctx, err.Error())
-		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+		return invalidRequest(ctx, fc, err)
 	}
 
 	user, err := h.userService.Create(ctx, req.FirstName, req.LastName, req.Email)
 	if err != nil {
+		return userError(ctx, fc, err)
+	}
+
+	return fc.JSON(user)
+}
+
+// ExportUserData returns everything stored about the user
+func (h *Handlers) ExportUserData(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	data, err := h.userService.ExportData(ctx, fc.Params("email"))
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return fc.SendStatus(fiber.StatusNotFound)
+		}
 		golog.Log().Error(ctx, err.Error())
 		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	return fc.JSON(user)
+	fc.Set(fiber.HeaderContentDisposition, `attachment; filename="user-data.json"`)
+	return fc.JSON(data)
+}
+
+// ForgetUser disconnects the user and erases all of their data
+func (h *Handlers) ForgetUser(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	email := validation.LookupEmail(fc.Params("email"))
+
+	h.DisconnectUser(ctx, email)
+
+	if err := h.userService.Forget(ctx, email); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return fc.SendStatus(fiber.StatusNotFound)
+		}
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.SendStatus(fiber.StatusNoContent)
 }
 
 // CreateReview
 func (h *Handlers) CreateReview(fc *fiber.Ctx) error {
-	ctx := gocontext.FromContext(fc.Context())
+	ctx := gocontext.FromContext(fc.UserContext())
 	var req datatypes.CreateReviewRequest
 
 	if err := fc.BodyParser(&req); err != nil {
@@ -92,39 +209,171 @@ func (h *Handlers) CreateReview(fc *fiber.Ctx) error {
 	}
 
 	if err := req.Validate(); err != nil {
-		golog.Log().Error(ctx, err.Error())
-		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+		return invalidRequest(ctx, fc, err)
 	}
 
-	h.sessionMutex.Lock()
-	defer h.sessionMutex.Unlock()
+	err := h.startReview(ctx, req.User.Name, req.User.Email, req.Product)
+	if err == nil {
+		return fc.SendStatus(fiber.StatusOK)
+	}
 
-	session, ok := h.sessions[req.User.Email]
-	if !ok {
-		return fc.SendStatus(fiber.StatusNotFound)
+	if errors.Is(err, ErrChatWithAgent) {
+		return fc.Status(fiber.StatusConflict).SendString(err.Error())
 	}
 
-	message := fmt.Sprintf("Start a new review with %s. He just bought a new %s", req.User.Name, req.Product)
-	messageResponse := session.chatSession.SendTextMessage(fc.Context(), message)
+	if errors.Is(err, ErrShuttingDown) {
+		return fc.Status(fiber.StatusServiceUnavailable).SendString(err.Error())
+	}
 
-	if _, err := h.chatService.CreateMessage(fc.Context(), session.chatID, "chatbot", messageResponse); err != nil {
+	if !errors.Is(err, ErrSessionNotFound) {
 		golog.Log().Error(ctx, err.Error())
 		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	if err := session.conn.WriteMessage(websocket.TextMessage, []byte(messageResponse)); err != nil {
-		delete(h.sessions, req.User.Email)
+	invitation, err := h.notificationService.SendReviewInvitation(ctx, req.User.Name, req.User.Email, req.Product)
+	if err != nil {
+		if errors.Is(err, notification.ErrNotificationsDisabled) {
+			return fc.SendStatus(fiber.StatusNotFound)
+		}
 		golog.Log().Error(ctx, err.Error())
 		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	return fc.SendStatus(fiber.StatusOK)
+	return fc.Status(fiber.StatusAccepted).JSON(invitation)
+}
+
+// OpenInvitation tracks the invitation and redirects the user to the web chat. Expired and used
+// invitations answer 410 Gone.
+func (h *Handlers) OpenInvitation(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	invitation, err := h.notificationService.OpenInvitation(ctx, fc.Params("id"), fc.Query("exp"), fc.Query("signature"))
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		switch {
+		case errors.Is(err, notification.ErrInvalidSignature):
+			return fc.SendStatus(fiber.StatusForbidden)
+		case errors.Is(err, notification.ErrInvitationExpired), errors.Is(err, notification.ErrInvitationUsed):
+			return fc.SendStatus(fiber.StatusGone)
+		}
+		return fc.SendStatus(fiber.StatusNotFound)
+	}
+
+	token, err := h.tokenIssuer.IssueToken(invitation.TenantID, invitation.UserEmail)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	query := url.Values{}
+	query.Set("email", invitation.UserEmail)
+	query.Set("token", token.Token)
+	query.Set("invitation", invitation.ID)
+	query.Set("exp", fc.Query("exp"))
+	query.Set("signature", fc.Query("signature"))
+
+	return fc.Redirect(fmt.Sprintf("/?%s", query.Encode()), fiber.StatusFound)
+}
+
+// StartReview starts the review when the user is connected or emails an invitation otherwise
+func (h *Handlers) StartReview(ctx context.Context, name string, email string, product string) error {
+	err := h.startReview(ctx, name, email, product)
+	if errors.Is(err, ErrSessionNotFound) {
+		_, err = h.notificationService.SendReviewInvitation(ctx, name, email, product)
+		if errors.Is(err, notification.ErrNotificationsDisabled) {
+			return ErrSessionNotFound
+		}
+	}
+	return err
+}
+
+// startReview asks the chatbot to start a new review with a connected user. It's a turn of the chat,
+// so the shutdown waits for it to be saved and refuses it once the chats are drained.
+func (h *Handlers) startReview(ctx context.Context, name string, email string, product string) error {
+	if !h.beginTurn() {
+		return ErrShuttingDown
+	}
+	defer h.turns.Done()
+
+	h.sessionMutex.Lock()
+	defer h.sessionMutex.Unlock()
+
+	key := sessionKey(ctx, email)
+	session, ok := h.sessions[key]
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	if session.agentMode {
+		return ErrChatWithAgent
+	}
+
+	greeting, err := h.conversations.StartReview(ctx, session.conversation, name, product)
+	if err != nil {
+		return err
+	}
+
+	if err := session.writeTraced(ctx, greeting); err != nil {
+		delete(h.sessions, key)
+		return err
+	}
+
+	session.reviewProduct = product
+	h.sessions[key] = session
+	return nil
+}
+
+// SessionCount returns the number of connected customers
+func (h *Handlers) SessionCount() int {
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+	return len(h.sessions)
+}
+
+// DisconnectChat closes the websocket connection of the chat, if it's still connected
+func (h *Handlers) DisconnectChat(chatID string) bool {
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+
+	if _, session, ok := h.findSessionByChatID(chatID); ok {
+		session.conn.Close()
+		return true
+	}
+	return false
+}
+
+// DisconnectUser closes the websocket connection of the user of the tenant, if it's still connected
+func (h *Handlers) DisconnectUser(ctx context.Context, email string) {
+	key := sessionKey(ctx, email)
+
+	h.sessionMutex.Lock()
+	session, ok := h.sessions[key]
+	delete(h.sessions, key)
+	h.sessionMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	if session.agent != nil {
+		session.agent.write(customerLeftMessage)
+		session.agent.conn.Close()
+	}
+	session.conn.Close()
 }
 
 // HandleWebsocketConnection
 func (h *Handlers) HandleWebsocketConnection() func(*fiber.Ctx) error {
 	return websocket.New(func(conn *websocket.Conn) {
 		ctx := gocontext.FromContext(context.Background())
+		connTenant, _ := conn.Locals(TenantLocal).(datatypes.Tenant)
+		ctx = tenant.WithTenant(ctx, connTenant)
+
+		if !h.beginConnection() {
+			rejectConnection(conn)
+			return
+		}
+		defer h.connections.Done()
 
 		user, err := h.userService.FindByEmail(ctx, conn.Params("email"))
 		if err != nil {
@@ -140,48 +389,200 @@ func (h *Handlers) HandleWebsocketConnection() func(*fiber.Ctx) error {
 			return
 		}
 
-		chatSession := h.chatbotService.StartChat()
 		h.sessionMutex.Lock()
-		h.sessions[user.Email] = connection{
-			conn:        conn,
-			chatID:      chatID,
-			chatSession: chatSession,
+		if h.draining {
+			h.sessionMutex.Unlock()
+			rejectConnection(conn)
+			return
+		}
+
+		key := sessionKey(ctx, user.Email)
+		h.sessions[key] = connection{
+			conn:         conn,
+			writeMutex:   &sync.Mutex{},
+			tenant:       connTenant,
+			chatID:       chatID,
+			conversation: h.conversations.NewConversation(chatID, h.chatbotService.StartChat(ctx)),
 		}
 		h.sessionMutex.Unlock()
 
+		if invitationID := conn.Query("invitation"); invitationID != "" {
+			h.resumeInvitation(ctx, user, invitationID, conn.Query("exp"), conn.Query("signature"))
+		}
+
 		removeConnection := func() {
 			h.sessionMutex.Lock()
-			delete(h.sessions, user.Email)
+			session := h.sessions[key]
+			if session.chatID == chatID {
+				delete(h.sessions, key)
+			}
 			h.sessionMutex.Unlock()
+
+			if session.chatID != chatID {
+				return
+			}
+
+			if session.agent != nil {
+				session.agent.write(customerLeftMessage)
+				session.agent.conn.Close()
+			}
+
+			if session.reviewProduct != "" {
+				if err := h.chatService.CompleteReview(ctx, chatID, user, session.reviewProduct); err != nil {
+					h.logError(ctx, err.Error())
+				}
+			}
 		}
 
 		for {
-			messageType, message, err := conn.ReadMessage()
+			_, message, err := conn.ReadMessage()
 			if err != nil {
 				removeConnection()
-				golog.Log().Error(ctx, fmt.Sprintf("failed to read message. Cause: %s", err))
+				h.logError(ctx, fmt.Sprintf("failed to read message. Cause: %s", err))
 				break
 			}
 
-			if _, err = h.chatService.CreateMessage(ctx, chatID, "user", string(message)); err != nil {
-				removeConnection()
-				golog.Log().Error(ctx, err.Error())
-				break
+			// the messages sent after the shutdown started aren't saved, the customer was told to reconnect
+			if !h.beginTurn() {
+				continue
 			}
 
-			messageResponse := chatSession.SendTextMessage(ctx, string(message))
+			// every message starts a new trace, the connection can stay open for a long time
+			turnCtx, span := tracing.Start(ctx, "websocket.Turn", attribute.String("chat.id", chatID))
+			err = h.handleCustomerMessage(turnCtx, key, chatID, string(message))
+			tracing.End(span, err)
+			h.turns.Done()
 
-			if _, err = h.chatService.CreateMessage(ctx, chatID, "chatbot", messageResponse); err != nil {
+			if err != nil {
 				removeConnection()
-				golog.Log().Error(ctx, err.Error())
+				h.logError(ctx, err.Error())
 				break
 			}
+		}
+	})
+}
 
-			if err = conn.WriteMessage(messageType, []byte(messageResponse)); err != nil {
-				removeConnection()
-				golog.Log().Error(ctx, fmt.Sprintf("failed to write message. Cause: %s", err))
-				break
+// handleCustomerMessage saves the customer message and answers it with the chatbot,
+// unless the chat was handed over to a human agent. The personal data is replaced by
+// tokens before the message reaches the model.
+func (h *Handlers) handleCustomerMessage(ctx context.Context, key string, chatID string, message string) error {
+	session, ok := h.findSession(key, chatID)
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	redacted, err := h.conversations.CustomerMessage(tenant.WithTenant(ctx, session.tenant), session.conversation, message)
+	if err != nil {
+		return err
+	}
+
+	if session.agentMode {
+		if session.agent != nil {
+			if err := session.agent.write(message); err != nil {
+				h.logError(ctx, fmt.Sprintf("failed to forward message to the agent. Cause: %s", err))
 			}
 		}
-	})
+		return nil
+	}
+
+	if strings.TrimSpace(message) == RequestAgentCommand {
+		return h.requestAgent(ctx, key, session, "requested by the customer")
+	}
+
+	reply, err := h.conversations.Reply(ctx, session.conversation, redacted)
+	if err != nil {
+		return err
+	}
+
+	if err := session.writeTraced(ctx, reply.Text); err != nil {
+		return fmt.Errorf("failed to write message. Cause: %w", err)
+	}
+
+	if reply.Escalate {
+		return h.escalate(ctx, key, chatID, reply.EscalationReason)
+	}
+	return nil
+}
+
+// logError logs the error without the personal data it may contain
+func (h *Handlers) logError(ctx context.Context, message string) {
+	golog.Log().Error(ctx, h.redactor.RedactText(message))
+}
+
+// requestAgent tells the customer a human agent will join and hands the chat over
+func (h *Handlers) requestAgent(ctx context.Context, key string, session connection, reason string) error {
+	if _, err := h.chatService.CreateMessage(ctx, session.chatID, datatypes.AuthorChatbot, agentRequestedMessage); err != nil {
+		return err
+	}
+
+	if err := session.writeTraced(ctx, agentRequestedMessage); err != nil {
+		return err
+	}
+	return h.escalate(ctx, key, session.chatID, reason)
+}
+
+// OnNegativeSentiment hands the chat over to a human agent when the customer is upset
+func (h *Handlers) OnNegativeSentiment(ctx context.Context, analysis datatypes.MessageAnalysis) error {
+	h.sessionMutex.RLock()
+	key, session, ok := h.findSessionByChatID(analysis.ChatID)
+	h.sessionMutex.RUnlock()
+
+	if !ok || session.agentMode {
+		return nil
+	}
+	return h.requestAgent(tenant.WithTenant(ctx, session.tenant), key, session, fmt.Sprintf("negative sentiment (%.2f)", analysis.Sentiment))
+}
+
+// escalate moves the chat to the agent state so the chatbot stops answering
+func (h *Handlers) escalate(ctx context.Context, key string, chatID string, reason string) error {
+	h.sessionMutex.Lock()
+	session, ok := h.sessions[key]
+	if ok && session.chatID == chatID {
+		session.agentMode = true
+		h.sessions[key] = session
+	}
+	h.sessionMutex.Unlock()
+
+	return h.chatService.EscalateChat(ctx, chatID, reason)
+}
+
+// findSession returns the session of the customer if it's still the same chat
+func (h *Handlers) findSession(key string, chatID string) (connection, bool) {
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+
+	session, ok := h.sessions[key]
+	if !ok || session.chatID != chatID {
+		return connection{}, false
+	}
+	return session, true
+}
+
+// resumeInvitation starts the review the user was invited to by email. The invitation is used, so
+// reconnecting with the same link doesn't start the review again.
+func (h *Handlers) resumeInvitation(ctx context.Context, user datatypes.User, invitationID string, expires string, signature string) {
+	invitation, err := h.notificationService.OpenInvitation(ctx, invitationID, expires, signature)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return
+	}
+
+	if invitation.UserEmail != user.Email || invitation.TenantID != tenant.IDFromContext(ctx) {
+		golog.Log().Error(ctx, fmt.Sprintf("invitation %s does not belong to the connected user", invitationID))
+		return
+	}
+
+	if err := h.notificationService.UseInvitation(ctx, invitation); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return
+	}
+
+	if err := h.startReview(ctx, invitation.UserName, user.Email, invitation.Product); err != nil {
+		golog.Log().Error(ctx, err.Error())
+	}
+}
+
+// sessionKey returns the key of the customer session. The same email can be a customer of several tenants.
+func sessionKey(ctx context.Context, email string) string {
+	return tenant.IDFromContext(ctx) + "/" + email
 }
@@ -2,22 +2,40 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/notification"
+	"github.com/JhonatanRSantos/review-chatbot/internal/redaction"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/require"
 )
 
+// testRedactor redacts with the built-in detectors and stores the original messages
+var testRedactor, _ = redaction.NewRedactionService(redaction.RedactionServiceConfig{
+	Detectors: redaction.DefaultDetectors(),
+})
+
 type userServiceMock struct {
 	Error               error
 	CallbackCreate      func(ctx context.Context, firstName string, lastName string, email string) (datatypes.User, error)
 	CallbackFindByEmail func(ctx context.Context, email string) (datatypes.User, error)
+	CallbackFindByID    func(ctx context.Context, id string) (datatypes.User, error)
+	CallbackList        func(ctx context.Context, search string, includeDeleted bool, page datatypes.Page) ([]datatypes.User, error)
+	CallbackUpdate      func(ctx context.Context, id string, req datatypes.UpdateUserRequest) (datatypes.User, error)
+	CallbackDeactivate  func(ctx context.Context, id string) error
+	CallbackExportData  func(ctx context.Context, email string) (datatypes.UserDataExport, error)
+	CallbackForget      func(ctx context.Context, email string) error
 }
 
 func (usm *userServiceMock) Create(ctx context.Context, firstName string, lastName string, email string) (datatypes.User, error) {
@@ -34,10 +52,61 @@ func (usm *userServiceMock) FindByEmail(ctx context.Context, email string) (data
 	return datatypes.User{}, usm.Error
 }
 
+func (usm *userServiceMock) FindByID(ctx context.Context, id string) (datatypes.User, error) {
+	if usm.CallbackFindByID != nil {
+		return usm.CallbackFindByID(ctx, id)
+	}
+	return datatypes.User{}, usm.Error
+}
+
+func (usm *userServiceMock) List(
+	ctx context.Context,
+	search string,
+	includeDeleted bool,
+	page datatypes.Page,
+) ([]datatypes.User, error) {
+	if usm.CallbackList != nil {
+		return usm.CallbackList(ctx, search, includeDeleted, page)
+	}
+	return nil, usm.Error
+}
+
+func (usm *userServiceMock) Update(ctx context.Context, id string, req datatypes.UpdateUserRequest) (datatypes.User, error) {
+	if usm.CallbackUpdate != nil {
+		return usm.CallbackUpdate(ctx, id, req)
+	}
+	return datatypes.User{}, usm.Error
+}
+
+func (usm *userServiceMock) Deactivate(ctx context.Context, id string) error {
+	if usm.CallbackDeactivate != nil {
+		return usm.CallbackDeactivate(ctx, id)
+	}
+	return usm.Error
+}
+
+func (usm *userServiceMock) ExportData(ctx context.Context, email string) (datatypes.UserDataExport, error) {
+	if usm.CallbackExportData != nil {
+		return usm.CallbackExportData(ctx, email)
+	}
+	return datatypes.UserDataExport{}, usm.Error
+}
+
+func (usm *userServiceMock) Forget(ctx context.Context, email string) error {
+	if usm.CallbackForget != nil {
+		return usm.CallbackForget(ctx, email)
+	}
+	return usm.Error
+}
+
 type chatServiceMock struct {
-	Error                 error
-	CallbackCreateChat    func(ctx context.Context, user datatypes.User) (string, error)
-	CallbackCreateMessage func(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error)
+	Error                         error
+	CallbackCreateChat            func(ctx context.Context, user datatypes.User) (string, error)
+	CallbackCreateMessage         func(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error)
+	CallbackStartReview           func(ctx context.Context, chatID string, product string) error
+	CallbackCompleteReview        func(ctx context.Context, chatID string, user datatypes.User, product string) error
+	CallbackEscalateChat          func(ctx context.Context, chatID string, reason string) error
+	CallbackCreateRedactedMessage func(ctx context.Context, chatID string, author string, message string, pii string) (datatypes.Message, error)
 }
 
 func (csm *chatServiceMock) CreateChat(ctx context.Context, user datatypes.User) (string, error) {
@@ -54,17 +123,91 @@ func (csm *chatServiceMock) CreateMessage(ctx context.Context, chatID string, au
 	return datatypes.Message{}, csm.Error
 }
 
+func (csm *chatServiceMock) CreateRedactedMessage(
+	ctx context.Context,
+	chatID string,
+	author string,
+	message string,
+	pii string,
+) (datatypes.Message, error) {
+	if csm.CallbackCreateRedactedMessage != nil {
+		return csm.CallbackCreateRedactedMessage(ctx, chatID, author, message, pii)
+	}
+	return datatypes.Message{}, csm.Error
+}
+
+func (csm *chatServiceMock) StartReview(ctx context.Context, chatID string, product string) error {
+	if csm.CallbackStartReview != nil {
+		return csm.CallbackStartReview(ctx, chatID, product)
+	}
+	return csm.Error
+}
+
+func (csm *chatServiceMock) CompleteReview(ctx context.Context, chatID string, user datatypes.User, product string) error {
+	if csm.CallbackCompleteReview != nil {
+		return csm.CallbackCompleteReview(ctx, chatID, user, product)
+	}
+	return csm.Error
+}
+
+func (csm *chatServiceMock) EscalateChat(ctx context.Context, chatID string, reason string) error {
+	if csm.CallbackEscalateChat != nil {
+		return csm.CallbackEscalateChat(ctx, chatID, reason)
+	}
+	return csm.Error
+}
+
 type chatbotServiceMock struct {
-	CallbackStartChat func() *chatbot.ChatbotServiceSession
+	CallbackStartChat func(ctx context.Context) *chatbot.ChatbotServiceSession
 }
 
-func (cbsm *chatbotServiceMock) StartChat() *chatbot.ChatbotServiceSession {
+func (cbsm *chatbotServiceMock) StartChat(ctx context.Context) *chatbot.ChatbotServiceSession {
 	if cbsm.CallbackStartChat != nil {
-		return cbsm.CallbackStartChat()
+		return cbsm.CallbackStartChat(ctx)
 	}
 	return &chatbot.ChatbotServiceSession{}
 }
 
+type notificationServiceMock struct {
+	Error                        error
+	CallbackSendReviewInvitation func(ctx context.Context, name string, email string, product string) (datatypes.ReviewInvitation, error)
+	CallbackOpenInvitation       func(ctx context.Context, id string, expires string, signature string) (datatypes.ReviewInvitation, error)
+	CallbackUseInvitation        func(ctx context.Context, invitation datatypes.ReviewInvitation) error
+}
+
+func (nsm *notificationServiceMock) SendReviewInvitation(ctx context.Context, name string, email string, product string) (datatypes.ReviewInvitation, error) {
+	if nsm.CallbackSendReviewInvitation != nil {
+		return nsm.CallbackSendReviewInvitation(ctx, name, email, product)
+	}
+	return datatypes.ReviewInvitation{}, nsm.Error
+}
+
+func (nsm *notificationServiceMock) OpenInvitation(ctx context.Context, id string, expires string, signature string) (datatypes.ReviewInvitation, error) {
+	if nsm.CallbackOpenInvitation != nil {
+		return nsm.CallbackOpenInvitation(ctx, id, expires, signature)
+	}
+	return datatypes.ReviewInvitation{}, nsm.Error
+}
+
+func (nsm *notificationServiceMock) UseInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) error {
+	if nsm.CallbackUseInvitation != nil {
+		return nsm.CallbackUseInvitation(ctx, invitation)
+	}
+	return nsm.Error
+}
+
+type messageAnalyzerMock struct {
+	Error                  error
+	CallbackAnalyzeMessage func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error)
+}
+
+func (mam *messageAnalyzerMock) AnalyzeMessage(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+	if mam.CallbackAnalyzeMessage != nil {
+		return mam.CallbackAnalyzeMessage(ctx, message)
+	}
+	return datatypes.MessageAnalysis{}, mam.Error
+}
+
 func TestHandlerCreateUser(t *testing.T) {
 	t.Run("should create a new user", func(t *testing.T) {
 		mockedUser := datatypes.User{
@@ -82,6 +225,10 @@ func TestHandlerCreateUser(t *testing.T) {
 			},
 			&chatServiceMock{},
 			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
 		)
 
 		mockedUserBs, err := json.Marshal(mockedUser)
@@ -107,4 +254,333 @@ func TestHandlerCreateUser(t *testing.T) {
 		require.NoError(t, json.Unmarshal(rawBody, &user))
 		require.EqualValues(t, mockedUser, user)
 	})
+
+	t.Run("should create the user with the normalized email", func(t *testing.T) {
+		var createdEmail string
+		handlers := NewHandlers(
+			&userServiceMock{
+				CallbackCreate: func(ctx context.Context, firstName, lastName, email string) (datatypes.User, error) {
+					createdEmail = email
+					return datatypes.User{Email: email}, nil
+				},
+			},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Post("/api/user", handlers.CreateUser)
+
+		body := `{"firstName":"john","lastName":"wick","email":" John.Wick@Continental.COM "}`
+		req, err := http.NewRequest("POST", "/api/user", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+		require.Equal(t, "john.wick@continental.com", createdEmail)
+	})
+
+	t.Run("should return the invalid fields", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Post("/api/user", handlers.CreateUser)
+
+		req, err := http.NewRequest("POST", "/api/user", strings.NewReader(`{"firstName":"john","email":"abc"}`))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusBadRequest, result.StatusCode)
+
+		var response struct {
+			Errors map[string]string `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+		require.Equal(t, map[string]string{
+			"lastName": validation.MsgRequired,
+			"email":    validation.MsgInvalidEmail,
+		}, response.Errors)
+	})
+}
+
+func TestHandlerCreateReview(t *testing.T) {
+	body := `{"user":{"name":"Mary Ann","email":"mary@continental.com"},"product":"Galaxy S24"}`
+
+	t.Run("should email an invitation when the user is not connected", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{
+				CallbackSendReviewInvitation: func(ctx context.Context, name, email, product string) (datatypes.ReviewInvitation, error) {
+					return datatypes.ReviewInvitation{ID: "qwerty", UserName: name, UserEmail: email, Product: product}, nil
+				},
+			},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		path := "/api/review"
+		app.Post(path, handlers.CreateReview)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusAccepted, result.StatusCode)
+
+		var invitation datatypes.ReviewInvitation
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&invitation))
+		require.Equal(t, "mary@continental.com", invitation.UserEmail)
+		require.Equal(t, "Mary Ann", invitation.UserName)
+		require.Equal(t, "Galaxy S24", invitation.Product)
+	})
+
+	t.Run("should return not found when emails are disabled", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{Error: notification.ErrNotificationsDisabled},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		path := "/api/review"
+		app.Post(path, handlers.CreateReview)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNotFound, result.StatusCode)
+	})
+
+	t.Run("should return the invalid fields", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		path := "/api/review"
+		app.Post(path, handlers.CreateReview)
+
+		body := `{"user":{"name":"Mary Ann","email":"Mary Ann <mary@continental.com>"}}`
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusBadRequest, result.StatusCode)
+
+		var response struct {
+			Errors map[string]string `json:"errors"`
+		}
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+		require.Equal(t, map[string]string{
+			"user.email": validation.MsgInvalidEmail,
+			"product":    validation.MsgRequired,
+		}, response.Errors)
+	})
+}
+
+func TestHandlerOpenInvitation(t *testing.T) {
+	path := "/api/invitations/:id/open"
+
+	t.Run("should redirect to the web chat with the link expiration", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{
+				CallbackOpenInvitation: func(ctx context.Context, id, expires, signature string) (datatypes.ReviewInvitation, error) {
+					require.Equal(t, "1700000000", expires)
+					return datatypes.ReviewInvitation{ID: id, UserEmail: "mary@continental.com"}, nil
+				},
+			},
+			&authServiceMock{
+				CallbackIssueToken: func(tenantID, subject string) (datatypes.AuthToken, error) {
+					return datatypes.AuthToken{Token: "token"}, nil
+				},
+			},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Get(path, handlers.OpenInvitation)
+
+		req, err := http.NewRequest("GET", "/api/invitations/qwerty/open?exp=1700000000&signature=abc", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusFound, result.StatusCode)
+
+		location, err := url.Parse(result.Header.Get("Location"))
+		require.NoError(t, err)
+		require.Equal(t, "qwerty", location.Query().Get("invitation"))
+		require.Equal(t, "1700000000", location.Query().Get("exp"))
+		require.Equal(t, "abc", location.Query().Get("signature"))
+	})
+
+	t.Run("should map the invitation errors", func(t *testing.T) {
+		for err, status := range map[error]int{
+			notification.ErrInvalidSignature:  fiber.StatusForbidden,
+			notification.ErrInvitationExpired: fiber.StatusGone,
+			notification.ErrInvitationUsed:    fiber.StatusGone,
+			sql.ErrNoRows:                     fiber.StatusNotFound,
+		} {
+			handlers := NewHandlers(
+				&userServiceMock{},
+				&chatServiceMock{},
+				&chatbotServiceMock{},
+				&notificationServiceMock{Error: err},
+				&authServiceMock{},
+				&messageAnalyzerMock{},
+				testRedactor,
+			)
+
+			app := fiber.New()
+			app.Get(path, handlers.OpenInvitation)
+
+			req, reqErr := http.NewRequest("GET", "/api/invitations/qwerty/open?exp=1&signature=abc", nil)
+			require.NoError(t, reqErr)
+
+			result, reqErr := app.Test(req)
+			require.NoError(t, reqErr)
+			require.Equal(t, status, result.StatusCode, err.Error())
+		}
+	})
+}
+
+func TestHandlerExportUserData(t *testing.T) {
+	t.Run("should return the user data", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{
+				CallbackExportData: func(ctx context.Context, email string) (datatypes.UserDataExport, error) {
+					return datatypes.UserDataExport{
+						User:  datatypes.User{ID: "qwerty", Email: email},
+						Chats: []datatypes.Transcript{{Chat: datatypes.Chat{ID: "chat-1"}}},
+					}, nil
+				},
+			},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Get("/api/users/:email/data", handlers.ExportUserData)
+
+		result, err := app.Test(httptest.NewRequest("GET", "/api/users/john@mail.com/data", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+
+		var data datatypes.UserDataExport
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&data))
+		require.Equal(t, "john@mail.com", data.User.Email)
+		require.Len(t, data.Chats, 1)
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{Error: user.ErrUserNotFound},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Get("/api/users/:email/data", handlers.ExportUserData)
+
+		result, err := app.Test(httptest.NewRequest("GET", "/api/users/john@mail.com/data", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNotFound, result.StatusCode)
+	})
+}
+
+func TestHandlerForgetUser(t *testing.T) {
+	t.Run("should erase the user data", func(t *testing.T) {
+		var forgotten string
+
+		handlers := NewHandlers(
+			&userServiceMock{
+				CallbackForget: func(ctx context.Context, email string) error {
+					forgotten = email
+					return nil
+				},
+			},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Delete("/api/users/:email", handlers.ForgetUser)
+
+		result, err := app.Test(httptest.NewRequest("DELETE", "/api/users/john@mail.com", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNoContent, result.StatusCode)
+		require.Equal(t, "john@mail.com", forgotten)
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		handlers := NewHandlers(
+			&userServiceMock{Error: user.ErrUserNotFound},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+
+		app := fiber.New()
+		app.Delete("/api/users/:email", handlers.ForgetUser)
+
+		result, err := app.Test(httptest.NewRequest("DELETE", "/api/users/john@mail.com", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNotFound, result.StatusCode)
+	})
 }
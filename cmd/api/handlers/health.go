@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+)
+
+type healthService interface {
+	Readiness(ctx context.Context) datatypes.Readiness
+}
+
+type HealthHandlers struct {
+	healthService healthService
+}
+
+// NewHealthHandlers
+func NewHealthHandlers(healthService healthService) *HealthHandlers {
+	return &HealthHandlers{
+		healthService: healthService,
+	}
+}
+
+// Live tells the process is running. It doesn't check the dependencies, so a database outage
+// doesn't restart every instance.
+func (hh *HealthHandlers) Live(fc *fiber.Ctx) error {
+	return fc.JSON(fiber.Map{"status": datatypes.HealthStatusUp})
+}
+
+// Ready checks the dependencies and answers 503 when any of them is down
+func (hh *HealthHandlers) Ready(fc *fiber.Ctx) error {
+	readiness := hh.healthService.Readiness(gocontext.FromContext(fc.UserContext()))
+	if readiness.Status != datatypes.HealthStatusUp {
+		return fc.Status(fiber.StatusServiceUnavailable).JSON(readiness)
+	}
+	return fc.JSON(readiness)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type healthServiceMock struct {
+	Result datatypes.Readiness
+}
+
+func (hsm *healthServiceMock) Readiness(ctx context.Context) datatypes.Readiness {
+	return hsm.Result
+}
+
+func TestHandlerReady(t *testing.T) {
+	t.Run("should answer ok when the dependencies are up", func(t *testing.T) {
+		handlers := NewHealthHandlers(&healthServiceMock{Result: datatypes.Readiness{
+			Status:       datatypes.HealthStatusUp,
+			Dependencies: map[string]datatypes.DependencyHealth{"database": {Status: datatypes.HealthStatusUp}},
+		}})
+
+		app := fiber.New()
+		app.Get("/readyz", handlers.Ready)
+
+		req, err := http.NewRequest("GET", "/readyz", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+	})
+
+	t.Run("should answer unavailable with the failed dependency", func(t *testing.T) {
+		handlers := NewHealthHandlers(&healthServiceMock{Result: datatypes.Readiness{
+			Status: datatypes.HealthStatusDown,
+			Dependencies: map[string]datatypes.DependencyHealth{
+				"database":   {Status: datatypes.HealthStatusUp},
+				"migrations": {Status: datatypes.HealthStatusDown, Error: "there are pending migrations: 12"},
+			},
+		}})
+
+		app := fiber.New()
+		app.Get("/readyz", handlers.Ready)
+
+		req, err := http.NewRequest("GET", "/readyz", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusServiceUnavailable, result.StatusCode)
+
+		var readiness datatypes.Readiness
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&readiness))
+		assert.Equal(t, "there are pending migrations: 12", readiness.Dependencies["migrations"].Error)
+	})
+}
+
+func TestHandlerLive(t *testing.T) {
+	t.Run("should answer ok without checking the dependencies", func(t *testing.T) {
+		handlers := NewHealthHandlers(&healthServiceMock{Result: datatypes.Readiness{Status: datatypes.HealthStatusDown}})
+
+		app := fiber.New()
+		app.Get("/healthz", handlers.Live)
+
+		req, err := http.NewRequest("GET", "/healthz", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+	})
+}
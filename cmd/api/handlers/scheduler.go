@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+)
+
+type schedulerService interface {
+	ScheduleReview(ctx context.Context, req datatypes.OrderDeliveredRequest) (datatypes.ReviewJob, error)
+	ScheduleReviews(ctx context.Context, reqs []datatypes.OrderDeliveredRequest) datatypes.ScheduleReviewsResponse
+}
+
+type SchedulerHandlers struct {
+	schedulerService schedulerService
+}
+
+// NewSchedulerHandlers
+func NewSchedulerHandlers(schedulerService schedulerService) *SchedulerHandlers {
+	return &SchedulerHandlers{
+		schedulerService: schedulerService,
+	}
+}
+
+// OrderDelivered schedules the review of a delivered order
+func (sh *SchedulerHandlers) OrderDelivered(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.OrderDeliveredRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	job, err := sh.schedulerService.ScheduleReview(ctx, req)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrReviewAlreadyScheduled) {
+			return fc.Status(fiber.StatusConflict).SendString(err.Error())
+		}
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.Status(fiber.StatusCreated).JSON(job)
+}
+
+// OrdersDeliveredBatch schedules the review of a batch of delivered orders. The response has the result of every order.
+func (sh *SchedulerHandlers) OrdersDeliveredBatch(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.OrderDeliveredBatchRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	return fc.JSON(sh.schedulerService.ScheduleReviews(ctx, req.Orders))
+}
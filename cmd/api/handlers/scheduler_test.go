@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/scheduler"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+type schedulerServiceMock struct {
+	Error                   error
+	CallbackScheduleReview  func(ctx context.Context, req datatypes.OrderDeliveredRequest) (datatypes.ReviewJob, error)
+	CallbackScheduleReviews func(ctx context.Context, reqs []datatypes.OrderDeliveredRequest) datatypes.ScheduleReviewsResponse
+}
+
+func (ssm *schedulerServiceMock) ScheduleReview(ctx context.Context, req datatypes.OrderDeliveredRequest) (datatypes.ReviewJob, error) {
+	if ssm.CallbackScheduleReview != nil {
+		return ssm.CallbackScheduleReview(ctx, req)
+	}
+	return datatypes.ReviewJob{}, ssm.Error
+}
+
+func (ssm *schedulerServiceMock) ScheduleReviews(ctx context.Context, reqs []datatypes.OrderDeliveredRequest) datatypes.ScheduleReviewsResponse {
+	if ssm.CallbackScheduleReviews != nil {
+		return ssm.CallbackScheduleReviews(ctx, reqs)
+	}
+	return datatypes.ScheduleReviewsResponse{}
+}
+
+func TestHandlerOrderDelivered(t *testing.T) {
+	body := fmt.Sprintf(
+		`{"orderId":"order-1","user":{"name":"Mary Ann","email":"mary@continental.com"},"product":"Galaxy S24","deliveredAt":"%s"}`,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+
+	t.Run("should schedule a new review", func(t *testing.T) {
+		handlers := NewSchedulerHandlers(&schedulerServiceMock{
+			CallbackScheduleReview: func(ctx context.Context, req datatypes.OrderDeliveredRequest) (datatypes.ReviewJob, error) {
+				return datatypes.ReviewJob{OrderID: req.OrderID, UserName: req.User.Name}, nil
+			},
+		})
+
+		app := fiber.New()
+		path := "/api/orders/delivered"
+		app.Post(path, handlers.OrderDelivered)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusCreated, result.StatusCode)
+
+		var job datatypes.ReviewJob
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&job))
+		require.Equal(t, "order-1", job.OrderID)
+		require.Equal(t, "Mary Ann", job.UserName)
+	})
+
+	t.Run("should return conflict for duplicated orders", func(t *testing.T) {
+		handlers := NewSchedulerHandlers(&schedulerServiceMock{
+			Error: scheduler.ErrReviewAlreadyScheduled,
+		})
+
+		app := fiber.New()
+		path := "/api/orders/delivered"
+		app.Post(path, handlers.OrderDelivered)
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusConflict, result.StatusCode)
+	})
+}
+
+func TestHandlerOrdersDeliveredBatch(t *testing.T) {
+	path := "/api/orders/delivered/batch"
+	order := func(orderID string) string {
+		return fmt.Sprintf(
+			`{"orderId":"%s","user":{"name":"Mary Ann","email":"mary@continental.com"},"product":"Galaxy S24","deliveredAt":"%s"}`,
+			orderID,
+			time.Now().UTC().Format(time.RFC3339),
+		)
+	}
+
+	t.Run("should return the result of every order", func(t *testing.T) {
+		handlers := NewSchedulerHandlers(&schedulerServiceMock{
+			CallbackScheduleReviews: func(ctx context.Context, reqs []datatypes.OrderDeliveredRequest) datatypes.ScheduleReviewsResponse {
+				return datatypes.ScheduleReviewsResponse{Results: []datatypes.ScheduleReviewResult{
+					{OrderID: reqs[0].OrderID, Status: scheduler.ScheduleStatusScheduled},
+					{OrderID: reqs[1].OrderID, Status: scheduler.ScheduleStatusFailed, Error: "database is down"},
+				}}
+			},
+		})
+
+		app := fiber.New()
+		app.Post(path, handlers.OrdersDeliveredBatch)
+
+		body := fmt.Sprintf(`{"orders":[%s,%s]}`, order("order-1"), order("order-2"))
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+
+		var response datatypes.ScheduleReviewsResponse
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&response))
+		require.Len(t, response.Results, 2)
+		require.Equal(t, scheduler.ScheduleStatusFailed, response.Results[1].Status)
+	})
+
+	t.Run("should reject batches over the maximum size", func(t *testing.T) {
+		handlers := NewSchedulerHandlers(&schedulerServiceMock{
+			CallbackScheduleReviews: func(ctx context.Context, reqs []datatypes.OrderDeliveredRequest) datatypes.ScheduleReviewsResponse {
+				t.Fatal("the batch must not be scheduled")
+				return datatypes.ScheduleReviewsResponse{}
+			},
+		})
+
+		app := fiber.New()
+		app.Post(path, handlers.OrdersDeliveredBatch)
+
+		orders := make([]string, 501)
+		for i := range orders {
+			orders[i] = order(fmt.Sprintf("order-%d", i))
+		}
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(fmt.Sprintf(`{"orders":[%s]}`, strings.Join(orders, ","))))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusBadRequest, result.StatusCode)
+	})
+}
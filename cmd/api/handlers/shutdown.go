@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+)
+
+const (
+	serverRestartingMessage = "The server is restarting. Please reconnect in a few seconds, your chat was saved."
+	closeWriteTimeout       = time.Second
+)
+
+// beginConnection registers a new websocket connection, unless the server is shutting down
+func (h *Handlers) beginConnection() bool {
+	h.sessionMutex.Lock()
+	defer h.sessionMutex.Unlock()
+
+	if h.draining {
+		return false
+	}
+	h.connections.Add(1)
+	return true
+}
+
+// beginTurn registers a message being answered and saved, unless the server is shutting down
+func (h *Handlers) beginTurn() bool {
+	h.sessionMutex.Lock()
+	defer h.sessionMutex.Unlock()
+
+	if h.draining {
+		return false
+	}
+	h.turns.Add(1)
+	return true
+}
+
+// Shutdown drains the web chats: new connections and messages are refused, the connected customers
+// and agents are told the server is restarting, the messages being answered are finished and saved,
+// their analyses are finished, then every connection is closed. It gives up when the context is done.
+func (h *Handlers) Shutdown(ctx context.Context) error {
+	h.sessionMutex.Lock()
+	h.draining = true
+	sessions := make([]connection, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	h.sessionMutex.Unlock()
+
+	for _, session := range sessions {
+		session.write(serverRestartingMessage)
+		if session.agent != nil {
+			session.agent.write(serverRestartingMessage)
+		}
+	}
+
+	// the analyses are started by the turns, none is added once they are finished
+	turnsErr := wait(ctx, h.turns)
+	if turnsErr == nil {
+		turnsErr = h.conversations.Wait(ctx)
+	}
+
+	for _, session := range sessions {
+		closeConnection(session.conn, session.writeMutex)
+		if session.agent != nil {
+			closeConnection(session.agent.conn, session.agent.writeMutex)
+		}
+	}
+
+	if err := wait(ctx, h.connections); err != nil || turnsErr != nil {
+		return fmt.Errorf("failed to drain the web chats. Cause: %w", ctx.Err())
+	}
+	return nil
+}
+
+// rejectConnection tells the customer the server is restarting and closes the connection
+func rejectConnection(conn *websocket.Conn) {
+	writeMutex := &sync.Mutex{}
+	conn.WriteMessage(websocket.TextMessage, []byte(serverRestartingMessage))
+	closeConnection(conn, writeMutex)
+}
+
+// closeConnection sends the service restart close frame, so the browser knows it can reconnect, and closes the connection
+func closeConnection(conn *websocket.Conn, writeMutex *sync.Mutex) {
+	writeMutex.Lock()
+	defer writeMutex.Unlock()
+
+	conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting"),
+		time.Now().Add(closeWriteTimeout),
+	)
+	conn.Close()
+}
+
+// wait waits for the group or the context, whatever comes first
+func wait(ctx context.Context, group *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerShutdown(t *testing.T) {
+	t.Run("should wait for the messages being answered", func(t *testing.T) {
+		handlers := newUserHandlers(&userServiceMock{})
+		require.True(t, handlers.beginTurn())
+
+		answered := false
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			answered = true
+			handlers.turns.Done()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, handlers.Shutdown(ctx))
+		assert.True(t, answered)
+	})
+
+	t.Run("should wait for the message analyses", func(t *testing.T) {
+		analyzed := false
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{},
+			&authServiceMock{},
+			&messageAnalyzerMock{
+				CallbackAnalyzeMessage: func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+					time.Sleep(20 * time.Millisecond)
+					analyzed = true
+					return datatypes.MessageAnalysis{}, nil
+				},
+			},
+			testRedactor,
+		)
+
+		_, err := handlers.conversations.CustomerMessage(context.Background(), handlers.conversations.NewConversation("qwerty", nil), "my phone is broken")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, handlers.Shutdown(ctx))
+		assert.True(t, analyzed)
+	})
+
+	t.Run("should refuse new connections and messages", func(t *testing.T) {
+		handlers := newUserHandlers(&userServiceMock{})
+		require.NoError(t, handlers.Shutdown(context.Background()))
+
+		assert.False(t, handlers.beginConnection())
+		assert.False(t, handlers.beginTurn())
+	})
+
+	t.Run("should count the reviews started as turns", func(t *testing.T) {
+		handlers := newUserHandlers(&userServiceMock{})
+
+		err := handlers.startReview(context.Background(), "Mary", "mary@continental.com", "Galaxy S24")
+		assert.ErrorIs(t, err, ErrSessionNotFound)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, handlers.Shutdown(ctx))
+		assert.ErrorIs(t, handlers.StartReview(context.Background(), "Mary", "mary@continental.com", "Galaxy S24"), ErrShuttingDown)
+	})
+
+	t.Run("should give up after the deadline", func(t *testing.T) {
+		handlers := newUserHandlers(&userServiceMock{})
+		require.True(t, handlers.beginConnection())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, handlers.Shutdown(ctx), context.DeadlineExceeded)
+	})
+}
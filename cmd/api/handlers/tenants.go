@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TenantLocal is the fiber local holding the datatypes.Tenant of the request
+const TenantLocal = "tenant"
+
+type tenantService interface {
+	FindByHost(host string) datatypes.Tenant
+	List(ctx context.Context) ([]datatypes.Tenant, error)
+	Create(ctx context.Context, req datatypes.CreateTenantRequest) (datatypes.CreatedTenant, error)
+}
+
+type TenantHandlers struct {
+	tenantService tenantService
+}
+
+// NewTenantHandlers
+func NewTenantHandlers(tenantService tenantService) *TenantHandlers {
+	return &TenantHandlers{
+		tenantService: tenantService,
+	}
+}
+
+// ResolveHost sets the tenant of the request host. The api keys and the customer tokens of a
+// tenant switch the request to their own tenant.
+func (th *TenantHandlers) ResolveHost(fc *fiber.Ctx) error {
+	setTenant(fc, th.tenantService.FindByHost(fc.Hostname()))
+	return fc.Next()
+}
+
+// CreateTenant creates a tenant. Its api key is only returned in this response.
+func (th *TenantHandlers) CreateTenant(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.CreateTenantRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	created, err := th.tenantService.Create(ctx, req)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		if errors.Is(err, tenant.ErrDomainAlreadyUsed) {
+			return fc.Status(fiber.StatusConflict).SendString(err.Error())
+		}
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.Status(fiber.StatusCreated).JSON(created)
+}
+
+// ListTenants lists the tenants, the default one first
+func (th *TenantHandlers) ListTenants(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	tenants, err := th.tenantService.List(ctx)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	return fc.JSON(tenants)
+}
+
+// setTenant switches the request to the tenant
+func setTenant(fc *fiber.Ctx, requestTenant datatypes.Tenant) {
+	fc.SetUserContext(tenant.WithTenant(fc.UserContext(), requestTenant))
+	fc.Locals(TenantLocal, requestTenant)
+}
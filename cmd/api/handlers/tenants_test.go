@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var acmeTenant = datatypes.Tenant{ID: "acme", Name: "Acme", Domain: "acme.com"}
+
+type tenantServiceMock struct {
+	Error          error
+	CallbackCreate func(ctx context.Context, req datatypes.CreateTenantRequest) (datatypes.CreatedTenant, error)
+}
+
+func (tsm *tenantServiceMock) FindByID(id string) (datatypes.Tenant, error) {
+	switch id {
+	case tenant.DefaultID:
+		return datatypes.Tenant{ID: tenant.DefaultID}, nil
+	case acmeTenant.ID:
+		return acmeTenant, nil
+	}
+	return datatypes.Tenant{}, tenant.ErrTenantNotFound
+}
+
+func (tsm *tenantServiceMock) FindByAPIKey(key string) (datatypes.Tenant, error) {
+	if key == "acme-key" {
+		return acmeTenant, nil
+	}
+	return datatypes.Tenant{}, tenant.ErrTenantNotFound
+}
+
+func (tsm *tenantServiceMock) FindByHost(host string) datatypes.Tenant {
+	if host == acmeTenant.Domain {
+		return acmeTenant
+	}
+	return datatypes.Tenant{ID: tenant.DefaultID}
+}
+
+func (tsm *tenantServiceMock) List(ctx context.Context) ([]datatypes.Tenant, error) {
+	return []datatypes.Tenant{{ID: tenant.DefaultID}, acmeTenant}, tsm.Error
+}
+
+func (tsm *tenantServiceMock) Create(ctx context.Context, req datatypes.CreateTenantRequest) (datatypes.CreatedTenant, error) {
+	if tsm.CallbackCreate != nil {
+		return tsm.CallbackCreate(ctx, req)
+	}
+	return datatypes.CreatedTenant{}, tsm.Error
+}
+
+// readBody reads the whole response body
+func readBody(t *testing.T, result *http.Response) string {
+	body, err := io.ReadAll(result.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// tenantIDHandler answers with the tenant of the request context
+func tenantIDHandler(fc *fiber.Ctx) error {
+	return fc.SendString(tenant.IDFromContext(fc.UserContext()))
+}
+
+func TestHandlerResolveHost(t *testing.T) {
+	app := fiber.New()
+	app.Use(NewTenantHandlers(&tenantServiceMock{}).ResolveHost)
+	app.Get("/api/tenant", tenantIDHandler)
+
+	t.Run("should use the tenant of the host", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://acme.com/api/tenant", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, acmeTenant.ID, readBody(t, result))
+	})
+
+	t.Run("should use the default tenant on unknown hosts", func(t *testing.T) {
+		req, err := http.NewRequest("GET", "http://localhost:9000/api/tenant", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, tenant.DefaultID, readBody(t, result))
+	})
+}
+
+func TestHandlerTenantAuthentication(t *testing.T) {
+	t.Run("should switch to the tenant of the api key", func(t *testing.T) {
+		handlers := NewAuthHandlers(&authServiceMock{Error: auth.ErrInvalidAPIKey}, &tenantServiceMock{})
+
+		app := fiber.New()
+		app.Post("/api/review", handlers.RequireAPIKey, tenantIDHandler)
+
+		req, err := http.NewRequest("POST", "/api/review", nil)
+		require.NoError(t, err)
+		req.Header.Add(APIKeyHeader, "acme-key")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+		assert.Equal(t, acmeTenant.ID, readBody(t, result))
+	})
+
+	t.Run("should issue the token for the tenant of the request", func(t *testing.T) {
+		var tokenTenant string
+		handlers := NewAuthHandlers(&authServiceMock{
+			Error: auth.ErrInvalidAPIKey,
+			CallbackIssueToken: func(tenantID string, subject string) (datatypes.AuthToken, error) {
+				tokenTenant = tenantID
+				return datatypes.AuthToken{Token: subject}, nil
+			},
+		}, &tenantServiceMock{})
+
+		app := fiber.New()
+		app.Post("/api/auth/token", handlers.RequireAPIKey, handlers.IssueToken)
+
+		req, err := http.NewRequest("POST", "/api/auth/token", strings.NewReader(`{"email":"mary@continental.com"}`))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add(APIKeyHeader, "acme-key")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusCreated, result.StatusCode)
+		assert.Equal(t, acmeTenant.ID, tokenTenant)
+	})
+
+	customerToken := func(t *testing.T, claims auth.Claims) *http.Response {
+		handlers := NewAuthHandlers(&authServiceMock{
+			CallbackVerifyToken: func(token string) (auth.Claims, error) {
+				return claims, nil
+			},
+		}, &tenantServiceMock{})
+
+		app := fiber.New()
+		app.Get("/api/ws/:email", handlers.RequireCustomerToken, tenantIDHandler)
+
+		req, err := http.NewRequest("GET", "/api/ws/mary@continental.com?token=valid-token", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("should switch to the tenant of the customer token", func(t *testing.T) {
+		result := customerToken(t, auth.Claims{Subject: "mary@continental.com", Tenant: acmeTenant.ID})
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+		assert.Equal(t, acmeTenant.ID, readBody(t, result))
+	})
+
+	t.Run("should use the default tenant for the tokens issued before the tenants", func(t *testing.T) {
+		result := customerToken(t, auth.Claims{Subject: "mary@continental.com"})
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+		assert.Equal(t, tenant.DefaultID, readBody(t, result))
+	})
+
+	t.Run("should reject tokens of unknown tenants", func(t *testing.T) {
+		result := customerToken(t, auth.Claims{Subject: "mary@continental.com", Tenant: "unknown"})
+		require.Equal(t, fiber.StatusUnauthorized, result.StatusCode)
+	})
+}
+
+func TestHandlerCreateTenant(t *testing.T) {
+	create := func(t *testing.T, service *tenantServiceMock, body string) *http.Response {
+		app := fiber.New()
+		app.Post("/api/admin/tenants", NewTenantHandlers(service).CreateTenant)
+
+		req, err := http.NewRequest("POST", "/api/admin/tenants", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		return result
+	}
+
+	body := `{"name":"Acme","domain":"acme.com","supportEmail":"support@acme.com"}`
+
+	t.Run("should return the api key of the new tenant", func(t *testing.T) {
+		result := create(t, &tenantServiceMock{
+			CallbackCreate: func(ctx context.Context, req datatypes.CreateTenantRequest) (datatypes.CreatedTenant, error) {
+				return datatypes.CreatedTenant{Tenant: datatypes.Tenant{ID: "acme", Domain: req.Domain}, APIKey: "qwerty"}, nil
+			},
+		}, body)
+		require.Equal(t, fiber.StatusCreated, result.StatusCode)
+
+		var created datatypes.CreatedTenant
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&created))
+		assert.Equal(t, "acme.com", created.Domain)
+		assert.Equal(t, "qwerty", created.APIKey)
+	})
+
+	t.Run("should reject a domain already used", func(t *testing.T) {
+		result := create(t, &tenantServiceMock{Error: tenant.ErrDomainAlreadyUsed}, body)
+		require.Equal(t, fiber.StatusConflict, result.StatusCode)
+	})
+
+	t.Run("should reject invalid prompts", func(t *testing.T) {
+		result := create(t, &tenantServiceMock{}, `{"name":"Acme","domain":"acme.com","supportEmail":"support@acme.com","prompt":"{{.Name"}`)
+		require.Equal(t, fiber.StatusBadRequest, result.StatusCode)
+		assert.Contains(t, readBody(t, result), "is not a valid template")
+	})
+}
+
+func TestHandlerTenantSessions(t *testing.T) {
+	t.Run("should not reach the customer with the same email in another tenant", func(t *testing.T) {
+		var invitationTenant string
+		handlers := NewHandlers(
+			&userServiceMock{},
+			&chatServiceMock{},
+			&chatbotServiceMock{},
+			&notificationServiceMock{
+				CallbackSendReviewInvitation: func(ctx context.Context, name, email, product string) (datatypes.ReviewInvitation, error) {
+					invitationTenant = tenant.IDFromContext(ctx)
+					return datatypes.ReviewInvitation{ID: "qwerty"}, nil
+				},
+			},
+			&authServiceMock{},
+			&messageAnalyzerMock{},
+			testRedactor,
+		)
+		// the customer of the default tenant is talking to an agent, a review would be refused
+		handlers.sessions[sessionKey(context.Background(), "mary@continental.com")] = connection{chatID: "qwerty", agentMode: true}
+
+		app := fiber.New()
+		app.Use(NewTenantHandlers(&tenantServiceMock{}).ResolveHost)
+		app.Post("/api/review", handlers.CreateReview)
+
+		body := `{"user":{"name":"Mary Ann","email":"mary@continental.com"},"product":"Galaxy S24"}`
+		req, err := http.NewRequest("POST", "http://acme.com/api/review", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusAccepted, result.StatusCode)
+		assert.Equal(t, acmeTenant.ID, invitationTenant)
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// untracedPaths aren't traced, they are called by the monitoring and would only add noise
+var untracedPaths = map[string]bool{
+	"/metrics": true,
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// TraceRequests starts a span for every request, as a child of the traceparent header if there is one.
+// The handlers read the span from fc.UserContext().
+func TraceRequests(fc *fiber.Ctx) error {
+	if untracedPaths[fc.Path()] {
+		return fc.Next()
+	}
+
+	carrier := propagation.HeaderCarrier{}
+	fc.Request().Header.VisitAll(func(key []byte, value []byte) {
+		carrier.Set(string(key), string(value))
+	})
+
+	ctx := otel.GetTextMapPropagator().Extract(fc.UserContext(), carrier)
+	ctx, span := tracing.Start(ctx, fc.Method(),
+		semconv.HTTPRequestMethodKey.String(fc.Method()),
+		semconv.URLPath(fc.Path()),
+	)
+	fc.SetUserContext(ctx)
+
+	err := fc.Next()
+
+	// the route is only known after the router matched it
+	span.SetName(fmt.Sprintf("%s %s", fc.Method(), fc.Route().Path))
+	span.SetAttributes(semconv.HTTPRoute(fc.Route().Path))
+
+	statusCode := fc.Response().StatusCode()
+	var fiberError *fiber.Error
+	if errors.As(err, &fiberError) {
+		statusCode = fiberError.Code
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+
+	// the body isn't recorded, the error messages may have personal data
+	spanError := err
+	if spanError == nil && statusCode >= fiber.StatusInternalServerError {
+		spanError = errors.New(utils.StatusMessage(statusCode))
+	}
+	tracing.End(span, spanError)
+	return err
+}
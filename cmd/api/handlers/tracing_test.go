@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans registers a tracer provider that keeps the ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTraceRequests(t *testing.T) {
+	t.Run("should continue the trace of the traceparent header", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		var handlerSpan trace.SpanContext
+		app := fiber.New()
+		app.Use(TraceRequests)
+		app.Get("/api/users/:id", func(fc *fiber.Ctx) error {
+			handlerSpan = trace.SpanContextFromContext(fc.UserContext())
+			return fc.SendStatus(fiber.StatusOK)
+		})
+
+		req, err := http.NewRequest("GET", "/api/users/42", nil)
+		require.NoError(t, err)
+		req.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET /api/users/:id", spans[0].Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+		assert.Equal(t, spans[0].SpanContext().SpanID(), handlerSpan.SpanID())
+		assert.Contains(t, spans[0].Attributes(), semconv.HTTPResponseStatusCode(fiber.StatusOK))
+	})
+
+	t.Run("should mark the server errors", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		app := fiber.New()
+		app.Use(TraceRequests)
+		app.Get("/api/fail", func(fc *fiber.Ctx) error {
+			return fiber.ErrServiceUnavailable
+		})
+
+		req, err := http.NewRequest("GET", "/api/fail", nil)
+		require.NoError(t, err)
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusServiceUnavailable, result.StatusCode)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Contains(t, spans[0].Attributes(), semconv.HTTPResponseStatusCode(fiber.StatusServiceUnavailable))
+	})
+
+	t.Run("should not trace the metrics scrapes", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		app := fiber.New()
+		app.Use(TraceRequests)
+		app.Get("/metrics", func(fc *fiber.Ctx) error { return fc.SendStatus(fiber.StatusOK) })
+
+		req, err := http.NewRequest("GET", "/metrics", nil)
+		require.NoError(t, err)
+
+		_, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Empty(t, recorder.Ended())
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetUser returns the user, including the deactivated ones
+func (h *Handlers) GetUser(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	found, err := h.userService.FindByID(ctx, fc.Params("id"))
+	if err != nil {
+		return userError(ctx, fc, err)
+	}
+	return fc.JSON(found)
+}
+
+// ListUsers lists the users filtered by the search, includeDeleted, limit and offset query params
+func (h *Handlers) ListUsers(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	users, err := h.userService.List(ctx, fc.Query("search"), fc.QueryBool("includeDeleted"), pageFromQuery(fc))
+	if err != nil {
+		return userError(ctx, fc, err)
+	}
+	return fc.JSON(users)
+}
+
+// UpdateUser updates the user. The body must have the version of the user that was read.
+func (h *Handlers) UpdateUser(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.UpdateUserRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	updated, err := h.userService.Update(ctx, fc.Params("id"), req)
+	if err != nil {
+		return userError(ctx, fc, err)
+	}
+	return fc.JSON(updated)
+}
+
+// DeactivateUser soft deletes the user and closes its web chat
+func (h *Handlers) DeactivateUser(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	found, err := h.userService.FindByID(ctx, fc.Params("id"))
+	if err != nil {
+		return userError(ctx, fc, err)
+	}
+
+	if err := h.userService.Deactivate(ctx, found.ID); err != nil {
+		return userError(ctx, fc, err)
+	}
+
+	h.DisconnectUser(ctx, found.Email)
+	return fc.SendStatus(fiber.StatusNoContent)
+}
+
+// invalidRequest answers 400 with the invalid fields as JSON, e.g. {"errors":{"email":"is required"}}
+func invalidRequest(ctx context.Context, fc *fiber.Ctx, err error) error {
+	golog.Log().Error(ctx, err.Error())
+
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		return fc.Status(fiber.StatusBadRequest).JSON(fiber.Map{"errors": errs})
+	}
+	return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+}
+
+// userError maps the user service errors to status codes
+func userError(ctx context.Context, fc *fiber.Ctx, err error) error {
+	golog.Log().Error(ctx, err.Error())
+
+	switch {
+	case errors.Is(err, user.ErrUserNotFound):
+		return fc.SendStatus(fiber.StatusNotFound)
+	case errors.Is(err, user.ErrUserAlreadyExists), errors.Is(err, user.ErrVersionConflict):
+		return fc.Status(fiber.StatusConflict).SendString(err.Error())
+	case errors.Is(err, user.ErrMissingRequiredFields):
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	default:
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newUserHandlers(userService *userServiceMock) *Handlers {
+	return NewHandlers(
+		userService,
+		&chatServiceMock{},
+		&chatbotServiceMock{},
+		&notificationServiceMock{},
+		&authServiceMock{},
+		&messageAnalyzerMock{},
+		testRedactor,
+	)
+}
+
+func TestHandlerCreateDuplicatedUser(t *testing.T) {
+	t.Run("should return conflict when the email is already used", func(t *testing.T) {
+		app := fiber.New()
+		app.Post("/api/user", newUserHandlers(&userServiceMock{Error: user.ErrUserAlreadyExists}).CreateUser)
+
+		req := httptest.NewRequest("POST", "/api/user", strings.NewReader(`{"firstName":"john","lastName":"wick","email":"john@mail.com"}`))
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusConflict, result.StatusCode)
+	})
+}
+
+func TestHandlerListUsers(t *testing.T) {
+	t.Run("should list the users with the query params", func(t *testing.T) {
+		app := fiber.New()
+		app.Get("/api/user", newUserHandlers(&userServiceMock{
+			CallbackList: func(ctx context.Context, search string, includeDeleted bool, page datatypes.Page) ([]datatypes.User, error) {
+				require.Equal(t, "john", search)
+				require.True(t, includeDeleted)
+				require.Equal(t, datatypes.Page{Limit: 10, Offset: 20}, page)
+				return []datatypes.User{{ID: "qwerty"}}, nil
+			},
+		}).ListUsers)
+
+		result, err := app.Test(httptest.NewRequest("GET", "/api/user?search=john&includeDeleted=true&limit=10&offset=20", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+
+		var users []datatypes.User
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&users))
+		require.Len(t, users, 1)
+	})
+}
+
+func TestHandlerUpdateUser(t *testing.T) {
+	body := `{"firstName":"john","lastName":"wick","email":"john@mail.com","version":1}`
+
+	t.Run("should update the user", func(t *testing.T) {
+		app := fiber.New()
+		app.Put("/api/user/:id", newUserHandlers(&userServiceMock{
+			CallbackUpdate: func(ctx context.Context, id string, req datatypes.UpdateUserRequest) (datatypes.User, error) {
+				return datatypes.User{ID: id, Email: req.Email, Version: req.Version + 1}, nil
+			},
+		}).UpdateUser)
+
+		req := httptest.NewRequest("PUT", "/api/user/qwerty", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, result.StatusCode)
+
+		var updated datatypes.User
+		require.NoError(t, json.NewDecoder(result.Body).Decode(&updated))
+		require.Equal(t, 2, updated.Version)
+	})
+
+	t.Run("should return conflict on an outdated version", func(t *testing.T) {
+		app := fiber.New()
+		app.Put("/api/user/:id", newUserHandlers(&userServiceMock{Error: user.ErrVersionConflict}).UpdateUser)
+
+		req := httptest.NewRequest("PUT", "/api/user/qwerty", strings.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusConflict, result.StatusCode)
+	})
+
+	t.Run("should require the version", func(t *testing.T) {
+		app := fiber.New()
+		app.Put("/api/user/:id", newUserHandlers(&userServiceMock{}).UpdateUser)
+
+		req := httptest.NewRequest("PUT", "/api/user/qwerty", strings.NewReader(`{"firstName":"john","lastName":"wick","email":"john@mail.com"}`))
+		req.Header.Add("Content-Type", "application/json")
+
+		result, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusBadRequest, result.StatusCode)
+	})
+}
+
+func TestHandlerDeactivateUser(t *testing.T) {
+	t.Run("should deactivate the user", func(t *testing.T) {
+		var deactivated string
+		app := fiber.New()
+		app.Delete("/api/user/:id", newUserHandlers(&userServiceMock{
+			CallbackFindByID: func(ctx context.Context, id string) (datatypes.User, error) {
+				return datatypes.User{ID: id, Email: "john@mail.com"}, nil
+			},
+			CallbackDeactivate: func(ctx context.Context, id string) error {
+				deactivated = id
+				return nil
+			},
+		}).DeactivateUser)
+
+		result, err := app.Test(httptest.NewRequest("DELETE", "/api/user/qwerty", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNoContent, result.StatusCode)
+		require.Equal(t, "qwerty", deactivated)
+	})
+
+	t.Run("should return not found when the user does not exist", func(t *testing.T) {
+		app := fiber.New()
+		app.Delete("/api/user/:id", newUserHandlers(&userServiceMock{Error: user.ErrUserNotFound}).DeactivateUser)
+
+		result, err := app.Test(httptest.NewRequest("DELETE", "/api/user/qwerty", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNotFound, result.StatusCode)
+	})
+}
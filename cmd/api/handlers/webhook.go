@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/webhook"
+	"github.com/gofiber/fiber/v2"
+)
+
+type webhookService interface {
+	RegisterEndpoint(ctx context.Context, req datatypes.CreateWebhookEndpointRequest) (datatypes.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]datatypes.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, endpointID string, limit int) ([]datatypes.WebhookDelivery, error)
+}
+
+type WebhookHandlers struct {
+	webhookService webhookService
+}
+
+// NewWebhookHandlers
+func NewWebhookHandlers(webhookService webhookService) *WebhookHandlers {
+	return &WebhookHandlers{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhookEndpoint registers a new webhook endpoint
+func (wh *WebhookHandlers) CreateWebhookEndpoint(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+	var req datatypes.CreateWebhookEndpointRequest
+
+	if err := fc.BodyParser(&req); err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if err := req.Validate(); err != nil {
+		return invalidRequest(ctx, fc, err)
+	}
+
+	endpoint, err := wh.webhookService.RegisterEndpoint(ctx, req)
+	if err != nil {
+		if errors.Is(err, webhook.ErrInvalidEndpointURL) {
+			return fc.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.Status(fiber.StatusCreated).JSON(endpoint)
+}
+
+// ListWebhookEndpoints lists the registered webhook endpoints
+func (wh *WebhookHandlers) ListWebhookEndpoints(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	endpoints, err := wh.webhookService.ListEndpoints(ctx)
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.JSON(endpoints)
+}
+
+// DeleteWebhookEndpoint removes a webhook endpoint
+func (wh *WebhookHandlers) DeleteWebhookEndpoint(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	if err := wh.webhookService.DeleteEndpoint(ctx, fc.Params("id")); err != nil {
+		if errors.Is(err, webhook.ErrEndpointNotFound) {
+			return fc.SendStatus(fiber.StatusNotFound)
+		}
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.SendStatus(fiber.StatusNoContent)
+}
+
+// ListWebhookDeliveries lists the latest deliveries of a webhook endpoint
+func (wh *WebhookHandlers) ListWebhookDeliveries(fc *fiber.Ctx) error {
+	ctx := gocontext.FromContext(fc.UserContext())
+
+	deliveries, err := wh.webhookService.ListDeliveries(ctx, fc.Params("id"), fc.QueryInt("limit"))
+	if err != nil {
+		golog.Log().Error(ctx, err.Error())
+		return fc.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	return fc.JSON(deliveries)
+}
@@ -2,17 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/JhonatanRSantos/review-chatbot/cmd/api/handlers"
 	"github.com/JhonatanRSantos/review-chatbot/cmd/api/router"
 	"github.com/JhonatanRSantos/review-chatbot/config"
+	"github.com/JhonatanRSantos/review-chatbot/internal/admin"
+	"github.com/JhonatanRSantos/review-chatbot/internal/analysis"
+	"github.com/JhonatanRSantos/review-chatbot/internal/analytics"
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/botsettings"
 	"github.com/JhonatanRSantos/review-chatbot/internal/chat"
 	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/export"
+	"github.com/JhonatanRSantos/review-chatbot/internal/health"
+	"github.com/JhonatanRSantos/review-chatbot/internal/metrics"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
+	"github.com/JhonatanRSantos/review-chatbot/internal/notification"
+	"github.com/JhonatanRSantos/review-chatbot/internal/redaction"
+	"github.com/JhonatanRSantos/review-chatbot/internal/retention"
+	"github.com/JhonatanRSantos/review-chatbot/internal/scheduler"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
 	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/JhonatanRSantos/review-chatbot/internal/webhook"
 	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
 
 	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
 	"github.com/JhonatanRSantos/gocore/pkg/godb"
@@ -27,49 +51,202 @@ func main() {
 	golog.SetEnv(goenv.Local)
 
 	ctx := gocontext.FromContext(context.Background())
-	configs := config.LoadConfiguration()
+
+	loader := config.NewLoader(flag.CommandLine)
+	flag.Parse()
+
+	configs, err := loader.Load(config.APISettings...)
+	if err != nil {
+		fatal(ctx, err)
+	}
+	golog.Log().Info(ctx, fmt.Sprintf("configuration loaded:\n%s", configs))
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.TracingConfig{
+		Exporter:    configs.Tracing.Exporter,
+		ServiceName: "review-chatbot",
+		Endpoint:    configs.Tracing.OTLPEndpoint,
+		Insecure:    configs.Tracing.OTLPInsecure,
+		SampleRatio: configs.Tracing.SampleRatio,
+	})
+	if err != nil {
+		fatal(ctx, err)
+	}
+	defer shutdownTracing(ctx)
 
 	database := newDatabaseConnection(ctx, configs)
 	defer database.Close()
 
-	userService := user.NewUserService(user.NewRepository(database))
-	chatService := chat.NewChatService(chat.NewRepository(database))
+	if err := migrations.NewMigrator(database).Migrate(ctx); err != nil {
+		fatal(ctx, err)
+	}
+
+	configureEmailValidation(configs)
+
+	server := newAPI(ctx, configs, database)
+	defer server.chatbot.Close()
+
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	server.startWorkers(workersCtx)
+
+	signalCtx, stopSignals := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	go func() {
+		if err := server.ws.Listen(fmt.Sprintf(":%s", configs.ServerPort)); err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to start server. Cause: %s", err))
+		}
+		stopSignals()
+	}()
+
+	<-signalCtx.Done()
+	stopWorkers()
+	server.shutdown(ctx, configs.ShutdownTimeout)
+}
+
+// api is the web server of the API and the services running in background
+type api struct {
+	ws       *goweb.WebServer
+	handlers *handlers.Handlers
+	chatbot  *chatbot.ChatbotService
+	workers  []func(ctx context.Context)
+	// running tracks the started workers, so the database isn't closed while they use it
+	running sync.WaitGroup
+}
+
+// newAPI creates the services of a migrated database and adds their routes to a new web server
+func newAPI(ctx context.Context, configs config.Configuration, database godb.DB) *api {
+	authService := newAuthService(ctx, configs)
+	tenantService := newTenantService(ctx, database, configs)
+	webhookService := newWebhookService(database, configs)
+	userService := user.NewUserService(user.NewRepository(instrumentDB(database, "user")))
+	chatService := chat.NewChatService(chat.NewRepository(instrumentDB(database, "chat")), webhookService)
+	schedulerService := newSchedulerService(database, tenantService, configs)
+	notificationService := newNotificationService(ctx, database, configs)
+	adminService := admin.NewAdminService(admin.NewRepository(instrumentDB(database, "admin")))
+	analysisService := newAnalysisService(ctx, database, configs, webhookService)
+	analyticsService := analytics.NewAnalyticsService(analytics.NewRepository(instrumentDB(database, "analytics")))
+	exportService := export.NewExportService(export.NewRepository(instrumentDB(database, "export")), export.ExportServiceConfig{
+		Directory:    configs.Export.Directory,
+		PollInterval: configs.Export.PollInterval,
+		StaleTimeout: configs.Export.StaleTimeout,
+	})
+
+	redactionService := newRedactionService(ctx, configs)
+	retentionService := newRetentionService(database, configs)
 
 	chatbotService := newChatbotService(ctx, configs)
-	defer chatbotService.Close()
+	settingsService := newSettingsService(ctx, chatbotService, configs)
+	healthService := newHealthService(database, chatbotService, configs)
+
+	ws := newWebServer(configs, tenantService)
+	webHandlers := configureWebRoutes(
+		ws, authService, tenantService, userService, chatService, chatbotService, notificationService, schedulerService,
+		webhookService, adminService, analysisService, analyticsService, exportService, redactionService, healthService,
+		settingsService,
+	)
 
-	ws := newWebServer(configs)
-	configureWebRoutes(ws, userService, chatService, chatbotService)
+	if configs.Analysis.AutoEscalate {
+		analysisService.AddEscalationHook(webHandlers)
+	}
 
-	if err := ws.Listen(fmt.Sprintf(":%s", configs.ServerPort)); err != nil {
-		golog.Log().Error(ctx, fmt.Sprintf("failed to start server. Cause: %s", err))
+	workers := []func(ctx context.Context){
+		func(ctx context.Context) { schedulerService.Start(ctx, webHandlers) },
+		webhookService.Start,
+		exportService.Start,
+		tenantService.Start,
 	}
 
+	if retentionService.Enabled() {
+		workers = append(workers, retentionService.Start)
+	}
+
+	if settingsService.Enabled() {
+		workers = append(workers, settingsService.Start)
+	}
+
+	return &api{ws: ws, handlers: webHandlers, chatbot: chatbotService, workers: workers}
+}
+
+// startWorkers runs the background services until the context is canceled
+func (a *api) startWorkers(ctx context.Context) {
+	for _, worker := range a.workers {
+		a.running.Add(1)
+		go func(worker func(ctx context.Context)) {
+			defer a.running.Done()
+			worker(ctx)
+		}(worker)
+	}
+}
+
+// shutdown drains the web chats, stops the web server and waits for the workers, whose context must
+// be canceled already. It gives up after the timeout. The deferred calls of main close the database
+// and the model client after it.
+func (a *api) shutdown(ctx context.Context, timeout time.Duration) {
+	golog.Log().Info(ctx, "shutting down the server")
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// stops accepting connections while the web chats are drained
+	serverStopped := make(chan error, 1)
+	go func() {
+		serverStopped <- a.ws.GetApp().ShutdownWithContext(ctx)
+	}()
+
+	if err := a.handlers.Shutdown(ctx); err != nil {
+		golog.Log().Error(ctx, err.Error())
+	}
+
+	if err := <-serverStopped; err != nil {
+		golog.Log().Error(ctx, fmt.Sprintf("failed to stop server. Cause: %s", err))
+	}
+
+	workersStopped := make(chan struct{})
+	go func() {
+		a.running.Wait()
+		close(workersStopped)
+	}()
+
+	select {
+	case <-workersStopped:
+	case <-ctx.Done():
+		golog.Log().Error(ctx, fmt.Sprintf("failed to stop the workers. Cause: %s", ctx.Err()))
+	}
 }
 
 // newDatabaseConnection
 func newDatabaseConnection(ctx context.Context, configs config.Configuration) godb.DB {
-	db, err := godb.NewDB(configs.Database)
+	db, err := godb.NewDB(configs.Database.DBConfig())
 	if err != nil {
 		fatal(ctx, fmt.Errorf("failed to open new database connection. Cause %w", err))
 	}
 	return db
 }
 
+// instrumentDB records the latency and traces the queries of a repository
+func instrumentDB(database godb.DB, repository string) godb.DB {
+	return metrics.InstrumentDB(tracing.InstrumentDB(database, repository), repository)
+}
+
 // newWebServer
-func newWebServer(configs config.Configuration) *goweb.WebServer {
+func newWebServer(configs config.Configuration, tenantService *tenant.TenantService) *goweb.WebServer {
 	ws := goweb.NewWebServer(goweb.DefaultConfig(goweb.WebServerDefaultConfig{}))
 
 	// websocket configs
 	app := ws.GetApp()
 	app.Static("/", configs.StaticFilesRelativePath)
-	app.Use("/api/ws", func(ctx *fiber.Ctx) error {
+	app.Use(handlers.TraceRequests)
+	app.Use(handlers.NewTenantHandlers(tenantService).ResolveHost)
+	upgradeWebsocket := func(ctx *fiber.Ctx) error {
 		if websocket.IsWebSocketUpgrade(ctx) {
 			ctx.Locals("allowed", true)
 			return ctx.Next()
 		}
 		return fiber.ErrUpgradeRequired
-	})
+	}
+	app.Use("/api/ws", upgradeWebsocket)
+	app.Use("/api/agent/ws", upgradeWebsocket)
 
 	return ws
 }
@@ -82,7 +259,7 @@ func newChatbotService(ctx context.Context, configs config.Configuration) *chatb
 		client *genai.Client
 	)
 
-	if client, err = genai.NewClient(ctx, option.WithAPIKey(configs.GenAIAPIKey)); err != nil {
+	if client, err = genai.NewClient(ctx, configs.GenAIOptions()...); err != nil {
 		fatal(ctx, fmt.Errorf("failed to create new genai client. Cause: %w", err))
 	}
 
@@ -96,15 +273,252 @@ func newChatbotService(ctx context.Context, configs config.Configuration) *chatb
 	return bot
 }
 
+// newSettingsService applies the bot settings file, if configured, before the chats start
+func newSettingsService(
+	ctx context.Context,
+	chatbotService *chatbot.ChatbotService,
+	configs config.Configuration,
+) *botsettings.SettingsService {
+	settingsService := botsettings.NewSettingsService(chatbotService, botsettings.SettingsServiceConfig{
+		File:     configs.BotSettings.File,
+		Interval: configs.BotSettings.ReloadInterval,
+	})
+
+	if settingsService.Enabled() {
+		if _, err := settingsService.Reload(ctx); err != nil {
+			fatal(ctx, err)
+		}
+	}
+	return settingsService
+}
+
+// newAuthService
+func newAuthService(ctx context.Context, configs config.Configuration) *auth.AuthService {
+	apiKeys := []string{}
+	for _, key := range strings.Split(configs.Auth.APIKeys.Value(), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+
+	authService, err := auth.NewAuthService(auth.AuthServiceConfig{
+		SigningKeys: auth.ParseSigningKeys(configs.Auth.SigningKeys.Value()),
+		APIKeys:     apiKeys,
+		TokenTTL:    configs.Auth.TokenTTL,
+		Admins:      auth.ParseAdminCredentials(configs.Auth.AdminCredentials.Value()),
+	})
+	if err != nil {
+		fatal(ctx, err)
+	}
+	return authService
+}
+
+// configureEmailValidation blocks the disposable and the configured email domains
+func configureEmailValidation(configs config.Configuration) {
+	domains := []string{}
+	if configs.Validation.BlockDisposableEmails {
+		domains = append(domains, validation.DisposableDomains()...)
+	}
+
+	if configs.Validation.BlockedEmailDomains != "" {
+		domains = append(domains, strings.Split(configs.Validation.BlockedEmailDomains, ",")...)
+	}
+	validation.BlockDomains(domains...)
+}
+
+// newRedactionService creates the pii redaction with the configured detectors
+func newRedactionService(ctx context.Context, configs config.Configuration) *redaction.RedactionService {
+	detectors, err := redaction.NewDetectors(strings.Split(configs.Redaction.Detectors, ",")...)
+	if err != nil {
+		fatal(ctx, fmt.Errorf("%w: %s", err, configs.Redaction.Detectors))
+	}
+
+	redactionService, err := redaction.NewRedactionService(redaction.RedactionServiceConfig{
+		Detectors: detectors,
+		StoreMode: configs.Redaction.StoreMode,
+		Key:       configs.Redaction.Key.Value(),
+	})
+	if err != nil {
+		fatal(ctx, err)
+	}
+	return redactionService
+}
+
+// newRetentionService creates the retention job. The rules are disabled by default.
+func newRetentionService(database godb.DB, configs config.Configuration) *retention.RetentionService {
+	return retention.NewRetentionService(retention.NewRepository(instrumentDB(database, "retention")), retention.RetentionServiceConfig{
+		MessageContentTTL: time.Duration(configs.Retention.MessageContentDays) * 24 * time.Hour,
+		InactiveUserTTL:   time.Duration(configs.Retention.InactiveUserDays) * 24 * time.Hour,
+		Interval:          configs.Retention.Interval,
+		DryRun:            configs.Retention.DryRun,
+	})
+}
+
+// newAnalysisService creates the message analysis pipeline with the configured classifier
+func newAnalysisService(
+	ctx context.Context,
+	database godb.DB,
+	configs config.Configuration,
+	publisher *webhook.WebhookService,
+) *analysis.AnalysisService {
+	var classifier analysis.Classifier
+
+	switch configs.Analysis.Classifier {
+	case "keyword":
+		classifier = analysis.NewKeywordClassifier()
+	case "gemini":
+		client, err := genai.NewClient(ctx, configs.GenAIOptions()...)
+		if err != nil {
+			fatal(ctx, fmt.Errorf("failed to create new genai client. Cause: %w", err))
+		}
+		classifier = analysis.NewGeminiClassifier(client.GenerativeModel(configs.Analysis.Model))
+	default:
+		fatal(ctx, fmt.Errorf("%w: %s", analysis.ErrInvalidClassifier, configs.Analysis.Classifier))
+	}
+
+	return analysis.NewAnalysisService(analysis.NewRepository(instrumentDB(database, "analysis")), classifier, publisher, analysis.AnalysisServiceConfig{
+		NegativeThreshold: configs.Analysis.NegativeThreshold,
+	})
+}
+
+// newSchedulerService
+func newSchedulerService(
+	database godb.DB,
+	tenantService *tenant.TenantService,
+	configs config.Configuration,
+) *scheduler.SchedulerService {
+	return scheduler.NewSchedulerService(
+		scheduler.NewRepository(instrumentDB(database, "scheduler")),
+		tenantService,
+		scheduler.SchedulerServiceConfig{
+			ReviewDelay:   configs.Scheduler.ReviewDelay,
+			PollInterval:  configs.Scheduler.PollInterval,
+			RetryInterval: configs.Scheduler.RetryInterval,
+			MaxAttempts:   configs.Scheduler.MaxAttempts,
+		},
+	)
+}
+
+// newTenantService creates the tenant service with the default tenant of the configuration and
+// loads the other tenants before the server starts
+func newTenantService(ctx context.Context, database godb.DB, configs config.Configuration) *tenant.TenantService {
+	tenantService, err := tenant.NewTenantService(tenant.NewRepository(instrumentDB(database, "tenant")), tenant.TenantServiceConfig{
+		Default: datatypes.Tenant{
+			Name:         configs.Tenants.DefaultName,
+			Domain:       configs.Tenants.DefaultDomain,
+			SupportEmail: configs.Tenants.DefaultSupportEmail,
+			Policies:     configs.Tenants.DefaultPolicies,
+		},
+		RefreshInterval: configs.Tenants.RefreshInterval,
+	})
+	if err != nil {
+		fatal(ctx, err)
+	}
+
+	if err := tenantService.Refresh(ctx); err != nil {
+		fatal(ctx, err)
+	}
+	return tenantService
+}
+
+// newWebhookService
+func newWebhookService(database godb.DB, configs config.Configuration) *webhook.WebhookService {
+	return webhook.NewWebhookService(
+		webhook.NewRepository(instrumentDB(database, "webhook")),
+		webhook.NewHTTPClient(configs.Webhooks.Timeout),
+		webhook.WebhookServiceConfig{
+			DeliveryInterval: configs.Webhooks.DeliveryInterval,
+			RetryInterval:    configs.Webhooks.RetryInterval,
+			MaxAttempts:      configs.Webhooks.MaxAttempts,
+		},
+	)
+}
+
+// newNotificationService creates the notification service. Emails are disabled when there is no SMTP host.
+func newNotificationService(ctx context.Context, database godb.DB, configs config.Configuration) *notification.NotificationService {
+	notificationConfig := notification.NotificationServiceConfig{
+		BaseURL:    configs.Notification.BaseURL,
+		LinkSecret: configs.Notification.LinkSecret.Value(),
+		LinkTTL:    configs.Notification.LinkTTL,
+	}
+
+	if configs.Notification.SMTPHost == "" {
+		return notification.NewNotificationService(notification.NewRepository(instrumentDB(database, "notification")), nil, notificationConfig)
+	}
+
+	if configs.Notification.LinkSecret == "" {
+		fatal(ctx, errors.New("missing invitation link secret. Set REVIEW_CHATBOT_INVITATION_LINK_SECRET"))
+	}
+
+	return notification.NewNotificationService(
+		notification.NewRepository(instrumentDB(database, "notification")),
+		notification.NewSMTPSender(notification.SMTPSenderConfig{
+			Host:     configs.Notification.SMTPHost,
+			Port:     configs.Notification.SMTPPort,
+			Username: configs.Notification.SMTPUsername,
+			Password: configs.Notification.SMTPPassword.Value(),
+			From:     configs.Notification.From,
+			Timeout:  configs.Notification.SMTPTimeout,
+		}),
+		notificationConfig,
+	)
+}
+
+// newHealthService checks the database, the migrations and, if enabled, the model
+func newHealthService(
+	database godb.DB,
+	chatbotService *chatbot.ChatbotService,
+	configs config.Configuration,
+) *health.HealthService {
+	healthService := health.NewHealthService(health.HealthServiceConfig{Timeout: configs.Health.Timeout})
+	healthService.AddCheck("database", database.PingContext)
+	healthService.AddCheck("migrations", migrations.NewMigrator(database).Check)
+
+	if configs.Health.CheckModel {
+		healthService.AddCheck("model", health.Cached(chatbotService.Ping, configs.Health.ModelCheckTTL))
+	}
+	return healthService
+}
+
 // configureWebRoutes
 func configureWebRoutes(
 	ws *goweb.WebServer,
+	authService *auth.AuthService,
+	tenantService *tenant.TenantService,
 	userService *user.UserService,
 	chatService *chat.ChatService,
 	chatbotService *chatbot.ChatbotService,
-) {
-	handlers := handlers.NewHandlers(userService, chatService, chatbotService)
-	ws.AddRoutes(router.NewWebRoutes(handlers)...)
+	notificationService *notification.NotificationService,
+	schedulerService *scheduler.SchedulerService,
+	webhookService *webhook.WebhookService,
+	adminService *admin.AdminService,
+	analysisService *analysis.AnalysisService,
+	analyticsService *analytics.AnalyticsService,
+	exportService *export.ExportService,
+	redactionService *redaction.RedactionService,
+	healthService *health.HealthService,
+	settingsService *botsettings.SettingsService,
+) *handlers.Handlers {
+	authHandlers := handlers.NewAuthHandlers(authService, tenantService)
+	webHandlers := handlers.NewHandlers(
+		userService, chatService, chatbotService, notificationService, authService, analysisService, redactionService,
+	)
+	ws.AddRoutes(router.NewAuthRoutes(authHandlers)...)
+	ws.AddRoutes(router.NewWebRoutes(webHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewSchedulerRoutes(handlers.NewSchedulerHandlers(schedulerService), authHandlers)...)
+	ws.AddRoutes(router.NewWebhookRoutes(handlers.NewWebhookHandlers(webhookService), authHandlers)...)
+	adminHandlers := handlers.NewAdminHandlers(adminService, webHandlers)
+	ws.AddRoutes(router.NewAdminRoutes(adminHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewAgentRoutes(webHandlers, adminHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewAnalyticsRoutes(handlers.NewAnalyticsHandlers(analyticsService), adminHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewExportRoutes(handlers.NewExportHandlers(exportService), adminHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewBotSettingsRoutes(handlers.NewBotSettingsHandlers(settingsService), adminHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewTenantRoutes(handlers.NewTenantHandlers(tenantService), adminHandlers, authHandlers)...)
+	ws.AddRoutes(router.NewMetricsRoutes(metrics.Handler())...)
+	ws.AddRoutes(router.NewHealthRoutes(handlers.NewHealthHandlers(healthService))...)
+
+	metrics.WatchWebsocketConnections(webHandlers.SessionCount)
+	return webHandlers
 }
 
 // fatal
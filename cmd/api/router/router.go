@@ -1,33 +1,368 @@
 package router
 
 import (
+	"net/http"
+
 	"github.com/JhonatanRSantos/gocore/pkg/goweb"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 )
 
 type handlers interface {
 	CreateReview(*fiber.Ctx) error
 	CreateUser(ctx *fiber.Ctx) error
+	GetUser(*fiber.Ctx) error
+	ListUsers(*fiber.Ctx) error
+	UpdateUser(*fiber.Ctx) error
+	DeactivateUser(*fiber.Ctx) error
+	ExportUserData(*fiber.Ctx) error
+	ForgetUser(*fiber.Ctx) error
+	OpenInvitation(*fiber.Ctx) error
 	HandleWebsocketConnection() func(*fiber.Ctx) error
+	HandleAgentWebsocketConnection() func(*fiber.Ctx) error
+}
+
+type schedulerHandlers interface {
+	OrderDelivered(*fiber.Ctx) error
+	OrdersDeliveredBatch(*fiber.Ctx) error
+}
+
+type webhookHandlers interface {
+	CreateWebhookEndpoint(*fiber.Ctx) error
+	ListWebhookEndpoints(*fiber.Ctx) error
+	DeleteWebhookEndpoint(*fiber.Ctx) error
+	ListWebhookDeliveries(*fiber.Ctx) error
+}
+
+type adminHandlers interface {
+	ListUsers(*fiber.Ctx) error
+	ListChats(*fiber.Ctx) error
+	GetTranscript(*fiber.Ctx) error
+	CloseChat(*fiber.Ctx) error
+	AuthorizeChatTakeover(*fiber.Ctx) error
+	AuthorizeAnalytics(*fiber.Ctx) error
+	AuthorizeExport(*fiber.Ctx) error
+	AuthorizeBotSettings(*fiber.Ctx) error
+	AuthorizeTenants(*fiber.Ctx) error
+	DeleteChat(*fiber.Ctx) error
+	DeleteUser(*fiber.Ctx) error
+	ListAuditLog(*fiber.Ctx) error
+}
+
+type analyticsHandlers interface {
+	Summary(*fiber.Ctx) error
+	Volume(*fiber.Ctx) error
+	Ratings(*fiber.Ctx) error
+	Intents(*fiber.Ctx) error
+}
+
+type exportHandlers interface {
+	ExportChat(*fiber.Ctx) error
+	ExportChats(*fiber.Ctx) error
+	CreateExportJob(*fiber.Ctx) error
+	GetExportJob(*fiber.Ctx) error
+	DownloadExportJob(*fiber.Ctx) error
+}
+
+type botSettingsHandlers interface {
+	GetSettings(*fiber.Ctx) error
+	ReloadSettings(*fiber.Ctx) error
+}
+
+type tenantHandlers interface {
+	CreateTenant(*fiber.Ctx) error
+	ListTenants(*fiber.Ctx) error
+}
+
+type healthHandlers interface {
+	Live(*fiber.Ctx) error
+	Ready(*fiber.Ctx) error
+}
+
+type authHandlers interface {
+	RequireAPIKey(*fiber.Ctx) error
+	RequireAdmin(*fiber.Ctx) error
+	RequireCustomerToken(*fiber.Ctx) error
+	IssueToken(*fiber.Ctx) error
+}
+
+// NewHealthRoutes exposes the liveness and readiness probes
+func NewHealthRoutes(handlers healthHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/healthz",
+			Handlers: []func(c *fiber.Ctx) error{handlers.Live},
+		},
+		{
+			Method:   "GET",
+			Path:     "/readyz",
+			Handlers: []func(c *fiber.Ctx) error{handlers.Ready},
+		},
+	}
+}
+
+// NewMetricsRoutes exposes the Prometheus metrics
+func NewMetricsRoutes(metricsHandler http.Handler) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/metrics",
+			Handlers: []func(c *fiber.Ctx) error{adaptor.HTTPHandler(metricsHandler)},
+		},
+	}
 }
 
 // NewWebRoutes
-func NewWebRoutes(handlers handlers) []goweb.WebRoute {
+func NewWebRoutes(handlers handlers, auth authHandlers) []goweb.WebRoute {
 	return []goweb.WebRoute{
 		{
 			Method:   "GET",
 			Path:     "/api/ws/:email",
-			Handlers: []func(c *fiber.Ctx) error{handlers.HandleWebsocketConnection()},
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireCustomerToken, handlers.HandleWebsocketConnection()},
 		},
 		{
 			Method:   "POST",
 			Path:     "/api/user",
-			Handlers: []func(c *fiber.Ctx) error{handlers.CreateUser},
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.CreateUser},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/user",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.ListUsers},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/user/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.GetUser},
+		},
+		{
+			Method:   "PUT",
+			Path:     "/api/user/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.UpdateUser},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/api/user/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.DeactivateUser},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/users/:email/data",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireCustomerToken, handlers.ExportUserData},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/api/users/:email",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireCustomerToken, handlers.ForgetUser},
 		},
 		{
 			Method:   "POST",
 			Path:     "/api/review",
-			Handlers: []func(c *fiber.Ctx) error{handlers.CreateReview},
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.CreateReview},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/invitations/:id/open",
+			Handlers: []func(c *fiber.Ctx) error{handlers.OpenInvitation},
+		},
+	}
+}
+
+// NewSchedulerRoutes
+func NewSchedulerRoutes(handlers schedulerHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "POST",
+			Path:     "/api/orders/delivered",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.OrderDelivered},
+		},
+		{
+			Method:   "POST",
+			Path:     "/api/orders/delivered/batch",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.OrdersDeliveredBatch},
+		},
+	}
+}
+
+// NewWebhookRoutes
+func NewWebhookRoutes(handlers webhookHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "POST",
+			Path:     "/api/webhooks",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.CreateWebhookEndpoint},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/webhooks",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.ListWebhookEndpoints},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/api/webhooks/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.DeleteWebhookEndpoint},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/webhooks/:id/deliveries",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, handlers.ListWebhookDeliveries},
+		},
+	}
+}
+
+// NewAuthRoutes
+func NewAuthRoutes(auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "POST",
+			Path:     "/api/auth/token",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAPIKey, auth.IssueToken},
+		},
+	}
+}
+
+// NewAdminRoutes
+func NewAdminRoutes(handlers adminHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/api/admin/users",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.ListUsers},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/api/admin/users/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.DeleteUser},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/admin/chats",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.ListChats},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/admin/chats/:id/transcript",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.GetTranscript},
+		},
+		{
+			Method:   "POST",
+			Path:     "/api/admin/chats/:id/close",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.CloseChat},
+		},
+		{
+			Method:   "DELETE",
+			Path:     "/api/admin/chats/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.DeleteChat},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/admin/audit",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, handlers.ListAuditLog},
+		},
+	}
+}
+
+// NewBotSettingsRoutes
+func NewBotSettingsRoutes(handlers botSettingsHandlers, admin adminHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/api/admin/bot/settings",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeBotSettings, handlers.GetSettings},
+		},
+		{
+			Method:   "POST",
+			Path:     "/api/admin/bot/settings/reload",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeBotSettings, handlers.ReloadSettings},
+		},
+	}
+}
+
+// NewTenantRoutes
+func NewTenantRoutes(handlers tenantHandlers, admin adminHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/api/admin/tenants",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeTenants, handlers.ListTenants},
+		},
+		{
+			Method:   "POST",
+			Path:     "/api/admin/tenants",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeTenants, handlers.CreateTenant},
+		},
+	}
+}
+
+// NewAgentRoutes
+func NewAgentRoutes(handlers handlers, admin adminHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method: "GET",
+			Path:   "/api/agent/ws/:chatId",
+			Handlers: []func(c *fiber.Ctx) error{
+				auth.RequireAdmin,
+				admin.AuthorizeChatTakeover,
+				handlers.HandleAgentWebsocketConnection(),
+			},
+		},
+	}
+}
+
+// NewAnalyticsRoutes
+func NewAnalyticsRoutes(handlers analyticsHandlers, admin adminHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/api/analytics/summary",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeAnalytics, handlers.Summary},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/analytics/volume",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeAnalytics, handlers.Volume},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/analytics/ratings",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeAnalytics, handlers.Ratings},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/analytics/intents",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeAnalytics, handlers.Intents},
+		},
+	}
+}
+
+// NewExportRoutes
+func NewExportRoutes(handlers exportHandlers, admin adminHandlers, auth authHandlers) []goweb.WebRoute {
+	return []goweb.WebRoute{
+		{
+			Method:   "GET",
+			Path:     "/api/chats/export",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeExport, handlers.ExportChats},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/chats/:id/export",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeExport, handlers.ExportChat},
+		},
+		{
+			Method:   "POST",
+			Path:     "/api/exports",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeExport, handlers.CreateExportJob},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/exports/:id",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeExport, handlers.GetExportJob},
+		},
+		{
+			Method:   "GET",
+			Path:     "/api/exports/:id/download",
+			Handlers: []func(c *fiber.Ctx) error{auth.RequireAdmin, admin.AuthorizeExport, handlers.DownloadExportJob},
 		},
 	}
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/cmd/api/handlers"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/fasthttp/websocket"
+)
+
+const (
+	quitCommand   = "/quit"
+	helpCommand   = "/help"
+	reviewCommand = "/review"
+
+	helpMessage = `Type a message and press enter to send it. Commands:
+  /review <product>  ask the API to start a review of the product
+  /agent             ask for a human agent
+  /quit              leave the chat, the review is completed by the API
+  /help              show this message`
+)
+
+// chat is a customer connected to the web chat
+type chat struct {
+	conn *websocket.Conn
+	api  *apiClient
+	user datatypes.CreateReviewUser
+	// replyTimeout is how long a message or a review waits for the bot reply. Zero doesn't wait.
+	replyTimeout time.Duration
+	out          io.Writer
+	outMutex     sync.Mutex
+	replies      chan string
+	closed       chan struct{}
+}
+
+// newChat starts reading the messages of the connection
+func newChat(conn *websocket.Conn, api *apiClient, user datatypes.CreateReviewUser, replyTimeout time.Duration, out io.Writer) *chat {
+	c := &chat{
+		conn:         conn,
+		api:          api,
+		user:         user,
+		replyTimeout: replyTimeout,
+		out:          out,
+		replies:      make(chan string, 16),
+		closed:       make(chan struct{}),
+	}
+	go c.listen()
+	return c
+}
+
+// run sends the input lines until the input ends, the /quit command or the server closes the chat
+func (c *chat) run(ctx context.Context, input io.Reader) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		var (
+			line string
+			ok   bool
+		)
+
+		select {
+		case <-ctx.Done():
+			return c.close()
+		case <-c.closed:
+			return ErrConnectionClosed
+		case line, ok = <-lines:
+		}
+
+		if !ok {
+			return c.close()
+		}
+
+		line = strings.TrimSpace(line)
+		command, argument, _ := strings.Cut(line, " ")
+
+		var err error
+		switch {
+		case line == "":
+			continue
+		case command == quitCommand:
+			return c.close()
+		case command == helpCommand:
+			c.print(helpMessage)
+		case command == reviewCommand:
+			err = c.startReview(ctx, strings.TrimSpace(argument))
+		case strings.HasPrefix(line, "/") && line != handlers.RequestAgentCommand:
+			c.print(fmt.Sprintf("unknown command %s\n%s", command, helpMessage))
+		default:
+			err = c.send(line)
+		}
+
+		if err != nil {
+			c.close()
+			return err
+		}
+	}
+}
+
+// send sends a customer message and waits for the reply
+func (c *chat) send(message string) error {
+	c.drainReplies()
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send message. Cause: %w", err)
+	}
+	return c.waitReply()
+}
+
+// startReview asks the API to start the review and waits for the bot to open it
+func (c *chat) startReview(ctx context.Context, product string) error {
+	if product == "" {
+		c.print(ErrMissingProduct.Error())
+		return nil
+	}
+
+	c.drainReplies()
+	started, err := c.api.createReview(ctx, datatypes.CreateReviewRequest{User: c.user, Product: product})
+	if err != nil {
+		return err
+	}
+
+	if !started {
+		c.print("the API didn't find this chat and emailed an invitation instead")
+		return nil
+	}
+	return c.waitReply()
+}
+
+// listen prints the messages received until the connection is closed
+func (c *chat) listen() {
+	defer close(c.closed)
+
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) && !errors.Is(err, websocket.ErrCloseSent) {
+				c.print(fmt.Sprintf("connection closed: %s", err))
+			}
+			return
+		}
+
+		c.print("bot: " + string(message))
+		select {
+		case c.replies <- string(message):
+		default:
+		}
+	}
+}
+
+// waitReply waits for the next message of the bot, or of the agent
+func (c *chat) waitReply() error {
+	if c.replyTimeout <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(c.replyTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.replies:
+		return nil
+	case <-c.closed:
+		return ErrConnectionClosed
+	case <-timer.C:
+		return ErrReplyTimeout
+	}
+}
+
+// drainReplies discards the replies received before a new message is sent
+func (c *chat) drainReplies() {
+	for {
+		select {
+		case <-c.replies:
+		default:
+			return
+		}
+	}
+}
+
+// close leaves the chat and waits for the server to close the connection
+func (c *chat) close() error {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second)); err != nil {
+		return c.conn.Close()
+	}
+
+	select {
+	case <-c.closed:
+	case <-time.After(time.Second):
+	}
+	return c.conn.Close()
+}
+
+// print writes a line to the output. The replies are printed while the input is read.
+func (c *chat) print(line string) {
+	c.outMutex.Lock()
+	defer c.outMutex.Unlock()
+	fmt.Fprintln(c.out, line)
+}
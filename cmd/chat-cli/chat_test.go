@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/fasthttp/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chatServer answers every message with its text in upper case. The reviews are opened on the
+// connected chat and the messages starting with "silent" aren't answered.
+func chatServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	connections := make(chan *websocket.Conn, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ws/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/ws/mary@continental.com", r.URL.Path)
+		assert.Equal(t, "token", r.URL.Query().Get("token"))
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		connections <- conn
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				conn.Close()
+				return
+			}
+
+			if !strings.HasPrefix(string(message), "silent") {
+				conn.WriteMessage(websocket.TextMessage, bytes.ToUpper(message))
+			}
+		}
+	})
+	mux.HandleFunc("/api/review", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("X-API-Key"))
+
+		var req datatypes.CreateReviewRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		conn := <-connections
+		connections <- conn
+		conn.WriteMessage(websocket.TextMessage, []byte("How do you like the "+req.Product+", "+req.User.Name+"?"))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChatRun(t *testing.T) {
+	dial := func(t *testing.T, replyTimeout time.Duration) (*chat, *bytes.Buffer) {
+		server := chatServer(t)
+		api := &apiClient{baseURL: server.URL, apiKey: "key", client: server.Client()}
+
+		chatURL, err := api.websocketURL("mary@continental.com", "token")
+		require.NoError(t, err)
+
+		conn, _, err := websocket.DefaultDialer.Dial(chatURL, nil)
+		require.NoError(t, err)
+
+		out := &bytes.Buffer{}
+		user := datatypes.CreateReviewUser{Name: "Mary", Email: "mary@continental.com"}
+		return newChat(conn, api, user, replyTimeout, out), out
+	}
+
+	t.Run("should start the review and wait for every reply", func(t *testing.T) {
+		session, out := dial(t, time.Second)
+
+		err := session.run(context.Background(), strings.NewReader("/review Smart TV\n\nit works great\n/quit\nnot sent\n"))
+		require.NoError(t, err)
+
+		session.outMutex.Lock()
+		defer session.outMutex.Unlock()
+		assert.Equal(t, "bot: How do you like the Smart TV, Mary?\nbot: IT WORKS GREAT\n", out.String())
+	})
+
+	t.Run("should show the help for unknown commands", func(t *testing.T) {
+		session, out := dial(t, time.Second)
+
+		require.NoError(t, session.run(context.Background(), strings.NewReader("/rate 5\n")))
+
+		session.outMutex.Lock()
+		defer session.outMutex.Unlock()
+		assert.Contains(t, out.String(), "unknown command /rate\n"+helpMessage)
+	})
+
+	t.Run("should fail when the bot doesn't reply", func(t *testing.T) {
+		session, _ := dial(t, 50*time.Millisecond)
+
+		err := session.run(context.Background(), strings.NewReader("silent please\n"))
+		assert.ErrorIs(t, err, ErrReplyTimeout)
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/JhonatanRSantos/review-chatbot/cmd/api/handlers"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+// apiClient calls the API endpoints the chat needs besides the websocket
+type apiClient struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// issueToken issues the customer token used to open the chat
+func (ac *apiClient) issueToken(ctx context.Context, email string) (string, error) {
+	if ac.apiKey == "" {
+		return "", ErrMissingAPIKey
+	}
+
+	var token datatypes.AuthToken
+	if _, err := ac.post(ctx, "/api/auth/token", datatypes.IssueTokenRequest{Email: email}, &token, http.StatusCreated); err != nil {
+		return "", fmt.Errorf("failed to issue token. Cause: %w", err)
+	}
+	return token.Token, nil
+}
+
+// createUser creates the customer. Customers that already exist are kept.
+func (ac *apiClient) createUser(ctx context.Context, req datatypes.CreateUserRequest) error {
+	if _, err := ac.post(ctx, "/api/user", req, nil, http.StatusOK, http.StatusConflict); err != nil {
+		return fmt.Errorf("failed to create user. Cause: %w", err)
+	}
+	return nil
+}
+
+// createReview asks the API to start a review. It returns false when the customer isn't connected
+// and an invitation was emailed instead.
+func (ac *apiClient) createReview(ctx context.Context, req datatypes.CreateReviewRequest) (bool, error) {
+	status, err := ac.post(ctx, "/api/review", req, nil, http.StatusOK, http.StatusAccepted)
+	if err != nil {
+		return false, fmt.Errorf("failed to start review. Cause: %w", err)
+	}
+	return status == http.StatusOK, nil
+}
+
+// websocketURL returns the chat url of the customer
+func (ac *apiClient) websocketURL(email string, token string) (string, error) {
+	chatURL, err := url.Parse(ac.baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch chatURL.Scheme {
+	case "https":
+		chatURL.Scheme = "wss"
+	default:
+		chatURL.Scheme = "ws"
+	}
+
+	chatURL.Path = strings.TrimSuffix(chatURL.Path, "/") + "/api/ws/" + url.PathEscape(email)
+	chatURL.RawQuery = url.Values{"token": {token}}.Encode()
+	return chatURL.String(), nil
+}
+
+// post sends the body as JSON and decodes the response into out, if not nil. Any status
+// besides the expected ones is an error.
+func (ac *apiClient) post(ctx context.Context, path string, body any, out any, expected ...int) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(ac.baseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(handlers.APIKeyHeader, ac.apiKey)
+
+	resp, err := ac.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	for _, status := range expected {
+		if resp.StatusCode != status {
+			continue
+		}
+
+		if out != nil {
+			return resp.StatusCode, json.Unmarshal(respBody, out)
+		}
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, fmt.Errorf("%w %d: %s", ErrUnexpectedStatus, resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
@@ -0,0 +1,12 @@
+package main
+
+import "errors"
+
+var (
+	ErrMissingEmail     = errors.New("the customer email is required, set -email")
+	ErrMissingAPIKey    = errors.New("an api key is required to issue the chat token, set -api-key or -token")
+	ErrMissingProduct   = errors.New("the product is required: /review <product>")
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+	ErrReplyTimeout     = errors.New("the bot didn't reply in time")
+	ErrConnectionClosed = errors.New("the server closed the chat")
+)
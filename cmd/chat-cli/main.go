@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/fasthttp/websocket"
+)
+
+// chat-cli chats with the bot from a terminal like the web chat does. Every input line is sent
+// as a customer message and waits for the reply, so the conversations can also be piped by
+// the smoke tests:
+//
+//	printf 'It works great\n5 stars\n' | chat-cli -email mary@continental.com -review "Smart TV"
+func main() {
+	baseURL := flag.String("url", "http://localhost:9000", "base url of the API")
+	apiKey := flag.String("api-key", firstAPIKey(), "API key used to issue the token and start the reviews. Defaults to the first of REVIEW_CHATBOT_AUTH_API_KEYS")
+	email := flag.String("email", "", "email of the customer")
+	name := flag.String("name", "", "name of the customer in the reviews. Defaults to the email")
+	token := flag.String("token", "", "customer token. Issued with the API key when empty")
+	createUser := flag.Bool("create-user", false, "create the customer before connecting, the name is split in first and last name")
+	review := flag.String("review", "", "start a review of the product after connecting")
+	replyTimeout := flag.Duration("reply-timeout", 30*time.Second, "how long each message waits for the reply before the next line is sent. 0 doesn't wait")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *email == "" {
+		fatal(ErrMissingEmail)
+	}
+
+	if *name == "" {
+		*name = *email
+	}
+
+	api := &apiClient{baseURL: *baseURL, apiKey: *apiKey, client: &http.Client{Timeout: time.Minute}}
+
+	if *createUser {
+		firstName, lastName, _ := strings.Cut(*name, " ")
+		if err := api.createUser(ctx, datatypes.CreateUserRequest{FirstName: firstName, LastName: lastName, Email: *email}); err != nil {
+			fatal(err)
+		}
+	}
+
+	if *token == "" {
+		issued, err := api.issueToken(ctx, *email)
+		if err != nil {
+			fatal(err)
+		}
+		*token = issued
+	}
+
+	chatURL, err := api.websocketURL(*email, *token)
+	if err != nil {
+		fatal(err)
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, chatURL, nil)
+	if err != nil {
+		if resp != nil {
+			err = fmt.Errorf("%w (%s)", err, resp.Status)
+		}
+		fatal(fmt.Errorf("failed to connect to the chat. Cause: %w", err))
+	}
+
+	session := newChat(conn, api, datatypes.CreateReviewUser{Name: *name, Email: *email}, *replyTimeout, os.Stdout)
+	fmt.Fprintf(os.Stderr, "connected as %s. Type /help for the commands.\n", *email)
+
+	if *review != "" {
+		if err := session.startReview(ctx, *review); err != nil {
+			session.close()
+			fatal(err)
+		}
+	}
+
+	if err := session.run(ctx, os.Stdin); err != nil {
+		fatal(err)
+	}
+}
+
+// firstAPIKey returns the first API key configured for the API in the environment
+func firstAPIKey() string {
+	key, _, _ := strings.Cut(os.Getenv("REVIEW_CHATBOT_AUTH_API_KEYS"), ",")
+	return strings.TrimSpace(key)
+}
+
+// fatal
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(1)
+}
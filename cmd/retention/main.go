@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/config"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
+	"github.com/JhonatanRSantos/review-chatbot/internal/retention"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/gocore/pkg/goenv"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+)
+
+// retention applies the data retention rules once and prints the report.
+// It uses the same configuration file, environment variables and setting flags of the API.
+func main() {
+	golog.SetEnv(goenv.Local)
+
+	ctx := gocontext.FromContext(context.Background())
+
+	loader := config.NewLoader(flag.CommandLine)
+	dryRun := flag.Bool("dry-run", false, "only report what would be removed")
+	messageDays := flag.Int("message-content-days", -1, "remove the message content after these days (0 keeps it). Defaults to retention.message_content_days")
+	userDays := flag.Int("inactive-user-days", -1, "anonymize the users without new chats after these days (0 keeps them). Defaults to retention.inactive_user_days")
+	flag.Parse()
+
+	configs, err := loader.Load(config.DatabaseSettings...)
+	if err != nil {
+		fatal(ctx, err)
+	}
+
+	if *messageDays < 0 {
+		*messageDays = configs.Retention.MessageContentDays
+	}
+
+	if *userDays < 0 {
+		*userDays = configs.Retention.InactiveUserDays
+	}
+
+	database, err := godb.NewDB(configs.Database.DBConfig())
+	if err != nil {
+		fatal(ctx, fmt.Errorf("failed to open new database connection. Cause %w", err))
+	}
+	defer database.Close()
+
+	if err := migrations.NewMigrator(database).Migrate(ctx); err != nil {
+		fatal(ctx, err)
+	}
+
+	retentionService := retention.NewRetentionService(retention.NewRepository(database), retention.RetentionServiceConfig{
+		MessageContentTTL: time.Duration(*messageDays) * 24 * time.Hour,
+		InactiveUserTTL:   time.Duration(*userDays) * 24 * time.Hour,
+	})
+
+	report, err := retentionService.Run(ctx, *dryRun)
+	if err != nil {
+		fatal(ctx, err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fatal(ctx, err)
+	}
+}
+
+// fatal
+func fatal(ctx context.Context, message error) {
+	golog.Log().Error(ctx, message.Error())
+	os.Exit(1)
+}
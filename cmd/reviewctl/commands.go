@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/admin"
+	"github.com/JhonatanRSantos/review-chatbot/internal/analysis"
+	"github.com/JhonatanRSantos/review-chatbot/internal/botsettings"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chat"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/conversation"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/export"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
+	"github.com/JhonatanRSantos/review-chatbot/internal/redaction"
+	"github.com/JhonatanRSantos/review-chatbot/internal/retention"
+	"github.com/JhonatanRSantos/review-chatbot/internal/scheduler"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/JhonatanRSantos/review-chatbot/internal/webhook"
+	"github.com/google/generative-ai-go/genai"
+)
+
+// createUser creates a customer of the tenant
+func createUser(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("create-user")
+	req := datatypes.CreateUserRequest{}
+	fs.StringVar(&req.FirstName, "first-name", "", "first name of the customer")
+	fs.StringVar(&req.LastName, "last-name", "", "last name of the customer")
+	fs.StringVar(&req.Email, "email", "", "email of the customer")
+	fs.Parse(args)
+
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	created, err := user.NewUserService(user.NewRepository(app.database)).Create(ctx, req.FirstName, req.LastName, req.Email)
+	if err != nil {
+		return err
+	}
+	return printJSON(os.Stdout, created)
+}
+
+// listChats lists the chats of the tenant, newest first
+func listChats(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("list-chats")
+	filter := datatypes.ChatFilter{}
+	page := datatypes.Page{}
+	fs.StringVar(&filter.UserID, "user-id", "", "only the chats of the user")
+	fs.StringVar(&filter.Email, "email", "", "only the chats of the user email")
+	fs.StringVar(&filter.Status, "status", "", "only the chats with the status (open, agent or closed)")
+	fs.IntVar(&page.Limit, "limit", 50, "maximum number of chats")
+	fs.IntVar(&page.Offset, "offset", 0, "number of chats to skip")
+	fs.Parse(args)
+
+	chats, err := admin.NewAdminService(admin.NewRepository(app.database)).ListChats(ctx, app.actor, filter, page)
+	if err != nil {
+		return err
+	}
+	return printJSON(os.Stdout, chats)
+}
+
+// printTranscript prints a chat with all its messages
+func printTranscript(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("transcript")
+	chatID := fs.String("chat", "", "id of the chat")
+	format := fs.String("format", export.FormatMarkdown, "json, csv or markdown")
+	fs.Parse(args)
+
+	if *chatID == "" {
+		return ErrMissingChatID
+	}
+
+	if !export.IsValidFormat(*format) {
+		return export.ErrUnsupportedFormat
+	}
+
+	adminService := admin.NewAdminService(admin.NewRepository(app.database))
+	if err := adminService.ExportChats(ctx, app.actor, *chatID, "format="+*format); err != nil {
+		return err
+	}
+	return newExportService(app).ExportChat(ctx, os.Stdout, *chatID, *format)
+}
+
+// startReview schedules a review due now, started by the running API as soon as the customer
+// is connected. With -local the review runs in the terminal instead, the operator answering as the customer.
+func startReview(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("review")
+	req := datatypes.OrderDeliveredRequest{DeliveredAt: time.Now().UTC()}
+	fs.StringVar(&req.User.Name, "name", "", "name of the customer")
+	fs.StringVar(&req.User.Email, "email", "", "email of the customer")
+	fs.StringVar(&req.Product, "product", "", "product to be reviewed")
+	fs.StringVar(&req.OrderID, "order", fmt.Sprintf("reviewctl-%d", req.DeliveredAt.UnixNano()), "id of the delivered order")
+	local := fs.Bool("local", false, "run the review in the terminal. The customer must exist")
+	fs.Parse(args)
+
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	if *local {
+		return runLocalReview(ctx, app, req, os.Stdin, os.Stdout)
+	}
+
+	schedulerService := scheduler.NewSchedulerService(scheduler.NewRepository(app.database), nil, scheduler.SchedulerServiceConfig{})
+	job, err := schedulerService.ScheduleReview(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(os.Stdout, job)
+}
+
+// runLocalReview runs the review like a web chat: the messages are redacted, saved and analyzed,
+// and the review is completed when the input ends. The operator answers as the customer in the input.
+func runLocalReview(ctx context.Context, app *app, req datatypes.OrderDeliveredRequest, in io.Reader, out io.Writer) error {
+	if app.configs.GenAIAPIKey.Value() == "" {
+		return ErrMissingAPIKey
+	}
+
+	customer, err := user.NewUserService(user.NewRepository(app.database)).FindByEmail(ctx, req.User.Email)
+	if err != nil {
+		return err
+	}
+
+	redactionService, err := newRedactionService(app)
+	if err != nil {
+		return err
+	}
+
+	chatbotService, err := newChatbotService(ctx, app)
+	if err != nil {
+		return err
+	}
+	defer chatbotService.Close()
+
+	webhookService := newWebhookService(app)
+	analysisService, err := newAnalysisService(ctx, app, webhookService)
+	if err != nil {
+		return err
+	}
+
+	chatService := chat.NewChatService(chat.NewRepository(app.database), webhookService)
+	conversationService := conversation.NewConversationService(chatService, analysisService, redactionService)
+	// the analyses run in background, the last ones must be saved before leaving
+	defer conversationService.Wait(ctx)
+
+	chatID, err := chatService.CreateChat(ctx, customer)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "chat %s started. Answer as %s, end the input (Ctrl+D) to finish the review.\n", chatID, customer.Email)
+
+	review := conversationService.NewConversation(chatID, chatbotService.StartChat(ctx))
+	greeting, err := conversationService.StartReview(ctx, review, req.User.Name, req.Product)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "bot: %s\n", greeting)
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		reply, err := conversationService.Turn(ctx, review, text)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "bot: %s\n", reply.Text)
+
+		if reply.Escalate {
+			fmt.Fprintf(os.Stderr, "the chat was handed over to a human agent: %s\n", reply.EscalationReason)
+			return chatService.EscalateChat(ctx, chatID, reply.EscalationReason)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return chatService.CompleteReview(ctx, chatID, customer, req.Product)
+}
+
+// migrate applies the pending migrations
+func migrate(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("migrate")
+	check := fs.Bool("check", false, "only fail if there are pending migrations")
+	fs.Parse(args)
+
+	migrator := migrations.NewMigrator(app.database)
+	if *check {
+		return migrator.Check(ctx)
+	}
+	return migrator.Migrate(ctx)
+}
+
+// exportChats writes the transcripts of the chats matching the filter
+func exportChats(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("export")
+	req := datatypes.CreateExportJobRequest{}
+	fs.StringVar(&req.UserID, "user-id", "", "only the chats of the user")
+	fs.StringVar(&req.Email, "email", "", "only the chats of the user email")
+	fs.StringVar(&req.From, "from", "", "only the chats created from this date")
+	fs.StringVar(&req.To, "to", "", "only the chats created until this date")
+	fs.StringVar(&req.Format, "format", export.FormatJSON, "json, csv or markdown")
+	out := fs.String("out", "", "file written instead of the standard output")
+	fs.Parse(args)
+
+	filter, err := req.Filter()
+	if err != nil {
+		return err
+	}
+
+	if !export.IsValidFormat(req.Format) {
+		return export.ErrUnsupportedFormat
+	}
+
+	details := fmt.Sprintf("userId=%q email=%q from=%q to=%q format=%q", req.UserID, req.Email, req.From, req.To, req.Format)
+	if err := admin.NewAdminService(admin.NewRepository(app.database)).ExportChats(ctx, app.actor, "", details); err != nil {
+		return err
+	}
+
+	return writeOutput(*out, func(w io.Writer) error {
+		exported, err := newExportService(app).ExportChats(ctx, w, filter, req.Format)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "%d chats exported\n", exported)
+		}
+		return err
+	})
+}
+
+// exportUser writes everything stored about the customer
+func exportUser(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("export-user")
+	email := fs.String("email", "", "email of the customer")
+	out := fs.String("out", "", "file written instead of the standard output")
+	fs.Parse(args)
+
+	data, err := user.NewUserService(user.NewRepository(app.database)).ExportData(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, func(w io.Writer) error {
+		return printJSON(w, data)
+	})
+}
+
+// purge applies the data retention rules of every tenant once and prints the report
+func purge(ctx context.Context, app *app, args []string) error {
+	fs := newFlagSet("purge")
+	dryRun := fs.Bool("dry-run", false, "only report what would be removed")
+	messageDays := fs.Int("message-content-days", app.configs.Retention.MessageContentDays, "remove the message content after these days (0 keeps it)")
+	userDays := fs.Int("inactive-user-days", app.configs.Retention.InactiveUserDays, "anonymize the users without new chats after these days (0 keeps them)")
+	fs.Parse(args)
+
+	retentionService := retention.NewRetentionService(retention.NewRepository(app.database), retention.RetentionServiceConfig{
+		MessageContentTTL: time.Duration(*messageDays) * 24 * time.Hour,
+		InactiveUserTTL:   time.Duration(*userDays) * 24 * time.Hour,
+	})
+
+	report, err := retentionService.Run(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+	return printJSON(os.Stdout, report)
+}
+
+// writeOutput calls write with the file, or the standard output when there is no file
+func writeOutput(path string, write func(w io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// newExportService
+func newExportService(app *app) *export.ExportService {
+	return export.NewExportService(export.NewRepository(app.database), export.ExportServiceConfig{
+		Directory: app.configs.Export.Directory,
+	})
+}
+
+// newWebhookService queues the chat events, the API delivers them
+func newWebhookService(app *app) *webhook.WebhookService {
+	return webhook.NewWebhookService(
+		webhook.NewRepository(app.database),
+		webhook.NewHTTPClient(app.configs.Webhooks.Timeout),
+		webhook.WebhookServiceConfig{
+			DeliveryInterval: app.configs.Webhooks.DeliveryInterval,
+			RetryInterval:    app.configs.Webhooks.RetryInterval,
+			MaxAttempts:      app.configs.Webhooks.MaxAttempts,
+		},
+	)
+}
+
+// newRedactionService creates the pii redaction with the configured detectors
+func newRedactionService(app *app) (*redaction.RedactionService, error) {
+	detectors, err := redaction.NewDetectors(strings.Split(app.configs.Redaction.Detectors, ",")...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, app.configs.Redaction.Detectors)
+	}
+
+	return redaction.NewRedactionService(redaction.RedactionServiceConfig{
+		Detectors: detectors,
+		StoreMode: app.configs.Redaction.StoreMode,
+		Key:       app.configs.Redaction.Key.Value(),
+	})
+}
+
+// newAnalysisService creates the message analysis with the classifier of the API. The negative
+// messages aren't handed over to an agent, there is none in the terminal.
+func newAnalysisService(ctx context.Context, app *app, publisher *webhook.WebhookService) (*analysis.AnalysisService, error) {
+	var classifier analysis.Classifier
+
+	switch app.configs.Analysis.Classifier {
+	case "keyword":
+		classifier = analysis.NewKeywordClassifier()
+	case "gemini":
+		client, err := genai.NewClient(ctx, app.configs.GenAIOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new genai client. Cause: %w", err)
+		}
+		classifier = analysis.NewGeminiClassifier(client.GenerativeModel(app.configs.Analysis.Model))
+	default:
+		return nil, fmt.Errorf("%w: %s", analysis.ErrInvalidClassifier, app.configs.Analysis.Classifier)
+	}
+
+	return analysis.NewAnalysisService(analysis.NewRepository(app.database), classifier, publisher, analysis.AnalysisServiceConfig{
+		NegativeThreshold: app.configs.Analysis.NegativeThreshold,
+	}), nil
+}
+
+// newChatbotService creates the chatbot with the bot settings file of the API, if configured
+func newChatbotService(ctx context.Context, app *app) (*chatbot.ChatbotService, error) {
+	client, err := genai.NewClient(ctx, app.configs.GenAIOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new genai client. Cause: %w", err)
+	}
+
+	bot, err := chatbot.NewChatbotService(ctx, chatbot.ChatbotServiceConfig{
+		InitInstruction: app.configs.ReviewChatbotInitInstruction,
+		AIClient:        client,
+	})
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	settingsService := botsettings.NewSettingsService(bot, botsettings.SettingsServiceConfig{
+		File: app.configs.BotSettings.File,
+	})
+
+	if settingsService.Enabled() {
+		if _, err := settingsService.Reload(ctx); err != nil {
+			bot.Close()
+			return nil, err
+		}
+	}
+	return bot, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/config"
+	"github.com/JhonatanRSantos/review-chatbot/internal/admin"
+	"github.com/JhonatanRSantos/review-chatbot/internal/analysis"
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/fakegemini"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestApp creates the app of the commands with a migrated SQLite database and the settings flags
+func newTestApp(t *testing.T, args ...string) (context.Context, *app) {
+	ctx := gocontext.FromContext(context.Background())
+
+	flagSet := flag.NewFlagSet("reviewctl", flag.ContinueOnError)
+	loader := config.NewLoader(flagSet)
+	require.NoError(t, flagSet.Parse(append([]string{"-tracing.exporter", "none"}, args...)))
+
+	configs, err := loader.Load()
+	require.NoError(t, err)
+
+	database, err := godb.NewDB(godb.DBConfig{
+		User:             "reviewctl",
+		Password:         "reviewctl",
+		Database:         filepath.Join(t.TempDir(), "review-chatbot"),
+		DatabaseType:     godb.SQLiteDB,
+		ConnectTimeout:   100 * time.Millisecond,
+		ConnectionParams: godb.DBConnectionParams{"_busy_timeout": "5000"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	require.NoError(t, migrations.NewMigrator(database).Migrate(ctx))
+
+	app := &app{
+		configs:  configs,
+		database: database,
+		actor:    datatypes.Admin{Name: "reviewctl:test", Role: auth.RoleAdmin},
+	}
+
+	ctx, err = app.withTenant(ctx, tenant.DefaultID)
+	require.NoError(t, err)
+	return ctx, app
+}
+
+// newFakeGemini starts a fake Gemini API, skipping the test when the genai client can't read it
+func newFakeGemini(t *testing.T) *fakegemini.Server {
+	if !fakegemini.StreamsSupported() {
+		t.Skip("the genai client can't read the API streams with this Go toolchain, run with GOEXPERIMENT=nojsonv2")
+	}
+
+	gemini := fakegemini.NewServer()
+	t.Cleanup(gemini.Close)
+	return gemini
+}
+
+// findChatMessages returns the messages of the only chat of the tenant
+func findChatMessages(t *testing.T, ctx context.Context, app *app) (datatypes.Chat, []datatypes.Message) {
+	repository := admin.NewRepository(app.database)
+	chats, err := repository.FindChats(ctx, datatypes.ChatFilter{}, datatypes.Page{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+
+	messages, err := repository.FindMessagesByChat(ctx, chats[0].ID)
+	require.NoError(t, err)
+	return chats[0], messages
+}
+
+// countRows counts the rows of the query
+func countRows(t *testing.T, ctx context.Context, app *app, query string) int {
+	var counts []int
+	require.NoError(t, app.database.SelectContext(ctx, &counts, query))
+	require.Len(t, counts, 1)
+	return counts[0]
+}
+
+func TestRunLocalReview(t *testing.T) {
+	req := datatypes.OrderDeliveredRequest{
+		User:    datatypes.CreateReviewUser{Name: "Mary", Email: "mary@continental.com"},
+		Product: "Smart TV",
+	}
+
+	t.Run("should fail without the gen ai api key", func(t *testing.T) {
+		ctx, app := newTestApp(t)
+
+		err := runLocalReview(ctx, app, req, strings.NewReader(""), &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrMissingAPIKey)
+	})
+
+	t.Run("should fail when the customer doesn't exist", func(t *testing.T) {
+		ctx, app := newTestApp(t, "-gen_ai_api_key", fakegemini.APIKey)
+
+		err := runLocalReview(ctx, app, req, strings.NewReader(""), &bytes.Buffer{})
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+	})
+
+	t.Run("should run the review like the web chat and complete it when the input ends", func(t *testing.T) {
+		gemini := newFakeGemini(t)
+		gemini.Script(
+			fakegemini.Text("Hi Mary! How do you like your new Smart TV?"),
+			fakegemini.Text("Great to hear that! We'll write to you at [EMAIL_1]."),
+		)
+
+		ctx, app := newTestApp(t, "-gen_ai_api_key", fakegemini.APIKey, "-gen_ai_endpoint", gemini.URL())
+		_, err := user.NewUserService(user.NewRepository(app.database)).Create(ctx, "Mary", "Ann", "mary@continental.com")
+		require.NoError(t, err)
+
+		out := &bytes.Buffer{}
+		in := strings.NewReader("I love it, write to me at mary@continental.com\n\n")
+		require.NoError(t, runLocalReview(ctx, app, req, in, out))
+
+		assert.Equal(t, "bot: Hi Mary! How do you like your new Smart TV?\nbot: Great to hear that! We'll write to you at mary@continental.com.\n", out.String())
+
+		requests := gemini.Requests()
+		require.Len(t, requests, 2)
+		assert.Equal(t, "Start a new review with Mary, who just bought a new Smart TV", requests[0].LastMessage())
+		assert.Equal(t, "I love it, write to me at [EMAIL_1]", requests[1].LastMessage())
+
+		chat, messages := findChatMessages(t, ctx, app)
+		assert.Equal(t, "Smart TV", chat.Product)
+		require.Len(t, messages, 3)
+		assert.Equal(t, datatypes.AuthorUser, messages[1].Author)
+
+		assert.Equal(t, 1, countRows(t, ctx, app, "SELECT COUNT(*) FROM message_analyses"))
+		assert.Equal(t, 1, countRows(t, ctx, app, "SELECT COUNT(*) FROM chats WHERE reviewed_at IS NOT NULL"))
+	})
+
+	t.Run("should hand the chat over when the chatbot asks for an agent", func(t *testing.T) {
+		gemini := newFakeGemini(t)
+		gemini.Script(
+			fakegemini.Text("Hi Mary! How do you like your new Smart TV?"),
+			fakegemini.Call(chatbot.EscalationFunctionName, map[string]any{"reason": "broken screen"}),
+		)
+
+		ctx, app := newTestApp(t, "-gen_ai_api_key", fakegemini.APIKey, "-gen_ai_endpoint", gemini.URL())
+		_, err := user.NewUserService(user.NewRepository(app.database)).Create(ctx, "Mary", "Ann", "mary@continental.com")
+		require.NoError(t, err)
+
+		in := strings.NewReader("the screen is broken\nhello?\n")
+		require.NoError(t, runLocalReview(ctx, app, req, in, &bytes.Buffer{}))
+
+		// the input after the handoff isn't sent to the chatbot
+		assert.Len(t, gemini.Requests(), 2)
+
+		chat, _ := findChatMessages(t, ctx, app)
+		assert.Equal(t, datatypes.ChatStatusAgent, chat.Status)
+		assert.Equal(t, 0, countRows(t, ctx, app, "SELECT COUNT(*) FROM chats WHERE reviewed_at IS NOT NULL"))
+	})
+}
+
+func TestNewAnalysisService(t *testing.T) {
+	t.Run("should fail with an unknown classifier", func(t *testing.T) {
+		ctx, app := newTestApp(t)
+		app.configs.Analysis.Classifier = "magic"
+
+		_, err := newAnalysisService(ctx, app, newWebhookService(app))
+		assert.ErrorIs(t, err, analysis.ErrInvalidClassifier)
+	})
+}
+
+func TestWriteOutput(t *testing.T) {
+	t.Run("should write into the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "chats.json")
+		require.NoError(t, writeOutput(path, func(w io.Writer) error {
+			return printJSON(w, []string{})
+		}))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "[]\n", string(content))
+	})
+
+	t.Run("should fail when the file can't be created", func(t *testing.T) {
+		err := writeOutput(filepath.Join(t.TempDir(), "missing", "chats.json"), func(w io.Writer) error {
+			return nil
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,8 @@
+package main
+
+import "errors"
+
+var (
+	ErrMissingChatID = errors.New("the chat id is required, set -chat")
+	ErrMissingAPIKey = errors.New("gen_ai_api_key is required to run the review in the terminal")
+)
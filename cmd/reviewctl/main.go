@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	osuser "os/user"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/JhonatanRSantos/review-chatbot/config"
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/gocore/pkg/goenv"
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+)
+
+// command is a reviewctl subcommand. The commands parse their own flags.
+type command struct {
+	name        string
+	description string
+	// beforeSchema commands run without checking the migrations and resolving the tenant
+	beforeSchema bool
+	run          func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = []command{
+	{name: "create-user", description: "create a customer", run: createUser},
+	{name: "list-chats", description: "list the chats of the tenant", run: listChats},
+	{name: "transcript", description: "print the transcript of a chat", run: printTranscript},
+	{name: "review", description: "start a review with a customer, or try it in the terminal with -local", run: startReview},
+	{name: "migrate", description: "apply the pending migrations, or only check them with -check", beforeSchema: true, run: migrate},
+	{name: "export", description: "export the chat transcripts", run: exportChats},
+	{name: "export-user", description: "export everything stored about a customer", run: exportUser},
+	{name: "purge", description: "apply the data retention rules once", run: purge},
+}
+
+// app is what the commands share: the configuration, the database and the operator
+type app struct {
+	configs  config.Configuration
+	database godb.DB
+	// actor is the operator written to the audit log
+	actor datatypes.Admin
+}
+
+// reviewctl runs the operator tasks: creating users, reading chats, starting reviews, applying
+// the migrations, exporting and purging data. It uses the same configuration file, environment
+// variables and setting flags of the API, followed by the command and its flags:
+//
+//	reviewctl -config config.yaml list-chats -email mary@continental.com
+func main() {
+	golog.SetEnv(goenv.Local)
+
+	ctx := gocontext.FromContext(context.Background())
+
+	loader := config.NewLoader(flag.CommandLine)
+	tenantID := flag.String("tenant", tenant.DefaultID, "id of the tenant the command acts on")
+	actor := flag.String("actor", currentUserName(), "operator name written to the audit log")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := findCommand(flag.Arg(0))
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	configs, err := loader.Load(config.DatabaseSettings...)
+	if err != nil {
+		fatal(ctx, err)
+	}
+	configureEmailValidation(configs)
+
+	database, err := godb.NewDB(configs.Database.DBConfig())
+	if err != nil {
+		fatal(ctx, fmt.Errorf("failed to open new database connection. Cause %w", err))
+	}
+	defer database.Close()
+
+	app := &app{
+		configs:  configs,
+		database: database,
+		actor:    datatypes.Admin{Name: "reviewctl:" + *actor, Role: auth.RoleAdmin},
+	}
+
+	if !cmd.beforeSchema {
+		if err := migrations.NewMigrator(database).Check(ctx); err != nil {
+			fatal(ctx, fmt.Errorf("%w. Run reviewctl migrate first", err))
+		}
+
+		if ctx, err = app.withTenant(ctx, *tenantID); err != nil {
+			fatal(ctx, err)
+		}
+	}
+
+	if err := cmd.run(ctx, app, flag.Args()[1:]); err != nil {
+		fatal(ctx, err)
+	}
+}
+
+// findCommand
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// usage lists the global flags and the commands
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: reviewctl [flags] <command> [command flags]\n\nCommands:\n")
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nRun reviewctl <command> -h for the command flags.\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// newFlagSet creates the flag set of a command
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("reviewctl "+name, flag.ExitOnError)
+}
+
+// withTenant returns a copy of the context carrying the tenant, loaded like the API does
+func (a *app) withTenant(ctx context.Context, id string) (context.Context, error) {
+	tenantService, err := tenant.NewTenantService(tenant.NewRepository(a.database), tenant.TenantServiceConfig{
+		Default: datatypes.Tenant{
+			Name:         a.configs.Tenants.DefaultName,
+			Domain:       a.configs.Tenants.DefaultDomain,
+			SupportEmail: a.configs.Tenants.DefaultSupportEmail,
+			Policies:     a.configs.Tenants.DefaultPolicies,
+		},
+	})
+	if err != nil {
+		return ctx, err
+	}
+
+	if err := tenantService.Refresh(ctx); err != nil {
+		return ctx, err
+	}
+
+	found, err := tenantService.FindByID(id)
+	if err != nil {
+		return ctx, fmt.Errorf("%w: %s", err, id)
+	}
+	return tenant.WithTenant(ctx, found), nil
+}
+
+// configureEmailValidation blocks the disposable and the configured email domains, like the API
+func configureEmailValidation(configs config.Configuration) {
+	domains := []string{}
+	if configs.Validation.BlockDisposableEmails {
+		domains = append(domains, validation.DisposableDomains()...)
+	}
+
+	if configs.Validation.BlockedEmailDomains != "" {
+		domains = append(domains, strings.Split(configs.Validation.BlockedEmailDomains, ",")...)
+	}
+	validation.BlockDomains(domains...)
+}
+
+// currentUserName returns the name of the user running the command
+func currentUserName() string {
+	if current, err := osuser.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return "unknown"
+}
+
+// printJSON writes the value as indented JSON
+func printJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// fatal
+func fatal(ctx context.Context, message error) {
+	golog.Log().Error(ctx, message.Error())
+	os.Exit(1)
+}
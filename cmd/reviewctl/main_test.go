@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCommand(t *testing.T) {
+	t.Run("should find the command by its name", func(t *testing.T) {
+		cmd, ok := findCommand("review")
+		require.True(t, ok)
+		assert.Equal(t, "review", cmd.name)
+		assert.False(t, cmd.beforeSchema)
+	})
+
+	t.Run("should run the migrations before the schema is checked", func(t *testing.T) {
+		cmd, ok := findCommand("migrate")
+		require.True(t, ok)
+		assert.True(t, cmd.beforeSchema)
+	})
+
+	t.Run("should not find an unknown command", func(t *testing.T) {
+		_, ok := findCommand("deploy")
+		assert.False(t, ok)
+	})
+}
+
+func TestWithTenant(t *testing.T) {
+	t.Run("should load the default tenant", func(t *testing.T) {
+		ctx, _ := newTestApp(t)
+		assert.Equal(t, tenant.DefaultID, tenant.IDFromContext(ctx))
+	})
+
+	t.Run("should fail with an unknown tenant", func(t *testing.T) {
+		ctx, app := newTestApp(t)
+
+		_, err := app.withTenant(ctx, "acme")
+		assert.ErrorIs(t, err, tenant.ErrTenantNotFound)
+	})
+}
+
+func TestPrintJSON(t *testing.T) {
+	t.Run("should write the value as indented JSON", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		require.NoError(t, printJSON(out, map[string]int{"exported": 2}))
+		assert.Equal(t, "{\n  \"exported\": 2\n}\n", out.String())
+	})
+}
@@ -1,40 +1,345 @@
 package config
 
 import (
+	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
 )
 
+// Every setting has a yaml tag, its key in the configuration file and the name of its flag, and
+// usually an env tag. The secrets are redacted when the configuration is logged.
 type Configuration struct {
-	ServerPort                   string
-	GenAIAPIKey                  string
-	StaticFilesRelativePath      string
-	ReviewChatbotInitInstruction string
-	Database                     godb.DBConfig
+	ServerPort                   string             `yaml:"server_port"       env:"REVIEW_CHATBOT_SERVER_PORT"`
+	GenAIAPIKey                  Secret             `yaml:"gen_ai_api_key"    env:"REVIEW_CHATBOT_GEN_AI_API_KEY"`
+	GenAIEndpoint                string             `yaml:"gen_ai_endpoint"   env:"REVIEW_CHATBOT_GEN_AI_ENDPOINT"`
+	StaticFilesRelativePath      string             `yaml:"static_files_path" env:"REVIEW_CHATBOT_STATIC_FILES_PATH"`
+	ReviewChatbotInitInstruction string             `yaml:"init_instruction"`
+	ShutdownTimeout              time.Duration      `yaml:"shutdown_timeout"  env:"REVIEW_CHATBOT_SHUTDOWN_TIMEOUT"`
+	Database                     DatabaseConfig     `yaml:"database"`
+	Scheduler                    SchedulerConfig    `yaml:"scheduler"`
+	Webhooks                     WebhooksConfig     `yaml:"webhooks"`
+	Notification                 NotificationConfig `yaml:"notification"`
+	Auth                         AuthConfig         `yaml:"auth"`
+	Analysis                     AnalysisConfig     `yaml:"analysis"`
+	Export                       ExportConfig       `yaml:"export"`
+	Redaction                    RedactionConfig    `yaml:"redaction"`
+	Retention                    RetentionConfig    `yaml:"retention"`
+	Validation                   ValidationConfig   `yaml:"validation"`
+	Tracing                      TracingConfig      `yaml:"tracing"`
+	Health                       HealthConfig       `yaml:"health"`
+	BotSettings                  BotSettingsConfig  `yaml:"bot_settings"`
+	Tenants                      TenantsConfig      `yaml:"tenants"`
 }
 
-func LoadConfiguration() Configuration {
-	config := Configuration{
-		ServerPort:                   os.Getenv("REVIEW_CHATBOT_SERVER_PORT"),
-		GenAIAPIKey:                  os.Getenv("REVIEW_CHATBOT_GEN_AI_API_KEY"),
+type DatabaseConfig struct {
+	Host     string `yaml:"host"     env:"REVIEW_CHATBOT_DB_HOST"`
+	Port     string `yaml:"port"     env:"REVIEW_CHATBOT_DB_PORT"`
+	User     string `yaml:"user"     env:"REVIEW_CHATBOT_DB_USER"`
+	Password Secret `yaml:"password" env:"REVIEW_CHATBOT_DB_PASSWORD"`
+	Database string `yaml:"database" env:"REVIEW_CHATBOT_DB_DATABASE"`
+}
+
+type SchedulerConfig struct {
+	ReviewDelay   time.Duration `yaml:"review_delay"   env:"REVIEW_CHATBOT_SCHEDULER_REVIEW_DELAY"`
+	PollInterval  time.Duration `yaml:"poll_interval"  env:"REVIEW_CHATBOT_SCHEDULER_POLL_INTERVAL"`
+	RetryInterval time.Duration `yaml:"retry_interval" env:"REVIEW_CHATBOT_SCHEDULER_RETRY_INTERVAL"`
+	MaxAttempts   int           `yaml:"max_attempts"   env:"REVIEW_CHATBOT_SCHEDULER_MAX_ATTEMPTS"`
+}
+
+type WebhooksConfig struct {
+	DeliveryInterval time.Duration `yaml:"delivery_interval" env:"REVIEW_CHATBOT_WEBHOOKS_DELIVERY_INTERVAL"`
+	RetryInterval    time.Duration `yaml:"retry_interval"    env:"REVIEW_CHATBOT_WEBHOOKS_RETRY_INTERVAL"`
+	MaxAttempts      int           `yaml:"max_attempts"      env:"REVIEW_CHATBOT_WEBHOOKS_MAX_ATTEMPTS"`
+	Timeout          time.Duration `yaml:"timeout"           env:"REVIEW_CHATBOT_WEBHOOKS_TIMEOUT"`
+}
+
+type NotificationConfig struct {
+	SMTPHost     string        `yaml:"smtp_host"     env:"REVIEW_CHATBOT_SMTP_HOST"`
+	SMTPPort     string        `yaml:"smtp_port"     env:"REVIEW_CHATBOT_SMTP_PORT"`
+	SMTPUsername string        `yaml:"smtp_username" env:"REVIEW_CHATBOT_SMTP_USERNAME"`
+	SMTPPassword Secret        `yaml:"smtp_password" env:"REVIEW_CHATBOT_SMTP_PASSWORD"`
+	SMTPTimeout  time.Duration `yaml:"smtp_timeout"  env:"REVIEW_CHATBOT_SMTP_TIMEOUT"`
+	From         string        `yaml:"from"          env:"REVIEW_CHATBOT_SMTP_FROM"`
+	BaseURL      string        `yaml:"base_url"      env:"REVIEW_CHATBOT_PUBLIC_URL"`
+	LinkSecret   Secret        `yaml:"link_secret"   env:"REVIEW_CHATBOT_INVITATION_LINK_SECRET"`
+	LinkTTL      time.Duration `yaml:"link_ttl"      env:"REVIEW_CHATBOT_INVITATION_LINK_TTL"`
+}
+
+type AuthConfig struct {
+	// SigningKeys in the format "id1:secret1,id2:secret2". The first one signs new tokens.
+	SigningKeys Secret `yaml:"signing_keys" env:"REVIEW_CHATBOT_AUTH_SIGNING_KEYS"`
+	// APIKeys is a comma separated list of accepted api keys
+	APIKeys  Secret        `yaml:"api_keys"  env:"REVIEW_CHATBOT_AUTH_API_KEYS"`
+	TokenTTL time.Duration `yaml:"token_ttl" env:"REVIEW_CHATBOT_AUTH_TOKEN_TTL"`
+	// AdminCredentials in the format "name1:role1:key1,name2:role2:key2"
+	AdminCredentials Secret `yaml:"admin_credentials" env:"REVIEW_CHATBOT_ADMIN_CREDENTIALS"`
+}
+
+type AnalysisConfig struct {
+	// Classifier is "keyword" (default) or "gemini"
+	Classifier        string  `yaml:"classifier"         env:"REVIEW_CHATBOT_ANALYSIS_CLASSIFIER"`
+	Model             string  `yaml:"model"              env:"REVIEW_CHATBOT_ANALYSIS_MODEL"`
+	NegativeThreshold float64 `yaml:"negative_threshold" env:"REVIEW_CHATBOT_ANALYSIS_NEGATIVE_THRESHOLD"`
+	// AutoEscalate hands chats over to a human agent on negative messages
+	AutoEscalate bool `yaml:"auto_escalate" env:"REVIEW_CHATBOT_ANALYSIS_AUTO_ESCALATE"`
+}
+
+type ExportConfig struct {
+	Directory    string        `yaml:"directory"     env:"REVIEW_CHATBOT_EXPORT_DIR"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"REVIEW_CHATBOT_EXPORT_POLL_INTERVAL"`
+	// StaleTimeout is how long an export can run before it is run again by any instance
+	StaleTimeout time.Duration `yaml:"stale_timeout" env:"REVIEW_CHATBOT_EXPORT_STALE_TIMEOUT"`
+}
+
+type RedactionConfig struct {
+	// Detectors is a comma separated list of email, card, phone and address. Empty disables the redaction.
+	Detectors string `yaml:"detectors" env:"REVIEW_CHATBOT_REDACTION_DETECTORS"`
+	// StoreMode is "plain" (default), "redacted" or "encrypted"
+	StoreMode string `yaml:"store_mode" env:"REVIEW_CHATBOT_REDACTION_STORE"`
+	// Key encrypts the original values in the encrypted store mode
+	Key Secret `yaml:"key" env:"REVIEW_CHATBOT_REDACTION_KEY"`
+}
+
+type RetentionConfig struct {
+	// MessageContentDays removes the message content after these days. Zero keeps it forever.
+	MessageContentDays int `yaml:"message_content_days" env:"REVIEW_CHATBOT_RETENTION_MESSAGE_CONTENT_DAYS"`
+	// InactiveUserDays anonymizes the users without new chats after these days. Zero keeps them forever.
+	InactiveUserDays int           `yaml:"inactive_user_days" env:"REVIEW_CHATBOT_RETENTION_INACTIVE_USER_DAYS"`
+	Interval         time.Duration `yaml:"interval"           env:"REVIEW_CHATBOT_RETENTION_INTERVAL"`
+	// DryRun only logs what the background job would remove
+	DryRun bool `yaml:"dry_run" env:"REVIEW_CHATBOT_RETENTION_DRY_RUN"`
+}
+
+type ValidationConfig struct {
+	// BlockDisposableEmails rejects new users and reviews from the built-in disposable email domains
+	BlockDisposableEmails bool `yaml:"block_disposable_emails" env:"REVIEW_CHATBOT_BLOCK_DISPOSABLE_EMAILS"`
+	// BlockedEmailDomains is a comma separated list of extra domains to reject
+	BlockedEmailDomains string `yaml:"blocked_email_domains" env:"REVIEW_CHATBOT_BLOCKED_EMAIL_DOMAINS"`
+}
+
+type TracingConfig struct {
+	// Exporter is "none" (default), "stdout" or "otlp"
+	Exporter string `yaml:"exporter" env:"REVIEW_CHATBOT_TRACING_EXPORTER"`
+	// OTLPEndpoint of the collector, e.g. "localhost:4318"
+	OTLPEndpoint string `yaml:"otlp_endpoint" env:"REVIEW_CHATBOT_TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool   `yaml:"otlp_insecure" env:"REVIEW_CHATBOT_TRACING_OTLP_INSECURE"`
+	// SampleRatio of the new traces, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" env:"REVIEW_CHATBOT_TRACING_SAMPLE_RATIO"`
+}
+
+type HealthConfig struct {
+	// Timeout of each readiness check
+	Timeout time.Duration `yaml:"timeout" env:"REVIEW_CHATBOT_HEALTH_TIMEOUT"`
+	// CheckModel adds a token count request to the readiness checks
+	CheckModel bool `yaml:"check_model" env:"REVIEW_CHATBOT_HEALTH_CHECK_MODEL"`
+	// ModelCheckTTL skips the model check for this long after it succeeds
+	ModelCheckTTL time.Duration `yaml:"model_check_ttl" env:"REVIEW_CHATBOT_HEALTH_MODEL_CHECK_TTL"`
+}
+
+type BotSettingsConfig struct {
+	// File is a YAML file with the system instruction, model parameters and safety settings,
+	// reloaded without restarting the API. Empty keeps the built-in settings.
+	File           string        `yaml:"file"            env:"REVIEW_CHATBOT_BOT_SETTINGS_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"REVIEW_CHATBOT_BOT_SETTINGS_RELOAD_INTERVAL"`
+}
+
+type TenantsConfig struct {
+	// The default tenant is the store of the global api keys, of the hosts that don't belong to
+	// any tenant and of the data created before the tenants
+	DefaultName         string `yaml:"default_name"          env:"REVIEW_CHATBOT_TENANT_NAME"`
+	DefaultDomain       string `yaml:"default_domain"        env:"REVIEW_CHATBOT_TENANT_DOMAIN"`
+	DefaultSupportEmail string `yaml:"default_support_email" env:"REVIEW_CHATBOT_TENANT_SUPPORT_EMAIL"`
+	DefaultPolicies     string `yaml:"default_policies"`
+	// RefreshInterval is how often the tenants created by other instances are loaded
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"REVIEW_CHATBOT_TENANTS_REFRESH_INTERVAL"`
+}
+
+// DatabaseSettings are the settings required to connect to the database
+var DatabaseSettings = []string{"database.host", "database.port", "database.user", "database.database"}
+
+// APISettings are the settings required by the API
+var APISettings = append([]string{"server_port", "gen_ai_api_key", "auth.signing_keys", "auth.api_keys"}, DatabaseSettings...)
+
+// GenAIOptions returns the options of the Gemini API clients
+func (c Configuration) GenAIOptions() []option.ClientOption {
+	options := []option.ClientOption{option.WithAPIKey(c.GenAIAPIKey.Value())}
+	if c.GenAIEndpoint != "" {
+		options = append(options, option.WithEndpoint(c.GenAIEndpoint))
+	}
+	return options
+}
+
+// DBConfig returns the connection configs of the database. clientFoundRows makes MySQL report the
+// matched rows of an UPDATE, like SQLite, so an update that doesn't change the values isn't taken for a missing row.
+func (dc DatabaseConfig) DBConfig() godb.DBConfig {
+	return godb.DBConfig{
+		Host:             dc.Host,
+		Port:             dc.Port,
+		User:             dc.User,
+		Password:         dc.Password.Value(),
+		Database:         dc.Database,
+		DatabaseType:     godb.MySQLDB,
+		ConnectionParams: map[string]string{"parseTime": "true", "clientFoundRows": "true"},
+	}
+}
+
+// String returns the configuration in YAML with the secrets redacted, so it can be logged
+func (c Configuration) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("failed to encode configuration. Cause: %s", err)
+	}
+	return string(data)
+}
+
+// defaultConfiguration returns the configuration used when a setting isn't set
+func defaultConfiguration() Configuration {
+	return Configuration{
 		StaticFilesRelativePath:      "./static",
 		ReviewChatbotInitInstruction: reviewChatbotInitInstruction,
-		Database: godb.DBConfig{
-			Host:             os.Getenv("REVIEW_CHATBOT_DB_HOST"),
-			Port:             os.Getenv("REVIEW_CHATBOT_DB_PORT"),
-			User:             os.Getenv("REVIEW_CHATBOT_DB_USER"),
-			Password:         os.Getenv("REVIEW_CHATBOT_DB_PASSWORD"),
-			Database:         os.Getenv("REVIEW_CHATBOT_DB_DATABASE"),
-			DatabaseType:     godb.MySQLDB,
-			ConnectionParams: map[string]string{},
+		ShutdownTimeout:              30 * time.Second,
+		Scheduler: SchedulerConfig{
+			ReviewDelay:   72 * time.Hour,
+			PollInterval:  time.Minute,
+			RetryInterval: 15 * time.Minute,
+			MaxAttempts:   5,
+		},
+		Webhooks: WebhooksConfig{
+			DeliveryInterval: 5 * time.Second,
+			RetryInterval:    30 * time.Second,
+			MaxAttempts:      8,
+			Timeout:          10 * time.Second,
+		},
+		Notification: NotificationConfig{
+			SMTPPort:    "587",
+			SMTPTimeout: 30 * time.Second,
+			LinkTTL:     7 * 24 * time.Hour,
+			From:        "support@aitechshop.com",
+			BaseURL:     "http://localhost:9000",
+		},
+		Auth: AuthConfig{
+			TokenTTL: time.Hour,
+		},
+		Analysis: AnalysisConfig{
+			Classifier:        "keyword",
+			Model:             "gemini-1.5-flash-latest",
+			NegativeThreshold: -0.5,
+		},
+		Export: ExportConfig{
+			Directory:    filepath.Join(os.TempDir(), "review-chatbot-exports"),
+			PollInterval: 10 * time.Second,
+			StaleTimeout: time.Hour,
+		},
+		Redaction: RedactionConfig{
+			Detectors: "email,card,phone,address",
+			StoreMode: "plain",
+		},
+		Retention: RetentionConfig{
+			Interval: 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			Timeout:       2 * time.Second,
+			ModelCheckTTL: 5 * time.Minute,
+		},
+		BotSettings: BotSettingsConfig{
+			ReloadInterval: 10 * time.Second,
+		},
+		Tenants: TenantsConfig{
+			DefaultName:         "AI Tech Shop",
+			DefaultDomain:       "www.aitechshop.com",
+			DefaultSupportEmail: "support@aitechshop.com",
+			DefaultPolicies:     defaultTenantPolicies,
+			RefreshInterval:     time.Minute,
 		},
 	}
+}
 
-	if strings.ToLower(strings.TrimSpace(os.Getenv("REVIEW_CHATBOT_DEBUG"))) == "true" {
-		config.StaticFilesRelativePath = "../../static"
+// validate adds every invalid setting to the errors, keyed by its path
+func (c Configuration) validate(errs validation.Errors) {
+	if port, err := strconv.Atoi(c.ServerPort); c.ServerPort != "" && (err != nil || port < 1 || port > 65535) {
+		errs.Add("server_port", "must be a port number from 1 to 65535")
 	}
 
-	return config
+	positive := map[string]time.Duration{
+		"shutdown_timeout":             c.ShutdownTimeout,
+		"scheduler.poll_interval":      c.Scheduler.PollInterval,
+		"scheduler.retry_interval":     c.Scheduler.RetryInterval,
+		"webhooks.delivery_interval":   c.Webhooks.DeliveryInterval,
+		"webhooks.retry_interval":      c.Webhooks.RetryInterval,
+		"webhooks.timeout":             c.Webhooks.Timeout,
+		"auth.token_ttl":               c.Auth.TokenTTL,
+		"export.poll_interval":         c.Export.PollInterval,
+		"export.stale_timeout":         c.Export.StaleTimeout,
+		"retention.interval":           c.Retention.Interval,
+		"health.timeout":               c.Health.Timeout,
+		"health.model_check_ttl":       c.Health.ModelCheckTTL,
+		"bot_settings.reload_interval": c.BotSettings.ReloadInterval,
+		"tenants.refresh_interval":     c.Tenants.RefreshInterval,
+	}
+	for path, duration := range positive {
+		if duration <= 0 {
+			errs.Add(path, "must be greater than zero")
+		}
+	}
+
+	if c.Scheduler.ReviewDelay < 0 {
+		errs.Add("scheduler.review_delay", "can't be negative")
+	}
+
+	if c.Scheduler.MaxAttempts < 1 {
+		errs.Add("scheduler.max_attempts", "must be at least 1")
+	}
+
+	if c.Webhooks.MaxAttempts < 1 {
+		errs.Add("webhooks.max_attempts", "must be at least 1")
+	}
+
+	if c.Analysis.Classifier != "keyword" && c.Analysis.Classifier != "gemini" {
+		errs.Add("analysis.classifier", "must be keyword or gemini")
+	}
+
+	if c.Analysis.NegativeThreshold < -1 || c.Analysis.NegativeThreshold > 1 {
+		errs.Add("analysis.negative_threshold", "must be between -1 and 1")
+	}
+
+	switch c.Redaction.StoreMode {
+	case "plain", "redacted":
+	case "encrypted":
+		if c.Redaction.Key == "" {
+			errs.Add("redaction.key", "is required in the encrypted store mode")
+		}
+	default:
+		errs.Add("redaction.store_mode", "must be plain, redacted or encrypted")
+	}
+
+	if c.Retention.MessageContentDays < 0 {
+		errs.Add("retention.message_content_days", "can't be negative")
+	}
+
+	if c.Retention.InactiveUserDays < 0 {
+		errs.Add("retention.inactive_user_days", "can't be negative")
+	}
+
+	if c.Tracing.Exporter != "none" && c.Tracing.Exporter != "stdout" && c.Tracing.Exporter != "otlp" {
+		errs.Add("tracing.exporter", "must be none, stdout or otlp")
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs.Add("tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if c.Tenants.DefaultName == "" {
+		errs.Add("tenants.default_name", validation.MsgRequired)
+	}
 }
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigurationString(t *testing.T) {
+	t.Run("should redact the secrets", func(t *testing.T) {
+		configs := defaultConfiguration()
+		configs.GenAIAPIKey = "AIza-secret-key"
+		configs.Database.Host = "db.internal"
+		configs.Database.Password = "qwerty"
+
+		text := configs.String()
+		assert.Contains(t, text, "db.internal")
+		assert.Contains(t, text, "gen_ai_api_key: '[REDACTED]'")
+		assert.NotContains(t, text, "AIza-secret-key")
+		assert.NotContains(t, text, "qwerty")
+		assert.NotContains(t, fmt.Sprintf("%v %+v", configs.GenAIAPIKey, configs.Database), "qwerty")
+
+		data, err := json.Marshal(configs.Database)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "qwerty")
+	})
+
+	t.Run("should keep the empty secrets empty", func(t *testing.T) {
+		assert.Equal(t, "", Secret("").String())
+		assert.Equal(t, "qwerty", Secret("qwerty").Value())
+	})
+}
+
+func TestDatabaseConfigDBConfig(t *testing.T) {
+	t.Run("should report the matched rows of the updates", func(t *testing.T) {
+		configs := defaultConfiguration()
+
+		params := configs.Database.DBConfig().ConnectionParams
+		assert.Equal(t, "true", params["parseTime"])
+		assert.Equal(t, "true", params["clientFoundRows"])
+	})
+}
@@ -0,0 +1,7 @@
+package config
+
+import "errors"
+
+var (
+	ErrUnsupportedConfigFile = errors.New("configuration file must be .yaml, .yml or .toml")
+)
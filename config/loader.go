@@ -0,0 +1,259 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv has the path of the configuration file when the -config flag isn't set
+const configFileEnv = "REVIEW_CHATBOT_CONFIG_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting is a field of the configuration, addressed by its path, e.g. database.host
+type setting struct {
+	path  string
+	env   string
+	value reflect.Value
+}
+
+// flagValue keeps the raw value of a setting flag, applied after the file and the env vars
+type flagValue struct {
+	value string
+	set   bool
+}
+
+// String
+func (fv *flagValue) String() string {
+	return fv.value
+}
+
+// Set
+func (fv *flagValue) Set(value string) error {
+	fv.value = value
+	fv.set = true
+	return nil
+}
+
+// Loader loads the configuration in layers, each one overriding the previous: the defaults, the
+// YAML or TOML file, the env vars and the flags
+type Loader struct {
+	file   *string
+	flags  map[string]*flagValue
+	getenv func(string) (string, bool)
+}
+
+// NewLoader registers the -config flag and a flag for every setting, named after its path like
+// -database.host, in the flag set. Parse the flags before calling Load.
+func NewLoader(flagSet *flag.FlagSet) *Loader {
+	loader := &Loader{
+		file:   flagSet.String("config", "", fmt.Sprintf("path of the YAML or TOML configuration file (%s)", configFileEnv)),
+		flags:  map[string]*flagValue{},
+		getenv: os.LookupEnv,
+	}
+
+	defaults := defaultConfiguration()
+	for _, setting := range settings(&defaults) {
+		usage := fmt.Sprintf("overrides the %s setting", setting.path)
+		if setting.env != "" {
+			usage = fmt.Sprintf("%s (%s)", usage, setting.env)
+		}
+
+		value := &flagValue{}
+		flagSet.Var(value, setting.path, usage)
+		loader.flags[setting.path] = value
+	}
+	return loader
+}
+
+// Load returns the configuration or an error listing every invalid setting. The required
+// settings, e.g. APISettings, can't be empty.
+func (l *Loader) Load(required ...string) (Configuration, error) {
+	baseError := "failed to load configuration. Cause: %w"
+
+	config := defaultConfiguration()
+	byPath := map[string]setting{}
+	ordered := settings(&config)
+	for _, setting := range ordered {
+		byPath[setting.path] = setting
+	}
+
+	errs := validation.Errors{}
+	if err := l.loadFile(byPath, errs); err != nil {
+		return config, fmt.Errorf(baseError, err)
+	}
+
+	for _, setting := range ordered {
+		if setting.env == "" {
+			continue
+		}
+
+		if raw, ok := l.getenv(setting.env); ok && raw != "" {
+			if message := setValue(setting.value, raw); message != "" {
+				errs.Add(setting.env, message)
+			}
+		}
+	}
+
+	for path, value := range l.flags {
+		if setting, ok := byPath[path]; ok && value.set {
+			if message := setValue(setting.value, value.value); message != "" {
+				errs.Add("-"+path, message)
+			}
+		}
+	}
+
+	for _, path := range required {
+		if setting, ok := byPath[path]; !ok || setting.value.IsZero() {
+			errs.Add(path, validation.MsgRequired)
+		}
+	}
+
+	config.validate(errs)
+	if err := errs.Err(); err != nil {
+		return config, fmt.Errorf(baseError, err)
+	}
+	return config, nil
+}
+
+// loadFile applies the settings of the configuration file, if there is one. Unknown and invalid
+// settings are added to the errors, so they are listed with the others.
+func (l *Loader) loadFile(byPath map[string]setting, errs validation.Errors) error {
+	path := *l.file
+	if path == "" {
+		path, _ = l.getenv(configFileEnv)
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file. Cause: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedConfigFile, path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration file %s. Cause: %w", path, err)
+	}
+
+	for settingPath, raw := range flatten("", values) {
+		setting, ok := byPath[settingPath]
+		if !ok {
+			errs.Add(settingPath, "is not a known setting")
+			continue
+		}
+
+		if message := setValue(setting.value, raw); message != "" {
+			errs.Add(settingPath, message)
+		}
+	}
+	return nil
+}
+
+// flatten turns the nested tables of the file into paths like database.host. Lists are joined
+// with commas, like the env vars.
+func flatten(prefix string, values map[string]interface{}) map[string]string {
+	flat := map[string]string{}
+
+	for key, value := range values {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		switch typed := value.(type) {
+		case nil:
+		case map[string]interface{}:
+			for nestedPath, nestedValue := range flatten(path, typed) {
+				flat[nestedPath] = nestedValue
+			}
+		case []interface{}:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, fmt.Sprint(item))
+			}
+			flat[path] = strings.Join(items, ",")
+		default:
+			flat[path] = fmt.Sprint(typed)
+		}
+	}
+	return flat
+}
+
+// settings returns every setting of the configuration, in the order of the fields
+func settings(config *Configuration) []setting {
+	return appendSettings(nil, "", reflect.ValueOf(config).Elem())
+}
+
+// appendSettings appends the fields of the struct and of its nested structs
+func appendSettings(all []setting, prefix string, value reflect.Value) []setting {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		path := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct {
+			all = appendSettings(all, path+".", value.Field(i))
+			continue
+		}
+		all = append(all, setting{path: path, env: field.Tag.Get("env"), value: value.Field(i)})
+	}
+	return all
+}
+
+// setValue parses the raw value into the setting. It returns the error message when it's invalid.
+func setValue(value reflect.Value, raw string) string {
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case value.Type() == durationType:
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return "must be a duration like 30s, 15m or 72h"
+		}
+		value.SetInt(int64(duration))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "must be true or false"
+		}
+		value.SetBool(parsed)
+	case value.Kind() == reflect.Int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return "must be an integer"
+		}
+		value.SetInt(int64(parsed))
+	case value.Kind() == reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return "must be a number"
+		}
+		value.SetFloat(parsed)
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %s", value.Type()))
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLoader creates a loader reading the env vars from the map
+func newTestLoader(t *testing.T, env map[string]string, args ...string) *Loader {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(flagSet)
+	loader.getenv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	require.NoError(t, flagSet.Parse(args))
+	return loader
+}
+
+// writeFile writes a configuration file in a temporary directory
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoaderLoad(t *testing.T) {
+	t.Run("should use the defaults", func(t *testing.T) {
+		configs, err := newTestLoader(t, nil).Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, "./static", configs.StaticFilesRelativePath)
+		assert.Equal(t, 72*time.Hour, configs.Scheduler.ReviewDelay)
+		assert.Equal(t, "keyword", configs.Analysis.Classifier)
+		assert.Equal(t, reviewChatbotInitInstruction, configs.ReviewChatbotInitInstruction)
+		assert.Equal(t, "AI Tech Shop", configs.Tenants.DefaultName)
+		assert.Equal(t, defaultTenantPolicies, configs.Tenants.DefaultPolicies)
+	})
+
+	t.Run("should override the file with the env vars and the env vars with the flags", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server_port: 8080
+gen_ai_api_key: file-key
+database:
+  host: db.internal
+  port: 3306
+scheduler:
+  review_delay: 48h
+  max_attempts: 3
+validation:
+  blocked_email_domains: [example.com, example.org]
+`)
+		configs, err := newTestLoader(t, map[string]string{
+			"REVIEW_CHATBOT_SERVER_PORT": "9000",
+			"REVIEW_CHATBOT_DB_HOST":     "env.internal",
+			"REVIEW_CHATBOT_DB_USER":     "",
+		}, "-config", path, "-database.host", "flag.internal").Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, "9000", configs.ServerPort)
+		assert.Equal(t, "file-key", configs.GenAIAPIKey.Value())
+		assert.Equal(t, "flag.internal", configs.Database.Host)
+		assert.Equal(t, "3306", configs.Database.Port)
+		assert.Equal(t, 48*time.Hour, configs.Scheduler.ReviewDelay)
+		assert.Equal(t, 3, configs.Scheduler.MaxAttempts)
+		assert.Equal(t, "example.com,example.org", configs.Validation.BlockedEmailDomains)
+		assert.Equal(t, time.Minute, configs.Scheduler.PollInterval)
+	})
+
+	t.Run("should read toml files from the env var", func(t *testing.T) {
+		path := writeFile(t, "config.toml", `
+[analysis]
+classifier = "gemini"
+negative_threshold = -0.7
+
+[tracing]
+exporter = "stdout"
+sample_ratio = 0.25
+`)
+		configs, err := newTestLoader(t, map[string]string{configFileEnv: path}).Load()
+		require.NoError(t, err)
+
+		assert.Equal(t, "gemini", configs.Analysis.Classifier)
+		assert.Equal(t, -0.7, configs.Analysis.NegativeThreshold)
+		assert.Equal(t, "stdout", configs.Tracing.Exporter)
+		assert.Equal(t, 0.25, configs.Tracing.SampleRatio)
+	})
+
+	t.Run("should list every invalid setting", func(t *testing.T) {
+		path := writeFile(t, "config.yaml", `
+server_port: 99999
+scheduler:
+  review_dealy: 48h
+redaction:
+  store_mode: encrypted
+`)
+		_, err := newTestLoader(t, map[string]string{
+			"REVIEW_CHATBOT_WEBHOOKS_TIMEOUT": "ten seconds",
+		}, "-config", path, "-tracing.sample_ratio", "2").Load(APISettings...)
+
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "server_port must be a port number from 1 to 65535")
+		assert.ErrorContains(t, err, "scheduler.review_dealy is not a known setting")
+		assert.ErrorContains(t, err, "redaction.key is required in the encrypted store mode")
+		assert.ErrorContains(t, err, "REVIEW_CHATBOT_WEBHOOKS_TIMEOUT must be a duration like 30s, 15m or 72h")
+		assert.ErrorContains(t, err, "tracing.sample_ratio must be between 0 and 1")
+		assert.ErrorContains(t, err, "gen_ai_api_key is required")
+		assert.ErrorContains(t, err, "database.host is required")
+	})
+
+	t.Run("should reject unsupported files", func(t *testing.T) {
+		path := writeFile(t, "config.json", `{}`)
+		_, err := newTestLoader(t, nil, "-config", path).Load()
+		assert.ErrorIs(t, err, ErrUnsupportedConfigFile)
+	})
+}
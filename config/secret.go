@@ -0,0 +1,31 @@
+package config
+
+import "encoding/json"
+
+const redacted = "[REDACTED]"
+
+// Secret is a setting that is never printed, like passwords and api keys. Call Value to read it.
+type Secret string
+
+// Value returns the secret in plain text
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String redacts the secret, so it's safe to log. Empty secrets are kept empty to show they aren't set.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// MarshalYAML
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// MarshalJSON
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
@@ -0,0 +1,293 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+)
+
+const (
+	ActionListUsers         = "users.list"
+	ActionDeleteUser        = "users.delete"
+	ActionListChats         = "chats.list"
+	ActionViewTranscript    = "chats.transcript"
+	ActionCloseChat         = "chats.close"
+	ActionJoinChat          = "chats.join"
+	ActionDeleteChat        = "chats.delete"
+	ActionExportChats       = "chats.export"
+	ActionListAuditLog      = "audit.list"
+	ActionViewAnalytics     = "analytics.view"
+	ActionViewBotSettings   = "bot_settings.view"
+	ActionReloadBotSettings = "bot_settings.reload"
+	ActionCreateTenant      = "tenants.create"
+	ActionListTenants       = "tenants.list"
+)
+
+const (
+	ResourceUser        = "user"
+	ResourceChat        = "chat"
+	ResourceAuditLog    = "audit_log"
+	ResourceAnalytics   = "analytics"
+	ResourceBotSettings = "bot_settings"
+	ResourceTenant      = "tenant"
+)
+
+const (
+	OutcomeAllowed = "allowed"
+	OutcomeDenied  = "denied"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// permissions maps each action to the roles allowed to run it
+var permissions = map[string][]string{
+	ActionListUsers:         {auth.RoleAdmin, auth.RoleSupportAgent, auth.RoleAnalyst},
+	ActionListChats:         {auth.RoleAdmin, auth.RoleSupportAgent, auth.RoleAnalyst},
+	ActionViewTranscript:    {auth.RoleAdmin, auth.RoleSupportAgent, auth.RoleAnalyst},
+	ActionCloseChat:         {auth.RoleAdmin, auth.RoleSupportAgent},
+	ActionJoinChat:          {auth.RoleAdmin, auth.RoleSupportAgent},
+	ActionDeleteChat:        {auth.RoleAdmin},
+	ActionExportChats:       {auth.RoleAdmin, auth.RoleSupportAgent},
+	ActionDeleteUser:        {auth.RoleAdmin},
+	ActionListAuditLog:      {auth.RoleAdmin},
+	ActionViewAnalytics:     {auth.RoleAdmin, auth.RoleAnalyst},
+	ActionViewBotSettings:   {auth.RoleAdmin},
+	ActionReloadBotSettings: {auth.RoleAdmin},
+	ActionCreateTenant:      {auth.RoleAdmin},
+	ActionListTenants:       {auth.RoleAdmin},
+}
+
+type repository interface {
+	FindUsers(ctx context.Context, search string, page datatypes.Page) ([]datatypes.User, error)
+	FindChats(ctx context.Context, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error)
+	FindChatByID(ctx context.Context, id string) (datatypes.Chat, error)
+	FindMessagesByChat(ctx context.Context, chatID string) ([]datatypes.Message, error)
+	CloseChat(ctx context.Context, id string, closedAt time.Time) error
+	DeleteChat(ctx context.Context, id string) error
+	DeleteUser(ctx context.Context, id string, entry datatypes.AuditEntry) (datatypes.User, error)
+	CreateAuditEntry(ctx context.Context, entry datatypes.AuditEntry) error
+	FindAuditEntries(ctx context.Context, page datatypes.Page) ([]datatypes.AuditEntry, error)
+}
+
+type AdminService struct {
+	repository repository
+	now        func() time.Time
+}
+
+// NewAdminService create a new admin service
+func NewAdminService(repository repository) *AdminService {
+	return &AdminService{
+		repository: repository,
+		now:        time.Now,
+	}
+}
+
+// ListUsers lists the users whose name or email contains the search
+func (as *AdminService) ListUsers(ctx context.Context, actor datatypes.Admin, search string, page datatypes.Page) ([]datatypes.User, error) {
+	if err := as.authorize(ctx, actor, ActionListUsers, ResourceUser, "", fmt.Sprintf("search=%q", search)); err != nil {
+		return nil, err
+	}
+	return as.repository.FindUsers(ctx, search, normalizePage(page))
+}
+
+// ListChats lists the chats matching the filter
+func (as *AdminService) ListChats(ctx context.Context, actor datatypes.Admin, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error) {
+	details := fmt.Sprintf("userId=%q email=%q status=%q", filter.UserID, filter.Email, filter.Status)
+	if err := as.authorize(ctx, actor, ActionListChats, ResourceChat, "", details); err != nil {
+		return nil, err
+	}
+	return as.repository.FindChats(ctx, filter, normalizePage(page))
+}
+
+// GetTranscript returns the chat with all its messages
+func (as *AdminService) GetTranscript(ctx context.Context, actor datatypes.Admin, chatID string) (datatypes.Transcript, error) {
+	if err := as.authorize(ctx, actor, ActionViewTranscript, ResourceChat, chatID, ""); err != nil {
+		return datatypes.Transcript{}, err
+	}
+
+	chat, err := as.findChat(ctx, chatID)
+	if err != nil {
+		return datatypes.Transcript{}, err
+	}
+
+	messages, err := as.repository.FindMessagesByChat(ctx, chatID)
+	if err != nil {
+		return datatypes.Transcript{}, err
+	}
+
+	return datatypes.Transcript{Chat: chat, Messages: messages}, nil
+}
+
+// CloseChat marks an open chat as closed
+func (as *AdminService) CloseChat(ctx context.Context, actor datatypes.Admin, chatID string) error {
+	if err := as.authorize(ctx, actor, ActionCloseChat, ResourceChat, chatID, ""); err != nil {
+		return err
+	}
+
+	chat, err := as.findChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	if chat.Status == datatypes.ChatStatusClosed {
+		return ErrChatAlreadyClosed
+	}
+	return as.repository.CloseChat(ctx, chatID, as.now().UTC())
+}
+
+// JoinChat checks if the actor can take over the chat as a human agent
+func (as *AdminService) JoinChat(ctx context.Context, actor datatypes.Admin, chatID string) (datatypes.Chat, error) {
+	if err := as.authorize(ctx, actor, ActionJoinChat, ResourceChat, chatID, ""); err != nil {
+		return datatypes.Chat{}, err
+	}
+
+	chat, err := as.findChat(ctx, chatID)
+	if err != nil {
+		return datatypes.Chat{}, err
+	}
+
+	if chat.Status == datatypes.ChatStatusClosed {
+		return datatypes.Chat{}, ErrChatAlreadyClosed
+	}
+	return chat, nil
+}
+
+// DeleteChat deletes the chat with its messages and their analyses
+func (as *AdminService) DeleteChat(ctx context.Context, actor datatypes.Admin, chatID string) error {
+	if err := as.authorize(ctx, actor, ActionDeleteChat, ResourceChat, chatID, ""); err != nil {
+		return err
+	}
+	return as.repository.DeleteChat(ctx, chatID)
+}
+
+// DeleteUser erases the user like the customer erasure requests and returns it, so its web chat can
+// be disconnected. The erasure is written to the audit log with the actor.
+func (as *AdminService) DeleteUser(ctx context.Context, actor datatypes.Admin, userID string) (datatypes.User, error) {
+	if err := as.authorize(ctx, actor, ActionDeleteUser, ResourceUser, userID, ""); err != nil {
+		return datatypes.User{}, err
+	}
+
+	return as.repository.DeleteUser(ctx, userID, datatypes.AuditEntry{
+		Actor:        actor.Name,
+		Role:         actor.Role,
+		Action:       user.ActionEraseData,
+		ResourceType: ResourceUser,
+		ResourceID:   userID,
+		Outcome:      OutcomeAllowed,
+		CreatedAt:    as.now().UTC(),
+	})
+}
+
+// ListAuditLog lists the latest admin actions
+func (as *AdminService) ListAuditLog(ctx context.Context, actor datatypes.Admin, page datatypes.Page) ([]datatypes.AuditEntry, error) {
+	if err := as.authorize(ctx, actor, ActionListAuditLog, ResourceAuditLog, "", ""); err != nil {
+		return nil, err
+	}
+	return as.repository.FindAuditEntries(ctx, normalizePage(page))
+}
+
+// ExportChats checks if the actor can export chat transcripts
+func (as *AdminService) ExportChats(ctx context.Context, actor datatypes.Admin, resourceID string, details string) error {
+	return as.authorize(ctx, actor, ActionExportChats, ResourceChat, resourceID, details)
+}
+
+// ViewAnalytics checks if the actor can read the analytics report
+func (as *AdminService) ViewAnalytics(ctx context.Context, actor datatypes.Admin, report string, details string) error {
+	return as.authorize(ctx, actor, ActionViewAnalytics, ResourceAnalytics, report, details)
+}
+
+// ViewBotSettings checks if the actor can read the bot settings
+func (as *AdminService) ViewBotSettings(ctx context.Context, actor datatypes.Admin) error {
+	return as.authorize(ctx, actor, ActionViewBotSettings, ResourceBotSettings, "", "")
+}
+
+// ReloadBotSettings checks if the actor can reload the bot settings
+func (as *AdminService) ReloadBotSettings(ctx context.Context, actor datatypes.Admin) error {
+	return as.authorize(ctx, actor, ActionReloadBotSettings, ResourceBotSettings, "", "")
+}
+
+// CreateTenant checks if the actor can create a tenant for the domain
+func (as *AdminService) CreateTenant(ctx context.Context, actor datatypes.Admin, domain string) error {
+	return as.authorize(ctx, actor, ActionCreateTenant, ResourceTenant, "", fmt.Sprintf("domain=%q", domain))
+}
+
+// ListTenants checks if the actor can list the tenants
+func (as *AdminService) ListTenants(ctx context.Context, actor datatypes.Admin) error {
+	return as.authorize(ctx, actor, ActionListTenants, ResourceTenant, "", "")
+}
+
+// authorize checks the actor role and writes the attempt to the audit log.
+// Nothing runs when the audit entry can't be saved.
+func (as *AdminService) authorize(
+	ctx context.Context,
+	actor datatypes.Admin,
+	action string,
+	resourceType string,
+	resourceID string,
+	details string,
+) error {
+	outcome := OutcomeDenied
+	if isAllowed(actor.Role, action) {
+		outcome = OutcomeAllowed
+	}
+
+	entry := datatypes.AuditEntry{
+		Actor:        actor.Name,
+		Role:         actor.Role,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Outcome:      outcome,
+		Details:      details,
+		CreatedAt:    as.now().UTC(),
+	}
+
+	if err := as.repository.CreateAuditEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	if outcome == OutcomeDenied {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// findChat finds a chat mapping missing rows to ErrChatNotFound
+func (as *AdminService) findChat(ctx context.Context, chatID string) (datatypes.Chat, error) {
+	chat, err := as.repository.FindChatByID(ctx, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return datatypes.Chat{}, ErrChatNotFound
+	}
+	return chat, err
+}
+
+// isAllowed
+func isAllowed(role string, action string) bool {
+	for _, allowedRole := range permissions[action] {
+		if allowedRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePage applies the default and max limits
+func normalizePage(page datatypes.Page) datatypes.Page {
+	if page.Limit <= 0 || page.Limit > maxPageLimit {
+		page.Limit = defaultPageLimit
+	}
+
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	return page
+}
@@ -0,0 +1,321 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/auth"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+type repositoryMock struct {
+	Error                      error
+	AuditEntries               []datatypes.AuditEntry
+	CallbackFindUsers          func(ctx context.Context, search string, page datatypes.Page) ([]datatypes.User, error)
+	CallbackFindChats          func(ctx context.Context, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error)
+	CallbackFindChatByID       func(ctx context.Context, id string) (datatypes.Chat, error)
+	CallbackFindMessagesByChat func(ctx context.Context, chatID string) ([]datatypes.Message, error)
+	CallbackCloseChat          func(ctx context.Context, id string, closedAt time.Time) error
+	CallbackDeleteChat         func(ctx context.Context, id string) error
+	CallbackDeleteUser         func(ctx context.Context, id string, entry datatypes.AuditEntry) (datatypes.User, error)
+	CallbackCreateAuditEntry   func(ctx context.Context, entry datatypes.AuditEntry) error
+	CallbackFindAuditEntries   func(ctx context.Context, page datatypes.Page) ([]datatypes.AuditEntry, error)
+}
+
+func (rm *repositoryMock) FindUsers(ctx context.Context, search string, page datatypes.Page) ([]datatypes.User, error) {
+	if rm.CallbackFindUsers != nil {
+		return rm.CallbackFindUsers(ctx, search, page)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) FindChats(ctx context.Context, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error) {
+	if rm.CallbackFindChats != nil {
+		return rm.CallbackFindChats(ctx, filter, page)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) FindChatByID(ctx context.Context, id string) (datatypes.Chat, error) {
+	if rm.CallbackFindChatByID != nil {
+		return rm.CallbackFindChatByID(ctx, id)
+	}
+	return datatypes.Chat{}, rm.Error
+}
+
+func (rm *repositoryMock) FindMessagesByChat(ctx context.Context, chatID string) ([]datatypes.Message, error) {
+	if rm.CallbackFindMessagesByChat != nil {
+		return rm.CallbackFindMessagesByChat(ctx, chatID)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) CloseChat(ctx context.Context, id string, closedAt time.Time) error {
+	if rm.CallbackCloseChat != nil {
+		return rm.CallbackCloseChat(ctx, id, closedAt)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) DeleteChat(ctx context.Context, id string) error {
+	if rm.CallbackDeleteChat != nil {
+		return rm.CallbackDeleteChat(ctx, id)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) DeleteUser(ctx context.Context, id string, entry datatypes.AuditEntry) (datatypes.User, error) {
+	if rm.CallbackDeleteUser != nil {
+		return rm.CallbackDeleteUser(ctx, id, entry)
+	}
+	return datatypes.User{}, rm.Error
+}
+
+func (rm *repositoryMock) CreateAuditEntry(ctx context.Context, entry datatypes.AuditEntry) error {
+	if rm.CallbackCreateAuditEntry != nil {
+		return rm.CallbackCreateAuditEntry(ctx, entry)
+	}
+	rm.AuditEntries = append(rm.AuditEntries, entry)
+	return nil
+}
+
+func (rm *repositoryMock) FindAuditEntries(ctx context.Context, page datatypes.Page) ([]datatypes.AuditEntry, error) {
+	if rm.CallbackFindAuditEntries != nil {
+		return rm.CallbackFindAuditEntries(ctx, page)
+	}
+	return rm.AuditEntries, rm.Error
+}
+
+var (
+	admin        = datatypes.Admin{Name: "alice", Role: auth.RoleAdmin}
+	supportAgent = datatypes.Admin{Name: "bob", Role: auth.RoleSupportAgent}
+	analyst      = datatypes.Admin{Name: "carol", Role: auth.RoleAnalyst}
+)
+
+func TestAdminServiceListUsers(t *testing.T) {
+	t.Run("should list users and audit the action", func(t *testing.T) {
+		repository := &repositoryMock{
+			CallbackFindUsers: func(ctx context.Context, search string, page datatypes.Page) ([]datatypes.User, error) {
+				assert.Equal(t, "wick", search)
+				assert.Equal(t, defaultPageLimit, page.Limit)
+				return []datatypes.User{{ID: "qwerty"}}, nil
+			},
+		}
+
+		users, err := NewAdminService(repository).ListUsers(context.Background(), analyst, "wick", datatypes.Page{Limit: 1000})
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Len(t, repository.AuditEntries, 1)
+		assert.Equal(t, ActionListUsers, repository.AuditEntries[0].Action)
+		assert.Equal(t, OutcomeAllowed, repository.AuditEntries[0].Outcome)
+		assert.Equal(t, "carol", repository.AuditEntries[0].Actor)
+	})
+
+	t.Run("should not run when the audit entry can't be saved", func(t *testing.T) {
+		errCreateAuditEntry := errors.New("error to create audit entry for tests")
+		repository := &repositoryMock{
+			CallbackCreateAuditEntry: func(ctx context.Context, entry datatypes.AuditEntry) error {
+				return errCreateAuditEntry
+			},
+			CallbackFindUsers: func(ctx context.Context, search string, page datatypes.Page) ([]datatypes.User, error) {
+				t.Fatal("users must not be listed")
+				return nil, nil
+			},
+		}
+
+		_, err := NewAdminService(repository).ListUsers(context.Background(), admin, "", datatypes.Page{})
+		assert.ErrorIs(t, err, errCreateAuditEntry)
+	})
+}
+
+func TestAdminServiceGetTranscript(t *testing.T) {
+	t.Run("should return the chat messages", func(t *testing.T) {
+		repository := &repositoryMock{
+			CallbackFindChatByID: func(ctx context.Context, id string) (datatypes.Chat, error) {
+				return datatypes.Chat{ID: id}, nil
+			},
+			CallbackFindMessagesByChat: func(ctx context.Context, chatID string) ([]datatypes.Message, error) {
+				return []datatypes.Message{{ID: "1", ChatID: chatID}, {ID: "2", ChatID: chatID}}, nil
+			},
+		}
+
+		transcript, err := NewAdminService(repository).GetTranscript(context.Background(), supportAgent, "qwerty")
+		assert.NoError(t, err)
+		assert.Equal(t, "qwerty", transcript.Chat.ID)
+		assert.Len(t, transcript.Messages, 2)
+		assert.Equal(t, "qwerty", repository.AuditEntries[0].ResourceID)
+	})
+
+	t.Run("should fail when the chat does not exist", func(t *testing.T) {
+		repository := &repositoryMock{Error: sql.ErrNoRows}
+
+		_, err := NewAdminService(repository).GetTranscript(context.Background(), supportAgent, "qwerty")
+		assert.ErrorIs(t, err, ErrChatNotFound)
+	})
+}
+
+func TestAdminServiceCloseChat(t *testing.T) {
+	t.Run("should close an open chat", func(t *testing.T) {
+		var closed bool
+		repository := &repositoryMock{
+			CallbackFindChatByID: func(ctx context.Context, id string) (datatypes.Chat, error) {
+				return datatypes.Chat{ID: id, Status: datatypes.ChatStatusOpen}, nil
+			},
+			CallbackCloseChat: func(ctx context.Context, id string, closedAt time.Time) error {
+				closed = true
+				return nil
+			},
+		}
+
+		assert.NoError(t, NewAdminService(repository).CloseChat(context.Background(), supportAgent, "qwerty"))
+		assert.True(t, closed)
+	})
+
+	t.Run("should fail when the chat is already closed", func(t *testing.T) {
+		repository := &repositoryMock{
+			CallbackFindChatByID: func(ctx context.Context, id string) (datatypes.Chat, error) {
+				return datatypes.Chat{ID: id, Status: datatypes.ChatStatusClosed}, nil
+			},
+		}
+
+		err := NewAdminService(repository).CloseChat(context.Background(), supportAgent, "qwerty")
+		assert.ErrorIs(t, err, ErrChatAlreadyClosed)
+	})
+
+	t.Run("should deny analysts and audit the attempt", func(t *testing.T) {
+		repository := &repositoryMock{}
+
+		err := NewAdminService(repository).CloseChat(context.Background(), analyst, "qwerty")
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.Len(t, repository.AuditEntries, 1)
+		assert.Equal(t, OutcomeDenied, repository.AuditEntries[0].Outcome)
+	})
+}
+
+func TestAdminServiceDeleteUser(t *testing.T) {
+	t.Run("should erase the user as the admin", func(t *testing.T) {
+		var erasure datatypes.AuditEntry
+		repository := &repositoryMock{
+			CallbackDeleteUser: func(ctx context.Context, id string, entry datatypes.AuditEntry) (datatypes.User, error) {
+				erasure = entry
+				return datatypes.User{ID: id, Email: "john@wick.com"}, nil
+			},
+		}
+
+		erased, err := NewAdminService(repository).DeleteUser(context.Background(), admin, "qwerty")
+		assert.NoError(t, err)
+		assert.Equal(t, "john@wick.com", erased.Email)
+		assert.Equal(t, admin.Name, erasure.Actor)
+		assert.Equal(t, user.ActionEraseData, erasure.Action)
+		assert.Equal(t, "qwerty", erasure.ResourceID)
+	})
+
+	t.Run("should deny support agents", func(t *testing.T) {
+		_, err := NewAdminService(&repositoryMock{}).DeleteUser(context.Background(), supportAgent, "qwerty")
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		err = NewAdminService(&repositoryMock{}).DeleteChat(context.Background(), supportAgent, "qwerty")
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestAdminServiceListAuditLog(t *testing.T) {
+	t.Run("should only allow admins", func(t *testing.T) {
+		repository := &repositoryMock{}
+
+		_, err := NewAdminService(repository).ListAuditLog(context.Background(), supportAgent, datatypes.Page{})
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		entries, err := NewAdminService(repository).ListAuditLog(context.Background(), admin, datatypes.Page{})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+}
+
+func TestAdminServiceJoinChat(t *testing.T) {
+	t.Run("should let support agents join open chats", func(t *testing.T) {
+		repository := &repositoryMock{
+			CallbackFindChatByID: func(ctx context.Context, id string) (datatypes.Chat, error) {
+				return datatypes.Chat{ID: id, Status: datatypes.ChatStatusAgent}, nil
+			},
+		}
+
+		chat, err := NewAdminService(repository).JoinChat(context.Background(), supportAgent, "qwerty")
+		assert.NoError(t, err)
+		assert.Equal(t, "qwerty", chat.ID)
+		assert.Equal(t, ActionJoinChat, repository.AuditEntries[0].Action)
+	})
+
+	t.Run("should deny analysts", func(t *testing.T) {
+		_, err := NewAdminService(&repositoryMock{}).JoinChat(context.Background(), analyst, "qwerty")
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestAdminServiceExportChats(t *testing.T) {
+	t.Run("should allow support agents and deny analysts", func(t *testing.T) {
+		repository := &repositoryMock{}
+
+		assert.NoError(t, NewAdminService(repository).ExportChats(context.Background(), supportAgent, "qwerty", "format=csv"))
+		assert.Equal(t, ActionExportChats, repository.AuditEntries[0].Action)
+
+		err := NewAdminService(repository).ExportChats(context.Background(), analyst, "qwerty", "")
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestAdminServiceViewAnalytics(t *testing.T) {
+	t.Run("should allow analysts and audit the report", func(t *testing.T) {
+		repository := &repositoryMock{}
+
+		err := NewAdminService(repository).ViewAnalytics(context.Background(), analyst, "summary", "from=2024-05-01")
+		assert.NoError(t, err)
+		assert.Equal(t, ActionViewAnalytics, repository.AuditEntries[0].Action)
+		assert.Equal(t, "summary", repository.AuditEntries[0].ResourceID)
+	})
+
+	t.Run("should deny support agents", func(t *testing.T) {
+		err := NewAdminService(&repositoryMock{}).ViewAnalytics(context.Background(), supportAgent, "summary", "")
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestAdminServiceBotSettings(t *testing.T) {
+	t.Run("should allow admins and audit the reload", func(t *testing.T) {
+		repository := &repositoryMock{}
+
+		assert.NoError(t, NewAdminService(repository).ViewBotSettings(context.Background(), admin))
+		assert.NoError(t, NewAdminService(repository).ReloadBotSettings(context.Background(), admin))
+		assert.Equal(t, ActionViewBotSettings, repository.AuditEntries[0].Action)
+		assert.Equal(t, ActionReloadBotSettings, repository.AuditEntries[1].Action)
+		assert.Equal(t, ResourceBotSettings, repository.AuditEntries[1].ResourceType)
+	})
+
+	t.Run("should deny support agents and analysts", func(t *testing.T) {
+		assert.ErrorIs(t, NewAdminService(&repositoryMock{}).ViewBotSettings(context.Background(), analyst), ErrForbidden)
+		assert.ErrorIs(t, NewAdminService(&repositoryMock{}).ReloadBotSettings(context.Background(), supportAgent), ErrForbidden)
+	})
+}
+
+func TestAdminServiceTenants(t *testing.T) {
+	t.Run("should allow admins and audit the domain of the new tenant", func(t *testing.T) {
+		repository := &repositoryMock{}
+
+		assert.NoError(t, NewAdminService(repository).CreateTenant(context.Background(), admin, "acme.com"))
+		assert.NoError(t, NewAdminService(repository).ListTenants(context.Background(), admin))
+		assert.Equal(t, ActionCreateTenant, repository.AuditEntries[0].Action)
+		assert.Equal(t, `domain="acme.com"`, repository.AuditEntries[0].Details)
+		assert.Equal(t, ActionListTenants, repository.AuditEntries[1].Action)
+		assert.Equal(t, ResourceTenant, repository.AuditEntries[1].ResourceType)
+	})
+
+	t.Run("should deny support agents and analysts", func(t *testing.T) {
+		assert.ErrorIs(t, NewAdminService(&repositoryMock{}).CreateTenant(context.Background(), supportAgent, "acme.com"), ErrForbidden)
+		assert.ErrorIs(t, NewAdminService(&repositoryMock{}).ListTenants(context.Background(), analyst), ErrForbidden)
+	})
+}
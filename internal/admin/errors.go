@@ -0,0 +1,11 @@
+package admin
+
+import "errors"
+
+var (
+	ErrForbidden          = errors.New("admin role is not allowed to run this action")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrChatNotFound       = errors.New("chat not found")
+	ErrChatAlreadyClosed  = errors.New("chat already closed")
+	ErrCantSaveAuditEntry = errors.New("can't save audit entry")
+)
@@ -0,0 +1,56 @@
+package admin
+
+var findChats = `
+	SELECT c.id, c.user_id, COALESCE(u.email, '') AS user_email, c.product, c.status, c.created_at, c.closed_at
+	FROM chats c
+	LEFT JOIN users u ON u.id = c.user_id
+	WHERE c.tenant_id = :tenant_id
+		AND (:user_id = '' OR c.user_id = :user_id)
+		AND (:email = '' OR u.email = :email)
+		AND (:status = '' OR c.status = :status)
+	ORDER BY c.created_at DESC
+	LIMIT :limit OFFSET :offset;
+`
+
+var findChatByID = `
+	SELECT c.id, c.user_id, COALESCE(u.email, '') AS user_email, c.product, c.status, c.created_at, c.closed_at
+	FROM chats c
+	LEFT JOIN users u ON u.id = c.user_id
+	WHERE c.id = :id AND c.tenant_id = :tenant_id;
+`
+
+var findMessagesByChat = `
+	SELECT id, chat_id, author, message, created_at FROM messages
+	WHERE chat_id = :chat_id AND tenant_id = :tenant_id
+	ORDER BY created_at;
+`
+
+var closeChat = `
+	UPDATE chats SET status = :status, closed_at = :closed_at WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+// message_analyses has no tenant_id, the chat is matched in the tenant
+var deleteChatAnalyses = `
+	DELETE FROM message_analyses WHERE chat_id IN (SELECT id FROM chats WHERE id = :chat_id AND tenant_id = :tenant_id);
+`
+
+var deleteChatMessages = `
+	DELETE FROM messages WHERE chat_id = :chat_id AND tenant_id = :tenant_id;
+`
+
+var deleteChat = `
+	DELETE FROM chats WHERE id = :chat_id AND tenant_id = :tenant_id;
+`
+
+var createAuditEntry = `
+	INSERT INTO audit_log (id, tenant_id, actor, role, action, resource_type, resource_id, outcome, details, created_at)
+	VALUES (:id, :tenant_id, :actor, :role, :action, :resource_type, :resource_id, :outcome, :details, :created_at);
+`
+
+var findAuditEntries = `
+	SELECT id, tenant_id, actor, role, action, resource_type, resource_id, outcome, details, created_at
+	FROM audit_log
+	WHERE tenant_id = :tenant_id
+	ORDER BY created_at DESC
+	LIMIT :limit OFFSET :offset;
+`
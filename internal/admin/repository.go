@@ -0,0 +1,263 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/gofrs/uuid/v5"
+)
+
+type Repository struct {
+	db godb.DB
+	// users searches and erases the users like the user API
+	users *user.Repository
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{
+		db:    db,
+		users: user.NewRepository(db),
+	}
+}
+
+// FindUsers finds the users of the tenant whose name or email contains the search, including the
+// deactivated ones. The search is the same of the user API
+func (r *Repository) FindUsers(ctx context.Context, search string, page datatypes.Page) ([]datatypes.User, error) {
+	return r.users.FindUsers(ctx, search, true, page)
+}
+
+// FindChats finds the chats of the tenant matching the filter
+func (r *Repository) FindChats(ctx context.Context, filter datatypes.ChatFilter, page datatypes.Page) ([]datatypes.Chat, error) {
+	chats := []datatypes.Chat{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findChats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chats. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"user_id":   filter.UserID,
+		"email":     filter.Email,
+		"status":    filter.Status,
+		"limit":     page.Limit,
+		"offset":    page.Offset,
+	}
+
+	if err = stm.SelectContext(ctx, &chats, params); err != nil {
+		return nil, fmt.Errorf("failed to find chats. Cause: %w", err)
+	}
+
+	return chats, nil
+}
+
+// FindChatByID finds a chat of the tenant by id
+func (r *Repository) FindChatByID(ctx context.Context, id string) (datatypes.Chat, error) {
+	var chat datatypes.Chat
+
+	stm, err := r.db.PrepareNamedContext(ctx, findChatByID)
+	if err != nil {
+		return datatypes.Chat{}, fmt.Errorf("failed to find chat. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":        id,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	if err = stm.GetContext(ctx, &chat, params); err != nil {
+		return datatypes.Chat{}, fmt.Errorf("failed to find chat. Cause: %w", err)
+	}
+
+	return chat, nil
+}
+
+// FindMessagesByChat finds every message of a chat in the order they were sent
+func (r *Repository) FindMessagesByChat(ctx context.Context, chatID string) ([]datatypes.Message, error) {
+	messages := []datatypes.Message{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findMessagesByChat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find messages. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"chat_id":   chatID,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	if err = stm.SelectContext(ctx, &messages, params); err != nil {
+		return nil, fmt.Errorf("failed to find messages. Cause: %w", err)
+	}
+
+	return messages, nil
+}
+
+// CloseChat marks the chat of the tenant as closed
+func (r *Repository) CloseChat(ctx context.Context, id string, closedAt time.Time) error {
+	stm, err := r.db.PrepareNamedContext(ctx, closeChat)
+	if err != nil {
+		return fmt.Errorf("failed to close chat. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":        id,
+		"tenant_id": tenant.IDFromContext(ctx),
+		"status":    datatypes.ChatStatusClosed,
+		"closed_at": closedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to close chat. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to close chat. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrChatNotFound
+	}
+	return nil
+}
+
+// DeleteChat deletes the chat of the tenant with its messages and their analyses
+func (r *Repository) DeleteChat(ctx context.Context, id string) error {
+	params := map[string]interface{}{
+		"chat_id":   id,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	if err := r.deleteAll(ctx, params, ErrChatNotFound, deleteChatAnalyses, deleteChatMessages, deleteChat); err != nil {
+		return fmt.Errorf("failed to delete chat. Cause: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser erases the user of the tenant like the customer erasure requests: messages, analyses,
+// scheduled reviews, invitations, exports and webhook payloads are removed and the chats are kept
+// without the user. The entry is saved in the same transaction. Returns the erased user.
+func (r *Repository) DeleteUser(ctx context.Context, id string, entry datatypes.AuditEntry) (datatypes.User, error) {
+	found, err := r.users.FindByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return datatypes.User{}, ErrUserNotFound
+	}
+
+	if err != nil {
+		return datatypes.User{}, fmt.Errorf("failed to delete user. Cause: %w", err)
+	}
+
+	if err := r.users.EraseUser(ctx, found, entry); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return datatypes.User{}, ErrUserNotFound
+		}
+		return datatypes.User{}, fmt.Errorf("failed to delete user. Cause: %w", err)
+	}
+	return found, nil
+}
+
+// deleteAll runs the queries in a single transaction. The last query must delete the main row or errNotFound is returned.
+func (r *Repository) deleteAll(ctx context.Context, params map[string]interface{}, errNotFound error, queries ...string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rows int64
+	for _, query := range queries {
+		result, err := tx.NamedExecContext(ctx, query, params)
+		if err != nil {
+			return err
+		}
+
+		if rows, err = result.RowsAffected(); err != nil {
+			return err
+		}
+	}
+
+	if rows == 0 {
+		return errNotFound
+	}
+
+	return tx.Commit()
+}
+
+// CreateAuditEntry saves a new audit entry
+func (r *Repository) CreateAuditEntry(ctx context.Context, entry datatypes.AuditEntry) error {
+	stm, err := r.db.PrepareNamedContext(ctx, createAuditEntry)
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+
+	params := map[string]interface{}{
+		"id":            id.String(),
+		"tenant_id":     tenant.IDFromContext(ctx),
+		"actor":         entry.Actor,
+		"role":          entry.Role,
+		"action":        entry.Action,
+		"resource_type": entry.ResourceType,
+		"resource_id":   entry.ResourceID,
+		"outcome":       entry.Outcome,
+		"details":       entry.Details,
+		"created_at":    entry.CreatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantSaveAuditEntry
+	}
+	return nil
+}
+
+// FindAuditEntries finds the latest audit entries of the tenant
+func (r *Repository) FindAuditEntries(ctx context.Context, page datatypes.Page) ([]datatypes.AuditEntry, error) {
+	entries := []datatypes.AuditEntry{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findAuditEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit entries. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"limit":     page.Limit,
+		"offset":    page.Offset,
+	}
+
+	if err = stm.SelectContext(ctx, &entries, params); err != nil {
+		return nil, fmt.Errorf("failed to find audit entries. Cause: %w", err)
+	}
+
+	return entries, nil
+}
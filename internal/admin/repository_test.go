@@ -0,0 +1,281 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryFindUsers(t *testing.T) {
+	t.Run("should search users by name or email", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest interface{}, arg interface{}) error {
+						assert.Equal(t, "%wick%", arg.(map[string]interface{})["pattern"])
+						assert.Equal(t, true, arg.(map[string]interface{})["include_deleted"])
+						*dest.(*[]datatypes.User) = []datatypes.User{{ID: "qwerty"}}
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		users, err := repository.FindUsers(context.Background(), "wick", datatypes.Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+	})
+
+	t.Run("should match the wildcards of the search literally", func(t *testing.T) {
+		var pattern interface{}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest interface{}, arg interface{}) error {
+						pattern = arg.(map[string]interface{})["pattern"]
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.FindUsers(context.Background(), "50%_off", datatypes.Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, "%50!%!_off%", pattern)
+	})
+
+	t.Run("should fail when prapare named context", func(t *testing.T) {
+		errPrepareNamedContext := errors.New("error when preparing named context for tests")
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return nil, errPrepareNamedContext
+			},
+		})
+
+		_, err := repository.FindUsers(context.Background(), "", datatypes.Page{})
+		assert.ErrorIs(t, err, errPrepareNamedContext)
+	})
+}
+
+func TestRepositoryCloseChat(t *testing.T) {
+	t.Run("should only close the chats of the tenant", func(t *testing.T) {
+		var params map[string]interface{}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				assert.Contains(t, query, "tenant_id = :tenant_id")
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						params, _ = arg.(map[string]interface{})
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "store-a"})
+		assert.NoError(t, repository.CloseChat(ctx, "qwerty", time.Now()))
+		assert.Equal(t, "store-a", params["tenant_id"])
+	})
+
+	t.Run("should fail when the chat does not exist", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.CloseChat(context.Background(), "qwerty", time.Now())
+		assert.ErrorIs(t, err, ErrChatNotFound)
+	})
+}
+
+func TestRepositoryDeleteChat(t *testing.T) {
+	t.Run("should delete the analyses, messages and the chat in a transaction", func(t *testing.T) {
+		var (
+			executed  []string
+			committed bool
+		)
+
+		repository := NewRepository(&godb.DBMock{
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						executed = append(executed, query)
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+					CallbackCommit: func() error {
+						committed = true
+						return nil
+					},
+					CallbackRollback: func() error {
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		assert.NoError(t, repository.DeleteChat(context.Background(), "qwerty"))
+		assert.Equal(t, []string{deleteChatAnalyses, deleteChatMessages, deleteChat}, executed)
+		assert.True(t, committed)
+	})
+}
+
+func TestRepositoryDeleteUser(t *testing.T) {
+	findUser := func(found datatypes.User, err error) func(ctx context.Context, query string) (godb.NamedStmt, error) {
+		return func(ctx context.Context, query string) (godb.NamedStmt, error) {
+			return &godb.NamedStmtMock{
+				CallbackGetContext: func(ctx context.Context, dest interface{}, arg interface{}) error {
+					*dest.(*datatypes.User) = found
+					return err
+				},
+			}, nil
+		}
+	}
+
+	t.Run("should erase the user and save the entry in a transaction", func(t *testing.T) {
+		var (
+			params    []map[string]interface{}
+			committed bool
+		)
+
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: findUser(datatypes.User{ID: "qwerty", Email: "john@wick.com"}, nil),
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						params = append(params, arg.(map[string]interface{}))
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+					CallbackCommit: func() error {
+						committed = true
+						return nil
+					},
+					CallbackRollback: func() error {
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		erased, err := repository.DeleteUser(context.Background(), "qwerty", datatypes.AuditEntry{Actor: "admin", Action: user.ActionEraseData})
+		assert.NoError(t, err)
+		assert.Equal(t, "john@wick.com", erased.Email)
+		assert.True(t, committed)
+		assert.Equal(t, "admin", params[len(params)-1]["actor"])
+		assert.Equal(t, user.ActionEraseData, params[len(params)-1]["action"])
+	})
+
+	t.Run("should fail when the user does not exist", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: findUser(datatypes.User{}, sql.ErrNoRows),
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				t.Fatal("the erasure must not start")
+				return nil, nil
+			},
+		})
+
+		_, err := repository.DeleteUser(context.Background(), "qwerty", datatypes.AuditEntry{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("should rollback when the user is deleted during the erasure", func(t *testing.T) {
+		var committed, rolledBack bool
+
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: findUser(datatypes.User{ID: "qwerty"}, nil),
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+					CallbackCommit: func() error {
+						committed = true
+						return nil
+					},
+					CallbackRollback: func() error {
+						rolledBack = true
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.DeleteUser(context.Background(), "qwerty", datatypes.AuditEntry{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.False(t, committed)
+		assert.True(t, rolledBack)
+	})
+}
+
+func TestRepositoryCreateAuditEntry(t *testing.T) {
+	t.Run("should create a new audit entry", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						assert.Equal(t, ActionCloseChat, arg.(map[string]interface{})["action"])
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.CreateAuditEntry(context.Background(), datatypes.AuditEntry{Action: ActionCloseChat})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.CreateAuditEntry(context.Background(), datatypes.AuditEntry{})
+		assert.ErrorIs(t, err, ErrCantSaveAuditEntry)
+	})
+}
@@ -0,0 +1,158 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+const (
+	IntentComplaint    = "complaint"
+	IntentReturn       = "return"
+	IntentQuestion     = "question"
+	IntentReviewAnswer = "review_answer"
+	IntentOther        = "other"
+)
+
+const (
+	EventNegativeSentiment = "sentiment.negative"
+	EventReturnRequested   = "return.requested"
+)
+
+type repository interface {
+	CreateAnalysis(ctx context.Context, analysis datatypes.MessageAnalysis) error
+}
+
+// Classifier classifies a single user message. Sentiment goes from -1 (very negative) to 1 (very positive).
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, text string) (Classification, error)
+}
+
+type Classification struct {
+	Sentiment float64 `json:"sentiment"`
+	Intent    string  `json:"intent"`
+	Language  string  `json:"language"`
+}
+
+type eventPublisher interface {
+	Publish(ctx context.Context, eventType string, data interface{}) error
+}
+
+// EscalationHook is called when a message sentiment crosses the negative threshold
+type EscalationHook interface {
+	OnNegativeSentiment(ctx context.Context, analysis datatypes.MessageAnalysis) error
+}
+
+// AnalysisEvent is sent to the webhooks with the analyzed message
+type AnalysisEvent struct {
+	datatypes.MessageAnalysis
+	Message string `json:"message"`
+}
+
+type AnalysisServiceConfig struct {
+	// NegativeThreshold triggers the escalation hooks when the sentiment is lower or equal to it
+	NegativeThreshold float64
+}
+
+type AnalysisService struct {
+	repository repository
+	classifier Classifier
+	publisher  eventPublisher
+	hooks      []EscalationHook
+	config     AnalysisServiceConfig
+	now        func() time.Time
+}
+
+// NewAnalysisService create a new analysis service
+func NewAnalysisService(
+	repository repository,
+	classifier Classifier,
+	publisher eventPublisher,
+	config AnalysisServiceConfig,
+) *AnalysisService {
+	if config.NegativeThreshold == 0 {
+		config.NegativeThreshold = -0.5
+	}
+
+	return &AnalysisService{
+		repository: repository,
+		classifier: classifier,
+		publisher:  publisher,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// AddEscalationHook registers a hook called on negative messages
+func (as *AnalysisService) AddEscalationHook(hook EscalationHook) {
+	as.hooks = append(as.hooks, hook)
+}
+
+// AnalyzeMessage classifies the user message, saves the result and runs the hooks
+func (as *AnalysisService) AnalyzeMessage(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+	classification, err := as.classifier.Classify(ctx, message.Message)
+	if err != nil {
+		return datatypes.MessageAnalysis{}, fmt.Errorf("failed to analyze message. Cause: %w", err)
+	}
+
+	analysis := datatypes.MessageAnalysis{
+		MessageID:  message.ID,
+		ChatID:     message.ChatID,
+		Sentiment:  clamp(classification.Sentiment),
+		Intent:     normalizeIntent(classification.Intent),
+		Language:   classification.Language,
+		Classifier: as.classifier.Name(),
+		CreatedAt:  as.now().UTC(),
+	}
+
+	if err := as.repository.CreateAnalysis(ctx, analysis); err != nil {
+		return datatypes.MessageAnalysis{}, err
+	}
+
+	event := AnalysisEvent{MessageAnalysis: analysis, Message: message.Message}
+
+	if analysis.Intent == IntentReturn {
+		if err := as.publisher.Publish(ctx, EventReturnRequested, event); err != nil {
+			return analysis, fmt.Errorf("failed to analyze message. Cause: %w", err)
+		}
+	}
+
+	if analysis.Sentiment <= as.config.NegativeThreshold {
+		if err := as.publisher.Publish(ctx, EventNegativeSentiment, event); err != nil {
+			return analysis, fmt.Errorf("failed to analyze message. Cause: %w", err)
+		}
+
+		for _, hook := range as.hooks {
+			if err := hook.OnNegativeSentiment(ctx, analysis); err != nil {
+				golog.Log().Error(ctx, fmt.Sprintf("failed to run escalation hook. Cause: %s", err))
+			}
+		}
+	}
+
+	return analysis, nil
+}
+
+// clamp keeps the sentiment between -1 and 1
+func clamp(sentiment float64) float64 {
+	if sentiment < -1 {
+		return -1
+	}
+
+	if sentiment > 1 {
+		return 1
+	}
+	return sentiment
+}
+
+// normalizeIntent maps unknown intents to IntentOther
+func normalizeIntent(intent string) string {
+	switch intent {
+	case IntentComplaint, IntentReturn, IntentQuestion, IntentReviewAnswer:
+		return intent
+	}
+	return IntentOther
+}
@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+type repositoryMock struct {
+	Error                  error
+	CallbackCreateAnalysis func(ctx context.Context, analysis datatypes.MessageAnalysis) error
+}
+
+func (rm *repositoryMock) CreateAnalysis(ctx context.Context, analysis datatypes.MessageAnalysis) error {
+	if rm.CallbackCreateAnalysis != nil {
+		return rm.CallbackCreateAnalysis(ctx, analysis)
+	}
+	return rm.Error
+}
+
+type classifierMock struct {
+	Error          error
+	Classification Classification
+}
+
+func (cm *classifierMock) Name() string {
+	return "mock"
+}
+
+func (cm *classifierMock) Classify(ctx context.Context, text string) (Classification, error) {
+	return cm.Classification, cm.Error
+}
+
+type eventPublisherMock struct {
+	Events []string
+}
+
+func (epm *eventPublisherMock) Publish(ctx context.Context, eventType string, data interface{}) error {
+	epm.Events = append(epm.Events, eventType)
+	return nil
+}
+
+type escalationHookMock struct {
+	Calls []datatypes.MessageAnalysis
+}
+
+func (ehm *escalationHookMock) OnNegativeSentiment(ctx context.Context, analysis datatypes.MessageAnalysis) error {
+	ehm.Calls = append(ehm.Calls, analysis)
+	return nil
+}
+
+func TestAnalysisServiceAnalyzeMessage(t *testing.T) {
+	message := datatypes.Message{ID: "message-1", ChatID: "chat-1", Author: datatypes.AuthorUser, Message: "test"}
+
+	t.Run("should save the analysis", func(t *testing.T) {
+		var saved datatypes.MessageAnalysis
+		publisher := &eventPublisherMock{}
+		hook := &escalationHookMock{}
+
+		service := NewAnalysisService(&repositoryMock{
+			CallbackCreateAnalysis: func(ctx context.Context, analysis datatypes.MessageAnalysis) error {
+				saved = analysis
+				return nil
+			},
+		}, &classifierMock{
+			Classification: Classification{Sentiment: 0.8, Intent: "greeting", Language: "en"},
+		}, publisher, AnalysisServiceConfig{})
+		service.AddEscalationHook(hook)
+
+		analysis, err := service.AnalyzeMessage(context.Background(), message)
+		assert.NoError(t, err)
+		assert.Equal(t, analysis, saved)
+		assert.Equal(t, "message-1", saved.MessageID)
+		assert.Equal(t, IntentOther, saved.Intent)
+		assert.Equal(t, "mock", saved.Classifier)
+		assert.Empty(t, publisher.Events)
+		assert.Empty(t, hook.Calls)
+	})
+
+	t.Run("should run the escalation hooks on negative messages", func(t *testing.T) {
+		publisher := &eventPublisherMock{}
+		hook := &escalationHookMock{}
+
+		service := NewAnalysisService(&repositoryMock{}, &classifierMock{
+			Classification: Classification{Sentiment: -3, Intent: IntentComplaint},
+		}, publisher, AnalysisServiceConfig{NegativeThreshold: -0.4})
+		service.AddEscalationHook(hook)
+
+		analysis, err := service.AnalyzeMessage(context.Background(), message)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(-1), analysis.Sentiment)
+		assert.Equal(t, []string{EventNegativeSentiment}, publisher.Events)
+		assert.Len(t, hook.Calls, 1)
+	})
+
+	t.Run("should publish return requests", func(t *testing.T) {
+		publisher := &eventPublisherMock{}
+
+		service := NewAnalysisService(&repositoryMock{}, &classifierMock{
+			Classification: Classification{Sentiment: 0, Intent: IntentReturn},
+		}, publisher, AnalysisServiceConfig{})
+
+		_, err := service.AnalyzeMessage(context.Background(), message)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{EventReturnRequested}, publisher.Events)
+	})
+
+	t.Run("should fail when the classifier fails", func(t *testing.T) {
+		errClassify := errors.New("error to classify for tests")
+		service := NewAnalysisService(&repositoryMock{}, &classifierMock{Error: errClassify}, &eventPublisherMock{}, AnalysisServiceConfig{})
+
+		_, err := service.AnalyzeMessage(context.Background(), message)
+		assert.ErrorIs(t, err, errClassify)
+	})
+}
@@ -0,0 +1,9 @@
+package analysis
+
+import "errors"
+
+var (
+	ErrCantSaveAnalysis   = errors.New("can't save message analysis")
+	ErrInvalidClassifier  = errors.New("invalid message classifier")
+	ErrInvalidModelAnswer = errors.New("invalid classification answer from the model")
+)
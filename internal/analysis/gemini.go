@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
+	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OperationClassify labels the metrics of the model classification requests
+const OperationClassify = "classify"
+
+const classificationInstruction = `You classify customer messages sent to the support chat of an e-commerce.
+Answer only with a JSON object, without markdown, with the fields:
+"sentiment": number from -1 (very negative) to 1 (very positive);
+"intent": one of "complaint", "return", "question", "review_answer" or "other";
+"language": ISO 639-1 code of the message language.`
+
+type contentGenerator interface {
+	GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
+}
+
+// GeminiClassifier classifies messages with a lightweight model call
+type GeminiClassifier struct {
+	model contentGenerator
+}
+
+// NewGeminiClassifier create a new classifier using the given model, e.g. gemini-1.5-flash-latest
+func NewGeminiClassifier(model *genai.GenerativeModel) *GeminiClassifier {
+	model.SetTemperature(0)
+	model.SystemInstruction = &genai.Content{
+		Parts: []genai.Part{genai.Text(classificationInstruction)},
+	}
+
+	return &GeminiClassifier{model: model}
+}
+
+// Name
+func (gc *GeminiClassifier) Name() string {
+	return "gemini"
+}
+
+// Classify asks the model to classify the message
+func (gc *GeminiClassifier) Classify(ctx context.Context, text string) (Classification, error) {
+	ctx, span := tracing.Start(ctx, "analysis.Classify", attribute.String("gen_ai.operation", OperationClassify))
+	start := time.Now()
+	resp, err := gc.model.GenerateContent(ctx, genai.Text(text))
+	chatbot.ObserveResponse(OperationClassify, start, resp, err)
+	tracing.End(span, err)
+
+	if err != nil {
+		return Classification{}, fmt.Errorf("failed to classify message. Cause: %w", err)
+	}
+	return parseClassification(resp)
+}
+
+// parseClassification reads the JSON answer of the model
+func parseClassification(resp *genai.GenerateContentResponse) (Classification, error) {
+	for _, candidate := range resp.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+
+		for _, part := range candidate.Content.Parts {
+			value, ok := part.(genai.Text)
+			if !ok {
+				continue
+			}
+
+			// models sometimes wrap the JSON in a markdown code block
+			answer := strings.TrimSpace(string(value))
+			answer = strings.TrimPrefix(answer, "```json")
+			answer = strings.Trim(answer, "`\n ")
+
+			var classification Classification
+			if err := json.Unmarshal([]byte(answer), &classification); err != nil {
+				return Classification{}, fmt.Errorf("%w: %s", ErrInvalidModelAnswer, err)
+			}
+
+			classification.Intent = strings.ToLower(classification.Intent)
+			classification.Language = strings.ToLower(classification.Language)
+			return classification, nil
+		}
+	}
+	return Classification{}, ErrInvalidModelAnswer
+}
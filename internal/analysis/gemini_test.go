@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/stretchr/testify/assert"
+)
+
+type contentGeneratorMock struct {
+	Error    error
+	Response *genai.GenerateContentResponse
+}
+
+func (cgm *contentGeneratorMock) GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+	return cgm.Response, cgm.Error
+}
+
+func textResponse(text string) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{
+			{Content: &genai.Content{Parts: []genai.Part{genai.Text(text)}}},
+		},
+	}
+}
+
+func TestGeminiClassifier(t *testing.T) {
+	t.Run("should parse the model answer", func(t *testing.T) {
+		classifier := &GeminiClassifier{model: &contentGeneratorMock{
+			Response: textResponse("```json\n{\"sentiment\": -0.7, \"intent\": \"Complaint\", \"language\": \"EN\"}\n```"),
+		}}
+
+		classification, err := classifier.Classify(context.Background(), "my phone is broken")
+		assert.NoError(t, err)
+		assert.Equal(t, Classification{Sentiment: -0.7, Intent: IntentComplaint, Language: "en"}, classification)
+	})
+
+	t.Run("should fail with an invalid answer", func(t *testing.T) {
+		classifier := &GeminiClassifier{model: &contentGeneratorMock{Response: textResponse("I think it's negative")}}
+
+		_, err := classifier.Classify(context.Background(), "my phone is broken")
+		assert.ErrorIs(t, err, ErrInvalidModelAnswer)
+
+		classifier = &GeminiClassifier{model: &contentGeneratorMock{Response: &genai.GenerateContentResponse{}}}
+		_, err = classifier.Classify(context.Background(), "my phone is broken")
+		assert.ErrorIs(t, err, ErrInvalidModelAnswer)
+	})
+}
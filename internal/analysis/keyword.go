@@ -0,0 +1,162 @@
+package analysis
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+var (
+	positiveWords = wordSet(
+		"good", "great", "excellent", "amazing", "awesome", "love", "loved", "perfect", "happy", "satisfied",
+		"fast", "easy", "nice", "recommend", "thanks", "thank", "fantastic", "best",
+		"bom", "boa", "ótimo", "ótima", "excelente", "adorei", "perfeito", "feliz", "satisfeito", "rápido", "fácil", "obrigado", "obrigada",
+		"bueno", "buena", "genial", "encantó", "perfecto", "contento", "satisfecho", "rápida", "gracias",
+	)
+	negativeWords = wordSet(
+		"bad", "terrible", "awful", "horrible", "worst", "hate", "angry", "broken", "defective", "damaged",
+		"late", "slow", "disappointed", "useless", "never", "problem", "wrong", "scam", "unacceptable", "annoyed",
+		"ruim", "péssimo", "péssima", "horrível", "odeio", "quebrado", "quebrou", "defeito", "danificado", "atrasado", "lento", "decepcionado", "problema", "errado", "golpe",
+		"malo", "mala", "terrible", "pésimo", "odio", "roto", "defectuoso", "dañado", "tarde", "lenta", "decepcionado", "estafa",
+	)
+	negationWords = wordSet("not", "no", "don't", "didn't", "isn't", "wasn't", "não", "nunca", "nem")
+
+	returnWords = wordSet(
+		"return", "returns", "refund", "exchange", "devolver", "devolução", "reembolso", "troca", "trocar", "devolución", "devolucion",
+	)
+	complaintWords = wordSet(
+		"broken", "defective", "damaged", "late", "missing", "wrong", "complaint", "scam",
+		"quebrado", "quebrou", "defeito", "danificado", "atrasado", "faltando", "reclamação",
+		"roto", "defectuoso", "dañado", "queja",
+	)
+	questionWords = wordSet(
+		"what", "how", "when", "where", "why", "which", "can", "could", "do", "does", "is", "are",
+		"qual", "como", "quando", "onde", "porque", "pode", "quanto",
+		"cuál", "cómo", "cuándo", "dónde", "puedo", "cuánto",
+	)
+
+	languageStopWords = map[string]map[string]bool{
+		"en": wordSet("the", "and", "is", "it", "my", "i", "to", "of", "was", "with", "you", "this", "that", "not"),
+		"pt": wordSet("o", "os", "e", "é", "meu", "minha", "eu", "para", "de", "com", "você", "isso", "não", "foi", "um", "uma"),
+		"es": wordSet("el", "los", "y", "es", "mi", "yo", "para", "de", "con", "usted", "esto", "no", "fue", "un", "una", "muy"),
+	}
+)
+
+// KeywordClassifier is a lightweight classifier based on word lists. It doesn't need any external service.
+type KeywordClassifier struct{}
+
+// NewKeywordClassifier create a new keyword classifier
+func NewKeywordClassifier() *KeywordClassifier {
+	return &KeywordClassifier{}
+}
+
+// Name
+func (kc *KeywordClassifier) Name() string {
+	return "keyword"
+}
+
+// Classify classifies the message using the word lists
+func (kc *KeywordClassifier) Classify(ctx context.Context, text string) (Classification, error) {
+	words := tokenize(text)
+
+	return Classification{
+		Sentiment: sentiment(words),
+		Intent:    intent(text, words),
+		Language:  language(words),
+	}, nil
+}
+
+// sentiment returns the balance between positive and negative words, flipping negated words
+func sentiment(words []string) float64 {
+	var positive, negative float64
+
+	for i, word := range words {
+		negated := i > 0 && negationWords[words[i-1]]
+
+		switch {
+		case positiveWords[word] && !negated, negativeWords[word] && negated:
+			positive++
+		case negativeWords[word], positiveWords[word]:
+			negative++
+		}
+	}
+
+	if positive+negative == 0 {
+		return 0
+	}
+	return (positive - negative) / (positive + negative)
+}
+
+// intent picks the first matching intent by priority
+func intent(text string, words []string) string {
+	var hasComplaint, hasSentiment bool
+
+	for _, word := range words {
+		if returnWords[word] {
+			return IntentReturn
+		}
+
+		if complaintWords[word] {
+			hasComplaint = true
+		}
+
+		if positiveWords[word] || negativeWords[word] {
+			hasSentiment = true
+		}
+	}
+
+	switch {
+	case hasComplaint:
+		return IntentComplaint
+	case strings.HasSuffix(strings.TrimSpace(text), "?"), len(words) > 0 && questionWords[words[0]]:
+		return IntentQuestion
+	case hasSentiment || isRating(words):
+		return IntentReviewAnswer
+	}
+	return IntentOther
+}
+
+// language returns the language with more stop words
+func language(words []string) string {
+	detected, best := "unknown", 0
+
+	for _, lang := range []string{"en", "pt", "es"} {
+		count := 0
+		for _, word := range words {
+			if languageStopWords[lang][word] {
+				count++
+			}
+		}
+
+		if count > best {
+			detected, best = lang, count
+		}
+	}
+	return detected
+}
+
+// isRating checks for answers like "4" or "5 stars"
+func isRating(words []string) bool {
+	for _, word := range words {
+		if len(word) == 1 && word[0] >= '1' && word[0] <= '5' {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits the text in lower case words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// wordSet
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
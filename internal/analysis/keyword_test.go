@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywordClassifier(t *testing.T) {
+	classifier := NewKeywordClassifier()
+
+	tests := []struct {
+		text      string
+		sentiment func(float64) bool
+		intent    string
+		language  string
+	}{
+		{
+			text:      "The phone arrived broken and the delivery was terrible",
+			sentiment: func(s float64) bool { return s < 0 },
+			intent:    IntentComplaint,
+			language:  "en",
+		},
+		{
+			text:      "I want to return my order and get a refund",
+			sentiment: func(s float64) bool { return s == 0 },
+			intent:    IntentReturn,
+			language:  "en",
+		},
+		{
+			text:      "How long does the shipping take?",
+			sentiment: func(s float64) bool { return s == 0 },
+			intent:    IntentQuestion,
+			language:  "en",
+		},
+		{
+			text:      "It was not bad, the product is great",
+			sentiment: func(s float64) bool { return s > 0 },
+			intent:    IntentReviewAnswer,
+			language:  "en",
+		},
+		{
+			text:      "O produto chegou quebrado, não gostei",
+			sentiment: func(s float64) bool { return s < 0 },
+			intent:    IntentComplaint,
+			language:  "pt",
+		},
+		{
+			text:      "5",
+			sentiment: func(s float64) bool { return s == 0 },
+			intent:    IntentReviewAnswer,
+			language:  "unknown",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("should classify "+test.text, func(t *testing.T) {
+			classification, err := classifier.Classify(context.Background(), test.text)
+			assert.NoError(t, err)
+			assert.True(t, test.sentiment(classification.Sentiment), "unexpected sentiment %f", classification.Sentiment)
+			assert.Equal(t, test.intent, classification.Intent)
+			assert.Equal(t, test.language, classification.Language)
+		})
+	}
+}
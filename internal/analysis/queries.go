@@ -0,0 +1,6 @@
+package analysis
+
+var createAnalysis = `
+	INSERT INTO message_analyses (message_id, chat_id, sentiment, intent, language, classifier, created_at)
+	VALUES (:message_id, :chat_id, :sentiment, :intent, :language, :classifier, :created_at);
+`
@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// CreateAnalysis saves the analysis of a message
+func (r *Repository) CreateAnalysis(ctx context.Context, analysis datatypes.MessageAnalysis) error {
+	stm, err := r.db.PrepareNamedContext(ctx, createAnalysis)
+	if err != nil {
+		return fmt.Errorf("failed to create new message analysis. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"message_id": analysis.MessageID,
+		"chat_id":    analysis.ChatID,
+		"sentiment":  analysis.Sentiment,
+		"intent":     analysis.Intent,
+		"language":   analysis.Language,
+		"classifier": analysis.Classifier,
+		"created_at": analysis.CreatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to create new message analysis. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create new message analysis. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantSaveAnalysis
+	}
+	return nil
+}
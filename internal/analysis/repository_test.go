@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateAnalysis(t *testing.T) {
+	t.Run("should create a new analysis", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						assert.Equal(t, "message-1", arg.(map[string]interface{})["message_id"])
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.CreateAnalysis(context.Background(), datatypes.MessageAnalysis{MessageID: "message-1"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("should fail to run exec context", func(t *testing.T) {
+		errExecContext := errors.New("error to run exec context for tests")
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return nil, errExecContext
+					},
+				}, nil
+			},
+		})
+
+		err := repository.CreateAnalysis(context.Background(), datatypes.MessageAnalysis{})
+		assert.ErrorIs(t, err, errExecContext)
+	})
+
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.CreateAnalysis(context.Background(), datatypes.MessageAnalysis{})
+		assert.ErrorIs(t, err, ErrCantSaveAnalysis)
+	})
+}
@@ -0,0 +1,186 @@
+package analytics
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+var (
+	// ratingQuestion matches the chatbot question that asks the customer for a rating
+	ratingQuestion = regexp.MustCompile(`(?i)\b1 to 5\b|\b1-5\b`)
+	ratingAnswer   = regexp.MustCompile(`(?i)\b([1-5]|one|two|three|four|five)\b`)
+	ratingWords    = map[string]int{"one": 1, "two": 2, "three": 3, "four": 4, "five": 5}
+)
+
+type repository interface {
+	FindSummary(ctx context.Context, filter datatypes.AnalyticsFilter) (summaryRow, error)
+	FindChatDurations(ctx context.Context, filter datatypes.AnalyticsFilter) ([]chatDuration, error)
+	FindVolume(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error)
+	FindReviewMessages(ctx context.Context, filter datatypes.AnalyticsFilter) ([]reviewMessage, error)
+	FindIntents(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.IntentCount, error)
+}
+
+type AnalyticsService struct {
+	repository repository
+}
+
+// NewAnalyticsService create a new analytics service
+func NewAnalyticsService(repository repository) *AnalyticsService {
+	return &AnalyticsService{repository}
+}
+
+// NewFilter creates a filter from the from, to and product query params
+func NewFilter(from string, to string, product string) (datatypes.AnalyticsFilter, error) {
+	fromDate, toDate, err := datatypes.ParseDateRange(from, to)
+	if err != nil {
+		return datatypes.AnalyticsFilter{}, err
+	}
+
+	return datatypes.AnalyticsFilter{
+		From:    fromDate,
+		To:      toDate,
+		Product: strings.TrimSpace(product),
+	}, nil
+}
+
+// Summary returns the totals of the chats that match the filter
+func (as *AnalyticsService) Summary(ctx context.Context, filter datatypes.AnalyticsFilter) (datatypes.AnalyticsSummary, error) {
+	row, err := as.repository.FindSummary(ctx, filter)
+	if err != nil {
+		return datatypes.AnalyticsSummary{}, err
+	}
+
+	durations, err := as.repository.FindChatDurations(ctx, filter)
+	if err != nil {
+		return datatypes.AnalyticsSummary{}, err
+	}
+
+	ratings, err := as.Ratings(ctx, filter)
+	if err != nil {
+		return datatypes.AnalyticsSummary{}, err
+	}
+
+	summary := datatypes.AnalyticsSummary{
+		Chats:            row.Chats,
+		Users:            row.Users,
+		Messages:         row.Messages,
+		Reviews:          row.Reviews,
+		CompletedReviews: row.CompletedReviews,
+		CompletionRate:   ratio(float64(row.CompletedReviews), row.Reviews),
+		Escalations:      row.Escalations,
+		AverageTurns:     ratio(float64(row.UserMessages), row.Chats),
+	}
+
+	var totalDuration time.Duration
+	for _, duration := range durations {
+		if duration.LastMessageAt.After(duration.StartedAt) {
+			totalDuration += duration.LastMessageAt.Sub(duration.StartedAt)
+		}
+	}
+	summary.AverageDurationSeconds = ratio(totalDuration.Seconds(), len(durations))
+
+	var ratingsSum float64
+	for _, product := range ratings {
+		summary.Ratings += product.Ratings
+		ratingsSum += product.AverageRating * float64(product.Ratings)
+	}
+	summary.AverageRating = ratio(ratingsSum, summary.Ratings)
+
+	return summary, nil
+}
+
+// Volume returns the number of chats, users and messages per day
+func (as *AnalyticsService) Volume(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error) {
+	return as.repository.FindVolume(ctx, filter)
+}
+
+// Ratings returns the satisfaction ratings per product. The rating is the first
+// customer answer to the chatbot question that asks for a rating from 1 to 5.
+func (as *AnalyticsService) Ratings(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.ProductRatings, error) {
+	messages, err := as.repository.FindReviewMessages(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ratings  = []datatypes.ProductRatings{}
+		products = map[string]int{}
+		rated    = map[string]bool{}
+		asked    = map[string]bool{}
+	)
+
+	for _, message := range messages {
+		if rated[message.ChatID] {
+			continue
+		}
+
+		switch message.Author {
+		case datatypes.AuthorChatbot:
+			asked[message.ChatID] = ratingQuestion.MatchString(message.Message)
+			continue
+		case datatypes.AuthorUser:
+		default:
+			continue
+		}
+
+		if !asked[message.ChatID] {
+			continue
+		}
+		asked[message.ChatID] = false
+
+		rating, ok := ParseRating(message.Message)
+		if !ok {
+			continue
+		}
+		rated[message.ChatID] = true
+
+		index, ok := products[message.Product]
+		if !ok {
+			index = len(ratings)
+			products[message.Product] = index
+			ratings = append(ratings, datatypes.ProductRatings{Product: message.Product})
+		}
+		ratings[index].Ratings++
+		ratings[index].Distribution[rating-1]++
+	}
+
+	for i, product := range ratings {
+		var sum int
+		for rating, count := range product.Distribution {
+			sum += (rating + 1) * count
+		}
+		ratings[i].AverageRating = ratio(float64(sum), product.Ratings)
+	}
+
+	return ratings, nil
+}
+
+// Intents returns the number of analyzed messages per intent, like complaints and returns
+func (as *AnalyticsService) Intents(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.IntentCount, error) {
+	return as.repository.FindIntents(ctx, filter)
+}
+
+// ParseRating finds a rating from 1 to 5 in the customer answer
+func ParseRating(answer string) (int, bool) {
+	match := ratingAnswer.FindStringSubmatch(answer)
+	if match == nil {
+		return 0, false
+	}
+
+	if rating, ok := ratingWords[strings.ToLower(match[1])]; ok {
+		return rating, true
+	}
+	return int(match[1][0] - '0'), true
+}
+
+// ratio divides the value by the total, returning 0 when there is nothing to divide
+func ratio(value float64, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / float64(total)
+}
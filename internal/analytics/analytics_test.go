@@ -0,0 +1,150 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repositoryMock struct {
+	Error          error
+	Summary        summaryRow
+	Durations      []chatDuration
+	ReviewMessages []reviewMessage
+}
+
+func (rm *repositoryMock) FindSummary(ctx context.Context, filter datatypes.AnalyticsFilter) (summaryRow, error) {
+	return rm.Summary, rm.Error
+}
+
+func (rm *repositoryMock) FindChatDurations(ctx context.Context, filter datatypes.AnalyticsFilter) ([]chatDuration, error) {
+	return rm.Durations, rm.Error
+}
+
+func (rm *repositoryMock) FindVolume(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error) {
+	return []datatypes.VolumeEntry{}, rm.Error
+}
+
+func (rm *repositoryMock) FindReviewMessages(ctx context.Context, filter datatypes.AnalyticsFilter) ([]reviewMessage, error) {
+	return rm.ReviewMessages, rm.Error
+}
+
+func (rm *repositoryMock) FindIntents(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.IntentCount, error) {
+	return []datatypes.IntentCount{}, rm.Error
+}
+
+var reviewMessages = []reviewMessage{
+	{ChatID: "1", Product: "Galaxy S24", Author: datatypes.AuthorChatbot, Message: "How was the shipping?"},
+	{ChatID: "1", Product: "Galaxy S24", Author: datatypes.AuthorUser, Message: "It took 2 days"},
+	{ChatID: "1", Product: "Galaxy S24", Author: datatypes.AuthorChatbot, Message: "On a scale of 1 to 5, how satisfied are you?"},
+	{ChatID: "1", Product: "Galaxy S24", Author: datatypes.AuthorUser, Message: "I would give it a 4"},
+	{ChatID: "1", Product: "Galaxy S24", Author: datatypes.AuthorChatbot, Message: "From 1 to 5, how was our service?"},
+	{ChatID: "1", Product: "Galaxy S24", Author: datatypes.AuthorUser, Message: "1"},
+	{ChatID: "2", Product: "Galaxy S24", Author: datatypes.AuthorChatbot, Message: "On a scale of 1 to 5, how satisfied are you?"},
+	{ChatID: "2", Product: "Galaxy S24", Author: datatypes.AuthorUser, Message: "Five stars!"},
+	{ChatID: "3", Product: "Pixel 8", Author: datatypes.AuthorChatbot, Message: "On a scale of 1 to 5, how satisfied are you?"},
+	{ChatID: "3", Product: "Pixel 8", Author: datatypes.AuthorUser, Message: "I don't know"},
+}
+
+func TestNewFilter(t *testing.T) {
+	t.Run("should include the whole to day", func(t *testing.T) {
+		filter, err := NewFilter("2024-05-01", "2024-05-31", " Galaxy S24 ")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), filter.From)
+		assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), filter.To)
+		assert.Equal(t, "Galaxy S24", filter.Product)
+	})
+
+	t.Run("should accept RFC 3339 dates", func(t *testing.T) {
+		filter, err := NewFilter("", "2024-05-31T12:00:00-03:00", "")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), filter.From)
+		assert.Equal(t, time.Date(2024, 5, 31, 15, 0, 0, 0, time.UTC), filter.To)
+	})
+
+	t.Run("should fail with invalid dates", func(t *testing.T) {
+		_, err := NewFilter("05/01/2024", "", "")
+		assert.ErrorIs(t, err, ErrInvalidDate)
+
+		_, err = NewFilter("2024-06-01", "2024-05-01", "")
+		assert.ErrorIs(t, err, ErrInvalidDateRange)
+	})
+}
+
+func TestParseRating(t *testing.T) {
+	for answer, expected := range map[string]int{
+		"5":                    5,
+		"I'd say 3 out of 5":   3,
+		"four, the box was ok": 4,
+		"ONE":                  1,
+	} {
+		rating, ok := ParseRating(answer)
+		assert.True(t, ok, answer)
+		assert.Equal(t, expected, rating, answer)
+	}
+
+	for _, answer := range []string{"10", "it was great", "0"} {
+		_, ok := ParseRating(answer)
+		assert.False(t, ok, answer)
+	}
+}
+
+func TestServiceRatings(t *testing.T) {
+	t.Run("should use the first answer to the rating question of each chat", func(t *testing.T) {
+		service := NewAnalyticsService(&repositoryMock{ReviewMessages: reviewMessages})
+
+		ratings, err := service.Ratings(context.Background(), datatypes.AnalyticsFilter{})
+		require.NoError(t, err)
+		require.Len(t, ratings, 1)
+		assert.Equal(t, "Galaxy S24", ratings[0].Product)
+		assert.Equal(t, 2, ratings[0].Ratings)
+		assert.Equal(t, 4.5, ratings[0].AverageRating)
+		assert.Equal(t, [5]int{0, 0, 0, 1, 1}, ratings[0].Distribution)
+	})
+}
+
+func TestServiceSummary(t *testing.T) {
+	t.Run("should compute the rates and averages", func(t *testing.T) {
+		start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		service := NewAnalyticsService(&repositoryMock{
+			Summary: summaryRow{Chats: 4, Users: 3, Messages: 40, UserMessages: 18, Reviews: 4, CompletedReviews: 3},
+			Durations: []chatDuration{
+				{StartedAt: start, LastMessageAt: start.Add(10 * time.Minute)},
+				{StartedAt: start, LastMessageAt: start.Add(20 * time.Minute)},
+			},
+			ReviewMessages: reviewMessages,
+		})
+
+		summary, err := service.Summary(context.Background(), datatypes.AnalyticsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, 0.75, summary.CompletionRate)
+		assert.Equal(t, 4.5, summary.AverageTurns)
+		assert.Equal(t, float64(15*60), summary.AverageDurationSeconds)
+		assert.Equal(t, 2, summary.Ratings)
+		assert.Equal(t, 4.5, summary.AverageRating)
+	})
+
+	t.Run("should not divide by zero without chats", func(t *testing.T) {
+		summary, err := NewAnalyticsService(&repositoryMock{}).Summary(context.Background(), datatypes.AnalyticsFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, datatypes.AnalyticsSummary{}, summary)
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Run("should write the header and the rows", func(t *testing.T) {
+		var body bytes.Buffer
+		err := WriteCSV(&body, []datatypes.IntentCount{{Intent: "complaint", Messages: 3, AverageSentiment: -0.5}})
+		require.NoError(t, err)
+		assert.Equal(t, "intent,messages,average_sentiment\ncomplaint,3,-0.5000\n", body.String())
+	})
+
+	t.Run("should fail with unknown reports", func(t *testing.T) {
+		assert.ErrorIs(t, WriteCSV(&bytes.Buffer{}, "summary"), ErrUnsupportedReport)
+	})
+}
@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+// WriteCSV writes one of the analytics reports as CSV with a header line
+func WriteCSV(w io.Writer, report interface{}) error {
+	var records [][]string
+
+	switch report := report.(type) {
+	case datatypes.AnalyticsSummary:
+		records = [][]string{
+			{
+				"chats", "users", "messages", "reviews", "completed_reviews", "completion_rate",
+				"escalations", "average_duration_seconds", "average_turns", "ratings", "average_rating",
+			},
+			{
+				itoa(report.Chats), itoa(report.Users), itoa(report.Messages), itoa(report.Reviews),
+				itoa(report.CompletedReviews), ftoa(report.CompletionRate), itoa(report.Escalations),
+				ftoa(report.AverageDurationSeconds), ftoa(report.AverageTurns), itoa(report.Ratings),
+				ftoa(report.AverageRating),
+			},
+		}
+	case []datatypes.VolumeEntry:
+		records = [][]string{{"date", "chats", "users", "messages"}}
+		for _, entry := range report {
+			records = append(records, []string{entry.Date, itoa(entry.Chats), itoa(entry.Users), itoa(entry.Messages)})
+		}
+	case []datatypes.ProductRatings:
+		records = [][]string{{"product", "ratings", "average_rating", "rating_1", "rating_2", "rating_3", "rating_4", "rating_5"}}
+		for _, product := range report {
+			record := []string{product.Product, itoa(product.Ratings), ftoa(product.AverageRating)}
+			for _, count := range product.Distribution {
+				record = append(record, itoa(count))
+			}
+			records = append(records, record)
+		}
+	case []datatypes.IntentCount:
+		records = [][]string{{"intent", "messages", "average_sentiment"}}
+		for _, intent := range report {
+			records = append(records, []string{intent.Intent, itoa(intent.Messages), ftoa(intent.AverageSentiment)})
+		}
+	default:
+		return ErrUnsupportedReport
+	}
+
+	return csv.NewWriter(w).WriteAll(records)
+}
+
+func itoa(value int) string {
+	return strconv.Itoa(value)
+}
+
+func ftoa(value float64) string {
+	return strconv.FormatFloat(value, 'f', 4, 64)
+}
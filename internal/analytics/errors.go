@@ -0,0 +1,14 @@
+package analytics
+
+import (
+	"errors"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+var (
+	ErrInvalidDate       = datatypes.ErrInvalidDate
+	ErrInvalidDateRange  = datatypes.ErrInvalidDateRange
+	ErrUnsupportedFormat = errors.New("unsupported report format")
+	ErrUnsupportedReport = errors.New("unsupported report")
+)
@@ -0,0 +1,57 @@
+package analytics
+
+var chatsFilter = `
+	c.tenant_id = :tenant_id AND c.created_at >= :from AND c.created_at < :to AND (:product = '' OR c.product = :product)
+`
+
+var findSummary = `
+	SELECT
+		COUNT(DISTINCT c.id) AS chats,
+		COUNT(DISTINCT c.user_id) AS users,
+		COUNT(m.id) AS messages,
+		COALESCE(SUM(CASE WHEN m.author = 'user' THEN 1 ELSE 0 END), 0) AS user_messages,
+		COUNT(DISTINCT CASE WHEN c.product <> '' THEN c.id END) AS reviews,
+		COUNT(DISTINCT CASE WHEN c.reviewed_at IS NOT NULL THEN c.id END) AS completed_reviews,
+		COUNT(DISTINCT CASE WHEN c.status = 'agent' OR m.author = 'agent' THEN c.id END) AS escalations
+	FROM chats c
+	LEFT JOIN messages m ON m.chat_id = c.id
+	WHERE` + chatsFilter + `;
+`
+
+var findChatDurations = `
+	SELECT c.created_at AS started_at, MAX(m.created_at) AS last_message_at
+	FROM chats c
+	JOIN messages m ON m.chat_id = c.id
+	WHERE` + chatsFilter + `
+	GROUP BY c.id, c.created_at;
+`
+
+var findVolume = `
+	SELECT
+		SUBSTR(c.created_at, 1, 10) AS day,
+		COUNT(DISTINCT c.id) AS chats,
+		COUNT(DISTINCT c.user_id) AS users,
+		COUNT(m.id) AS messages
+	FROM chats c
+	LEFT JOIN messages m ON m.chat_id = c.id
+	WHERE` + chatsFilter + `
+	GROUP BY SUBSTR(c.created_at, 1, 10)
+	ORDER BY day;
+`
+
+var findReviewMessages = `
+	SELECT c.id AS chat_id, c.product, m.author, m.message
+	FROM chats c
+	JOIN messages m ON m.chat_id = c.id
+	WHERE c.product <> '' AND` + chatsFilter + `
+	ORDER BY c.product, c.id, m.created_at;
+`
+
+var findIntents = `
+	SELECT a.intent, COUNT(*) AS messages, AVG(a.sentiment) AS average_sentiment
+	FROM message_analyses a
+	JOIN chats c ON c.id = a.chat_id
+	WHERE` + chatsFilter + `
+	GROUP BY a.intent
+	ORDER BY messages DESC, a.intent;
+`
@@ -0,0 +1,137 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+)
+
+type summaryRow struct {
+	Chats            int `db:"chats"`
+	Users            int `db:"users"`
+	Messages         int `db:"messages"`
+	UserMessages     int `db:"user_messages"`
+	Reviews          int `db:"reviews"`
+	CompletedReviews int `db:"completed_reviews"`
+	Escalations      int `db:"escalations"`
+}
+
+type chatDuration struct {
+	StartedAt     time.Time `db:"started_at"`
+	LastMessageAt time.Time `db:"last_message_at"`
+}
+
+type reviewMessage struct {
+	ChatID  string `db:"chat_id"`
+	Product string `db:"product"`
+	Author  string `db:"author"`
+	Message string `db:"message"`
+}
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// FindSummary counts the chats, users and messages that match the filter
+func (r *Repository) FindSummary(ctx context.Context, filter datatypes.AnalyticsFilter) (summaryRow, error) {
+	var summary summaryRow
+
+	stm, err := r.db.PrepareNamedContext(ctx, findSummary)
+	if err != nil {
+		return summaryRow{}, fmt.Errorf("failed to find summary. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.GetContext(ctx, &summary, filterParams(ctx, filter)); err != nil {
+		return summaryRow{}, fmt.Errorf("failed to find summary. Cause: %w", err)
+	}
+
+	return summary, nil
+}
+
+// FindChatDurations finds when each chat started and when its last message was sent
+func (r *Repository) FindChatDurations(ctx context.Context, filter datatypes.AnalyticsFilter) ([]chatDuration, error) {
+	durations := []chatDuration{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findChatDurations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chat durations. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.SelectContext(ctx, &durations, filterParams(ctx, filter)); err != nil {
+		return nil, fmt.Errorf("failed to find chat durations. Cause: %w", err)
+	}
+
+	return durations, nil
+}
+
+// FindVolume counts the chats, users and messages per day
+func (r *Repository) FindVolume(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.VolumeEntry, error) {
+	volume := []datatypes.VolumeEntry{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findVolume)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find volume. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.SelectContext(ctx, &volume, filterParams(ctx, filter)); err != nil {
+		return nil, fmt.Errorf("failed to find volume. Cause: %w", err)
+	}
+
+	return volume, nil
+}
+
+// FindReviewMessages finds the messages of the review chats ordered by product, chat and time
+func (r *Repository) FindReviewMessages(ctx context.Context, filter datatypes.AnalyticsFilter) ([]reviewMessage, error) {
+	messages := []reviewMessage{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findReviewMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find review messages. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.SelectContext(ctx, &messages, filterParams(ctx, filter)); err != nil {
+		return nil, fmt.Errorf("failed to find review messages. Cause: %w", err)
+	}
+
+	return messages, nil
+}
+
+// FindIntents counts the analyzed messages per intent
+func (r *Repository) FindIntents(ctx context.Context, filter datatypes.AnalyticsFilter) ([]datatypes.IntentCount, error) {
+	intents := []datatypes.IntentCount{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findIntents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find intents. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.SelectContext(ctx, &intents, filterParams(ctx, filter)); err != nil {
+		return nil, fmt.Errorf("failed to find intents. Cause: %w", err)
+	}
+
+	return intents, nil
+}
+
+// filterParams maps the filter of the tenant to the chatsFilter query params
+func filterParams(ctx context.Context, filter datatypes.AnalyticsFilter) map[string]interface{} {
+	return map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"from":      filter.From,
+		"to":        filter.To,
+		"product":   filter.Product,
+	}
+}
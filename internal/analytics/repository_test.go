@@ -0,0 +1,75 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryFindSummary(t *testing.T) {
+	t.Run("should pass the filter and the tenant to the query", func(t *testing.T) {
+		filter := datatypes.AnalyticsFilter{From: time.Unix(0, 0), To: time.Now(), Product: "Galaxy S24"}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest, arg interface{}) error {
+						assert.Equal(t, map[string]interface{}{
+							"tenant_id": "store-a",
+							"from":      filter.From,
+							"to":        filter.To,
+							"product":   "Galaxy S24",
+						}, arg)
+						if summary, ok := dest.(*summaryRow); ok {
+							summary.Chats = 3
+						}
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		summary, err := repository.FindSummary(tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "store-a"}), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, summary.Chats)
+	})
+
+	t.Run("should fail when prapare named context", func(t *testing.T) {
+		errPrepareNamedContext := errors.New("error when preparing named context for tests")
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return nil, errPrepareNamedContext
+			},
+		})
+
+		_, err := repository.FindSummary(context.Background(), datatypes.AnalyticsFilter{})
+		assert.ErrorIs(t, err, errPrepareNamedContext)
+	})
+}
+
+func TestRepositoryFindVolume(t *testing.T) {
+	t.Run("should find the volume per day", func(t *testing.T) {
+		mockedVolume := []datatypes.VolumeEntry{{Date: "2024-05-01", Chats: 1}}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+						if volume, ok := dest.(*[]datatypes.VolumeEntry); ok {
+							*volume = mockedVolume
+						}
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		volume, err := repository.FindVolume(context.Background(), datatypes.AnalyticsFilter{To: time.Now()})
+		assert.NoError(t, err)
+		assert.EqualValues(t, mockedVolume, volume)
+	})
+}
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+const tokenAlgorithm = "HS256"
+
+const (
+	RoleAdmin        = "admin"
+	RoleSupportAgent = "support_agent"
+	RoleAnalyst      = "analyst"
+)
+
+// AdminCredential is the key used by an internal staff member on the admin api
+type AdminCredential struct {
+	Name string
+	Role string
+	Key  string
+}
+
+// SigningKey is a key used to sign the customer tokens. The ID is sent on the token header so keys can be rotated.
+type SigningKey struct {
+	ID     string
+	Secret string
+}
+
+// Claims are the claims carried by a customer token
+type Claims struct {
+	Subject string `json:"sub"`
+	// Tenant is the store of the customer, empty on the tokens issued before the tenants
+	Tenant    string `json:"tid,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+type AuthServiceConfig struct {
+	// SigningKeys are the keys accepted to verify tokens. The first one signs the new tokens.
+	SigningKeys []SigningKey
+	// APIKeys are the keys accepted on server-to-server endpoints
+	APIKeys []string
+	// TokenTTL is how long a customer token is valid
+	TokenTTL time.Duration
+	// Admins are the credentials accepted on the admin api
+	Admins []AdminCredential
+}
+
+// validate check if configs are valid
+func (asc AuthServiceConfig) validate() error {
+	if len(asc.SigningKeys) == 0 {
+		return ErrMissingSigningKeys
+	}
+
+	for _, key := range asc.SigningKeys {
+		if key.ID == "" || key.Secret == "" {
+			return ErrMissingSigningKeys
+		}
+	}
+
+	if len(asc.APIKeys) == 0 {
+		return ErrMissingAPIKeys
+	}
+
+	for _, admin := range asc.Admins {
+		if admin.Name == "" || admin.Key == "" {
+			return ErrInvalidAdminCredential
+		}
+
+		if !IsValidRole(admin.Role) {
+			return fmt.Errorf("%w: %s", ErrInvalidRole, admin.Role)
+		}
+	}
+	return nil
+}
+
+type adminKey struct {
+	admin datatypes.Admin
+	key   []byte
+}
+
+type AuthService struct {
+	signingKeys map[string][]byte
+	activeKey   SigningKey
+	apiKeys     [][]byte
+	admins      []adminKey
+	tokenTTL    time.Duration
+	now         func() time.Time
+}
+
+// NewAuthService create a new auth service
+func NewAuthService(config AuthServiceConfig) (*AuthService, error) {
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("failed to create auth service. Cause: %w", err)
+	}
+
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = time.Hour
+	}
+
+	signingKeys := make(map[string][]byte, len(config.SigningKeys))
+	for _, key := range config.SigningKeys {
+		signingKeys[key.ID] = []byte(key.Secret)
+	}
+
+	apiKeys := make([][]byte, 0, len(config.APIKeys))
+	for _, key := range config.APIKeys {
+		apiKeys = append(apiKeys, hashAPIKey(key))
+	}
+
+	admins := make([]adminKey, 0, len(config.Admins))
+	for _, admin := range config.Admins {
+		admins = append(admins, adminKey{
+			admin: datatypes.Admin{Name: admin.Name, Role: admin.Role},
+			key:   hashAPIKey(admin.Key),
+		})
+	}
+
+	return &AuthService{
+		signingKeys: signingKeys,
+		activeKey:   config.SigningKeys[0],
+		apiKeys:     apiKeys,
+		admins:      admins,
+		tokenTTL:    config.TokenTTL,
+		now:         time.Now,
+	}, nil
+}
+
+// IssueToken issues a short-lived token for the customer of the tenant
+func (as *AuthService) IssueToken(tenantID string, subject string) (datatypes.AuthToken, error) {
+	now := as.now()
+	expiresAt := now.Add(as.tokenTTL)
+
+	header, err := encodeSegment(tokenHeader{
+		Algorithm: tokenAlgorithm,
+		Type:      "JWT",
+		KeyID:     as.activeKey.ID,
+	})
+	if err != nil {
+		return datatypes.AuthToken{}, fmt.Errorf("failed to issue token. Cause: %w", err)
+	}
+
+	claims, err := encodeSegment(Claims{
+		Subject:   subject,
+		Tenant:    tenantID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return datatypes.AuthToken{}, fmt.Errorf("failed to issue token. Cause: %w", err)
+	}
+
+	unsigned := header + "." + claims
+	signature := sign([]byte(as.activeKey.Secret), unsigned)
+
+	return datatypes.AuthToken{
+		Token:     unsigned + "." + signature,
+		ExpiresAt: expiresAt.UTC(),
+	}, nil
+}
+
+// VerifyToken checks the token signature and expiration and returns its claims
+func (as *AuthService) VerifyToken(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Algorithm != tokenAlgorithm {
+		return Claims{}, ErrInvalidToken
+	}
+
+	secret, ok := as.signingKeys[header.KeyID]
+	if !ok {
+		return Claims{}, ErrUnknownSigningKey
+	}
+
+	if !hmac.Equal([]byte(sign(secret, parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil || claims.Subject == "" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	if as.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+// VerifyAPIKey checks if the key is one of the accepted api keys
+func (as *AuthService) VerifyAPIKey(key string) error {
+	if key == "" {
+		return ErrInvalidAPIKey
+	}
+
+	hashedKey := hashAPIKey(key)
+	for _, apiKey := range as.apiKeys {
+		if subtle.ConstantTimeCompare(apiKey, hashedKey) == 1 {
+			return nil
+		}
+	}
+	return ErrInvalidAPIKey
+}
+
+// AuthenticateAdmin returns the staff member that owns the key
+func (as *AuthService) AuthenticateAdmin(key string) (datatypes.Admin, error) {
+	if key == "" {
+		return datatypes.Admin{}, ErrInvalidAPIKey
+	}
+
+	hashedKey := hashAPIKey(key)
+	for _, admin := range as.admins {
+		if subtle.ConstantTimeCompare(admin.key, hashedKey) == 1 {
+			return admin.admin, nil
+		}
+	}
+	return datatypes.Admin{}, ErrInvalidAPIKey
+}
+
+// IsValidRole checks if the role is one of the admin roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleSupportAgent, RoleAnalyst:
+		return true
+	}
+	return false
+}
+
+// ParseAdminCredentials parses credentials in the format "name1:role1:key1,name2:role2:key2"
+func ParseAdminCredentials(value string) []AdminCredential {
+	credentials := []AdminCredential{}
+	for _, rawCredential := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(rawCredential), ":", 3)
+		if len(parts) == 3 {
+			credentials = append(credentials, AdminCredential{Name: parts[0], Role: parts[1], Key: parts[2]})
+		}
+	}
+	return credentials
+}
+
+// ParseSigningKeys parses keys in the format "id1:secret1,id2:secret2"
+func ParseSigningKeys(value string) []SigningKey {
+	keys := []SigningKey{}
+	for _, rawKey := range strings.Split(value, ",") {
+		id, secret, found := strings.Cut(strings.TrimSpace(rawKey), ":")
+		if found {
+			keys = append(keys, SigningKey{ID: id, Secret: secret})
+		}
+	}
+	return keys
+}
+
+// sign returns the base64 HMAC-SHA256 signature of the value
+func sign(secret []byte, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hashAPIKey hashes the api key so all comparisons have the same length
+func hashAPIKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// encodeSegment encodes a token segment
+func encodeSegment(value interface{}) (string, error) {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+// decodeSegment decodes a token segment
+func decodeSegment(segment string, value interface{}) error {
+	bs, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, value)
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService(t *testing.T) {
+	config := AuthServiceConfig{
+		SigningKeys: []SigningKey{{ID: "2024-02", Secret: "new-secret"}, {ID: "2024-01", Secret: "old-secret"}},
+		APIKeys:     []string{"api-key-1", "api-key-2"},
+		TokenTTL:    time.Minute,
+		Admins:      []AdminCredential{{Name: "alice", Role: RoleSupportAgent, Key: "admin-key-1"}},
+	}
+
+	t.Run("should fail without signing keys", func(t *testing.T) {
+		_, err := NewAuthService(AuthServiceConfig{APIKeys: []string{"api-key-1"}})
+		require.ErrorIs(t, err, ErrMissingSigningKeys)
+	})
+
+	t.Run("should fail without api keys", func(t *testing.T) {
+		_, err := NewAuthService(AuthServiceConfig{SigningKeys: config.SigningKeys})
+		require.ErrorIs(t, err, ErrMissingAPIKeys)
+	})
+
+	t.Run("should issue and verify a token", func(t *testing.T) {
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		token, err := authService.IssueToken("acme", "mary@continental.com")
+		require.NoError(t, err)
+
+		claims, err := authService.VerifyToken(token.Token)
+		require.NoError(t, err)
+		require.Equal(t, "mary@continental.com", claims.Subject)
+		require.Equal(t, "acme", claims.Tenant)
+	})
+
+	t.Run("should verify tokens signed with a rotated key", func(t *testing.T) {
+		oldService, err := NewAuthService(AuthServiceConfig{
+			SigningKeys: []SigningKey{{ID: "2024-01", Secret: "old-secret"}},
+			APIKeys:     config.APIKeys,
+		})
+		require.NoError(t, err)
+
+		token, err := oldService.IssueToken("default", "mary@continental.com")
+		require.NoError(t, err)
+
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		_, err = authService.VerifyToken(token.Token)
+		require.NoError(t, err)
+	})
+
+	t.Run("should reject tokens signed with a removed key", func(t *testing.T) {
+		oldService, err := NewAuthService(AuthServiceConfig{
+			SigningKeys: []SigningKey{{ID: "2023-12", Secret: "removed-secret"}},
+			APIKeys:     config.APIKeys,
+		})
+		require.NoError(t, err)
+
+		token, err := oldService.IssueToken("default", "mary@continental.com")
+		require.NoError(t, err)
+
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		_, err = authService.VerifyToken(token.Token)
+		require.ErrorIs(t, err, ErrUnknownSigningKey)
+	})
+
+	t.Run("should reject a tampered token", func(t *testing.T) {
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		token, err := authService.IssueToken("default", "mary@continental.com")
+		require.NoError(t, err)
+
+		forged, err := authService.IssueToken("default", "john@continental.com")
+		require.NoError(t, err)
+
+		parts := strings.Split(token.Token, ".")
+		forgedParts := strings.Split(forged.Token, ".")
+
+		_, err = authService.VerifyToken(strings.Join([]string{parts[0], forgedParts[1], parts[2]}, "."))
+		require.ErrorIs(t, err, ErrInvalidToken)
+
+		_, err = authService.VerifyToken("qwerty")
+		require.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("should reject an expired token", func(t *testing.T) {
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		token, err := authService.IssueToken("default", "mary@continental.com")
+		require.NoError(t, err)
+
+		authService.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		_, err = authService.VerifyToken(token.Token)
+		require.ErrorIs(t, err, ErrExpiredToken)
+	})
+
+	t.Run("should verify api keys", func(t *testing.T) {
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		require.NoError(t, authService.VerifyAPIKey("api-key-2"))
+		require.ErrorIs(t, authService.VerifyAPIKey("api-key-3"), ErrInvalidAPIKey)
+		require.ErrorIs(t, authService.VerifyAPIKey(""), ErrInvalidAPIKey)
+	})
+
+	t.Run("should fail with an invalid admin role", func(t *testing.T) {
+		_, err := NewAuthService(AuthServiceConfig{
+			SigningKeys: config.SigningKeys,
+			APIKeys:     config.APIKeys,
+			Admins:      []AdminCredential{{Name: "alice", Role: "root", Key: "admin-key-1"}},
+		})
+		require.ErrorIs(t, err, ErrInvalidRole)
+	})
+
+	t.Run("should authenticate admins", func(t *testing.T) {
+		authService, err := NewAuthService(config)
+		require.NoError(t, err)
+
+		admin, err := authService.AuthenticateAdmin("admin-key-1")
+		require.NoError(t, err)
+		require.Equal(t, "alice", admin.Name)
+		require.Equal(t, RoleSupportAgent, admin.Role)
+
+		_, err = authService.AuthenticateAdmin("api-key-1")
+		require.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("should parse admin credentials", func(t *testing.T) {
+		credentials := ParseAdminCredentials("alice:support_agent:admin-key-1, invalid")
+		require.Equal(t, config.Admins, credentials)
+	})
+
+	t.Run("should parse signing keys", func(t *testing.T) {
+		keys := ParseSigningKeys("2024-02:new-secret, 2024-01:old-secret,invalid")
+		require.Equal(t, config.SigningKeys, keys)
+	})
+}
@@ -0,0 +1,14 @@
+package auth
+
+import "errors"
+
+var (
+	ErrMissingSigningKeys     = errors.New("missing auth signing keys")
+	ErrMissingAPIKeys         = errors.New("missing auth api keys")
+	ErrInvalidToken           = errors.New("invalid token")
+	ErrExpiredToken           = errors.New("expired token")
+	ErrUnknownSigningKey      = errors.New("unknown token signing key")
+	ErrInvalidAPIKey          = errors.New("invalid api key")
+	ErrInvalidAdminCredential = errors.New("invalid admin credential")
+	ErrInvalidRole            = errors.New("invalid admin role")
+)
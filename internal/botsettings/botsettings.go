@@ -0,0 +1,149 @@
+package botsettings
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"gopkg.in/yaml.v3"
+)
+
+type chatbotService interface {
+	Settings() chatbot.Settings
+	ApplySettings(settings chatbot.Settings) error
+}
+
+type SettingsServiceConfig struct {
+	// File is the YAML file with the bot settings. The settings missing in the file keep
+	// their default value.
+	File string
+	// Interval is how often the file is checked for changes
+	Interval time.Duration
+}
+
+type SettingsService struct {
+	chatbot  chatbotService
+	config   SettingsServiceConfig
+	defaults chatbot.Settings
+	mutex    sync.Mutex
+	modTime  time.Time
+}
+
+// NewSettingsService create a new bot settings service. The current chatbot settings are
+// the defaults of the settings file.
+func NewSettingsService(chatbot chatbotService, config SettingsServiceConfig) *SettingsService {
+	if config.Interval <= 0 {
+		config.Interval = 10 * time.Second
+	}
+
+	return &SettingsService{
+		chatbot:  chatbot,
+		config:   config,
+		defaults: chatbot.Settings(),
+	}
+}
+
+// Enabled tells if a settings file is configured
+func (ss *SettingsService) Enabled() bool {
+	return ss.config.File != ""
+}
+
+// Current returns the settings of the new chat sessions
+func (ss *SettingsService) Current() chatbot.Settings {
+	return ss.chatbot.Settings()
+}
+
+// Reload reads the settings file and applies it to the new chat sessions. When the file
+// is invalid the current settings are kept.
+func (ss *SettingsService) Reload(ctx context.Context) (chatbot.Settings, error) {
+	baseError := "failed to reload the bot settings. Cause: %w"
+
+	if !ss.Enabled() {
+		return chatbot.Settings{}, fmt.Errorf(baseError, ErrNoSettingsFile)
+	}
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	info, err := os.Stat(ss.config.File)
+	if err != nil {
+		return chatbot.Settings{}, fmt.Errorf(baseError, err)
+	}
+	// an invalid file is only reported once by the watcher, until it changes again
+	ss.modTime = info.ModTime()
+
+	settings, err := ss.read()
+	if err != nil {
+		return chatbot.Settings{}, fmt.Errorf(baseError, err)
+	}
+
+	if err := ss.chatbot.ApplySettings(settings); err != nil {
+		return chatbot.Settings{}, fmt.Errorf(baseError, err)
+	}
+
+	golog.Log().Info(ctx, fmt.Sprintf("bot settings reloaded from %s", ss.config.File))
+	return settings, nil
+}
+
+// Start reloads the settings file when it changes until the context is done
+func (ss *SettingsService) Start(ctx context.Context) {
+	ticker := time.NewTicker(ss.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !ss.changed() {
+			continue
+		}
+
+		if _, err := ss.Reload(ctx); err != nil {
+			golog.Log().Error(ctx, err.Error())
+		}
+	}
+}
+
+// changed tells if the settings file was modified after the last reload
+func (ss *SettingsService) changed() bool {
+	info, err := os.Stat(ss.config.File)
+	if err != nil {
+		return false
+	}
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	return !info.ModTime().Equal(ss.modTime)
+}
+
+// read decodes the settings file over the defaults
+func (ss *SettingsService) read() (chatbot.Settings, error) {
+	data, err := os.ReadFile(ss.config.File)
+	if err != nil {
+		return chatbot.Settings{}, err
+	}
+
+	settings := ss.defaults
+	settings.SafetySettings = map[string]string{}
+	for category, threshold := range ss.defaults.SafetySettings {
+		settings.SafetySettings[category] = threshold
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&settings); err != nil && !errors.Is(err, io.EOF) {
+		return chatbot.Settings{}, fmt.Errorf("%w: %s", ErrInvalidSettingsFile, err)
+	}
+	return settings, nil
+}
@@ -0,0 +1,130 @@
+package botsettings
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chatbotServiceMock struct {
+	mutex    sync.Mutex
+	settings chatbot.Settings
+	applied  int
+}
+
+func (csm *chatbotServiceMock) Settings() chatbot.Settings {
+	csm.mutex.Lock()
+	defer csm.mutex.Unlock()
+	return csm.settings
+}
+
+func (csm *chatbotServiceMock) ApplySettings(settings chatbot.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	csm.mutex.Lock()
+	defer csm.mutex.Unlock()
+	csm.settings = settings
+	csm.applied++
+	return nil
+}
+
+func (csm *chatbotServiceMock) Applied() int {
+	csm.mutex.Lock()
+	defer csm.mutex.Unlock()
+	return csm.applied
+}
+
+// writeSettings writes the settings file and moves its modification time forward
+func writeSettings(t *testing.T, path string, content string, modTime time.Time) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestSettingsServiceReload(t *testing.T) {
+	t.Run("should apply the file over the default settings", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bot.yaml")
+		writeSettings(t, path, `
+system_instruction: You are Max, the support agent of AI Tech Shop
+temperature: 0.3
+safety_settings:
+  harassment: only_high
+`, time.Now())
+
+		bot := &chatbotServiceMock{settings: chatbot.DefaultSettings("You are a support agent")}
+		service := NewSettingsService(bot, SettingsServiceConfig{File: path})
+
+		settings, err := service.Reload(context.Background())
+		require.NoError(t, err)
+
+		expected := chatbot.DefaultSettings("You are Max, the support agent of AI Tech Shop")
+		expected.Temperature = 0.3
+		expected.SafetySettings["harassment"] = "only_high"
+		assert.Equal(t, expected, settings)
+		assert.Equal(t, expected, service.Current())
+
+		writeSettings(t, path, `model: gemini-1.5-flash-latest`, time.Now())
+		settings, err = service.Reload(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "You are a support agent", settings.SystemInstruction)
+		assert.Equal(t, "medium_and_above", settings.SafetySettings["harassment"])
+	})
+
+	t.Run("should keep the current settings when the file is invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bot.yaml")
+		bot := &chatbotServiceMock{settings: chatbot.DefaultSettings("You are a support agent")}
+		service := NewSettingsService(bot, SettingsServiceConfig{File: path})
+
+		writeSettings(t, path, `temprature: 0.3`, time.Now())
+		_, err := service.Reload(context.Background())
+		assert.ErrorIs(t, err, ErrInvalidSettingsFile)
+
+		writeSettings(t, path, `top_p: 3`, time.Now())
+		_, err = service.Reload(context.Background())
+		assert.ErrorContains(t, err, "top_p must be between 0 and 1")
+
+		assert.Equal(t, chatbot.DefaultSettings("You are a support agent"), service.Current())
+		assert.Zero(t, bot.Applied())
+	})
+
+	t.Run("should fail without a settings file", func(t *testing.T) {
+		service := NewSettingsService(&chatbotServiceMock{}, SettingsServiceConfig{})
+		_, err := service.Reload(context.Background())
+		assert.ErrorIs(t, err, ErrNoSettingsFile)
+	})
+}
+
+func TestSettingsServiceStart(t *testing.T) {
+	t.Run("should reload the file when it changes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bot.yaml")
+		modTime := time.Now().Add(-time.Hour)
+		writeSettings(t, path, `temperature: 0.5`, modTime)
+
+		bot := &chatbotServiceMock{settings: chatbot.DefaultSettings("You are a support agent")}
+		service := NewSettingsService(bot, SettingsServiceConfig{File: path, Interval: time.Millisecond})
+
+		_, err := service.Reload(context.Background())
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go service.Start(ctx)
+
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 1, bot.Applied())
+
+		writeSettings(t, path, `temperature: 0.7`, modTime.Add(time.Minute))
+		assert.Eventually(t, func() bool {
+			return service.Current().Temperature == 0.7
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, 2, bot.Applied())
+	})
+}
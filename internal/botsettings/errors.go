@@ -0,0 +1,8 @@
+package botsettings
+
+import "errors"
+
+var (
+	ErrNoSettingsFile      = errors.New("no bot settings file configured")
+	ErrInvalidSettingsFile = errors.New("invalid bot settings file")
+)
@@ -2,29 +2,177 @@ package chat
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/metrics"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
+	"github.com/gofrs/uuid/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	EventChatStarted     = "chat.started"
+	EventMessageCreated  = "message.created"
+	EventReviewCompleted = "review.completed"
+	EventChatEscalated   = "chat.escalated"
 )
 
 type repository interface {
-	CreateChat(ctx context.Context, user datatypes.User) (string, error)
-	CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error)
+	CreateChat(ctx context.Context, chatID string, user datatypes.User, deliveries []datatypes.WebhookDelivery) error
+	CreateMessage(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error
+	UpdateChatStatus(ctx context.Context, chatID string, status string, deliveries []datatypes.WebhookDelivery) error
+	UpdateChatProduct(ctx context.Context, chatID string, product string) error
+	UpdateChatReviewedAt(ctx context.Context, chatID string, reviewedAt time.Time, deliveries []datatypes.WebhookDelivery) error
+}
+
+// outbox builds the webhook deliveries of an event. The repository writes them in the transaction
+// of the chat change the event is about, so both are saved or none is.
+type outbox interface {
+	Deliveries(ctx context.Context, eventType string, data interface{}) ([]datatypes.WebhookDelivery, error)
+}
+
+type ChatStartedEvent struct {
+	ChatID string         `json:"chatId"`
+	User   datatypes.User `json:"user"`
+}
+
+type ReviewCompletedEvent struct {
+	ChatID  string         `json:"chatId"`
+	User    datatypes.User `json:"user"`
+	Product string         `json:"product"`
+}
+
+type ChatEscalatedEvent struct {
+	ChatID string `json:"chatId"`
+	Reason string `json:"reason"`
 }
 
 type ChatService struct {
 	repository repository
+	outbox     outbox
 }
 
 func NewChatService(
 	repository repository,
+	outbox outbox,
 ) *ChatService {
-	return &ChatService{repository}
+	return &ChatService{repository, outbox}
 }
 
 func (cs *ChatService) CreateChat(ctx context.Context, user datatypes.User) (string, error) {
-	return cs.repository.CreateChat(ctx, user)
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", fmt.Errorf("failed to create new chat. Cause: %w", err)
+	}
+
+	deliveries, err := cs.deliveries(ctx, EventChatStarted, ChatStartedEvent{ChatID: id.String(), User: user})
+	if err != nil {
+		return "", err
+	}
+
+	if err := cs.repository.CreateChat(ctx, id.String(), user, deliveries); err != nil {
+		return "", err
+	}
+	return id.String(), nil
 }
 
 func (cs *ChatService) CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error) {
-	return cs.repository.CreateMessage(ctx, chatID, author, message)
+	return cs.CreateRedactedMessage(ctx, chatID, author, message, "")
+}
+
+// CreateRedactedMessage creates a message with personal data replaced by tokens and the
+// encrypted original values, if they are kept
+func (cs *ChatService) CreateRedactedMessage(
+	ctx context.Context,
+	chatID string,
+	author string,
+	message string,
+	pii string,
+) (created datatypes.Message, err error) {
+	ctx, span := startMessageSpan(ctx, chatID, author)
+	defer func() { tracing.End(span, err) }()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.Message{}, fmt.Errorf("failed to create new message. Cause: %w", err)
+	}
+
+	createdAt := time.Now().UTC()
+	createdMessage := datatypes.Message{
+		ID:        id.String(),
+		ChatID:    chatID,
+		Author:    author,
+		Message:   message,
+		CreatedAt: &createdAt,
+	}
+
+	deliveries, err := cs.deliveries(ctx, EventMessageCreated, createdMessage)
+	if err != nil {
+		return datatypes.Message{}, err
+	}
+
+	if err := cs.repository.CreateMessage(ctx, createdMessage, pii, deliveries); err != nil {
+		return datatypes.Message{}, err
+	}
+
+	metrics.ObserveMessage(author)
+	return createdMessage, nil
+}
+
+// startMessageSpan starts the span of a new message. The content isn't recorded, it may have personal data.
+func startMessageSpan(ctx context.Context, chatID string, author string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "chat.CreateMessage",
+		attribute.String("chat.id", chatID),
+		attribute.String("chat.message.author", author),
+	)
+}
+
+// deliveries builds the outbox deliveries of the event, saved with the change it's about
+func (cs *ChatService) deliveries(ctx context.Context, eventType string, data interface{}) ([]datatypes.WebhookDelivery, error) {
+	deliveries, err := cs.outbox.Deliveries(ctx, eventType, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the %s event into the outbox. Cause: %w", eventType, err)
+	}
+	return deliveries, nil
+}
+
+// StartReview records the product reviewed in the chat
+func (cs *ChatService) StartReview(ctx context.Context, chatID string, product string) error {
+	if err := cs.repository.UpdateChatProduct(ctx, chatID, product); err != nil {
+		return err
+	}
+
+	metrics.ReviewStarted()
+	return nil
+}
+
+// CompleteReview notifies that the review started in the chat has finished
+func (cs *ChatService) CompleteReview(ctx context.Context, chatID string, user datatypes.User, product string) error {
+	deliveries, err := cs.deliveries(ctx, EventReviewCompleted, ReviewCompletedEvent{
+		ChatID:  chatID,
+		User:    user,
+		Product: product,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := cs.repository.UpdateChatReviewedAt(ctx, chatID, time.Now().UTC(), deliveries); err != nil {
+		return err
+	}
+
+	metrics.ReviewCompleted()
+	return nil
+}
+
+// EscalateChat hands the chat over to a human agent. The chatbot must not answer the chat anymore.
+func (cs *ChatService) EscalateChat(ctx context.Context, chatID string, reason string) error {
+	deliveries, err := cs.deliveries(ctx, EventChatEscalated, ChatEscalatedEvent{ChatID: chatID, Reason: reason})
+	if err != nil {
+		return err
+	}
+	return cs.repository.UpdateChatStatus(ctx, chatID, datatypes.ChatStatusAgent, deliveries)
 }
@@ -2,63 +2,264 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
 	"github.com/stretchr/testify/assert"
 )
 
 type repositoryMock struct {
-	Error                 error
-	CallbackCreateChat    func(ctx context.Context, user datatypes.User) (string, error)
-	CallbackCreateMessage func(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error)
+	Error                        error
+	CallbackCreateChat           func(ctx context.Context, chatID string, user datatypes.User, deliveries []datatypes.WebhookDelivery) error
+	CallbackCreateMessage        func(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error
+	CallbackUpdateChatStatus     func(ctx context.Context, chatID string, status string, deliveries []datatypes.WebhookDelivery) error
+	CallbackUpdateChatProduct    func(ctx context.Context, chatID string, product string) error
+	CallbackUpdateChatReviewedAt func(ctx context.Context, chatID string, reviewedAt time.Time, deliveries []datatypes.WebhookDelivery) error
 }
 
-func (rm *repositoryMock) CreateChat(ctx context.Context, user datatypes.User) (string, error) {
+func (rm *repositoryMock) UpdateChatProduct(ctx context.Context, chatID string, product string) error {
+	if rm.CallbackUpdateChatProduct != nil {
+		return rm.CallbackUpdateChatProduct(ctx, chatID, product)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) UpdateChatReviewedAt(ctx context.Context, chatID string, reviewedAt time.Time, deliveries []datatypes.WebhookDelivery) error {
+	if rm.CallbackUpdateChatReviewedAt != nil {
+		return rm.CallbackUpdateChatReviewedAt(ctx, chatID, reviewedAt, deliveries)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) CreateChat(ctx context.Context, chatID string, user datatypes.User, deliveries []datatypes.WebhookDelivery) error {
 	if rm.CallbackCreateChat != nil {
-		return rm.CallbackCreateChat(ctx, user)
+		return rm.CallbackCreateChat(ctx, chatID, user, deliveries)
 	}
-	return "", rm.Error
+	return rm.Error
 }
 
-func (rm *repositoryMock) CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error) {
+func (rm *repositoryMock) CreateMessage(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error {
 	if rm.CallbackCreateMessage != nil {
-		return rm.CallbackCreateMessage(ctx, chatID, author, message)
+		return rm.CallbackCreateMessage(ctx, message, pii, deliveries)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) UpdateChatStatus(ctx context.Context, chatID string, status string, deliveries []datatypes.WebhookDelivery) error {
+	if rm.CallbackUpdateChatStatus != nil {
+		return rm.CallbackUpdateChatStatus(ctx, chatID, status, deliveries)
+	}
+	return rm.Error
+}
+
+type outboxMock struct {
+	Error              error
+	CallbackDeliveries func(ctx context.Context, eventType string, data interface{}) ([]datatypes.WebhookDelivery, error)
+}
+
+func (om *outboxMock) Deliveries(ctx context.Context, eventType string, data interface{}) ([]datatypes.WebhookDelivery, error) {
+	if om.CallbackDeliveries != nil {
+		return om.CallbackDeliveries(ctx, eventType, data)
+	}
+	return []datatypes.WebhookDelivery{}, om.Error
+}
+
+// eventDeliveries returns a single delivery of the event, keeping the event data to be checked
+func eventDeliveries(data *interface{}) func(ctx context.Context, eventType string, eventData interface{}) ([]datatypes.WebhookDelivery, error) {
+	return func(ctx context.Context, eventType string, eventData interface{}) ([]datatypes.WebhookDelivery, error) {
+		*data = eventData
+		return []datatypes.WebhookDelivery{{EndpointID: "crm", EventType: eventType}}, nil
 	}
-	return datatypes.Message{}, rm.Error
 }
 
 func TestServiceCreateChat(t *testing.T) {
 	t.Run("should create a new chat", func(t *testing.T) {
-		chatID := "qwerty"
+		var savedID string
 		service := NewChatService(&repositoryMock{
-			CallbackCreateChat: func(ctx context.Context, user datatypes.User) (string, error) {
-				return chatID, nil
+			CallbackCreateChat: func(ctx context.Context, chatID string, user datatypes.User, deliveries []datatypes.WebhookDelivery) error {
+				savedID = chatID
+				return nil
 			},
-		})
+		}, &outboxMock{})
 
 		id, err := service.CreateChat(context.Background(), datatypes.User{})
 		assert.NoError(t, err)
-		assert.Equal(t, chatID, id)
+		assert.NotEmpty(t, id)
+		assert.Equal(t, savedID, id)
+	})
+
+	t.Run("should save the chat started event with the chat", func(t *testing.T) {
+		var (
+			event    interface{}
+			received []datatypes.WebhookDelivery
+		)
+
+		service := NewChatService(&repositoryMock{
+			CallbackCreateChat: func(ctx context.Context, chatID string, user datatypes.User, deliveries []datatypes.WebhookDelivery) error {
+				received = deliveries
+				return nil
+			},
+		}, &outboxMock{CallbackDeliveries: eventDeliveries(&event)})
+
+		id, err := service.CreateChat(context.Background(), datatypes.User{Email: "john.wick@continental.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, []datatypes.WebhookDelivery{{EndpointID: "crm", EventType: EventChatStarted}}, received)
+		assert.Equal(t, id, event.(ChatStartedEvent).ChatID)
+		assert.Equal(t, "john.wick@continental.com", event.(ChatStartedEvent).User.Email)
 	})
 }
 
 func TestServiceCreateMessage(t *testing.T) {
 	t.Run("should create a new message", func(t *testing.T) {
-		mockedMessage := datatypes.Message{
-			ID:      "qwerty",
-			ChatID:  "qwerty",
-			Message: "Hi",
-			Author:  "user",
-		}
+		var saved datatypes.Message
+		service := NewChatService(&repositoryMock{
+			CallbackCreateMessage: func(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error {
+				saved = message
+				return nil
+			},
+		}, &outboxMock{})
+
+		message, err := service.CreateMessage(context.Background(), "qwerty", "user", "Hi")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, message.ID)
+		assert.NotNil(t, message.CreatedAt)
+		assert.Equal(t, "qwerty", message.ChatID)
+		assert.Equal(t, "user", message.Author)
+		assert.Equal(t, "Hi", message.Message)
+		assert.EqualValues(t, saved, message)
+	})
+
+	t.Run("should create a new redacted message", func(t *testing.T) {
+		var savedPII string
 		service := NewChatService(&repositoryMock{
-			CallbackCreateMessage: func(ctx context.Context, chatID, author, message string) (datatypes.Message, error) {
-				return mockedMessage, nil
+			CallbackCreateMessage: func(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error {
+				savedPII = pii
+				return nil
 			},
-		})
+		}, &outboxMock{})
 
-		message, err := service.CreateMessage(context.Background(), "", "", "")
+		message, err := service.CreateRedactedMessage(context.Background(), "qwerty", "user", "call me at [PHONE_1]", "sealed")
 		assert.NoError(t, err)
-		assert.EqualValues(t, mockedMessage, message)
+		assert.Equal(t, "call me at [PHONE_1]", message.Message)
+		assert.Equal(t, "sealed", savedPII)
+	})
+}
+
+func TestServiceCreateMessageOutbox(t *testing.T) {
+	t.Run("should save the message created event with the message", func(t *testing.T) {
+		var (
+			event    interface{}
+			received []datatypes.WebhookDelivery
+		)
+
+		service := NewChatService(&repositoryMock{
+			CallbackCreateMessage: func(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error {
+				received = deliveries
+				return nil
+			},
+		}, &outboxMock{CallbackDeliveries: eventDeliveries(&event)})
+
+		message, err := service.CreateMessage(context.Background(), "qwerty", "user", "Hi")
+		assert.NoError(t, err)
+		assert.Equal(t, []datatypes.WebhookDelivery{{EndpointID: "crm", EventType: EventMessageCreated}}, received)
+		assert.Equal(t, message, event)
+	})
+
+	t.Run("should not save the message when the event can't be written into the outbox", func(t *testing.T) {
+		errDeliveries := errors.New("error to build the deliveries for tests")
+		service := NewChatService(&repositoryMock{
+			CallbackCreateMessage: func(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error {
+				t.Fatal("message must not be saved")
+				return nil
+			},
+		}, &outboxMock{Error: errDeliveries})
+
+		_, err := service.CreateMessage(context.Background(), "qwerty", "user", "Hi")
+		assert.ErrorIs(t, err, errDeliveries)
+	})
+
+	t.Run("should fail when the message and its events can't be saved", func(t *testing.T) {
+		errSave := errors.New("error to save the message for tests")
+		service := NewChatService(&repositoryMock{Error: errSave}, &outboxMock{})
+
+		_, err := service.CreateMessage(context.Background(), "qwerty", "user", "Hi")
+		assert.ErrorIs(t, err, errSave)
+
+		_, err = service.CreateChat(context.Background(), datatypes.User{})
+		assert.ErrorIs(t, err, errSave)
+	})
+}
+
+func TestServiceCompleteReview(t *testing.T) {
+	t.Run("should save the review completed event with the review", func(t *testing.T) {
+		var (
+			event    interface{}
+			received []datatypes.WebhookDelivery
+		)
+
+		service := NewChatService(&repositoryMock{
+			CallbackUpdateChatReviewedAt: func(ctx context.Context, chatID string, reviewedAt time.Time, deliveries []datatypes.WebhookDelivery) error {
+				received = deliveries
+				return nil
+			},
+		}, &outboxMock{CallbackDeliveries: eventDeliveries(&event)})
+
+		assert.NoError(t, service.CompleteReview(context.Background(), "qwerty", datatypes.User{}, "Galaxy S24"))
+		assert.Equal(t, []datatypes.WebhookDelivery{{EndpointID: "crm", EventType: EventReviewCompleted}}, received)
+		assert.Equal(t, ReviewCompletedEvent{ChatID: "qwerty", Product: "Galaxy S24"}, event)
+	})
+
+	t.Run("should fail when the review can't be saved", func(t *testing.T) {
+		service := NewChatService(&repositoryMock{Error: ErrChatNotFound}, &outboxMock{})
+
+		err := service.CompleteReview(context.Background(), "qwerty", datatypes.User{}, "Galaxy S24")
+		assert.ErrorIs(t, err, ErrChatNotFound)
+	})
+}
+
+func TestServiceStartReview(t *testing.T) {
+	t.Run("should save the reviewed product", func(t *testing.T) {
+		var product string
+		service := NewChatService(&repositoryMock{
+			CallbackUpdateChatProduct: func(ctx context.Context, chatID string, p string) error {
+				product = p
+				return nil
+			},
+		}, &outboxMock{})
+
+		assert.NoError(t, service.StartReview(context.Background(), "qwerty", "Galaxy S24"))
+		assert.Equal(t, "Galaxy S24", product)
+	})
+}
+
+func TestServiceEscalateChat(t *testing.T) {
+	t.Run("should move the chat to the agent state with the escalated event", func(t *testing.T) {
+		var (
+			status   string
+			event    interface{}
+			received []datatypes.WebhookDelivery
+		)
+
+		service := NewChatService(&repositoryMock{
+			CallbackUpdateChatStatus: func(ctx context.Context, chatID string, newStatus string, deliveries []datatypes.WebhookDelivery) error {
+				status = newStatus
+				received = deliveries
+				return nil
+			},
+		}, &outboxMock{CallbackDeliveries: eventDeliveries(&event)})
+
+		assert.NoError(t, service.EscalateChat(context.Background(), "qwerty", "defective product"))
+		assert.Equal(t, datatypes.ChatStatusAgent, status)
+		assert.Equal(t, []datatypes.WebhookDelivery{{EndpointID: "crm", EventType: EventChatEscalated}}, received)
+		assert.Equal(t, ChatEscalatedEvent{ChatID: "qwerty", Reason: "defective product"}, event)
+	})
+
+	t.Run("should fail when the status can't be updated", func(t *testing.T) {
+		service := NewChatService(&repositoryMock{Error: ErrChatNotFound}, &outboxMock{})
+
+		err := service.EscalateChat(context.Background(), "qwerty", "")
+		assert.ErrorIs(t, err, ErrChatNotFound)
 	})
 }
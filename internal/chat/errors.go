@@ -3,6 +3,8 @@ package chat
 import "errors"
 
 var (
-	ErrCantCreateChat    = errors.New("failed to create new chat. Cause: error saving chat")
-	ErrCantCreateMessage = errors.New("failed to create new message. Cause: error saving message")
+	ErrCantCreateChat     = errors.New("failed to create new chat. Cause: error saving chat")
+	ErrCantCreateMessage  = errors.New("failed to create new message. Cause: error saving message")
+	ErrChatNotFound       = errors.New("chat not found")
+	ErrCantCreateDelivery = errors.New("failed to create new webhook delivery. Cause: error saving delivery")
 )
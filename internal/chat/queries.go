@@ -1,10 +1,34 @@
 package chat
 
 var createChat = `
-	INSERT INTO chats (id, user_id) VALUES (:id, :user_id);
+	INSERT INTO chats (id, tenant_id, user_id, status, created_at) VALUES (:id, :tenant_id, :user_id, :status, :created_at);
 `
 
 var createMessage = `
-	INSERT INTO messages (id, chat_id, author, message)
-	VALUES (:id, :chat_id, :author, :message);
+	INSERT INTO messages (id, tenant_id, chat_id, author, message, pii, created_at)
+	VALUES (:id, :tenant_id, :chat_id, :author, :message, :pii, :created_at);
+`
+
+var updateChatProduct = `
+	UPDATE chats SET product = :product WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+var updateChatReviewedAt = `
+	UPDATE chats SET reviewed_at = :reviewed_at WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+var updateChatStatus = `
+	UPDATE chats SET status = :status WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+// the webhook deliveries are written with the chat change they're about, the webhook service sends them
+var createDelivery = `
+	INSERT INTO webhook_deliveries (
+		id, tenant_id, endpoint_id, event_id, event_type, payload, status, attempts,
+		response_status, last_error, next_attempt_at, created_at, updated_at
+	)
+	VALUES (
+		:id, :tenant_id, :endpoint_id, :event_id, :event_type, :payload, :status, :attempts,
+		:response_status, :last_error, :next_attempt_at, :created_at, :updated_at
+	);
 `
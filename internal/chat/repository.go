@@ -3,8 +3,10 @@ package chat
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
 	"github.com/gofrs/uuid/v5"
 
 	"github.com/JhonatanRSantos/gocore/pkg/godb"
@@ -22,77 +24,158 @@ func NewRepository(
 	}
 }
 
-func (r *Repository) CreateChat(ctx context.Context, user datatypes.User) (string, error) {
-	stm, err := r.db.PrepareNamedContext(ctx, createChat)
-	if err != nil {
-		return "", fmt.Errorf("failed to create new chat. Cause: %w", err)
+// CreateChat creates the chat and writes the webhook deliveries about it into the outbox
+func (r *Repository) CreateChat(ctx context.Context, chatID string, user datatypes.User, deliveries []datatypes.WebhookDelivery) error {
+	params := map[string]interface{}{
+		"id":         chatID,
+		"tenant_id":  tenant.IDFromContext(ctx),
+		"user_id":    user.ID,
+		"status":     datatypes.ChatStatusOpen,
+		"created_at": time.Now().UTC(),
 	}
-	defer stm.Close()
 
-	id, err := uuid.NewV4()
-	if err != nil {
-		return "", fmt.Errorf("failed to create new chat. Cause: %w", err)
+	if err := r.execWithDeliveries(ctx, createChat, params, deliveries, ErrCantCreateChat); err != nil {
+		return fmt.Errorf("failed to create new chat. Cause: %w", err)
+	}
+	return nil
+}
+
+// CreateMessage creates a message and writes the webhook deliveries about it into the outbox. The
+// message text may have personal data replaced by tokens, pii holds the encrypted original values
+// if they are kept.
+func (r *Repository) CreateMessage(ctx context.Context, message datatypes.Message, pii string, deliveries []datatypes.WebhookDelivery) error {
+	params := map[string]interface{}{
+		"id":         message.ID,
+		"tenant_id":  tenant.IDFromContext(ctx),
+		"chat_id":    message.ChatID,
+		"author":     message.Author,
+		"message":    message.Message,
+		"pii":        pii,
+		"created_at": message.CreatedAt,
+	}
+
+	if err := r.execWithDeliveries(ctx, createMessage, params, deliveries, ErrCantCreateMessage); err != nil {
+		return fmt.Errorf("failed to create new message. Cause: %w", err)
+	}
+	return nil
+}
+
+// UpdateChatProduct sets the product reviewed in the chat
+func (r *Repository) UpdateChatProduct(ctx context.Context, chatID string, product string) error {
+	params := map[string]interface{}{
+		"id":        chatID,
+		"tenant_id": tenant.IDFromContext(ctx),
+		"product":   product,
+	}
+
+	if err := r.execWithDeliveries(ctx, updateChatProduct, params, nil, ErrChatNotFound); err != nil {
+		return fmt.Errorf("failed to update chat product. Cause: %w", err)
+	}
+	return nil
+}
+
+// UpdateChatReviewedAt sets when the review of the chat was completed
+func (r *Repository) UpdateChatReviewedAt(ctx context.Context, chatID string, reviewedAt time.Time, deliveries []datatypes.WebhookDelivery) error {
+	params := map[string]interface{}{
+		"id":          chatID,
+		"tenant_id":   tenant.IDFromContext(ctx),
+		"reviewed_at": reviewedAt,
 	}
 
+	if err := r.execWithDeliveries(ctx, updateChatReviewedAt, params, deliveries, ErrChatNotFound); err != nil {
+		return fmt.Errorf("failed to update chat review. Cause: %w", err)
+	}
+	return nil
+}
+
+// UpdateChatStatus updates the chat status
+func (r *Repository) UpdateChatStatus(ctx context.Context, chatID string, status string, deliveries []datatypes.WebhookDelivery) error {
 	params := map[string]interface{}{
-		"id":      id.String(),
-		"user_id": user.ID,
+		"id":        chatID,
+		"tenant_id": tenant.IDFromContext(ctx),
+		"status":    status,
+	}
+
+	if err := r.execWithDeliveries(ctx, updateChatStatus, params, deliveries, ErrChatNotFound); err != nil {
+		return fmt.Errorf("failed to update chat status. Cause: %w", err)
 	}
+	return nil
+}
 
-	result, err := stm.ExecContext(ctx, params)
+// execWithDeliveries runs the query over a single row and writes the webhook deliveries in the same
+// transaction, so an event is only sent when the change it's about was saved. errNoRows is returned
+// when the query changed nothing.
+func (r *Repository) execWithDeliveries(
+	ctx context.Context,
+	query string,
+	params map[string]interface{},
+	deliveries []datatypes.WebhookDelivery,
+	errNoRows error,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create new chat. Cause: %w", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return err
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return "", fmt.Errorf("failed to create new chat. Cause: %w", err)
+		return err
 	}
 
 	if rows == 0 {
-		return "", ErrCantCreateChat
+		return errNoRows
 	}
 
-	return id.String(), nil
-}
-
-func (r *Repository) CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error) {
-	stm, err := r.db.PrepareNamedContext(ctx, createMessage)
-	if err != nil {
-		return datatypes.Message{}, fmt.Errorf("failed to create new message. Cause: %w", err)
+	for _, delivery := range deliveries {
+		if err := createDeliveryTx(ctx, tx, delivery); err != nil {
+			return err
+		}
 	}
-	defer stm.Close()
+	return tx.Commit()
+}
 
+// createDeliveryTx writes a pending webhook delivery into the outbox inside the transaction
+func createDeliveryTx(ctx context.Context, tx godb.Tx, delivery datatypes.WebhookDelivery) error {
 	id, err := uuid.NewV4()
 	if err != nil {
-		return datatypes.Message{}, fmt.Errorf("failed to create new message. Cause: %w", err)
+		return err
 	}
 
+	now := time.Now().UTC()
 	params := map[string]interface{}{
-		"id":      id.String(),
-		"chat_id": chatID,
-		"author":  author,
-		"message": message,
+		"id":              id.String(),
+		"tenant_id":       delivery.TenantID,
+		"endpoint_id":     delivery.EndpointID,
+		"event_id":        delivery.EventID,
+		"event_type":      delivery.EventType,
+		"payload":         delivery.Payload,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"created_at":      now,
+		"updated_at":      now,
 	}
 
-	result, err := stm.ExecContext(ctx, params)
+	result, err := tx.NamedExecContext(ctx, createDelivery, params)
 	if err != nil {
-		return datatypes.Message{}, fmt.Errorf("failed to create new message. Cause: %w", err)
+		return err
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return datatypes.Message{}, fmt.Errorf("failed to create new message. Cause: %w", err)
+		return err
 	}
 
 	if rows == 0 {
-		return datatypes.Message{}, ErrCantCreateMessage
+		return ErrCantCreateDelivery
 	}
-
-	return datatypes.Message{
-		ID:      chatID,
-		ChatID:  chatID,
-		Author:  author,
-		Message: message,
-	}, nil
+	return nil
 }
@@ -5,209 +5,160 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/godb"
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// txRecorder records the queries run in the transaction of the mocked database. Every query changes
+// rows, unless the query is in failing.
+type txRecorder struct {
+	rows      int64
+	failing   map[string]error
+	queries   []string
+	params    []map[string]interface{}
+	committed bool
+}
+
+func (tr *txRecorder) db() *godb.DBMock {
+	return &godb.DBMock{
+		CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+			return &godb.TxMock{
+				CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+					if err := tr.failing[query]; err != nil {
+						return nil, err
+					}
+
+					tr.queries = append(tr.queries, query)
+					tr.params = append(tr.params, arg.(map[string]interface{}))
+					return &godb.ResultMock{
+						CallbackRowsAffected: func() (int64, error) {
+							return tr.rows, nil
+						},
+					}, nil
+				},
+				CallbackCommit: func() error {
+					tr.committed = true
+					return nil
+				},
+				CallbackRollback: func() error {
+					return nil
+				},
+			}, nil
+		},
+	}
+}
+
 func TestRepositoryCreateChat(t *testing.T) {
-	t.Run("should create a new chat", func(t *testing.T) {
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return &godb.ResultMock{
-							CallbackRowsAffected: func() (int64, error) {
-								return 1, nil
-							},
-						}, nil
-					},
-				}, nil
-			},
-		})
+	t.Run("should create the chat and its deliveries in a transaction", func(t *testing.T) {
+		recorder := &txRecorder{rows: 1}
+		repository := NewRepository(recorder.db())
 
-		id, err := repository.CreateChat(context.Background(), datatypes.User{})
+		err := repository.CreateChat(context.Background(), "qwerty", datatypes.User{ID: "john"}, []datatypes.WebhookDelivery{
+			{TenantID: "default", EndpointID: "crm", EventType: EventChatStarted, Payload: "{}", Status: "pending"},
+		})
 		assert.NoError(t, err)
-		assert.True(t, len(id) > 0)
+		assert.Equal(t, []string{createChat, createDelivery}, recorder.queries)
+		assert.Equal(t, "qwerty", recorder.params[0]["id"])
+		assert.Equal(t, "john", recorder.params[0]["user_id"])
+		assert.Equal(t, "crm", recorder.params[1]["endpoint_id"])
+		assert.NotEmpty(t, recorder.params[1]["id"])
+		assert.True(t, recorder.committed)
 	})
 
-	t.Run("should fail when prapare named context", func(t *testing.T) {
-		errPrepareNamedContext := errors.New("error when preparing named context for tests")
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return nil, errPrepareNamedContext
-			},
-		})
+	t.Run("should fail to begin the transaction", func(t *testing.T) {
+		errBeginTx := errors.New("error to begin the transaction for tests")
+		repository := NewRepository(&godb.DBMock{Error: errBeginTx})
 
-		id, err := repository.CreateChat(context.Background(), datatypes.User{})
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, errPrepareNamedContext)
-		assert.Equal(t, "", id)
+		err := repository.CreateChat(context.Background(), "qwerty", datatypes.User{}, nil)
+		assert.ErrorIs(t, err, errBeginTx)
 	})
 
 	t.Run("should fail to run exec context", func(t *testing.T) {
 		errExecContext := errors.New("error to run exec context for tests")
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return nil, errExecContext
-					},
-				}, nil
-			},
-		})
+		recorder := &txRecorder{rows: 1, failing: map[string]error{createChat: errExecContext}}
+		repository := NewRepository(recorder.db())
 
-		id, err := repository.CreateChat(context.Background(), datatypes.User{})
-		assert.Error(t, err)
+		err := repository.CreateChat(context.Background(), "qwerty", datatypes.User{}, nil)
 		assert.ErrorIs(t, err, errExecContext)
-		assert.Equal(t, "", id)
-	})
-
-	t.Run("should fail when to run affected rows", func(t *testing.T) {
-		errRowsAffected := errors.New("error to run affected rows for tests")
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return &godb.ResultMock{
-							CallbackRowsAffected: func() (int64, error) {
-								return 0, errRowsAffected
-							},
-						}, nil
-					},
-				}, nil
-			},
-		})
-
-		id, err := repository.CreateChat(context.Background(), datatypes.User{})
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, errRowsAffected)
-		assert.Equal(t, "", id)
+		assert.False(t, recorder.committed)
 	})
 
-	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return &godb.ResultMock{
-							CallbackRowsAffected: func() (int64, error) {
-								return 0, nil
-							},
-						}, nil
-					},
-				}, nil
-			},
-		})
+	t.Run("should fail when there are no affected rows", func(t *testing.T) {
+		recorder := &txRecorder{rows: 0}
+		repository := NewRepository(recorder.db())
 
-		id, err := repository.CreateChat(context.Background(), datatypes.User{})
-		assert.Error(t, err)
+		err := repository.CreateChat(context.Background(), "qwerty", datatypes.User{}, []datatypes.WebhookDelivery{{EndpointID: "crm"}})
 		assert.ErrorIs(t, err, ErrCantCreateChat)
-		assert.Equal(t, "", id)
+		assert.Equal(t, []string{createChat}, recorder.queries)
+		assert.False(t, recorder.committed)
 	})
 }
 
 func TestRepositoryCreateMessage(t *testing.T) {
-	t.Run("should create a new message", func(t *testing.T) {
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return &godb.ResultMock{
-							CallbackRowsAffected: func() (int64, error) {
-								return 1, nil
-							},
-						}, nil
-					},
-				}, nil
-			},
-		})
-
-		mockedMessage := datatypes.Message{
-			ChatID:  "qwert",
-			Message: "test",
-			Author:  "user",
-		}
-		message, err := repository.CreateMessage(context.Background(), mockedMessage.ChatID, mockedMessage.Author, mockedMessage.Message)
-		assert.NoError(t, err)
-		assert.Equal(t, mockedMessage.ChatID, message.ChatID)
-		assert.Equal(t, mockedMessage.Author, message.Author)
-		assert.Equal(t, mockedMessage.Message, message.Message)
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	message := datatypes.Message{
+		ID:        "qwerty",
+		ChatID:    "chat-1",
+		Author:    "user",
+		Message:   "call me at [PHONE_1]",
+		CreatedAt: &createdAt,
+	}
+
+	t.Run("should save the message with the sealed pii", func(t *testing.T) {
+		recorder := &txRecorder{rows: 1}
+		repository := NewRepository(recorder.db())
+
+		assert.NoError(t, repository.CreateMessage(context.Background(), message, "sealed", nil))
+		assert.Equal(t, []string{createMessage}, recorder.queries)
+		assert.Equal(t, "call me at [PHONE_1]", recorder.params[0]["message"])
+		assert.Equal(t, "sealed", recorder.params[0]["pii"])
+		assert.True(t, recorder.committed)
 	})
 
-	t.Run("should fail when prapare named context", func(t *testing.T) {
-		errPrepareNamedContext := errors.New("error when preparing named context for tests")
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return nil, errPrepareNamedContext
-			},
-		})
+	t.Run("should not save the message when a delivery can't be written", func(t *testing.T) {
+		errDelivery := errors.New("error to write the delivery for tests")
+		recorder := &txRecorder{rows: 1, failing: map[string]error{createDelivery: errDelivery}}
+		repository := NewRepository(recorder.db())
 
-		message, err := repository.CreateMessage(context.Background(), "", "", "")
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, errPrepareNamedContext)
-		assert.Equal(t, datatypes.Message{}, message)
+		err := repository.CreateMessage(context.Background(), message, "", []datatypes.WebhookDelivery{{EndpointID: "crm"}})
+		assert.ErrorIs(t, err, errDelivery)
+		assert.False(t, recorder.committed)
 	})
 
-	t.Run("should fail to run exec context", func(t *testing.T) {
-		errExecContext := errors.New("error to run exec context for tests")
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return nil, errExecContext
-					},
-				}, nil
-			},
-		})
+	t.Run("should fail when there are no affected rows", func(t *testing.T) {
+		recorder := &txRecorder{rows: 0}
+		repository := NewRepository(recorder.db())
 
-		message, err := repository.CreateMessage(context.Background(), "", "", "")
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, errExecContext)
-		assert.Equal(t, datatypes.Message{}, message)
+		err := repository.CreateMessage(context.Background(), message, "", nil)
+		assert.ErrorIs(t, err, ErrCantCreateMessage)
+		assert.False(t, recorder.committed)
 	})
+}
 
-	t.Run("should fail when to run affected rows", func(t *testing.T) {
-		errRowsAffected := errors.New("error to run affected rows for tests")
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return &godb.ResultMock{
-							CallbackRowsAffected: func() (int64, error) {
-								return 0, errRowsAffected
-							},
-						}, nil
-					},
-				}, nil
-			},
-		})
+func TestRepositoryUpdateChatStatus(t *testing.T) {
+	t.Run("should update the status and write the deliveries in a transaction", func(t *testing.T) {
+		recorder := &txRecorder{rows: 1}
+		repository := NewRepository(recorder.db())
 
-		message, err := repository.CreateMessage(context.Background(), "", "", "")
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, errRowsAffected)
-		assert.Equal(t, datatypes.Message{}, message)
+		err := repository.UpdateChatStatus(context.Background(), "qwerty", datatypes.ChatStatusAgent, []datatypes.WebhookDelivery{{EndpointID: "crm"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{updateChatStatus, createDelivery}, recorder.queries)
+		assert.Equal(t, datatypes.ChatStatusAgent, recorder.params[0]["status"])
+		assert.True(t, recorder.committed)
 	})
+}
 
-	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
-		repository := NewRepository(&godb.DBMock{
-			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
-				return &godb.NamedStmtMock{
-					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
-						return &godb.ResultMock{
-							CallbackRowsAffected: func() (int64, error) {
-								return 0, nil
-							},
-						}, nil
-					},
-				}, nil
-			},
-		})
+func TestRepositoryUpdateChatProduct(t *testing.T) {
+	t.Run("should fail when the chat does not exist", func(t *testing.T) {
+		recorder := &txRecorder{rows: 0}
+		repository := NewRepository(recorder.db())
 
-		message, err := repository.CreateMessage(context.Background(), "", "", "")
+		err := repository.UpdateChatProduct(context.Background(), "qwerty", "Galaxy S24")
 		assert.Error(t, err)
-		assert.ErrorIs(t, err, ErrCantCreateMessage)
-		assert.Equal(t, datatypes.Message{}, message)
+		assert.ErrorIs(t, err, ErrChatNotFound)
 	})
 }
@@ -2,37 +2,131 @@ package chatbot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/metrics"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
 	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	// EscalationFunctionName is the tool the model calls to hand the chat over to a human agent
+	EscalationFunctionName = "request_human_agent"
+
+	// OperationChat labels the metrics of the chat messages sent to the model
+	OperationChat = "chat"
+
+	defaultMessageResponse    = "I'm sorry but can't help you right now. Can you please try later."
+	escalationMessageResponse = "I'm transferring you to one of our human agents. Please wait, they will join this chat soon."
+)
+
+// Reply is the chatbot answer to a message
+type Reply struct {
+	Text string
+	// Escalate is true when the model asked for a human agent
+	Escalate         bool
+	EscalationReason string
+}
+
 type ChatbotServiceSession struct {
 	session *genai.ChatSession
 }
 
 // SendTextMessage
 func (rcss *ChatbotServiceSession) SendTextMessage(ctx context.Context, message string) string {
-	resp, err := rcss.session.SendMessage(ctx, genai.Text(message))
+	return rcss.SendMessage(ctx, message).Text
+}
 
-	messageResponse := "I'm sorry but can't help you right now. Can you please try later."
+// SendMessage sends the message and tells if the model asked for a human agent
+func (rcss *ChatbotServiceSession) SendMessage(ctx context.Context, message string) Reply {
+	ctx, span := tracing.Start(ctx, "chatbot.SendMessage", attribute.String("gen_ai.operation", OperationChat))
+	start := time.Now()
+	resp, err := rcss.session.SendMessage(ctx, genai.Text(message))
+	ObserveResponse(OperationChat, start, resp, err)
+	tracing.End(span, err)
 
 	if err != nil {
 		golog.Log().Error(ctx, err.Error())
-		return messageResponse
+		return Reply{Text: defaultMessageResponse}
+	}
+	return parseResponse(resp)
+}
+
+// ObserveResponse records the latency, the error class and the tokens of a model request
+func ObserveResponse(operation string, start time.Time, resp *genai.GenerateContentResponse, err error) {
+	metrics.ObserveModelRequest(operation, time.Since(start), ErrorClass(err))
+
+	if resp != nil && resp.UsageMetadata != nil {
+		metrics.AddModelTokens(operation, int(resp.UsageMetadata.PromptTokenCount), int(resp.UsageMetadata.CandidatesTokenCount))
+	}
+}
+
+// ErrorClass groups the model errors in a few classes: timeout, canceled, blocked, rate_limited,
+// invalid_request, unauthorized, unavailable or other. It's empty when there is no error.
+func ErrorClass(err error) string {
+	var blockedError *genai.BlockedError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &blockedError):
+		return "blocked"
+	}
+
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return "timeout"
+	case codes.ResourceExhausted:
+		return "rate_limited"
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound:
+		return "invalid_request"
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return "unauthorized"
+	case codes.Unavailable, codes.Internal:
+		return "unavailable"
 	}
+	return "other"
+}
+
+// parseResponse reads the first candidate with content
+func parseResponse(resp *genai.GenerateContentResponse) Reply {
+	reply := Reply{Text: defaultMessageResponse}
 
 	for _, candidate := range resp.Candidates {
-		if len(candidate.Content.Parts) > 0 {
-			if value, ok := candidate.Content.Parts[0].(genai.Text); ok {
-				messageResponse = string(value)
+		if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+			continue
+		}
+
+		for _, part := range candidate.Content.Parts {
+			switch value := part.(type) {
+			case genai.Text:
+				if !reply.Escalate {
+					reply.Text = string(value)
+				}
+			case genai.FunctionCall:
+				if value.Name == EscalationFunctionName {
+					reply.Escalate = true
+					reply.Text = escalationMessageResponse
+					reply.EscalationReason, _ = value.Args["reason"].(string)
+				}
 			}
-			break
 		}
+		break
 	}
 
-	return messageResponse
+	return reply
 }
 
 type ChatbotServiceConfig struct {
@@ -49,8 +143,10 @@ func (rcc ChatbotServiceConfig) validate() error {
 }
 
 type ChatbotService struct {
-	model  *genai.GenerativeModel
-	client aiClient
+	mutex    sync.RWMutex
+	model    *genai.GenerativeModel
+	settings Settings
+	client   aiClient
 }
 
 // NewChatbotService create a new review chatbot
@@ -64,45 +160,20 @@ func NewChatbotService(
 		return &ChatbotService{}, fmt.Errorf(baseError, err)
 	}
 
-	model := config.AIClient.GenerativeModel("gemini-1.5-pro-latest")
-	model.GenerationConfig.SetTopK(0)
-	model.GenerationConfig.SetTopP(0.95)
-	model.GenerationConfig.SetMaxOutputTokens(8192)
-	model.GenerationConfig.SetTemperature(1)
-
-	model.SafetySettings = []*genai.SafetySetting{
-		{
-			Category:  genai.HarmCategoryHarassment,
-			Threshold: genai.HarmBlockMediumAndAbove,
-		},
-		{
-			Category:  genai.HarmCategoryHateSpeech,
-			Threshold: genai.HarmBlockMediumAndAbove,
-		},
-		{
-			Category:  genai.HarmCategorySexuallyExplicit,
-			Threshold: genai.HarmBlockMediumAndAbove,
-		},
-		{
-			Category:  genai.HarmCategoryDangerousContent,
-			Threshold: genai.HarmBlockMediumAndAbove,
-		},
-	}
-
-	model.SystemInstruction = &genai.Content{
-		Parts: []genai.Part{
-			genai.Text(config.InitInstruction),
-		},
-	}
+	settings := DefaultSettings(config.InitInstruction)
 
 	return &ChatbotService{
-		model:  model,
-		client: config.AIClient,
+		model:    newModel(config.AIClient, settings),
+		settings: settings,
+		client:   config.AIClient,
 	}, nil
 }
 
 // Close closes the client connection
 func (rc *ChatbotService) Close() error {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
 	if rc.model != nil && rc.client != nil {
 		if err := rc.client.Close(); err != nil {
 			return err
@@ -113,9 +184,88 @@ func (rc *ChatbotService) Close() error {
 	return nil
 }
 
-// StartChat starts a chat session.
-func (rc *ChatbotService) StartChat() *ChatbotServiceSession {
+// Settings returns the settings of the new chat sessions
+func (rc *ChatbotService) Settings() Settings {
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+	return rc.settings
+}
+
+// ApplySettings creates a model with the settings for the new chat sessions. The sessions
+// already started keep their model until they end.
+func (rc *ChatbotService) ApplySettings(settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("failed to apply the chatbot settings. Cause: %w", err)
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if rc.model == nil {
+		return ErrChatbotClosed
+	}
+
+	rc.model = newModel(rc.client, settings)
+	rc.settings = settings
+	return nil
+}
+
+// Ping counts the tokens of a short text, a cheap request to check the model is reachable
+// and the api key is valid
+func (rc *ChatbotService) Ping(ctx context.Context) error {
+	model := rc.currentModel()
+	if model == nil {
+		return ErrChatbotClosed
+	}
+
+	if _, err := model.CountTokens(ctx, genai.Text("ping")); err != nil {
+		return fmt.Errorf("failed to reach the model. Cause: %w", err)
+	}
+	return nil
+}
+
+// StartChat starts a chat session with the prompt and model of the tenant in the context.
+func (rc *ChatbotService) StartChat(ctx context.Context) *ChatbotServiceSession {
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+
+	chatTenant, _ := tenant.FromContext(ctx)
+	settings, err := rc.settings.ForTenant(chatTenant)
+	if err != nil {
+		golog.Log().Error(ctx, fmt.Sprintf("failed to use the settings of the tenant %s. Cause: %s", chatTenant.ID, err))
+		// the bot instruction is validated, only a tenant prompt can fail
+		chatTenant.Prompt = ""
+		settings, _ = rc.settings.ForTenant(chatTenant)
+	}
+
 	return &ChatbotServiceSession{
-		session: rc.model.StartChat(),
+		session: newModel(rc.client, settings).StartChat(),
 	}
 }
+
+// currentModel returns the model of the new chat sessions
+func (rc *ChatbotService) currentModel() *genai.GenerativeModel {
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+	return rc.model
+}
+
+// escalationTool lets the model hand the chat over to a human agent
+var escalationTool = &genai.Tool{
+	FunctionDeclarations: []*genai.FunctionDeclaration{
+		{
+			Name:        EscalationFunctionName,
+			Description: "Transfers the chat to a human support agent. Use it when the customer asks for a human or when you can't solve the complaint.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"reason": {
+						Type:        genai.TypeString,
+						Description: "Short summary of why the customer needs a human agent",
+					},
+				},
+				Required: []string{"reason"},
+			},
+		},
+	},
+}
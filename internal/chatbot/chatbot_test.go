@@ -3,11 +3,19 @@ package chatbot
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/fakegemini"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
 	"github.com/google/generative-ai-go/genai"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type aiClientMock struct {
@@ -114,3 +122,246 @@ func TestChatbotService(t *testing.T) {
 		assert.ErrorIs(t, err, errClose)
 	})
 }
+
+func TestChatbotParseResponse(t *testing.T) {
+	t.Run("should return the text answer", func(t *testing.T) {
+		reply := parseResponse(&genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{
+				{Content: &genai.Content{Parts: []genai.Part{genai.Text("Hello")}}},
+			},
+		})
+		assert.Equal(t, Reply{Text: "Hello"}, reply)
+	})
+
+	t.Run("should escalate when the model calls the escalation tool", func(t *testing.T) {
+		reply := parseResponse(&genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{
+				{
+					Content: &genai.Content{Parts: []genai.Part{
+						genai.FunctionCall{Name: EscalationFunctionName, Args: map[string]any{"reason": "defective product after 30 days"}},
+					}},
+				},
+			},
+		})
+		assert.True(t, reply.Escalate)
+		assert.Equal(t, "defective product after 30 days", reply.EscalationReason)
+		assert.Equal(t, escalationMessageResponse, reply.Text)
+	})
+
+	t.Run("should return the default answer when there is no content", func(t *testing.T) {
+		reply := parseResponse(&genai.GenerateContentResponse{})
+		assert.Equal(t, Reply{Text: defaultMessageResponse}, reply)
+	})
+}
+
+func TestChatbotErrorClass(t *testing.T) {
+	t.Run("should group the model errors", func(t *testing.T) {
+		assert.Equal(t, "", ErrorClass(nil))
+		assert.Equal(t, "timeout", ErrorClass(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
+		assert.Equal(t, "canceled", ErrorClass(context.Canceled))
+		assert.Equal(t, "blocked", ErrorClass(&genai.BlockedError{}))
+		assert.Equal(t, "rate_limited", ErrorClass(status.Error(codes.ResourceExhausted, "quota exceeded")))
+		assert.Equal(t, "invalid_request", ErrorClass(status.Error(codes.InvalidArgument, "bad request")))
+		assert.Equal(t, "unauthorized", ErrorClass(status.Error(codes.PermissionDenied, "invalid api key")))
+		assert.Equal(t, "unavailable", ErrorClass(status.Error(codes.Unavailable, "try again")))
+		assert.Equal(t, "other", ErrorClass(errors.New("unknown")))
+	})
+}
+
+func TestChatbotPing(t *testing.T) {
+	t.Run("should fail after the client was closed", func(t *testing.T) {
+		service := &ChatbotService{}
+		assert.ErrorIs(t, service.Ping(context.Background()), ErrChatbotClosed)
+	})
+}
+
+func TestChatbotApplySettings(t *testing.T) {
+	newService := func(t *testing.T, models *[]string) *ChatbotService {
+		service, err := NewChatbotService(context.Background(), ChatbotServiceConfig{
+			InitInstruction: "You are a support agent",
+			AIClient: &aiClientMock{
+				CallbackGenerativeModel: func(name string) *genai.GenerativeModel {
+					*models = append(*models, name)
+					return &genai.GenerativeModel{}
+				},
+			},
+		})
+		assert.NoError(t, err)
+		return service
+	}
+
+	t.Run("should use a new model for the new sessions", func(t *testing.T) {
+		models := []string{}
+		service := newService(t, &models)
+		oldModel := service.model
+
+		settings := DefaultSettings("You are a friendly support agent")
+		settings.Model = "gemini-1.5-flash-latest"
+		settings.Temperature = 0.4
+		settings.SafetySettings = map[string]string{"harassment": "only_high"}
+
+		assert.NoError(t, service.ApplySettings(settings))
+		assert.Equal(t, []string{"gemini-1.5-pro-latest", "gemini-1.5-flash-latest"}, models)
+		assert.Equal(t, settings, service.Settings())
+
+		assert.NotSame(t, oldModel, service.model)
+		assert.Equal(t, float32(1), *oldModel.GenerationConfig.Temperature)
+		assert.Equal(t, float32(0.4), *service.model.GenerationConfig.Temperature)
+		assert.Equal(t, genai.Text("You are a friendly support agent"), service.model.SystemInstruction.Parts[0])
+		assert.Equal(t, []*genai.SafetySetting{{
+			Category:  genai.HarmCategoryHarassment,
+			Threshold: genai.HarmBlockOnlyHigh,
+		}}, service.model.SafetySettings)
+	})
+
+	t.Run("should keep the current settings when the new ones are invalid", func(t *testing.T) {
+		models := []string{}
+		service := newService(t, &models)
+
+		settings := DefaultSettings("")
+		settings.TopP = 2
+		settings.SafetySettings = map[string]string{"violence": "none", "harassment": "sometimes"}
+
+		err := service.ApplySettings(settings)
+		assert.ErrorContains(t, err, "system_instruction is required")
+		assert.ErrorContains(t, err, "top_p must be between 0 and 1")
+		assert.ErrorContains(t, service.ApplySettings(DefaultSettings("{{.Name")), "system_instruction is not a valid template")
+		assert.ErrorContains(t, err, "safety_settings.violence is not a known harm category")
+		assert.ErrorContains(t, err, `safety_settings.harassment has the unknown threshold "sometimes"`)
+		assert.Equal(t, DefaultSettings("You are a support agent"), service.Settings())
+		assert.Len(t, models, 1)
+	})
+
+	t.Run("should fail after the client was closed", func(t *testing.T) {
+		service := &ChatbotService{}
+		assert.ErrorIs(t, service.ApplySettings(DefaultSettings("You are a support agent")), ErrChatbotClosed)
+	})
+}
+
+func TestChatbotStartChat(t *testing.T) {
+	newService := func(t *testing.T, models *[]*genai.GenerativeModel) *ChatbotService {
+		service, err := NewChatbotService(context.Background(), ChatbotServiceConfig{
+			InitInstruction: "You are the support agent of {{.Name}}, {{.Policies}}",
+			AIClient: &aiClientMock{
+				CallbackGenerativeModel: func(name string) *genai.GenerativeModel {
+					model := &genai.GenerativeModel{}
+					*models = append(*models, model)
+					return model
+				},
+			},
+		})
+		assert.NoError(t, err)
+		return service
+	}
+
+	t.Run("should fill the tenant fields in the bot instruction", func(t *testing.T) {
+		models := []*genai.GenerativeModel{}
+		service := newService(t, &models)
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "acme", Name: "Acme", Policies: "no returns"})
+		service.StartChat(ctx)
+
+		assert.Len(t, models, 2)
+		assert.Equal(t, genai.Text("You are the support agent of Acme, no returns"), models[1].SystemInstruction.Parts[0])
+		assert.Equal(t, float32(1), *models[1].GenerationConfig.Temperature)
+	})
+
+	t.Run("should use the tenant prompt and model configuration", func(t *testing.T) {
+		models := []*genai.GenerativeModel{}
+		service := newService(t, &models)
+
+		temperature := float32(0.2)
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{
+			ID:          "acme",
+			Name:        "Acme",
+			Prompt:      "You only talk about {{.Name}} rockets",
+			Temperature: &temperature,
+		})
+		service.StartChat(ctx)
+
+		assert.Equal(t, genai.Text("You only talk about Acme rockets"), models[1].SystemInstruction.Parts[0])
+		assert.Equal(t, float32(0.2), *models[1].GenerationConfig.Temperature)
+	})
+
+	t.Run("should use the bot instruction when the tenant prompt is invalid", func(t *testing.T) {
+		models := []*genai.GenerativeModel{}
+		service := newService(t, &models)
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "acme", Name: "Acme", Prompt: "{{.Unknown}}"})
+		service.StartChat(ctx)
+
+		assert.Equal(t, genai.Text("You are the support agent of Acme, "), models[1].SystemInstruction.Parts[0])
+	})
+}
+
+func TestChatbotSendMessage(t *testing.T) {
+	if !fakegemini.StreamsSupported() {
+		t.Skip("the genai client can't read the API streams with this Go toolchain, run with GOEXPERIMENT=nojsonv2")
+	}
+
+	newSession := func(t *testing.T) (*fakegemini.Server, *ChatbotServiceSession) {
+		server := fakegemini.NewServer()
+		t.Cleanup(server.Close)
+
+		client, err := server.NewClient(context.Background())
+		assert.NoError(t, err)
+
+		service, err := NewChatbotService(context.Background(), ChatbotServiceConfig{
+			InitInstruction: "You are the support agent of {{.Name}}",
+			AIClient:        client,
+		})
+		assert.NoError(t, err)
+		t.Cleanup(func() { service.Close() })
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "acme", Name: "Acme"})
+		return server, service.StartChat(ctx)
+	}
+
+	t.Run("should send the chat history with the tenant instruction", func(t *testing.T) {
+		server, session := newSession(t)
+		server.Script(fakegemini.Text("Hello! How do you like your Smart TV?"), fakegemini.Text("Great to hear that!"))
+
+		assert.Equal(t, "Hello! How do you like your Smart TV?", session.SendTextMessage(context.Background(), "Hi"))
+		assert.Equal(t, "Great to hear that!", session.SendTextMessage(context.Background(), "It works great"))
+
+		requests := server.Requests()
+		assert.Len(t, requests, 2)
+		assert.Equal(t, "You are the support agent of Acme", requests[1].SystemInstruction)
+		assert.Equal(t, []string{EscalationFunctionName}, requests[1].Tools)
+		assert.Equal(t, []fakegemini.Message{
+			{Role: "user", Text: "Hi"},
+			{Role: "model", Text: "Hello! How do you like your Smart TV?"},
+			{Role: "user", Text: "It works great"},
+		}, requests[1].Messages)
+	})
+
+	t.Run("should escalate when the model calls the escalation tool", func(t *testing.T) {
+		server, session := newSession(t)
+		server.Script(fakegemini.Call(EscalationFunctionName, map[string]any{"reason": "broken screen"}))
+
+		reply := session.SendMessage(context.Background(), "I want to talk to a human")
+		assert.Equal(t, Reply{Text: escalationMessageResponse, Escalate: true, EscalationReason: "broken screen"}, reply)
+	})
+
+	t.Run("should return the default answer when the model fails or blocks the message", func(t *testing.T) {
+		server, session := newSession(t)
+		server.Script(
+			fakegemini.Error(http.StatusTooManyRequests, "quota exceeded"),
+			fakegemini.BlockedPrompt("SAFETY"),
+			fakegemini.BlockedReply(),
+		)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, Reply{Text: defaultMessageResponse}, session.SendMessage(context.Background(), "Hi"))
+		}
+	})
+
+	t.Run("should return the default answer when the model is too slow", func(t *testing.T) {
+		server, session := newSession(t)
+		server.Script(fakegemini.Text("Too late").After(time.Minute))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.Equal(t, defaultMessageResponse, session.SendTextMessage(ctx, "Hi"))
+	})
+}
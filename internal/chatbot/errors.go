@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrMissingChatbotConfigs = errors.New("missing chatbot required configs")
+	ErrChatbotClosed         = errors.New("chatbot client is closed")
 )
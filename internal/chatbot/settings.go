@@ -0,0 +1,148 @@
+package chatbot
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+	"github.com/google/generative-ai-go/genai"
+)
+
+const defaultModel = "gemini-1.5-pro-latest"
+
+// harmCategories are the safety categories accepted in the settings
+var harmCategories = map[string]genai.HarmCategory{
+	"harassment":        genai.HarmCategoryHarassment,
+	"hate_speech":       genai.HarmCategoryHateSpeech,
+	"sexually_explicit": genai.HarmCategorySexuallyExplicit,
+	"dangerous_content": genai.HarmCategoryDangerousContent,
+}
+
+// harmThresholds are the block thresholds accepted in the settings
+var harmThresholds = map[string]genai.HarmBlockThreshold{
+	"low_and_above":    genai.HarmBlockLowAndAbove,
+	"medium_and_above": genai.HarmBlockMediumAndAbove,
+	"only_high":        genai.HarmBlockOnlyHigh,
+	"none":             genai.HarmBlockNone,
+}
+
+// Settings are the bot settings that can be changed without restarting the API
+type Settings struct {
+	SystemInstruction string  `yaml:"system_instruction" json:"systemInstruction"`
+	Model             string  `yaml:"model"              json:"model"`
+	Temperature       float32 `yaml:"temperature"        json:"temperature"`
+	TopP              float32 `yaml:"top_p"              json:"topP"`
+	TopK              int32   `yaml:"top_k"              json:"topK"`
+	MaxOutputTokens   int32   `yaml:"max_output_tokens"  json:"maxOutputTokens"`
+	// SafetySettings maps each harm category (harassment, hate_speech, sexually_explicit and
+	// dangerous_content) to its block threshold (low_and_above, medium_and_above, only_high or none)
+	SafetySettings map[string]string `yaml:"safety_settings" json:"safetySettings"`
+}
+
+// DefaultSettings returns the settings used when the chatbot starts
+func DefaultSettings(systemInstruction string) Settings {
+	return Settings{
+		SystemInstruction: systemInstruction,
+		Model:             defaultModel,
+		Temperature:       1,
+		TopP:              0.95,
+		TopK:              0,
+		MaxOutputTokens:   8192,
+		SafetySettings: map[string]string{
+			"harassment":        "medium_and_above",
+			"hate_speech":       "medium_and_above",
+			"sexually_explicit": "medium_and_above",
+			"dangerous_content": "medium_and_above",
+		},
+	}
+}
+
+// Validate lists every invalid setting
+func (s Settings) Validate() error {
+	errs := validation.Errors{}
+
+	if s.SystemInstruction == "" {
+		errs.Add("system_instruction", validation.MsgRequired)
+	} else if _, err := (datatypes.Tenant{}).Render(s.SystemInstruction); err != nil {
+		errs.Add("system_instruction", "is not a valid template")
+	}
+	if s.Model == "" {
+		errs.Add("model", validation.MsgRequired)
+	}
+	if s.Temperature < 0 || s.Temperature > 2 {
+		errs.Add("temperature", "must be between 0 and 2")
+	}
+	if s.TopP < 0 || s.TopP > 1 {
+		errs.Add("top_p", "must be between 0 and 1")
+	}
+	if s.TopK < 0 {
+		errs.Add("top_k", "must not be negative")
+	}
+	if s.MaxOutputTokens < 1 {
+		errs.Add("max_output_tokens", "must be positive")
+	}
+
+	for category, threshold := range s.SafetySettings {
+		if _, ok := harmCategories[category]; !ok {
+			errs.Add("safety_settings."+category, "is not a known harm category")
+		} else if _, ok := harmThresholds[threshold]; !ok {
+			errs.Add("safety_settings."+category, fmt.Sprintf("has the unknown threshold %q", threshold))
+		}
+	}
+	return errs.Err()
+}
+
+// ForTenant returns the settings of the tenant chats. The tenant prompt, model and temperature
+// replace the bot ones and the tenant fields are filled in the system instruction.
+func (s Settings) ForTenant(tenant datatypes.Tenant) (Settings, error) {
+	if tenant.Prompt != "" {
+		s.SystemInstruction = tenant.Prompt
+	}
+	if tenant.Model != "" {
+		s.Model = tenant.Model
+	}
+	if tenant.Temperature != nil {
+		s.Temperature = *tenant.Temperature
+	}
+
+	instruction, err := tenant.Render(s.SystemInstruction)
+	if err != nil {
+		return Settings{}, fmt.Errorf("failed to render the system instruction. Cause: %w", err)
+	}
+	s.SystemInstruction = instruction
+	return s, nil
+}
+
+// newModel creates a model with the settings. The models are never changed after they are
+// created because the chat sessions started with them keep a reference.
+func newModel(client aiClient, settings Settings) *genai.GenerativeModel {
+	model := client.GenerativeModel(settings.Model)
+	model.GenerationConfig.SetTopK(settings.TopK)
+	model.GenerationConfig.SetTopP(settings.TopP)
+	model.GenerationConfig.SetMaxOutputTokens(settings.MaxOutputTokens)
+	model.GenerationConfig.SetTemperature(settings.Temperature)
+
+	categories := make([]string, 0, len(settings.SafetySettings))
+	for category := range settings.SafetySettings {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	model.SafetySettings = make([]*genai.SafetySetting, 0, len(categories))
+	for _, category := range categories {
+		model.SafetySettings = append(model.SafetySettings, &genai.SafetySetting{
+			Category:  harmCategories[category],
+			Threshold: harmThresholds[settings.SafetySettings[category]],
+		})
+	}
+
+	model.Tools = []*genai.Tool{escalationTool}
+
+	model.SystemInstruction = &genai.Content{
+		Parts: []genai.Part{
+			genai.Text(settings.SystemInstruction),
+		},
+	}
+	return model
+}
@@ -0,0 +1,173 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/redaction"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tracing"
+)
+
+type chatService interface {
+	CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error)
+	CreateRedactedMessage(ctx context.Context, chatID string, author string, message string, pii string) (datatypes.Message, error)
+	StartReview(ctx context.Context, chatID string, product string) error
+}
+
+type chatbotSession interface {
+	SendMessage(ctx context.Context, message string) chatbot.Reply
+}
+
+type messageAnalyzer interface {
+	AnalyzeMessage(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error)
+}
+
+type piiRedactor interface {
+	NewSession() *redaction.Session
+	StoredMessage(original string, redacted redaction.Redacted) (string, string, error)
+}
+
+// Conversation is a review chat between a customer and the chatbot. The personal data is
+// replaced by the same tokens during the whole chat.
+type Conversation struct {
+	ChatID    string
+	chatbot   chatbotSession
+	redaction *redaction.Session
+}
+
+// ConversationService runs the turns of the review chats the same way for the web chat and the
+// terminal: the messages are redacted before the chatbot and the analysis, and saved as configured.
+type ConversationService struct {
+	chatService chatService
+	analyzer    messageAnalyzer
+	redactor    piiRedactor
+	analyses    *sync.WaitGroup
+}
+
+// NewConversationService create a new conversation service
+func NewConversationService(chatService chatService, analyzer messageAnalyzer, redactor piiRedactor) *ConversationService {
+	return &ConversationService{
+		chatService: chatService,
+		analyzer:    analyzer,
+		redactor:    redactor,
+		analyses:    &sync.WaitGroup{},
+	}
+}
+
+// NewConversation starts the conversation of the chat with the chatbot session
+func (cs *ConversationService) NewConversation(chatID string, chatbot chatbotSession) *Conversation {
+	return &Conversation{
+		ChatID:    chatID,
+		chatbot:   chatbot,
+		redaction: cs.redactor.NewSession(),
+	}
+}
+
+// OpeningPrompt is the instruction that makes the chatbot greet the customer and ask about the product
+func OpeningPrompt(name string, product string) string {
+	return fmt.Sprintf("Start a new review with %s, who just bought a new %s", name, product)
+}
+
+// StartReview asks the chatbot to open the review of the product, saves its greeting and records
+// the product reviewed in the chat. It returns the greeting to be sent to the customer.
+func (cs *ConversationService) StartReview(ctx context.Context, conversation *Conversation, name string, product string) (string, error) {
+	greeting := conversation.chatbot.SendMessage(ctx, OpeningPrompt(name, product)).Text
+
+	if _, err := cs.chatService.CreateMessage(ctx, conversation.ChatID, datatypes.AuthorChatbot, greeting); err != nil {
+		return "", err
+	}
+
+	if err := cs.chatService.StartReview(ctx, conversation.ChatID, product); err != nil {
+		return "", err
+	}
+	return greeting, nil
+}
+
+// CustomerMessage redacts and saves the customer message, then analyzes it in background so the
+// chatbot answer isn't delayed. It returns the redacted message, the only one the chatbot may see.
+func (cs *ConversationService) CustomerMessage(ctx context.Context, conversation *Conversation, message string) (redaction.Redacted, error) {
+	redacted := conversation.redaction.Redact(message)
+
+	created, err := cs.save(ctx, conversation.ChatID, datatypes.AuthorUser, message, redacted)
+	if err != nil {
+		return redaction.Redacted{}, err
+	}
+	created.Message = redacted.Text
+
+	analysisCtx := tracing.Detach(ctx)
+	if found, ok := tenant.FromContext(ctx); ok {
+		analysisCtx = tenant.WithTenant(analysisCtx, found)
+	}
+
+	cs.analyses.Add(1)
+	go cs.analyze(analysisCtx, created)
+	return redacted, nil
+}
+
+// Reply sends the redacted customer message to the chatbot and saves its answer. The reply text
+// has the personal data of the customer restored.
+func (cs *ConversationService) Reply(ctx context.Context, conversation *Conversation, redacted redaction.Redacted) (chatbot.Reply, error) {
+	reply := conversation.chatbot.SendMessage(ctx, redacted.Text)
+	tokenized := conversation.redaction.Tokenized(reply.Text)
+	reply.Text = conversation.redaction.Restore(reply.Text)
+
+	if _, err := cs.save(ctx, conversation.ChatID, datatypes.AuthorChatbot, reply.Text, tokenized); err != nil {
+		return chatbot.Reply{}, err
+	}
+	return reply, nil
+}
+
+// Turn saves the customer message and the chatbot answer to it
+func (cs *ConversationService) Turn(ctx context.Context, conversation *Conversation, message string) (chatbot.Reply, error) {
+	redacted, err := cs.CustomerMessage(ctx, conversation, message)
+	if err != nil {
+		return chatbot.Reply{}, err
+	}
+	return cs.Reply(ctx, conversation, redacted)
+}
+
+// Wait waits for the analyses started or the context, whatever comes first
+func (cs *ConversationService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		cs.analyses.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// save saves the message as configured: the original text, the redacted one or the redacted one
+// with the original values encrypted
+func (cs *ConversationService) save(
+	ctx context.Context,
+	chatID string,
+	author string,
+	original string,
+	redacted redaction.Redacted,
+) (datatypes.Message, error) {
+	message, pii, err := cs.redactor.StoredMessage(original, redacted)
+	if err != nil {
+		return datatypes.Message{}, err
+	}
+	return cs.chatService.CreateRedactedMessage(ctx, chatID, author, message, pii)
+}
+
+// analyze classifies the customer message, counted in the analyses so they can be waited for
+func (cs *ConversationService) analyze(ctx context.Context, message datatypes.Message) {
+	defer cs.analyses.Done()
+
+	if _, err := cs.analyzer.AnalyzeMessage(ctx, message); err != nil {
+		golog.Log().Error(ctx, err.Error())
+	}
+}
@@ -0,0 +1,202 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/chatbot"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/redaction"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRedactor redacts with the built-in detectors and stores the original messages
+var testRedactor, _ = redaction.NewRedactionService(redaction.RedactionServiceConfig{
+	Detectors: redaction.DefaultDetectors(),
+})
+
+type savedMessage struct {
+	author  string
+	message string
+}
+
+type chatServiceMock struct {
+	Error               error
+	Messages            []savedMessage
+	CallbackStartReview func(ctx context.Context, chatID string, product string) error
+}
+
+func (csm *chatServiceMock) CreateMessage(ctx context.Context, chatID string, author string, message string) (datatypes.Message, error) {
+	return csm.CreateRedactedMessage(ctx, chatID, author, message, "")
+}
+
+func (csm *chatServiceMock) CreateRedactedMessage(ctx context.Context, chatID string, author string, message string, pii string) (datatypes.Message, error) {
+	if csm.Error != nil {
+		return datatypes.Message{}, csm.Error
+	}
+	csm.Messages = append(csm.Messages, savedMessage{author: author, message: message})
+	return datatypes.Message{ID: "message-1", ChatID: chatID, Author: author, Message: message}, nil
+}
+
+func (csm *chatServiceMock) StartReview(ctx context.Context, chatID string, product string) error {
+	if csm.CallbackStartReview != nil {
+		return csm.CallbackStartReview(ctx, chatID, product)
+	}
+	return nil
+}
+
+type chatbotSessionMock struct {
+	Received []string
+	Replies  []chatbot.Reply
+}
+
+func (csm *chatbotSessionMock) SendMessage(ctx context.Context, message string) chatbot.Reply {
+	csm.Received = append(csm.Received, message)
+	reply := csm.Replies[0]
+	csm.Replies = csm.Replies[1:]
+	return reply
+}
+
+type messageAnalyzerMock struct {
+	CallbackAnalyzeMessage func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error)
+}
+
+func (mam *messageAnalyzerMock) AnalyzeMessage(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+	if mam.CallbackAnalyzeMessage != nil {
+		return mam.CallbackAnalyzeMessage(ctx, message)
+	}
+	return datatypes.MessageAnalysis{}, nil
+}
+
+func TestConversationServiceStartReview(t *testing.T) {
+	t.Run("should save the greeting and record the product", func(t *testing.T) {
+		var reviewed string
+		chatService := &chatServiceMock{
+			CallbackStartReview: func(ctx context.Context, chatID string, product string) error {
+				reviewed = product
+				return nil
+			},
+		}
+		bot := &chatbotSessionMock{Replies: []chatbot.Reply{{Text: "Hi Mary! How do you like your new phone?"}}}
+		service := NewConversationService(chatService, &messageAnalyzerMock{}, testRedactor)
+
+		greeting, err := service.StartReview(context.Background(), service.NewConversation("qwerty", bot), "Mary", "phone")
+		require.NoError(t, err)
+		assert.Equal(t, "Hi Mary! How do you like your new phone?", greeting)
+		assert.Equal(t, []string{"Start a new review with Mary, who just bought a new phone"}, bot.Received)
+		assert.Equal(t, []savedMessage{{author: datatypes.AuthorChatbot, message: greeting}}, chatService.Messages)
+		assert.Equal(t, "phone", reviewed)
+	})
+
+	t.Run("should not record the product when the greeting isn't saved", func(t *testing.T) {
+		started := false
+		chatService := &chatServiceMock{
+			Error: errors.New("database is down"),
+			CallbackStartReview: func(ctx context.Context, chatID string, product string) error {
+				started = true
+				return nil
+			},
+		}
+		bot := &chatbotSessionMock{Replies: []chatbot.Reply{{Text: "Hi Mary!"}}}
+		service := NewConversationService(chatService, &messageAnalyzerMock{}, testRedactor)
+
+		_, err := service.StartReview(context.Background(), service.NewConversation("qwerty", bot), "Mary", "phone")
+		assert.Error(t, err)
+		assert.False(t, started)
+	})
+}
+
+func TestConversationServiceTurn(t *testing.T) {
+	t.Run("should only send the redacted message to the chatbot and the analysis", func(t *testing.T) {
+		analyzed := make(chan datatypes.Message, 1)
+		analyzedTenant := make(chan string, 1)
+		chatService := &chatServiceMock{}
+		bot := &chatbotSessionMock{Replies: []chatbot.Reply{{Text: "We'll call you at [PHONE_1]"}}}
+		service := NewConversationService(chatService, &messageAnalyzerMock{
+			CallbackAnalyzeMessage: func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+				analyzedTenant <- tenant.IDFromContext(ctx)
+				analyzed <- message
+				return datatypes.MessageAnalysis{}, nil
+			},
+		}, testRedactor)
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "acme"})
+		reply, err := service.Turn(ctx, service.NewConversation("qwerty", bot), "call me at (212) 555-0199")
+		require.NoError(t, err)
+
+		assert.Equal(t, "We'll call you at (212) 555-0199", reply.Text)
+		assert.Equal(t, []string{"call me at [PHONE_1]"}, bot.Received)
+		assert.Equal(t, []savedMessage{
+			{author: datatypes.AuthorUser, message: "call me at (212) 555-0199"},
+			{author: datatypes.AuthorChatbot, message: "We'll call you at (212) 555-0199"},
+		}, chatService.Messages)
+
+		select {
+		case message := <-analyzed:
+			assert.Equal(t, "call me at [PHONE_1]", message.Message)
+			assert.Equal(t, "acme", <-analyzedTenant)
+		case <-time.After(time.Second):
+			t.Fatal("message was not analyzed")
+		}
+	})
+
+	t.Run("should keep the escalation of the chatbot", func(t *testing.T) {
+		bot := &chatbotSessionMock{Replies: []chatbot.Reply{{Text: "A human will help you", Escalate: true, EscalationReason: "broken screen"}}}
+		service := NewConversationService(&chatServiceMock{}, &messageAnalyzerMock{}, testRedactor)
+
+		reply, err := service.Turn(context.Background(), service.NewConversation("qwerty", bot), "the screen is broken")
+		require.NoError(t, err)
+		assert.True(t, reply.Escalate)
+		assert.Equal(t, "broken screen", reply.EscalationReason)
+	})
+
+	t.Run("should not call the chatbot when the message isn't saved", func(t *testing.T) {
+		bot := &chatbotSessionMock{}
+		service := NewConversationService(&chatServiceMock{Error: errors.New("database is down")}, &messageAnalyzerMock{}, testRedactor)
+
+		_, err := service.Turn(context.Background(), service.NewConversation("qwerty", bot), "hello")
+		assert.Error(t, err)
+		assert.Empty(t, bot.Received)
+	})
+}
+
+func TestConversationServiceWait(t *testing.T) {
+	t.Run("should wait for the analyses", func(t *testing.T) {
+		analyzed := false
+		service := NewConversationService(&chatServiceMock{}, &messageAnalyzerMock{
+			CallbackAnalyzeMessage: func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+				time.Sleep(20 * time.Millisecond)
+				analyzed = true
+				return datatypes.MessageAnalysis{}, nil
+			},
+		}, testRedactor)
+
+		_, err := service.CustomerMessage(context.Background(), service.NewConversation("qwerty", nil), "hello")
+		require.NoError(t, err)
+
+		require.NoError(t, service.Wait(context.Background()))
+		assert.True(t, analyzed)
+	})
+
+	t.Run("should give up when the context is done", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		service := NewConversationService(&chatServiceMock{}, &messageAnalyzerMock{
+			CallbackAnalyzeMessage: func(ctx context.Context, message datatypes.Message) (datatypes.MessageAnalysis, error) {
+				<-release
+				return datatypes.MessageAnalysis{}, nil
+			},
+		}, testRedactor)
+
+		_, err := service.CustomerMessage(context.Background(), service.NewConversation("qwerty", nil), "hello")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, service.Wait(ctx), context.DeadlineExceeded)
+	})
+}
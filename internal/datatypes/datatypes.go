@@ -3,6 +3,23 @@ package datatypes
 import (
 	"errors"
 	"strings"
+	"text/template"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	ErrInvalidDate      = errors.New("invalid date, use YYYY-MM-DD or RFC 3339")
+	ErrInvalidDateRange = errors.New("invalid date range, from must be before to")
+)
+
+var (
+	// minDate and maxDate bound a date range without from or to
+	minDate = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDate = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
 )
 
 type User struct {
@@ -10,54 +27,474 @@ type User struct {
 	FirstName string `db:"first_name" json:"firstName"`
 	LastName  string `db:"last_name"  json:"lastName"`
 	Email     string `db:"email"      json:"email"`
+	// Version is incremented on every update. Updates must send the version they read.
+	Version   int        `db:"version"    json:"version,omitempty"`
+	CreatedAt *time.Time `db:"created_at" json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt,omitempty"`
+	// DeletedAt is set when the user is deactivated. The row is kept so the chats are still attributable.
+	DeletedAt *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
 }
 
+const (
+	ChatStatusOpen   = "open"
+	ChatStatusAgent  = "agent"
+	ChatStatusClosed = "closed"
+)
+
+const (
+	AuthorUser    = "user"
+	AuthorChatbot = "chatbot"
+	AuthorAgent   = "agent"
+)
+
 type Chat struct {
-	ID     string `db:"id" json:"-"`
-	UserID string `db:"id" json:"-"`
+	ID        string     `db:"id"         json:"id"`
+	UserID    string     `db:"user_id"    json:"userId"`
+	UserEmail string     `db:"user_email" json:"userEmail,omitempty"`
+	Product   string     `db:"product"    json:"product,omitempty"`
+	Status    string     `db:"status"     json:"status"`
+	CreatedAt *time.Time `db:"created_at" json:"createdAt,omitempty"`
+	ClosedAt  *time.Time `db:"closed_at"  json:"closedAt,omitempty"`
 }
 
 type Message struct {
-	ID      string `db:"id"      json:"id"`
-	ChatID  string `db:"id"      json:"chatId"`
-	Message string `db:"message" json:"message"`
-	Author  string `db:"author"  json:"author"`
+	ID        string     `db:"id"         json:"id"`
+	ChatID    string     `db:"chat_id"    json:"chatId"`
+	Message   string     `db:"message"    json:"message"`
+	Author    string     `db:"author"     json:"author"`
+	CreatedAt *time.Time `db:"created_at" json:"createdAt,omitempty"`
 }
 
 type CreateUserRequest struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
+	FirstName string `json:"firstName" validate:"required,max=100,charset=name"`
+	LastName  string `json:"lastName"  validate:"required,max=100,charset=name"`
+	Email     string `json:"email"     validate:"required,email,blocklist"`
 }
 
 func (cur *CreateUserRequest) Validate() error {
-	cur.FirstName = strings.ReplaceAll(cur.FirstName, " ", "")
-	cur.LastName = strings.ReplaceAll(cur.LastName, " ", "")
-	cur.Email = strings.ReplaceAll(cur.Email, " ", "")
+	return validation.Struct(cur)
+}
 
-	if cur.FirstName == "" || cur.LastName == "" || cur.Email == "" {
-		return errors.New("missing required fields")
-	}
-	return nil
+type UpdateUserRequest struct {
+	FirstName string `json:"firstName" validate:"required,max=100,charset=name"`
+	LastName  string `json:"lastName"  validate:"required,max=100,charset=name"`
+	Email     string `json:"email"     validate:"required,email,blocklist"`
+	Version   int    `json:"version"   validate:"required,min=1"`
+}
+
+func (uur *UpdateUserRequest) Validate() error {
+	return validation.Struct(uur)
 }
 
 type CreateReviewUser struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name  string `json:"name"  validate:"required,max=200,charset=name"`
+	Email string `json:"email" validate:"required,email,blocklist"`
 }
 
 type CreateReviewRequest struct {
 	User    CreateReviewUser `json:"user"`
-	Product string           `json:"product"`
+	Product string           `json:"product" validate:"required,max=200,charset=text"`
 }
 
 func (crr *CreateReviewRequest) Validate() error {
-	crr.User.Name = strings.ReplaceAll(crr.User.Name, " ", "")
-	crr.User.Email = strings.ReplaceAll(crr.User.Email, " ", "")
-	crr.Product = strings.ReplaceAll(crr.Product, " ", "")
+	return validation.Struct(crr)
+}
+
+type ReviewJob struct {
+	ID          string    `db:"id"           json:"id"`
+	TenantID    string    `db:"tenant_id"    json:"tenantId"`
+	OrderID     string    `db:"order_id"     json:"orderId"`
+	UserName    string    `db:"user_name"    json:"userName"`
+	UserEmail   string    `db:"user_email"   json:"userEmail"`
+	Product     string    `db:"product"      json:"product"`
+	DeliveredAt time.Time `db:"delivered_at" json:"deliveredAt"`
+	RunAt       time.Time `db:"run_at"       json:"runAt"`
+	Status      string    `db:"status"       json:"status"`
+	Attempts    int       `db:"attempts"     json:"attempts"`
+	LastError   string    `db:"last_error"   json:"lastError,omitempty"`
+	CreatedAt   time.Time `db:"created_at"   json:"createdAt"`
+	UpdatedAt   time.Time `db:"updated_at"   json:"updatedAt"`
+}
+
+type OrderDeliveredRequest struct {
+	OrderID     string           `json:"orderId"     validate:"required,max=64,charset=text"`
+	User        CreateReviewUser `json:"user"`
+	Product     string           `json:"product"     validate:"required,max=200,charset=text"`
+	DeliveredAt time.Time        `json:"deliveredAt" validate:"required"`
+}
+
+func (odr *OrderDeliveredRequest) Validate() error {
+	return validation.Struct(odr)
+}
+
+type OrderDeliveredBatchRequest struct {
+	Orders []OrderDeliveredRequest `json:"orders" validate:"required,max=500"`
+}
+
+func (odbr *OrderDeliveredBatchRequest) Validate() error {
+	return validation.Struct(odbr)
+}
+
+type ScheduleReviewsResponse struct {
+	Results []ScheduleReviewResult `json:"results"`
+}
+
+// ScheduleReviewResult is the outcome of a single order of a batch: scheduled, duplicated or failed
+type ScheduleReviewResult struct {
+	OrderID string     `json:"orderId"`
+	Status  string     `json:"status"`
+	Job     *ReviewJob `json:"job,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+type WebhookEndpoint struct {
+	ID        string    `db:"id"         json:"id"`
+	TenantID  string    `db:"tenant_id"  json:"tenantId"`
+	URL       string    `db:"url"        json:"url"`
+	Secret    string    `db:"secret"     json:"secret,omitempty"`
+	Events    string    `db:"events"     json:"events"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID             string    `db:"id"              json:"id"`
+	TenantID       string    `db:"tenant_id"       json:"tenantId"`
+	EndpointID     string    `db:"endpoint_id"     json:"endpointId"`
+	EventID        string    `db:"event_id"        json:"eventId"`
+	EventType      string    `db:"event_type"      json:"eventType"`
+	Payload        string    `db:"payload"         json:"payload"`
+	Status         string    `db:"status"          json:"status"`
+	Attempts       int       `db:"attempts"        json:"attempts"`
+	ResponseStatus int       `db:"response_status" json:"responseStatus"`
+	LastError      string    `db:"last_error"      json:"lastError,omitempty"`
+	NextAttemptAt  time.Time `db:"next_attempt_at" json:"nextAttemptAt"`
+	CreatedAt      time.Time `db:"created_at"      json:"createdAt"`
+	UpdatedAt      time.Time `db:"updated_at"      json:"updatedAt"`
+}
+
+type CreateWebhookEndpointRequest struct {
+	URL    string   `json:"url"    validate:"required,max=2048"`
+	Secret string   `json:"secret" validate:"max=256"`
+	Events []string `json:"events" validate:"required,max=20"`
+}
+
+// Validate ignores the empty events
+func (cwer *CreateWebhookEndpointRequest) Validate() error {
+	events := []string{}
+	for _, event := range cwer.Events {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+	cwer.Events = events
+
+	return validation.Struct(cwer)
+}
+
+type ReviewInvitation struct {
+	ID        string     `db:"id"         json:"id"`
+	TenantID  string     `db:"tenant_id"  json:"tenantId"`
+	UserName  string     `db:"user_name"  json:"userName"`
+	UserEmail string     `db:"user_email" json:"userEmail"`
+	Product   string     `db:"product"    json:"product"`
+	Status    string     `db:"status"     json:"status"`
+	LastError string     `db:"last_error" json:"lastError,omitempty"`
+	SentAt    *time.Time `db:"sent_at"    json:"sentAt,omitempty"`
+	OpenedAt  *time.Time `db:"opened_at"  json:"openedAt,omitempty"`
+	UsedAt    *time.Time `db:"used_at"    json:"usedAt,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+}
+
+type AuthToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type IssueTokenRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (itr *IssueTokenRequest) Validate() error {
+	return validation.Struct(itr)
+}
+
+type Admin struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type Transcript struct {
+	Chat     Chat      `json:"chat"`
+	Messages []Message `json:"messages"`
+}
+
+type AuditEntry struct {
+	ID           string    `db:"id"            json:"id"`
+	TenantID     string    `db:"tenant_id"     json:"tenantId"`
+	Actor        string    `db:"actor"         json:"actor"`
+	Role         string    `db:"role"          json:"role"`
+	Action       string    `db:"action"        json:"action"`
+	ResourceType string    `db:"resource_type" json:"resourceType"`
+	ResourceID   string    `db:"resource_id"   json:"resourceId"`
+	Outcome      string    `db:"outcome"       json:"outcome"`
+	Details      string    `db:"details"       json:"details"`
+	CreatedAt    time.Time `db:"created_at"    json:"createdAt"`
+}
+
+type Page struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type ChatFilter struct {
+	UserID string
+	Email  string
+	Status string
+}
+
+type MessageAnalysis struct {
+	MessageID  string    `db:"message_id" json:"messageId"`
+	ChatID     string    `db:"chat_id"    json:"chatId"`
+	Sentiment  float64   `db:"sentiment"  json:"sentiment"`
+	Intent     string    `db:"intent"     json:"intent"`
+	Language   string    `db:"language"   json:"language"`
+	Classifier string    `db:"classifier" json:"classifier"`
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
 
-	if crr.User.Name == "" || crr.User.Email == "" || crr.Product == "" {
-		return errors.New("missing required fields")
+// ParseDateRange parses the from and to query params. Dates are YYYY-MM-DD or RFC 3339,
+// a date only "to" includes the whole day. Missing dates don't limit the range.
+func ParseDateRange(from string, to string) (time.Time, time.Time, error) {
+	fromDate, toDate := minDate, maxDate
+
+	if from != "" {
+		date, _, err := parseDate(from)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		fromDate = date
+	}
+
+	if to != "" {
+		date, dateOnly, err := parseDate(to)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if dateOnly {
+			date = date.AddDate(0, 0, 1)
+		}
+		toDate = date
+	}
+
+	if !fromDate.Before(toDate) {
+		return time.Time{}, time.Time{}, ErrInvalidDateRange
+	}
+	return fromDate, toDate, nil
+}
+
+// parseDate parses a YYYY-MM-DD or RFC 3339 date and tells if it was date only
+func parseDate(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(dateLayout, value); err == nil {
+		return date, true, nil
+	}
+
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, ErrInvalidDate
+	}
+	return date.UTC(), false, nil
+}
+
+type AnalyticsFilter struct {
+	From    time.Time
+	To      time.Time
+	Product string
+}
+
+type AnalyticsSummary struct {
+	Chats                  int     `json:"chats"`
+	Users                  int     `json:"users"`
+	Messages               int     `json:"messages"`
+	Reviews                int     `json:"reviews"`
+	CompletedReviews       int     `json:"completedReviews"`
+	CompletionRate         float64 `json:"completionRate"`
+	Escalations            int     `json:"escalations"`
+	AverageDurationSeconds float64 `json:"averageDurationSeconds"`
+	AverageTurns           float64 `json:"averageTurns"`
+	Ratings                int     `json:"ratings"`
+	AverageRating          float64 `json:"averageRating"`
+}
+
+type VolumeEntry struct {
+	Date     string `db:"day"      json:"date"`
+	Chats    int    `db:"chats"    json:"chats"`
+	Users    int    `db:"users"    json:"users"`
+	Messages int    `db:"messages" json:"messages"`
+}
+
+type ProductRatings struct {
+	Product       string  `json:"product"`
+	Ratings       int     `json:"ratings"`
+	AverageRating float64 `json:"averageRating"`
+	// Distribution holds the number of answers for each rating, from 1 to 5
+	Distribution [5]int `json:"distribution"`
+}
+
+type IntentCount struct {
+	Intent           string  `db:"intent"            json:"intent"`
+	Messages         int     `db:"messages"          json:"messages"`
+	AverageSentiment float64 `db:"average_sentiment" json:"averageSentiment"`
+}
+
+type ExportFilter struct {
+	UserID string
+	Email  string
+	From   time.Time
+	To     time.Time
+}
+
+type ExportJob struct {
+	ID          string     `db:"id"           json:"id"`
+	TenantID    string     `db:"tenant_id"    json:"tenantId"`
+	RequestedBy string     `db:"requested_by" json:"requestedBy"`
+	UserID      string     `db:"user_id"      json:"userId,omitempty"`
+	Email       string     `db:"email"        json:"email,omitempty"`
+	From        time.Time  `db:"from_date"    json:"from"`
+	To          time.Time  `db:"to_date"      json:"to"`
+	Format      string     `db:"format"       json:"format"`
+	Status      string     `db:"status"       json:"status"`
+	Chats       int        `db:"chats"        json:"chats"`
+	FilePath    string     `db:"file_path"    json:"-"`
+	LastError   string     `db:"last_error"   json:"lastError,omitempty"`
+	CreatedAt   time.Time  `db:"created_at"   json:"createdAt"`
+	StartedAt   *time.Time `db:"started_at"   json:"startedAt,omitempty"`
+	CompletedAt *time.Time `db:"completed_at" json:"completedAt,omitempty"`
+}
+
+type CreateExportJobRequest struct {
+	UserID string `json:"userId" validate:"max=36,charset=identifier"`
+	Email  string `json:"email"  validate:"email"`
+	From   string `json:"from"   validate:"max=35"`
+	To     string `json:"to"     validate:"max=35"`
+	Format string `json:"format" validate:"max=16,charset=identifier"`
+}
+
+// Filter validates the request and parses its date range
+func (cejr *CreateExportJobRequest) Filter() (ExportFilter, error) {
+	if err := validation.Struct(cejr); err != nil {
+		return ExportFilter{}, err
 	}
-	return nil
+
+	from, to, err := ParseDateRange(cejr.From, cejr.To)
+	if err != nil {
+		return ExportFilter{}, err
+	}
+
+	return ExportFilter{
+		UserID: cejr.UserID,
+		Email:  cejr.Email,
+		From:   from,
+		To:     to,
+	}, nil
+}
+
+type UserDataExport struct {
+	User              User               `json:"user"`
+	Chats             []Transcript       `json:"chats"`
+	Analyses          []MessageAnalysis  `json:"analyses"`
+	ReviewJobs        []ReviewJob        `json:"reviewJobs"`
+	ReviewInvitations []ReviewInvitation `json:"reviewInvitations"`
+	ExportedAt        time.Time          `json:"exportedAt"`
+}
+
+// RetentionReport tells what a retention run removed, or would remove on a dry run
+type RetentionReport struct {
+	DryRun bool `json:"dryRun"`
+	// MessagesBefore is the creation date limit of the messages with content, if the rule is enabled
+	MessagesBefore *time.Time `json:"messagesBefore,omitempty"`
+	Messages       int64      `json:"messages"`
+	// UsersBefore is the last activity limit of the users, if the rule is enabled
+	UsersBefore *time.Time `json:"usersBefore,omitempty"`
+	Users       int64      `json:"users"`
+	RanAt       time.Time  `json:"ranAt"`
+}
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// DependencyHealth is the result of the readiness check of a dependency
+type DependencyHealth struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Readiness is up only when every dependency is up
+type Readiness struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+// Tenant is a store served by the chatbot. The prompt and the model settings are optional,
+// the bot settings are used when they are empty.
+type Tenant struct {
+	ID           string     `db:"id"            json:"id"`
+	Name         string     `db:"name"          json:"name"`
+	Domain       string     `db:"domain"        json:"domain"`
+	SupportEmail string     `db:"support_email" json:"supportEmail"`
+	Prompt       string     `db:"prompt"        json:"prompt,omitempty"`
+	Policies     string     `db:"policies"      json:"policies"`
+	Model        string     `db:"model"         json:"model,omitempty"`
+	Temperature  *float32   `db:"temperature"   json:"temperature,omitempty"`
+	APIKeyHash   string     `db:"api_key_hash"  json:"-"`
+	CreatedAt    *time.Time `db:"created_at"    json:"createdAt,omitempty"`
+}
+
+// Render fills the tenant fields ({{.Name}}, {{.Domain}}, {{.SupportEmail}} and {{.Policies}})
+// in the instruction
+func (t Tenant) Render(instruction string) (string, error) {
+	tmpl, err := template.New("instruction").Parse(instruction)
+	if err != nil {
+		return "", err
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, t); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+type CreateTenantRequest struct {
+	Name         string   `json:"name"         validate:"required,max=200,charset=text"`
+	Domain       string   `json:"domain"       validate:"required,max=255,charset=identifier"`
+	SupportEmail string   `json:"supportEmail" validate:"required,email"`
+	Prompt       string   `json:"prompt"       validate:"max=20000"`
+	Policies     string   `json:"policies"     validate:"max=20000"`
+	Model        string   `json:"model"        validate:"max=64,charset=identifier"`
+	Temperature  *float32 `json:"temperature"`
+}
+
+func (ctr *CreateTenantRequest) Validate() error {
+	errs := validation.Errors{}
+	if err := validation.Struct(ctr); err != nil {
+		errs = err.(validation.Errors)
+	}
+
+	if ctr.Temperature != nil && (*ctr.Temperature < 0 || *ctr.Temperature > 2) {
+		errs.Add("temperature", "must be between 0 and 2")
+	}
+	if _, err := (Tenant{}).Render(ctr.Prompt); err != nil {
+		errs.Add("prompt", "is not a valid template")
+	}
+	return errs.Err()
+}
+
+// CreatedTenant is returned only once, the api key isn't stored
+type CreatedTenant struct {
+	Tenant
+	APIKey string `json:"apiKey"`
 }
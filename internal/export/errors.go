@@ -0,0 +1,11 @@
+package export
+
+import "errors"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported export format, use json, csv or markdown")
+	ErrChatNotFound      = errors.New("chat not found")
+	ErrJobNotFound       = errors.New("export job not found")
+	ErrJobNotCompleted   = errors.New("export job is not completed")
+	ErrCantSaveJob       = errors.New("can't save export job")
+)
@@ -0,0 +1,302 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+)
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+type repository interface {
+	FindChatByID(ctx context.Context, id string) (datatypes.Chat, error)
+	FindChats(ctx context.Context, filter datatypes.ExportFilter, page datatypes.Page) ([]datatypes.Chat, error)
+	FindMessagesByChat(ctx context.Context, chatID string) ([]datatypes.Message, error)
+	CreateJob(ctx context.Context, job datatypes.ExportJob) (datatypes.ExportJob, error)
+	FindJobByID(ctx context.Context, id string) (datatypes.ExportJob, error)
+	FindPendingJobs(ctx context.Context, limit int) ([]datatypes.ExportJob, error)
+	ClaimJob(ctx context.Context, job datatypes.ExportJob, startedAt time.Time) (bool, error)
+	RequeueStaleJobs(ctx context.Context, before time.Time) (int64, error)
+	UpdateJob(ctx context.Context, job datatypes.ExportJob) error
+}
+
+type ExportServiceConfig struct {
+	// Directory is where the bulk export files are written
+	Directory string
+	// PollInterval is how often pending export jobs are checked
+	PollInterval time.Duration
+	// PageSize is how many chats are loaded at once while exporting
+	PageSize int
+	// StaleTimeout is how long a job can run. The jobs running for longer were left by a
+	// stopped instance and run again.
+	StaleTimeout time.Duration
+}
+
+type ExportService struct {
+	repository repository
+	config     ExportServiceConfig
+	now        func() time.Time
+}
+
+// NewExportService create a new export service
+func NewExportService(repository repository, config ExportServiceConfig) *ExportService {
+	if config.Directory == "" {
+		config.Directory = filepath.Join(os.TempDir(), "review-chatbot-exports")
+	}
+
+	if config.PollInterval <= 0 {
+		config.PollInterval = 10 * time.Second
+	}
+
+	if config.PageSize <= 0 {
+		config.PageSize = 100
+	}
+
+	if config.StaleTimeout <= 0 {
+		config.StaleTimeout = time.Hour
+	}
+
+	return &ExportService{
+		repository: repository,
+		config:     config,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// ExportChat writes the transcript of a single chat
+func (es *ExportService) ExportChat(ctx context.Context, w io.Writer, chatID string, format string) error {
+	render, err := newRenderer(w, format, true)
+	if err != nil {
+		return err
+	}
+
+	chat, err := es.repository.FindChatByID(ctx, chatID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChatNotFound
+		}
+		return err
+	}
+
+	messages, err := es.repository.FindMessagesByChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	if err := render.begin(); err != nil {
+		return err
+	}
+
+	if err := render.transcript(datatypes.Transcript{Chat: chat, Messages: messages}); err != nil {
+		return err
+	}
+	return render.end()
+}
+
+// ExportChats writes the transcripts of every chat that matches the filter, page by page.
+// It returns how many chats were written.
+func (es *ExportService) ExportChats(ctx context.Context, w io.Writer, filter datatypes.ExportFilter, format string) (int, error) {
+	render, err := newRenderer(w, format, false)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := render.begin(); err != nil {
+		return 0, err
+	}
+
+	var exported int
+	page := datatypes.Page{Limit: es.config.PageSize}
+
+	for {
+		chats, err := es.repository.FindChats(ctx, filter, page)
+		if err != nil {
+			return exported, err
+		}
+
+		for _, chat := range chats {
+			messages, err := es.repository.FindMessagesByChat(ctx, chat.ID)
+			if err != nil {
+				return exported, err
+			}
+
+			if err := render.transcript(datatypes.Transcript{Chat: chat, Messages: messages}); err != nil {
+				return exported, err
+			}
+			exported++
+		}
+
+		if len(chats) < page.Limit {
+			break
+		}
+		page.Offset += page.Limit
+	}
+
+	return exported, render.end()
+}
+
+// CreateJob schedules a bulk export that is written to a file in the background
+func (es *ExportService) CreateJob(
+	ctx context.Context,
+	requestedBy string,
+	filter datatypes.ExportFilter,
+	format string,
+) (datatypes.ExportJob, error) {
+	if !IsValidFormat(format) {
+		return datatypes.ExportJob{}, ErrUnsupportedFormat
+	}
+
+	return es.repository.CreateJob(ctx, datatypes.ExportJob{
+		TenantID:    tenant.IDFromContext(ctx),
+		RequestedBy: requestedBy,
+		UserID:      filter.UserID,
+		Email:       filter.Email,
+		From:        filter.From,
+		To:          filter.To,
+		Format:      format,
+		Status:      JobStatusPending,
+		CreatedAt:   es.now(),
+	})
+}
+
+// FindJob finds an export job
+func (es *ExportService) FindJob(ctx context.Context, id string) (datatypes.ExportJob, error) {
+	job, err := es.repository.FindJobByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return datatypes.ExportJob{}, ErrJobNotFound
+		}
+		return datatypes.ExportJob{}, err
+	}
+	return job, nil
+}
+
+// OpenJobFile opens the file of a completed export job. The caller must close it.
+func (es *ExportService) OpenJobFile(ctx context.Context, id string) (datatypes.ExportJob, *os.File, error) {
+	job, err := es.FindJob(ctx, id)
+	if err != nil {
+		return datatypes.ExportJob{}, nil, err
+	}
+
+	if job.Status != JobStatusCompleted {
+		return datatypes.ExportJob{}, nil, ErrJobNotCompleted
+	}
+
+	file, err := os.Open(job.FilePath)
+	if err != nil {
+		return datatypes.ExportJob{}, nil, fmt.Errorf("failed to open export file. Cause: %w", err)
+	}
+	return job, file, nil
+}
+
+// Start runs the pending export jobs until the context is done
+func (es *ExportService) Start(ctx context.Context) {
+	ticker := time.NewTicker(es.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := es.RunPendingJobs(ctx); err != nil {
+			golog.Log().Error(ctx, err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunPendingJobs writes the export file of every pending job. Each job is claimed first, so the jobs
+// found by several instances run only once. The stale running jobs are requeued before.
+func (es *ExportService) RunPendingJobs(ctx context.Context) error {
+	requeued, err := es.repository.RequeueStaleJobs(ctx, es.now().Add(-es.config.StaleTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to run export jobs. Cause: %w", err)
+	}
+
+	if requeued > 0 {
+		golog.Log().Warn(ctx, fmt.Sprintf("requeued %d stale export jobs", requeued))
+	}
+
+	jobs, err := es.repository.FindPendingJobs(ctx, 10)
+	if err != nil {
+		return fmt.Errorf("failed to run export jobs. Cause: %w", err)
+	}
+
+	for _, job := range jobs {
+		startedAt := es.now()
+		claimed, err := es.repository.ClaimJob(ctx, job, startedAt)
+		if err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to run export job %s. Cause: %s", job.ID, err))
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		job.Status = JobStatusRunning
+		job.StartedAt = &startedAt
+
+		job.Chats, err = es.runJob(ctx, &job)
+		if err != nil {
+			job.Status = JobStatusFailed
+			job.LastError = err.Error()
+			golog.Log().Error(ctx, fmt.Sprintf("failed to run export job %s. Cause: %s", job.ID, err))
+		} else {
+			job.Status = JobStatusCompleted
+		}
+
+		completedAt := es.now()
+		job.CompletedAt = &completedAt
+
+		if err := es.repository.UpdateJob(ctx, job); err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to run export job %s. Cause: %s", job.ID, err))
+		}
+	}
+	return nil
+}
+
+// runJob writes the job export into its file, reading only the chats of the tenant that requested it
+func (es *ExportService) runJob(ctx context.Context, job *datatypes.ExportJob) (int, error) {
+	ctx = tenant.WithTenant(ctx, datatypes.Tenant{ID: job.TenantID})
+
+	if err := os.MkdirAll(es.config.Directory, 0o750); err != nil {
+		return 0, err
+	}
+
+	job.FilePath = filepath.Join(es.config.Directory, FileName(job.ID, job.Format))
+	file, err := os.OpenFile(job.FilePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	filter := datatypes.ExportFilter{
+		UserID: job.UserID,
+		Email:  job.Email,
+		From:   job.From,
+		To:     job.To,
+	}
+
+	exported, err := es.ExportChats(ctx, file, filter, job.Format)
+	if err != nil {
+		return exported, err
+	}
+	return exported, file.Close()
+}
@@ -0,0 +1,287 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repositoryMock struct {
+	Error               error
+	Chats               []datatypes.Chat
+	Jobs                []datatypes.ExportJob
+	UpdatedJobs         []datatypes.ExportJob
+	ClaimedJobs         []string
+	Tenants             []string
+	Requeued            []time.Time
+	CallbackFindJobByID func(ctx context.Context, id string) (datatypes.ExportJob, error)
+	CallbackClaimJob    func(ctx context.Context, job datatypes.ExportJob, startedAt time.Time) (bool, error)
+	CallbackUpdateJob   func(ctx context.Context, job datatypes.ExportJob) error
+}
+
+func (rm *repositoryMock) FindChatByID(ctx context.Context, id string) (datatypes.Chat, error) {
+	for _, chat := range rm.Chats {
+		if chat.ID == id {
+			return chat, nil
+		}
+	}
+	return datatypes.Chat{}, sql.ErrNoRows
+}
+
+func (rm *repositoryMock) FindChats(ctx context.Context, filter datatypes.ExportFilter, page datatypes.Page) ([]datatypes.Chat, error) {
+	rm.Tenants = append(rm.Tenants, tenant.IDFromContext(ctx))
+	if page.Offset >= len(rm.Chats) {
+		return []datatypes.Chat{}, rm.Error
+	}
+	end := page.Offset + page.Limit
+	if end > len(rm.Chats) {
+		end = len(rm.Chats)
+	}
+	return rm.Chats[page.Offset:end], rm.Error
+}
+
+func (rm *repositoryMock) FindMessagesByChat(ctx context.Context, chatID string) ([]datatypes.Message, error) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []datatypes.Message{
+		{ID: chatID + "-1", ChatID: chatID, Author: datatypes.AuthorChatbot, Message: "Hi, how was the delivery?", CreatedAt: &createdAt},
+		{ID: chatID + "-2", ChatID: chatID, Author: datatypes.AuthorUser, Message: "Fast,\nthanks", CreatedAt: &createdAt},
+	}, rm.Error
+}
+
+func (rm *repositoryMock) CreateJob(ctx context.Context, job datatypes.ExportJob) (datatypes.ExportJob, error) {
+	job.ID = "job-1"
+	return job, rm.Error
+}
+
+func (rm *repositoryMock) FindJobByID(ctx context.Context, id string) (datatypes.ExportJob, error) {
+	if rm.CallbackFindJobByID != nil {
+		return rm.CallbackFindJobByID(ctx, id)
+	}
+	return datatypes.ExportJob{}, sql.ErrNoRows
+}
+
+func (rm *repositoryMock) FindPendingJobs(ctx context.Context, limit int) ([]datatypes.ExportJob, error) {
+	return rm.Jobs, rm.Error
+}
+
+func (rm *repositoryMock) ClaimJob(ctx context.Context, job datatypes.ExportJob, startedAt time.Time) (bool, error) {
+	if rm.CallbackClaimJob != nil {
+		return rm.CallbackClaimJob(ctx, job, startedAt)
+	}
+	rm.ClaimedJobs = append(rm.ClaimedJobs, job.ID)
+	return rm.Error == nil, rm.Error
+}
+
+func (rm *repositoryMock) RequeueStaleJobs(ctx context.Context, before time.Time) (int64, error) {
+	rm.Requeued = append(rm.Requeued, before)
+	return 0, rm.Error
+}
+
+func (rm *repositoryMock) UpdateJob(ctx context.Context, job datatypes.ExportJob) error {
+	rm.UpdatedJobs = append(rm.UpdatedJobs, job)
+	if rm.CallbackUpdateJob != nil {
+		return rm.CallbackUpdateJob(ctx, job)
+	}
+	return rm.Error
+}
+
+func mockedChats(ids ...string) []datatypes.Chat {
+	createdAt := time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)
+	chats := []datatypes.Chat{}
+	for _, id := range ids {
+		chats = append(chats, datatypes.Chat{
+			ID:        id,
+			UserID:    "user-1",
+			UserEmail: "john@mail.com",
+			Product:   "Galaxy S24",
+			Status:    datatypes.ChatStatusClosed,
+			CreatedAt: &createdAt,
+		})
+	}
+	return chats
+}
+
+func TestServiceExportChat(t *testing.T) {
+	t.Run("should render a single json transcript", func(t *testing.T) {
+		var body bytes.Buffer
+		service := NewExportService(&repositoryMock{Chats: mockedChats("1")}, ExportServiceConfig{})
+
+		require.NoError(t, service.ExportChat(context.Background(), &body, "1", FormatJSON))
+
+		var transcript datatypes.Transcript
+		require.NoError(t, json.Unmarshal(body.Bytes(), &transcript))
+		assert.Equal(t, "1", transcript.Chat.ID)
+		assert.Len(t, transcript.Messages, 2)
+	})
+
+	t.Run("should render markdown", func(t *testing.T) {
+		var body bytes.Buffer
+		service := NewExportService(&repositoryMock{Chats: mockedChats("1")}, ExportServiceConfig{})
+
+		require.NoError(t, service.ExportChat(context.Background(), &body, "1", FormatMarkdown))
+		assert.Contains(t, body.String(), "# Chat 1\n")
+		assert.Contains(t, body.String(), "- Product: Galaxy S24\n")
+		assert.Contains(t, body.String(), "**user** (2024-05-01T12:00:00Z)\n\n> Fast,\n> thanks\n")
+	})
+
+	t.Run("should fail when the chat does not exist", func(t *testing.T) {
+		service := NewExportService(&repositoryMock{}, ExportServiceConfig{})
+		assert.ErrorIs(t, service.ExportChat(context.Background(), &bytes.Buffer{}, "1", FormatJSON), ErrChatNotFound)
+	})
+
+	t.Run("should fail with unsupported formats", func(t *testing.T) {
+		service := NewExportService(&repositoryMock{Chats: mockedChats("1")}, ExportServiceConfig{})
+		assert.ErrorIs(t, service.ExportChat(context.Background(), &bytes.Buffer{}, "1", "pdf"), ErrUnsupportedFormat)
+	})
+}
+
+func TestServiceExportChats(t *testing.T) {
+	t.Run("should export every page as a json array", func(t *testing.T) {
+		var body bytes.Buffer
+		service := NewExportService(&repositoryMock{Chats: mockedChats("1", "2", "3")}, ExportServiceConfig{PageSize: 2})
+
+		exported, err := service.ExportChats(context.Background(), &body, datatypes.ExportFilter{}, FormatJSON)
+		require.NoError(t, err)
+		assert.Equal(t, 3, exported)
+
+		var transcripts []datatypes.Transcript
+		require.NoError(t, json.Unmarshal(body.Bytes(), &transcripts))
+		assert.Len(t, transcripts, 3)
+	})
+
+	t.Run("should write one csv line per message", func(t *testing.T) {
+		var body bytes.Buffer
+		service := NewExportService(&repositoryMock{Chats: mockedChats("1", "2")}, ExportServiceConfig{})
+
+		_, err := service.ExportChats(context.Background(), &body, datatypes.ExportFilter{}, FormatCSV)
+		require.NoError(t, err)
+
+		lines := bytes.Count(body.Bytes(), []byte("\n"))
+		// header + 4 messages + the line breaks inside the quoted messages of both chats
+		assert.Equal(t, 7, lines)
+		assert.Contains(t, body.String(), "1,user-1,john@mail.com,Galaxy S24,closed,2024-05-01T11:00:00Z,1-1,chatbot")
+	})
+}
+
+func TestServiceRunPendingJobs(t *testing.T) {
+	t.Run("should write the export file and complete the job", func(t *testing.T) {
+		repository := &repositoryMock{
+			Chats: mockedChats("1", "2"),
+			Jobs:  []datatypes.ExportJob{{ID: "job-1", Format: FormatMarkdown, Status: JobStatusPending}},
+		}
+		service := NewExportService(repository, ExportServiceConfig{Directory: t.TempDir()})
+
+		require.NoError(t, service.RunPendingJobs(context.Background()))
+		assert.Equal(t, []string{"job-1"}, repository.ClaimedJobs)
+		require.Len(t, repository.UpdatedJobs, 1)
+
+		job := repository.UpdatedJobs[0]
+		assert.Equal(t, JobStatusCompleted, job.Status)
+		assert.NotNil(t, job.StartedAt)
+		assert.Equal(t, 2, job.Chats)
+		assert.NotNil(t, job.CompletedAt)
+
+		content, err := os.ReadFile(job.FilePath)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "# Chat 2\n")
+	})
+
+	t.Run("should export the chats of the tenant that requested the job", func(t *testing.T) {
+		repository := &repositoryMock{
+			Chats: mockedChats("1"),
+			Jobs: []datatypes.ExportJob{
+				{ID: "job-1", TenantID: "store-a", Format: FormatJSON, Status: JobStatusPending},
+				{ID: "job-2", TenantID: "store-b", Format: FormatJSON, Status: JobStatusPending},
+			},
+		}
+		service := NewExportService(repository, ExportServiceConfig{Directory: t.TempDir()})
+
+		require.NoError(t, service.RunPendingJobs(context.Background()))
+		assert.Equal(t, []string{"store-a", "store-b"}, repository.Tenants)
+	})
+
+	t.Run("should skip the jobs claimed by another instance", func(t *testing.T) {
+		repository := &repositoryMock{
+			Chats: mockedChats("1"),
+			Jobs: []datatypes.ExportJob{
+				{ID: "claimed", Format: FormatJSON, Status: JobStatusPending},
+				{ID: "free", Format: FormatJSON, Status: JobStatusPending},
+			},
+			CallbackClaimJob: func(ctx context.Context, job datatypes.ExportJob, startedAt time.Time) (bool, error) {
+				return job.ID == "free", nil
+			},
+		}
+		service := NewExportService(repository, ExportServiceConfig{Directory: t.TempDir()})
+
+		require.NoError(t, service.RunPendingJobs(context.Background()))
+		require.Len(t, repository.UpdatedJobs, 1)
+		assert.Equal(t, "free", repository.UpdatedJobs[0].ID)
+	})
+
+	t.Run("should keep running the batch when a job can't be updated", func(t *testing.T) {
+		repository := &repositoryMock{
+			Chats: mockedChats("1"),
+			Jobs: []datatypes.ExportJob{
+				{ID: "first", Format: FormatJSON, Status: JobStatusPending},
+				{ID: "second", Format: FormatJSON, Status: JobStatusPending},
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ExportJob) error {
+				if job.ID == "first" {
+					return ErrJobNotFound
+				}
+				return nil
+			},
+		}
+		service := NewExportService(repository, ExportServiceConfig{Directory: t.TempDir()})
+
+		require.NoError(t, service.RunPendingJobs(context.Background()))
+		assert.Equal(t, []string{"first", "second"}, repository.ClaimedJobs)
+		require.Len(t, repository.UpdatedJobs, 2)
+		assert.Equal(t, JobStatusCompleted, repository.UpdatedJobs[1].Status)
+	})
+
+	t.Run("should requeue the jobs running for longer than the stale timeout", func(t *testing.T) {
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		repository := &repositoryMock{}
+		service := NewExportService(repository, ExportServiceConfig{Directory: t.TempDir(), StaleTimeout: 30 * time.Minute})
+		service.now = func() time.Time { return now }
+
+		require.NoError(t, service.RunPendingJobs(context.Background()))
+		assert.Equal(t, []time.Time{now.Add(-30 * time.Minute)}, repository.Requeued)
+	})
+}
+
+func TestServiceOpenJobFile(t *testing.T) {
+	t.Run("should fail when the job is still running", func(t *testing.T) {
+		service := NewExportService(&repositoryMock{
+			CallbackFindJobByID: func(ctx context.Context, id string) (datatypes.ExportJob, error) {
+				return datatypes.ExportJob{ID: id, Status: JobStatusRunning}, nil
+			},
+		}, ExportServiceConfig{})
+
+		_, _, err := service.OpenJobFile(context.Background(), "job-1")
+		assert.ErrorIs(t, err, ErrJobNotCompleted)
+	})
+
+	t.Run("should fail when the job does not exist", func(t *testing.T) {
+		_, _, err := NewExportService(&repositoryMock{}, ExportServiceConfig{}).OpenJobFile(context.Background(), "job-1")
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+}
+
+func TestServiceCreateJob(t *testing.T) {
+	t.Run("should fail with unsupported formats", func(t *testing.T) {
+		_, err := NewExportService(&repositoryMock{}, ExportServiceConfig{}).
+			CreateJob(context.Background(), "alice", datatypes.ExportFilter{}, "xlsx")
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
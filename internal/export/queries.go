@@ -0,0 +1,68 @@
+package export
+
+var findChatByID = `
+	SELECT c.id, c.user_id, COALESCE(u.email, '') AS user_email, c.product, c.status, c.created_at, c.closed_at
+	FROM chats c
+	LEFT JOIN users u ON u.id = c.user_id
+	WHERE c.id = :id AND c.tenant_id = :tenant_id;
+`
+
+var findChats = `
+	SELECT c.id, c.user_id, COALESCE(u.email, '') AS user_email, c.product, c.status, c.created_at, c.closed_at
+	FROM chats c
+	LEFT JOIN users u ON u.id = c.user_id
+	WHERE c.tenant_id = :tenant_id
+		AND (:user_id = '' OR c.user_id = :user_id)
+		AND (:email = '' OR u.email = :email)
+		AND c.created_at >= :from AND c.created_at < :to
+	ORDER BY c.created_at, c.id
+	LIMIT :limit OFFSET :offset;
+`
+
+var findMessagesByChat = `
+	SELECT id, chat_id, author, message, created_at FROM messages
+	WHERE chat_id = :chat_id AND tenant_id = :tenant_id
+	ORDER BY created_at;
+`
+
+var createJob = `
+	INSERT INTO export_jobs (
+		id, tenant_id, requested_by, user_id, email, from_date, to_date, format, status, chats, file_path, last_error,
+		created_at
+	) VALUES (
+		:id, :tenant_id, :requested_by, :user_id, :email, :from_date, :to_date, :format, :status, 0, '', '', :created_at
+	);
+`
+
+var findJobByID = `
+	SELECT id, tenant_id, requested_by, user_id, email, from_date, to_date, format, status, chats, file_path,
+		last_error, created_at, started_at, completed_at
+	FROM export_jobs
+	WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+// the worker runs the jobs of every tenant, each one with the tenant that requested it
+var findPendingJobs = `
+	SELECT id, tenant_id, requested_by, user_id, email, from_date, to_date, format, status, chats, file_path,
+		last_error, created_at, started_at, completed_at
+	FROM export_jobs
+	WHERE status = :status
+	ORDER BY created_at
+	LIMIT :limit;
+`
+
+var claimJob = `
+	UPDATE export_jobs SET status = :running, started_at = :started_at
+	WHERE id = :id AND tenant_id = :tenant_id AND status = :pending;
+`
+
+var requeueStaleJobs = `
+	UPDATE export_jobs SET status = :pending, started_at = NULL
+	WHERE status = :running AND started_at < :before;
+`
+
+var updateJob = `
+	UPDATE export_jobs
+	SET status = :status, chats = :chats, file_path = :file_path, last_error = :last_error, completed_at = :completed_at
+	WHERE id = :id AND tenant_id = :tenant_id;
+`
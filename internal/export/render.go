@@ -0,0 +1,189 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+var contentTypes = map[string]string{
+	FormatJSON:     "application/json",
+	FormatCSV:      "text/csv; charset=utf-8",
+	FormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+var fileExtensions = map[string]string{
+	FormatJSON:     "json",
+	FormatCSV:      "csv",
+	FormatMarkdown: "md",
+}
+
+var csvHeader = []string{
+	"chat_id", "user_id", "user_email", "product", "chat_status", "chat_created_at",
+	"message_id", "author", "message", "message_created_at",
+}
+
+// renderer writes transcripts one at a time, so exports don't need to be kept in memory
+type renderer interface {
+	begin() error
+	transcript(transcript datatypes.Transcript) error
+	end() error
+}
+
+// ContentType returns the content type of the export format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FileName returns the name of an export file
+func FileName(name string, format string) string {
+	return fmt.Sprintf("%s.%s", name, fileExtensions[format])
+}
+
+// IsValidFormat checks if the export format is supported
+func IsValidFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// newRenderer creates the renderer of the format. Single renders one JSON object instead of an array.
+func newRenderer(w io.Writer, format string, single bool) (renderer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonRenderer{w: w, single: single}, nil
+	case FormatCSV:
+		return &csvRenderer{w: csv.NewWriter(w)}, nil
+	case FormatMarkdown:
+		return &markdownRenderer{w: w}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type jsonRenderer struct {
+	w       io.Writer
+	single  bool
+	written int
+}
+
+func (jr *jsonRenderer) begin() error {
+	if jr.single {
+		return nil
+	}
+	_, err := io.WriteString(jr.w, "[")
+	return err
+}
+
+func (jr *jsonRenderer) transcript(transcript datatypes.Transcript) error {
+	if jr.written > 0 && !jr.single {
+		if _, err := io.WriteString(jr.w, ","); err != nil {
+			return err
+		}
+	}
+	jr.written++
+
+	body, err := json.Marshal(transcript)
+	if err != nil {
+		return err
+	}
+	_, err = jr.w.Write(body)
+	return err
+}
+
+func (jr *jsonRenderer) end() error {
+	if jr.single {
+		return nil
+	}
+	_, err := io.WriteString(jr.w, "]\n")
+	return err
+}
+
+// csvRenderer writes one line per message with the chat columns repeated
+type csvRenderer struct {
+	w *csv.Writer
+}
+
+func (cr *csvRenderer) begin() error {
+	return cr.w.Write(csvHeader)
+}
+
+func (cr *csvRenderer) transcript(transcript datatypes.Transcript) error {
+	chat := transcript.Chat
+	for _, message := range transcript.Messages {
+		record := []string{
+			chat.ID, chat.UserID, chat.UserEmail, chat.Product, chat.Status, formatTime(chat.CreatedAt),
+			message.ID, message.Author, message.Message, formatTime(message.CreatedAt),
+		}
+		if err := cr.w.Write(record); err != nil {
+			return err
+		}
+	}
+	cr.w.Flush()
+	return cr.w.Error()
+}
+
+func (cr *csvRenderer) end() error {
+	cr.w.Flush()
+	return cr.w.Error()
+}
+
+type markdownRenderer struct {
+	w       io.Writer
+	written int
+}
+
+func (mr *markdownRenderer) begin() error {
+	return nil
+}
+
+func (mr *markdownRenderer) transcript(transcript datatypes.Transcript) error {
+	var sb strings.Builder
+	chat := transcript.Chat
+
+	if mr.written > 0 {
+		sb.WriteString("\n---\n\n")
+	}
+	mr.written++
+
+	fmt.Fprintf(&sb, "# Chat %s\n\n", chat.ID)
+	fmt.Fprintf(&sb, "- User: %s (%s)\n", chat.UserEmail, chat.UserID)
+	if chat.Product != "" {
+		fmt.Fprintf(&sb, "- Product: %s\n", chat.Product)
+	}
+	fmt.Fprintf(&sb, "- Status: %s\n", chat.Status)
+	fmt.Fprintf(&sb, "- Started at: %s\n", formatTime(chat.CreatedAt))
+	if chat.ClosedAt != nil {
+		fmt.Fprintf(&sb, "- Closed at: %s\n", formatTime(chat.ClosedAt))
+	}
+
+	for _, message := range transcript.Messages {
+		fmt.Fprintf(&sb, "\n**%s** (%s)\n\n", message.Author, formatTime(message.CreatedAt))
+		for _, line := range strings.Split(message.Message, "\n") {
+			fmt.Fprintf(&sb, "> %s\n", line)
+		}
+	}
+
+	_, err := io.WriteString(mr.w, sb.String())
+	return err
+}
+
+func (mr *markdownRenderer) end() error {
+	return nil
+}
+
+func formatTime(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,270 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofrs/uuid/v5"
+)
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// FindChatByID finds a chat of the tenant by id
+func (r *Repository) FindChatByID(ctx context.Context, id string) (datatypes.Chat, error) {
+	var chat datatypes.Chat
+
+	stm, err := r.db.PrepareNamedContext(ctx, findChatByID)
+	if err != nil {
+		return datatypes.Chat{}, fmt.Errorf("failed to find chat. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":        id,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	if err = stm.GetContext(ctx, &chat, params); err != nil {
+		return datatypes.Chat{}, fmt.Errorf("failed to find chat. Cause: %w", err)
+	}
+
+	return chat, nil
+}
+
+// FindChats finds the chats of the tenant that match the filter, oldest first
+func (r *Repository) FindChats(ctx context.Context, filter datatypes.ExportFilter, page datatypes.Page) ([]datatypes.Chat, error) {
+	chats := []datatypes.Chat{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findChats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chats. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"user_id":   filter.UserID,
+		"email":     filter.Email,
+		"from":      filter.From,
+		"to":        filter.To,
+		"limit":     page.Limit,
+		"offset":    page.Offset,
+	}
+
+	if err = stm.SelectContext(ctx, &chats, params); err != nil {
+		return nil, fmt.Errorf("failed to find chats. Cause: %w", err)
+	}
+
+	return chats, nil
+}
+
+// FindMessagesByChat finds every message of a chat in the order they were sent
+func (r *Repository) FindMessagesByChat(ctx context.Context, chatID string) ([]datatypes.Message, error) {
+	messages := []datatypes.Message{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findMessagesByChat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find messages. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"chat_id":   chatID,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	if err = stm.SelectContext(ctx, &messages, params); err != nil {
+		return nil, fmt.Errorf("failed to find messages. Cause: %w", err)
+	}
+
+	return messages, nil
+}
+
+// CreateJob saves a new pending export job
+func (r *Repository) CreateJob(ctx context.Context, job datatypes.ExportJob) (datatypes.ExportJob, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, createJob)
+	if err != nil {
+		return datatypes.ExportJob{}, fmt.Errorf("failed to create new export job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.ExportJob{}, fmt.Errorf("failed to create new export job. Cause: %w", err)
+	}
+	job.ID = id.String()
+
+	params := map[string]interface{}{
+		"id":           job.ID,
+		"tenant_id":    job.TenantID,
+		"requested_by": job.RequestedBy,
+		"user_id":      job.UserID,
+		"email":        job.Email,
+		"from_date":    job.From,
+		"to_date":      job.To,
+		"format":       job.Format,
+		"status":       job.Status,
+		"created_at":   job.CreatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return datatypes.ExportJob{}, fmt.Errorf("failed to create new export job. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return datatypes.ExportJob{}, fmt.Errorf("failed to create new export job. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return datatypes.ExportJob{}, ErrCantSaveJob
+	}
+
+	return job, nil
+}
+
+// FindJobByID finds an export job of the tenant by id
+func (r *Repository) FindJobByID(ctx context.Context, id string) (datatypes.ExportJob, error) {
+	var job datatypes.ExportJob
+
+	stm, err := r.db.PrepareNamedContext(ctx, findJobByID)
+	if err != nil {
+		return datatypes.ExportJob{}, fmt.Errorf("failed to find export job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":        id,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	if err = stm.GetContext(ctx, &job, params); err != nil {
+		return datatypes.ExportJob{}, fmt.Errorf("failed to find export job. Cause: %w", err)
+	}
+
+	return job, nil
+}
+
+// FindPendingJobs finds the oldest jobs of every tenant waiting to run
+func (r *Repository) FindPendingJobs(ctx context.Context, limit int) ([]datatypes.ExportJob, error) {
+	jobs := []datatypes.ExportJob{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findPendingJobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending export jobs. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"status": JobStatusPending,
+		"limit":  limit,
+	}
+
+	if err = stm.SelectContext(ctx, &jobs, params); err != nil {
+		return nil, fmt.Errorf("failed to find pending export jobs. Cause: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClaimJob moves the pending job to running. It returns false when another instance claimed it first.
+func (r *Repository) ClaimJob(ctx context.Context, job datatypes.ExportJob, startedAt time.Time) (bool, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, claimJob)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim export job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":         job.ID,
+		"tenant_id":  job.TenantID,
+		"pending":    JobStatusPending,
+		"running":    JobStatusRunning,
+		"started_at": startedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim export job. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim export job. Cause: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// RequeueStaleJobs moves back to pending the jobs running since before the given time. It returns how many were requeued.
+func (r *Repository) RequeueStaleJobs(ctx context.Context, before time.Time) (int64, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, requeueStaleJobs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale export jobs. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"pending": JobStatusPending,
+		"running": JobStatusRunning,
+		"before":  before,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale export jobs. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale export jobs. Cause: %w", err)
+	}
+
+	return rows, nil
+}
+
+// UpdateJob updates the job status and result
+func (r *Repository) UpdateJob(ctx context.Context, job datatypes.ExportJob) error {
+	stm, err := r.db.PrepareNamedContext(ctx, updateJob)
+	if err != nil {
+		return fmt.Errorf("failed to update export job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":           job.ID,
+		"tenant_id":    job.TenantID,
+		"status":       job.Status,
+		"chats":        job.Chats,
+		"file_path":    job.FilePath,
+		"last_error":   job.LastError,
+		"completed_at": job.CompletedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to update export job. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update export job. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
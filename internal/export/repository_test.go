@@ -0,0 +1,98 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateJob(t *testing.T) {
+	t.Run("should create a new job", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		job, err := repository.CreateJob(context.Background(), datatypes.ExportJob{Format: FormatCSV})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, job.ID)
+	})
+
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.CreateJob(context.Background(), datatypes.ExportJob{})
+		assert.ErrorIs(t, err, ErrCantSaveJob)
+	})
+}
+
+func TestRepositoryFindChats(t *testing.T) {
+	t.Run("should find the chats of the page", func(t *testing.T) {
+		mockedChats := []datatypes.Chat{{ID: "1"}}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+						assert.Equal(t, 100, arg.(map[string]interface{})["limit"])
+						if chats, ok := dest.(*[]datatypes.Chat); ok {
+							*chats = mockedChats
+						}
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		chats, err := repository.FindChats(context.Background(), datatypes.ExportFilter{}, datatypes.Page{Limit: 100})
+		assert.NoError(t, err)
+		assert.EqualValues(t, mockedChats, chats)
+	})
+}
+
+func TestRepositoryClaimJob(t *testing.T) {
+	t.Run("should not claim a job claimed by another instance", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		claimed, err := repository.ClaimJob(context.Background(), datatypes.ExportJob{ID: "qwerty"}, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+}
@@ -0,0 +1,365 @@
+package fakegemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	pb "cloud.google.com/go/ai/generativelanguage/apiv1beta/generativelanguagepb"
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// APIKey is accepted by the fake server, any other key works as well
+const APIKey = "fake-gemini-key"
+
+// errorStatus are the API status names of the HTTP errors
+var errorStatus = map[int]string{
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "PERMISSION_DENIED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusInternalServerError: "INTERNAL",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusGatewayTimeout:      "DEADLINE_EXCEEDED",
+}
+
+// Message is a turn of the conversation sent to the model
+type Message struct {
+	Role string
+	Text string
+}
+
+// Request is a generate content request received by the fake server
+type Request struct {
+	// Model is the model name, e.g. models/gemini-1.5-pro-latest
+	Model             string
+	SystemInstruction string
+	// Messages is the chat history, the last one is the message being answered
+	Messages []Message
+	// Tools are the names of the functions the model can call
+	Tools []string
+}
+
+// LastMessage returns the text of the message being answered
+func (r Request) LastMessage() string {
+	if len(r.Messages) == 0 {
+		return ""
+	}
+	return r.Messages[len(r.Messages)-1].Text
+}
+
+// Server is an in-process Gemini API answering the generate content requests with the
+// scripted responses, in order. When the script is over the default response is used.
+type Server struct {
+	mutex    sync.Mutex
+	server   *httptest.Server
+	script   []Response
+	fallback Response
+	requests []Request
+}
+
+// NewServer starts a fake Gemini API. The requests without a scripted response fail with 500.
+func NewServer() *Server {
+	s := &Server{
+		fallback: Error(http.StatusInternalServerError, "fakegemini: no scripted response"),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the endpoint of the fake API
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Script appends the responses of the next requests
+func (s *Server) Script(responses ...Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.script = append(s.script, responses...)
+}
+
+// SetDefault sets the response used when the script is over
+func (s *Server) SetDefault(response Response) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fallback = response
+}
+
+// Pending returns how many scripted responses weren't used yet
+func (s *Server) Pending() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.script)
+}
+
+// Requests returns the generate content requests received so far
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+// ClientOptions returns the options that point a genai client to the fake API
+func (s *Server) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{option.WithEndpoint(s.URL()), option.WithAPIKey(APIKey)}
+}
+
+// NewClient creates a genai client of the fake API
+func (s *Server) NewClient(ctx context.Context) (*genai.Client, error) {
+	return genai.NewClient(ctx, s.ClientOptions()...)
+}
+
+// StreamsSupported tells if the genai client can read the streamed responses of the API. The
+// stream reader of gax-go v2.12 never finds the end of the stream with the encoding/json v2 of
+// the newer Go toolchains, so every generate content request fails.
+func StreamsSupported() bool {
+	decoder := json.NewDecoder(strings.NewReader("[{}]"))
+	if _, err := decoder.Token(); err != nil {
+		return false
+	}
+
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return false
+	}
+
+	// the stream reader decodes until it fails, then checks for the end of the array
+	if err := decoder.Decode(&raw); err == nil {
+		return false
+	}
+	token, _ := decoder.Token()
+	return token == json.Delim(']')
+}
+
+// handle routes the requests by their method, e.g. /v1beta/models/gemini-1.5-pro-latest:generateContent.
+// The genai client streams every generate content request, the stream has a single response.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	model, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1beta/"), ":")
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && method == "generateContent":
+		s.generateContent(w, r, model, body, false)
+	case r.Method == http.MethodPost && method == "streamGenerateContent":
+		s.generateContent(w, r, model, body, true)
+	case r.Method == http.MethodPost && method == "countTokens":
+		countTokens(w, body)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("fakegemini: %s %s isn't supported", r.Method, r.URL.Path))
+	}
+}
+
+// generateContent records the request and answers it with the next response
+func (s *Server) generateContent(w http.ResponseWriter, r *http.Request, model string, body []byte, stream bool) {
+	req := &pb.GenerateContentRequest{}
+	if err := protojson.Unmarshal(body, req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests = append(s.requests, newRequest(model, req))
+	response := s.fallback
+	if len(s.script) > 0 {
+		response, s.script = s.script[0], s.script[1:]
+	}
+	s.mutex.Unlock()
+
+	if response.Delay > 0 {
+		timer := time.NewTimer(response.Delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if response.failed() {
+		writeError(w, response.Status, response.Message)
+		return
+	}
+
+	resp, err := newResponse(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if stream {
+		writeStream(w, resp)
+		return
+	}
+	writeMessage(w, resp)
+}
+
+// countTokens counts the words of the request
+func countTokens(w http.ResponseWriter, body []byte) {
+	req := &pb.CountTokensRequest{}
+	if err := protojson.Unmarshal(body, req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var tokens int32
+	for _, content := range req.GetContents() {
+		tokens += int32(len(strings.Fields(contentText(content))))
+	}
+	writeMessage(w, &pb.CountTokensResponse{TotalTokens: tokens})
+}
+
+// newRequest keeps the texts of the request
+func newRequest(model string, req *pb.GenerateContentRequest) Request {
+	request := Request{
+		Model:             model,
+		SystemInstruction: contentText(req.GetSystemInstruction()),
+	}
+
+	for _, content := range req.GetContents() {
+		request.Messages = append(request.Messages, Message{Role: content.GetRole(), Text: contentText(content)})
+	}
+
+	for _, tool := range req.GetTools() {
+		for _, function := range tool.GetFunctionDeclarations() {
+			request.Tools = append(request.Tools, function.GetName())
+		}
+	}
+	return request
+}
+
+// newResponse builds the API response of the scripted one
+func newResponse(response Response) (*pb.GenerateContentResponse, error) {
+	if response.BlockReason != "" {
+		reason, ok := pb.GenerateContentResponse_PromptFeedback_BlockReason_value[response.BlockReason]
+		if !ok {
+			return nil, fmt.Errorf("fakegemini: unknown block reason %s", response.BlockReason)
+		}
+
+		return &pb.GenerateContentResponse{
+			PromptFeedback: &pb.GenerateContentResponse_PromptFeedback{
+				BlockReason: pb.GenerateContentResponse_PromptFeedback_BlockReason(reason),
+			},
+		}, nil
+	}
+
+	finishReason := "STOP"
+	if response.FinishReason != "" {
+		finishReason = response.FinishReason
+	}
+
+	reason, ok := pb.Candidate_FinishReason_value[finishReason]
+	if !ok {
+		return nil, fmt.Errorf("fakegemini: unknown finish reason %s", finishReason)
+	}
+
+	candidate := &pb.Candidate{
+		Content:      &pb.Content{Role: "model"},
+		FinishReason: pb.Candidate_FinishReason(reason),
+	}
+
+	if candidate.FinishReason == pb.Candidate_SAFETY {
+		candidate.SafetyRatings = []*pb.SafetyRating{{
+			Category:    pb.HarmCategory_HARM_CATEGORY_HARASSMENT,
+			Probability: pb.SafetyRating_HIGH,
+			Blocked:     true,
+		}}
+	}
+
+	if response.Text != "" {
+		candidate.Content.Parts = append(candidate.Content.Parts, &pb.Part{Data: &pb.Part_Text{Text: response.Text}})
+	}
+
+	if response.FunctionCall != nil {
+		args, err := structpb.NewStruct(response.FunctionCall.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		candidate.Content.Parts = append(candidate.Content.Parts, &pb.Part{Data: &pb.Part_FunctionCall{
+			FunctionCall: &pb.FunctionCall{Name: response.FunctionCall.Name, Args: args},
+		}})
+	}
+
+	tokens := int32(len(strings.Fields(response.Text)))
+	return &pb.GenerateContentResponse{
+		Candidates: []*pb.Candidate{candidate},
+		UsageMetadata: &pb.GenerateContentResponse_UsageMetadata{
+			CandidatesTokenCount: tokens,
+			TotalTokenCount:      tokens,
+		},
+	}, nil
+}
+
+// contentText joins the text parts of the content
+func contentText(content *pb.Content) string {
+	texts := []string{}
+	for _, part := range content.GetParts() {
+		if text := part.GetText(); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// writeMessage writes the response in the JSON encoding of the API
+func writeMessage(w http.ResponseWriter, message proto.Message) {
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// writeStream writes a stream of a single response, a JSON array like the API does
+func writeStream(w http.ResponseWriter, message proto.Message) {
+	data, err := protojson.Marshal(message)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("["))
+	w.Write(data)
+	w.Write([]byte("]"))
+}
+
+// writeError writes an error like the API does
+func writeError(w http.ResponseWriter, status int, message string) {
+	code, ok := errorStatus[status]
+	if !ok {
+		code = "UNKNOWN"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": status, "message": message, "status": code},
+	})
+}
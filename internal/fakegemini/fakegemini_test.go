@@ -0,0 +1,178 @@
+package fakegemini
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func TestServer(t *testing.T) {
+	if !StreamsSupported() {
+		t.Skip("the genai client can't read the API streams with this Go toolchain, run with GOEXPERIMENT=nojsonv2")
+	}
+
+	newModel := func(t *testing.T) (*Server, *genai.GenerativeModel) {
+		server := NewServer()
+		t.Cleanup(server.Close)
+
+		client, err := server.NewClient(context.Background())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+
+		model := client.GenerativeModel("gemini-1.5-pro-latest")
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text("You are a support agent")}}
+		model.Tools = []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "request_human_agent"}}}}
+		return server, model
+	}
+
+	t.Run("should answer with the scripted responses in order", func(t *testing.T) {
+		server, model := newModel(t)
+		server.Script(Text("Hello Mary"), Call("request_human_agent", map[string]any{"reason": "broken screen"}))
+
+		session := model.StartChat()
+		resp, err := session.SendMessage(context.Background(), genai.Text("Hi"))
+		require.NoError(t, err)
+		assert.Equal(t, genai.Text("Hello Mary"), resp.Candidates[0].Content.Parts[0])
+		assert.Equal(t, int32(2), resp.UsageMetadata.CandidatesTokenCount)
+
+		resp, err = session.SendMessage(context.Background(), genai.Text("The screen is broken"))
+		require.NoError(t, err)
+		assert.Equal(t, genai.FunctionCall{
+			Name: "request_human_agent",
+			Args: map[string]any{"reason": "broken screen"},
+		}, resp.Candidates[0].Content.Parts[0])
+		assert.Equal(t, 0, server.Pending())
+
+		requests := server.Requests()
+		require.Len(t, requests, 2)
+		assert.Equal(t, "models/gemini-1.5-pro-latest", requests[1].Model)
+		assert.Equal(t, "You are a support agent", requests[1].SystemInstruction)
+		assert.Equal(t, []string{"request_human_agent"}, requests[1].Tools)
+		assert.Equal(t, []Message{
+			{Role: "user", Text: "Hi"},
+			{Role: "model", Text: "Hello Mary"},
+			{Role: "user", Text: "The screen is broken"},
+		}, requests[1].Messages)
+		assert.Equal(t, "The screen is broken", requests[1].LastMessage())
+	})
+
+	t.Run("should fail with the scripted errors", func(t *testing.T) {
+		server, model := newModel(t)
+		server.Script(Error(http.StatusTooManyRequests, "quota exceeded"))
+
+		_, err := model.GenerateContent(context.Background(), genai.Text("Hi"))
+		var apiError *googleapi.Error
+		require.True(t, errors.As(err, &apiError))
+		assert.Equal(t, http.StatusTooManyRequests, apiError.Code)
+		assert.Contains(t, err.Error(), "quota exceeded")
+
+		_, err = model.GenerateContent(context.Background(), genai.Text("Hi again"))
+		assert.ErrorContains(t, err, "no scripted response")
+	})
+
+	t.Run("should use the default response after the script", func(t *testing.T) {
+		server, model := newModel(t)
+		server.SetDefault(Text("Anything else?"))
+
+		for i := 0; i < 2; i++ {
+			resp, err := model.GenerateContent(context.Background(), genai.Text("Hi"))
+			require.NoError(t, err)
+			assert.Equal(t, genai.Text("Anything else?"), resp.Candidates[0].Content.Parts[0])
+		}
+	})
+
+	t.Run("should block the prompts and replies", func(t *testing.T) {
+		server, model := newModel(t)
+		server.Script(BlockedPrompt("SAFETY"), BlockedReply())
+
+		var blockedError *genai.BlockedError
+		_, err := model.GenerateContent(context.Background(), genai.Text("Hi"))
+		require.True(t, errors.As(err, &blockedError))
+		assert.Equal(t, genai.BlockReasonSafety, blockedError.PromptFeedback.BlockReason)
+
+		_, err = model.GenerateContent(context.Background(), genai.Text("Hi"))
+		require.True(t, errors.As(err, &blockedError))
+		assert.Equal(t, genai.FinishReasonSafety, blockedError.Candidate.FinishReason)
+	})
+
+	t.Run("should fail with unknown block reasons", func(t *testing.T) {
+		server, model := newModel(t)
+		server.Script(BlockedPrompt("RUDE"))
+
+		_, err := model.GenerateContent(context.Background(), genai.Text("Hi"))
+		assert.ErrorContains(t, err, "unknown block reason RUDE")
+	})
+
+	t.Run("should answer after the delay unless the request is canceled", func(t *testing.T) {
+		server, model := newModel(t)
+		server.Script(Text("Hello").After(50*time.Millisecond), Text("Too late").After(time.Minute))
+
+		start := time.Now()
+		_, err := model.GenerateContent(context.Background(), genai.Text("Hi"))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = model.GenerateContent(ctx, genai.Text("Hi"))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should count the tokens", func(t *testing.T) {
+		server, model := newModel(t)
+
+		resp, err := model.CountTokens(context.Background(), genai.Text("how many tokens"))
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), resp.TotalTokens)
+		assert.Empty(t, server.Requests())
+	})
+}
+
+func TestServerHTTP(t *testing.T) {
+	post := func(t *testing.T, server *Server, path string, body string) (int, string) {
+		resp, err := http.Post(server.URL()+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(data)
+	}
+
+	t.Run("should answer the generate content requests in the API encoding", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.Script(Text("Hello Mary"))
+
+		status, body := post(t, server, "/v1beta/models/gemini-1.5-pro-latest:streamGenerateContent",
+			`{"systemInstruction":{"parts":[{"text":"Be kind"}]},"contents":[{"role":"user","parts":[{"text":"Hi"}]}]}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.True(t, strings.HasPrefix(body, `[{"candidates":[{"content":{"parts":[{"text":"Hello Mary"}]`))
+		assert.Equal(t, []Request{{
+			Model:             "models/gemini-1.5-pro-latest",
+			SystemInstruction: "Be kind",
+			Messages:          []Message{{Role: "user", Text: "Hi"}},
+		}}, server.Requests())
+	})
+
+	t.Run("should fail like the API", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+
+		status, body := post(t, server, "/v1beta/models/gemini-1.5-pro-latest:generateContent", `{}`)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.JSONEq(t, `{"error":{"code":500,"message":"fakegemini: no scripted response","status":"INTERNAL"}}`, body)
+
+		status, _ = post(t, server, "/v1beta/models/gemini-1.5-pro-latest:embedContent", `{}`)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
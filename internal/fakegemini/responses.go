@@ -0,0 +1,67 @@
+package fakegemini
+
+import (
+	"net/http"
+	"time"
+)
+
+// Response is a scripted answer of the fake model
+type Response struct {
+	// Text is the reply of the model
+	Text string
+	// FunctionCall asks the caller to run one of its tools instead of replying
+	FunctionCall *FunctionCall
+	// Status fails the request with this HTTP status, e.g. 429 or 500. The client retries the
+	// 503 responses, so they slow the tests down.
+	Status int
+	// Message is the error message of the failed requests
+	Message string
+	// BlockReason blocks the prompt, e.g. SAFETY or OTHER. The response has no candidates.
+	BlockReason string
+	// FinishReason stops the candidate, e.g. SAFETY blocks the reply. Defaults to STOP.
+	FinishReason string
+	// Delay is waited before answering, unless the request is canceled first
+	Delay time.Duration
+}
+
+// FunctionCall is a tool call of the model
+type FunctionCall struct {
+	Name string
+	Args map[string]any
+}
+
+// Text replies with the text
+func Text(text string) Response {
+	return Response{Text: text}
+}
+
+// Call asks the caller to run the function with the args
+func Call(name string, args map[string]any) Response {
+	return Response{FunctionCall: &FunctionCall{Name: name, Args: args}}
+}
+
+// Error fails the request with the HTTP status and message
+func Error(status int, message string) Response {
+	return Response{Status: status, Message: message}
+}
+
+// BlockedPrompt refuses the prompt for the reason, e.g. SAFETY
+func BlockedPrompt(reason string) Response {
+	return Response{BlockReason: reason}
+}
+
+// BlockedReply stops the reply by the safety filters
+func BlockedReply() Response {
+	return Response{FinishReason: "SAFETY"}
+}
+
+// After returns a copy of the response answered after the delay
+func (r Response) After(delay time.Duration) Response {
+	r.Delay = delay
+	return r
+}
+
+// failed tells if the response is an error
+func (r Response) failed() bool {
+	return r.Status != 0 && r.Status != http.StatusOK
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+// Check returns an error when the dependency can't be used
+type Check func(ctx context.Context) error
+
+type HealthServiceConfig struct {
+	// Timeout of each check
+	Timeout time.Duration
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type HealthService struct {
+	checks  []namedCheck
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewHealthService create a new health service without checks
+func NewHealthService(config HealthServiceConfig) *HealthService {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	return &HealthService{
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// AddCheck registers the check of a dependency. Add every check before serving the requests.
+func (hs *HealthService) AddCheck(name string, check Check) {
+	hs.checks = append(hs.checks, namedCheck{name: name, check: check})
+}
+
+// Readiness runs every check concurrently and reports the status of each dependency
+func (hs *HealthService) Readiness(ctx context.Context) datatypes.Readiness {
+	readiness := datatypes.Readiness{
+		Status:       datatypes.HealthStatusUp,
+		Dependencies: make(map[string]datatypes.DependencyHealth, len(hs.checks)),
+	}
+
+	results := make([]datatypes.DependencyHealth, len(hs.checks))
+	wg := sync.WaitGroup{}
+	for i, check := range hs.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = hs.run(ctx, check)
+		}(i, check.check)
+	}
+	wg.Wait()
+
+	for i, check := range hs.checks {
+		readiness.Dependencies[check.name] = results[i]
+		if results[i].Status != datatypes.HealthStatusUp {
+			readiness.Status = datatypes.HealthStatusDown
+		}
+	}
+	return readiness
+}
+
+// run runs a single check with the timeout
+func (hs *HealthService) run(ctx context.Context, check Check) datatypes.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, hs.timeout)
+	defer cancel()
+
+	start := hs.now()
+	err := check(ctx)
+	result := datatypes.DependencyHealth{
+		Status:    datatypes.HealthStatusUp,
+		LatencyMS: hs.now().Sub(start).Milliseconds(),
+		CheckedAt: start,
+	}
+
+	if err != nil {
+		result.Status = datatypes.HealthStatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Cached skips the check for the ttl after it succeeds, for the checks that cost money or have a
+// rate limit, like the model requests. Failures aren't cached, so a recovered dependency is up again
+// on the next check.
+func Cached(check Check, ttl time.Duration) Check {
+	var (
+		mutex     sync.Mutex
+		expiresAt time.Time
+	)
+
+	return func(ctx context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if time.Now().Before(expiresAt) {
+			return nil
+		}
+
+		if err := check(ctx); err != nil {
+			return err
+		}
+		expiresAt = time.Now().Add(ttl)
+		return nil
+	}
+}
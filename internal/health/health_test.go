@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthServiceReadiness(t *testing.T) {
+	t.Run("should be up when every dependency is up", func(t *testing.T) {
+		service := NewHealthService(HealthServiceConfig{})
+		service.AddCheck("database", func(ctx context.Context) error { return nil })
+		service.AddCheck("migrations", func(ctx context.Context) error { return nil })
+
+		readiness := service.Readiness(context.Background())
+		assert.Equal(t, datatypes.HealthStatusUp, readiness.Status)
+		assert.Len(t, readiness.Dependencies, 2)
+		assert.Equal(t, datatypes.HealthStatusUp, readiness.Dependencies["database"].Status)
+	})
+
+	t.Run("should report the failed dependency", func(t *testing.T) {
+		service := NewHealthService(HealthServiceConfig{})
+		service.AddCheck("database", func(ctx context.Context) error { return errors.New("error when pinging for tests") })
+		service.AddCheck("migrations", func(ctx context.Context) error { return nil })
+
+		readiness := service.Readiness(context.Background())
+		assert.Equal(t, datatypes.HealthStatusDown, readiness.Status)
+		assert.Equal(t, datatypes.HealthStatusDown, readiness.Dependencies["database"].Status)
+		assert.Equal(t, "error when pinging for tests", readiness.Dependencies["database"].Error)
+		assert.Equal(t, datatypes.HealthStatusUp, readiness.Dependencies["migrations"].Status)
+	})
+
+	t.Run("should stop the slow checks after the timeout", func(t *testing.T) {
+		service := NewHealthService(HealthServiceConfig{Timeout: 10 * time.Millisecond})
+		service.AddCheck("model", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		readiness := service.Readiness(context.Background())
+		assert.Equal(t, datatypes.HealthStatusDown, readiness.Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), readiness.Dependencies["model"].Error)
+	})
+}
+
+func TestCached(t *testing.T) {
+	t.Run("should skip the check after a success", func(t *testing.T) {
+		calls := 0
+		check := Cached(func(ctx context.Context) error {
+			calls++
+			return nil
+		}, time.Minute)
+
+		assert.NoError(t, check(context.Background()))
+		assert.NoError(t, check(context.Background()))
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should not cache the failures", func(t *testing.T) {
+		calls := 0
+		errCheck := errors.New("error when checking for tests")
+		check := Cached(func(ctx context.Context) error {
+			calls++
+			if calls == 1 {
+				return errCheck
+			}
+			return nil
+		}, time.Minute)
+
+		assert.ErrorIs(t, check(context.Background()), errCheck)
+		assert.NoError(t, check(context.Background()))
+		assert.NoError(t, check(context.Background()))
+		assert.Equal(t, 2, calls)
+	})
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+)
+
+// InstrumentDB records the latency of the queries run by a repository. Only the methods used by
+// the repositories are timed: named statements, SelectContext and named executions in transactions.
+func InstrumentDB(db godb.DB, repository string) godb.DB {
+	return &instrumentedDB{DB: db, repository: repository}
+}
+
+type instrumentedDB struct {
+	godb.DB
+	repository string
+}
+
+// PrepareNamedContext
+func (idb *instrumentedDB) PrepareNamedContext(ctx context.Context, query string) (godb.NamedStmt, error) {
+	stmt, err := idb.DB.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return stmt, err
+	}
+	return &instrumentedNamedStmt{NamedStmt: stmt, repository: idb.repository, query: query}, nil
+}
+
+// SelectContext
+func (idb *instrumentedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer observeSince(idb.repository, query, time.Now())
+	return idb.DB.SelectContext(ctx, dest, query, args...)
+}
+
+// BeginTx
+func (idb *instrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+	tx, err := idb.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return tx, err
+	}
+	return &instrumentedTx{Tx: tx, repository: idb.repository}, nil
+}
+
+type instrumentedNamedStmt struct {
+	godb.NamedStmt
+	repository string
+	query      string
+}
+
+// ExecContext
+func (ins *instrumentedNamedStmt) ExecContext(ctx context.Context, arg interface{}) (sql.Result, error) {
+	defer observeSince(ins.repository, ins.query, time.Now())
+	return ins.NamedStmt.ExecContext(ctx, arg)
+}
+
+// GetContext
+func (ins *instrumentedNamedStmt) GetContext(ctx context.Context, dest interface{}, arg interface{}) error {
+	defer observeSince(ins.repository, ins.query, time.Now())
+	return ins.NamedStmt.GetContext(ctx, dest, arg)
+}
+
+// SelectContext
+func (ins *instrumentedNamedStmt) SelectContext(ctx context.Context, dest interface{}, arg interface{}) error {
+	defer observeSince(ins.repository, ins.query, time.Now())
+	return ins.NamedStmt.SelectContext(ctx, dest, arg)
+}
+
+type instrumentedTx struct {
+	godb.Tx
+	repository string
+}
+
+// NamedExecContext
+func (itx *instrumentedTx) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	defer observeSince(itx.repository, query, time.Now())
+	return itx.Tx.NamedExecContext(ctx, query, arg)
+}
+
+// observeSince records the query latency from the start time
+func observeSince(repository string, query string, start time.Time) {
+	ObserveQuery(repository, query, time.Since(start))
+}
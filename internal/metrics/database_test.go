@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentDB(t *testing.T) {
+	t.Run("should time the named statements", func(t *testing.T) {
+		db := InstrumentDB(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest interface{}, arg interface{}) error {
+						return nil
+					},
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{}, nil
+					},
+				}, nil
+			},
+		}, "stmt_test")
+
+		stmt, err := db.PrepareNamedContext(context.Background(), "SELECT * FROM users WHERE id = :id;")
+		assert.NoError(t, err)
+		assert.NoError(t, stmt.GetContext(context.Background(), &struct{}{}, map[string]interface{}{}))
+		_, err = stmt.ExecContext(context.Background(), map[string]interface{}{})
+		assert.NoError(t, err)
+
+		body := scrape(t)
+		assert.Contains(t, body, `review_chatbot_db_query_duration_seconds_count{repository="stmt_test",statement="select"} 2`)
+	})
+
+	t.Run("should time the transactions", func(t *testing.T) {
+		db := InstrumentDB(&godb.DBMock{
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{}, nil
+					},
+				}, nil
+			},
+		}, "tx_test")
+
+		tx, err := db.BeginTx(context.Background(), nil)
+		assert.NoError(t, err)
+		_, err = tx.NamedExecContext(context.Background(), "DELETE FROM users WHERE id = :id;", map[string]interface{}{})
+		assert.NoError(t, err)
+
+		assert.Contains(t, scrape(t), `review_chatbot_db_query_duration_seconds_count{repository="tx_test",statement="delete"} 1`)
+	})
+
+	t.Run("should return the prepare errors", func(t *testing.T) {
+		errPrepare := errors.New("error when preparing named context for tests")
+		db := InstrumentDB(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return nil, errPrepare
+			},
+		}, "error_test")
+
+		_, err := db.PrepareNamedContext(context.Background(), "SELECT 1;")
+		assert.ErrorIs(t, err, errPrepare)
+	})
+}
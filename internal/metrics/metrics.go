@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "review_chatbot"
+
+const (
+	TokensPrompt     = "prompt"
+	TokensCandidates = "candidates"
+)
+
+var registry = prometheus.NewRegistry()
+
+// websocketConnections returns the number of open web chats, set by WatchWebsocketConnections
+var websocketConnections atomic.Pointer[func() int]
+
+var (
+	messages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_total",
+		Help:      "Chat messages saved, by author.",
+	}, []string{"author"})
+
+	modelRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "model_request_duration_seconds",
+		Help:      "Gemini request latency, by operation.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 16, 32, 64},
+	}, []string{"operation"})
+
+	modelErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_errors_total",
+		Help:      "Failed Gemini requests, by operation and error class.",
+	}, []string{"operation", "class"})
+
+	modelTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "model_tokens_total",
+		Help:      "Gemini tokens consumed, by operation and type (prompt or candidates).",
+	}, []string{"operation", "type"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Repository query latency, by repository and statement (select, insert, update or delete).",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"repository", "statement"})
+
+	reviewsStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_started_total",
+		Help:      "Reviews started in the web chat.",
+	})
+
+	reviewsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reviews_completed_total",
+		Help:      "Reviews completed in the web chat. Divide by reviews_started_total for the completion rate.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "websocket_connections",
+			Help:      "Open web chat connections.",
+		}, func() float64 {
+			if count := websocketConnections.Load(); count != nil {
+				return float64((*count)())
+			}
+			return 0
+		}),
+		messages,
+		modelRequestDuration,
+		modelErrors,
+		modelTokens,
+		queryDuration,
+		reviewsStarted,
+		reviewsCompleted,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// WatchWebsocketConnections sets the func read on every scrape to count the open web chats
+func WatchWebsocketConnections(count func() int) {
+	websocketConnections.Store(&count)
+}
+
+// ObserveMessage counts a saved chat message
+func ObserveMessage(author string) {
+	messages.WithLabelValues(author).Inc()
+}
+
+// ObserveModelRequest records the latency of a Gemini request and, when it fails, its error class
+func ObserveModelRequest(operation string, duration time.Duration, errorClass string) {
+	modelRequestDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if errorClass != "" {
+		modelErrors.WithLabelValues(operation, errorClass).Inc()
+	}
+}
+
+// AddModelTokens counts the tokens consumed by a Gemini request
+func AddModelTokens(operation string, prompt int, candidates int) {
+	modelTokens.WithLabelValues(operation, TokensPrompt).Add(float64(prompt))
+	modelTokens.WithLabelValues(operation, TokensCandidates).Add(float64(candidates))
+}
+
+// ObserveQuery records the latency of a repository query
+func ObserveQuery(repository string, query string, duration time.Duration) {
+	queryDuration.WithLabelValues(repository, statement(query)).Observe(duration.Seconds())
+}
+
+// ReviewStarted counts a review started in the web chat
+func ReviewStarted() {
+	reviewsStarted.Inc()
+}
+
+// ReviewCompleted counts a review completed in the web chat
+func ReviewCompleted() {
+	reviewsCompleted.Inc()
+}
+
+// statement returns the lower case SQL verb of the query, so the label values are bounded
+func statement(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+
+	switch verb := strings.ToLower(fields[0]); verb {
+	case "select", "insert", "update", "delete":
+		return verb
+	}
+	return "other"
+}
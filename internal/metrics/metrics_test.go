@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns the metrics as served on /metrics
+func scrape(t *testing.T) string {
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("should count the websocket connections on every scrape", func(t *testing.T) {
+		connections := 3
+		WatchWebsocketConnections(func() int { return connections })
+		assert.Contains(t, scrape(t), "review_chatbot_websocket_connections 3")
+
+		connections = 1
+		assert.Contains(t, scrape(t), "review_chatbot_websocket_connections 1")
+	})
+
+	t.Run("should serve the model metrics", func(t *testing.T) {
+		ObserveModelRequest("test", 300*time.Millisecond, "")
+		ObserveModelRequest("test", 2*time.Second, "rate_limited")
+		AddModelTokens("test", 120, 45)
+
+		body := scrape(t)
+		assert.Contains(t, body, `review_chatbot_model_request_duration_seconds_count{operation="test"} 2`)
+		assert.Contains(t, body, `review_chatbot_model_errors_total{class="rate_limited",operation="test"} 1`)
+		assert.Contains(t, body, `review_chatbot_model_tokens_total{operation="test",type="prompt"} 120`)
+		assert.Contains(t, body, `review_chatbot_model_tokens_total{operation="test",type="candidates"} 45`)
+		assert.Contains(t, body, "go_goroutines")
+	})
+}
+
+func TestCounters(t *testing.T) {
+	t.Run("should count messages by author", func(t *testing.T) {
+		before := testutil.ToFloat64(messages.WithLabelValues("user"))
+		ObserveMessage("user")
+		assert.Equal(t, before+1, testutil.ToFloat64(messages.WithLabelValues("user")))
+	})
+
+	t.Run("should count the started and completed reviews", func(t *testing.T) {
+		started, completed := testutil.ToFloat64(reviewsStarted), testutil.ToFloat64(reviewsCompleted)
+		ReviewStarted()
+		ReviewStarted()
+		ReviewCompleted()
+		assert.Equal(t, started+2, testutil.ToFloat64(reviewsStarted))
+		assert.Equal(t, completed+1, testutil.ToFloat64(reviewsCompleted))
+	})
+}
+
+func TestStatement(t *testing.T) {
+	t.Run("should label the queries by the sql verb", func(t *testing.T) {
+		assert.Equal(t, "select", statement("\n\tSELECT * FROM users;"))
+		assert.Equal(t, "insert", statement("insert into users values (1)"))
+		assert.Equal(t, "update", statement("UPDATE users SET email = ''"))
+		assert.Equal(t, "delete", statement("DELETE FROM users"))
+		assert.Equal(t, "other", statement("WITH x AS (SELECT 1) SELECT * FROM x"))
+		assert.Equal(t, "other", statement(""))
+	})
+}
@@ -0,0 +1,8 @@
+package migrations
+
+import "errors"
+
+var (
+	ErrCantRegisterMigration = errors.New("failed to apply migration. Cause: can't register the migration")
+	ErrPendingMigrations     = errors.New("there are pending migrations")
+)
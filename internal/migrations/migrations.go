@@ -0,0 +1,240 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+)
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// migrations holds every schema change in the order they must be applied.
+// New migrations must always be appended with the next version number.
+var migrations = []migration{
+	{
+		version:    1,
+		name:       "create users, chats and messages tables",
+		statements: []string{createUsersTable, createChatsTable, createMessagesTable},
+	},
+	{
+		version:    2,
+		name:       "create review jobs table",
+		statements: []string{createReviewJobsTable},
+	},
+	{
+		version:    3,
+		name:       "create webhook endpoints and deliveries tables",
+		statements: []string{createWebhookEndpointsTable, createWebhookDeliveriesTable},
+	},
+	{
+		version:    4,
+		name:       "create review invitations table",
+		statements: []string{createReviewInvitationsTable},
+	},
+	{
+		version: 5,
+		name:    "add chat status and timestamps, create audit log table",
+		statements: []string{
+			addChatsStatusColumn,
+			addChatsCreatedAtColumn,
+			addChatsClosedAtColumn,
+			addMessagesCreatedAtColumn,
+			createAuditLogTable,
+		},
+	},
+	{
+		version:    6,
+		name:       "create message analyses table",
+		statements: []string{createMessageAnalysesTable},
+	},
+	{
+		version:    7,
+		name:       "add review product and completion to chats",
+		statements: []string{addChatsProductColumn, addChatsReviewedAtColumn},
+	},
+	{
+		version:    8,
+		name:       "create export jobs table",
+		statements: []string{createExportJobsTable},
+	},
+	{
+		version:    9,
+		name:       "add encrypted pii to messages",
+		statements: []string{addMessagesPIIColumn},
+	},
+	{
+		version:    10,
+		name:       "add creation date to users",
+		statements: []string{addUsersCreatedAtColumn},
+	},
+	{
+		version:    11,
+		name:       "add version and soft delete to users",
+		statements: []string{addUsersVersionColumn, addUsersUpdatedAtColumn, addUsersDeletedAtColumn},
+	},
+	{
+		version:    12,
+		name:       "lower case user emails",
+		statements: []string{lowerCaseUsersEmail},
+	},
+	{
+		version: 13,
+		name:    "create tenants table, add the tenant to users, chats, messages, review jobs and invitations",
+		statements: []string{
+			createTenantsTable,
+			addUsersTenantColumn,
+			replaceUsersEmailIndex,
+			addChatsTenantColumn,
+			addMessagesTenantColumn,
+			addReviewJobsTenantColumn,
+			replaceReviewJobsOrderIndex,
+			addReviewInvitationsTenantColumn,
+		},
+	},
+	{
+		version:    14,
+		name:       "add the tenant to webhook endpoints and deliveries",
+		statements: []string{addWebhookEndpointsTenantColumn, addWebhookDeliveriesTenantColumn},
+	},
+	{
+		version:    15,
+		name:       "add the tenant to export jobs and audit log",
+		statements: []string{addExportJobsTenantColumn, addAuditLogTenantColumn},
+	},
+	{
+		version:    16,
+		name:       "add start date to export jobs",
+		statements: []string{addExportJobsStartedAtColumn},
+	},
+	{
+		version:    17,
+		name:       "add use date to review invitations",
+		statements: []string{addReviewInvitationsUsedAtColumn},
+	},
+}
+
+// sqliteStatements replace the MySQL statements SQLite doesn't support. SQLite can't drop the
+// unique constraints declared with the columns, so an email or order id is still unique across
+// tenants there. SQLite only backs the offline end-to-end tests.
+var sqliteStatements = map[string]string{
+	replaceUsersEmailIndex:      sqliteCreateUsersEmailIndex,
+	replaceReviewJobsOrderIndex: sqliteCreateReviewJobsOrderIndex,
+}
+
+type Migrator struct {
+	db godb.DB
+}
+
+// NewMigrator create a new migrator
+func NewMigrator(db godb.DB) *Migrator {
+	return &Migrator{db}
+}
+
+// Migrate applies every migration that was not applied yet
+func (m *Migrator) Migrate(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return fmt.Errorf("failed to apply migrations. Cause: %w", err)
+	}
+
+	applied, err := m.AppliedVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to apply migrations. Cause: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if applied[migration.version] {
+			continue
+		}
+
+		if err := m.apply(ctx, migration); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s). Cause: %w", migration.version, migration.name, err)
+		}
+	}
+
+	return nil
+}
+
+// AppliedVersions returns the versions already registered in the database
+func (m *Migrator) AppliedVersions(ctx context.Context) (map[int]bool, error) {
+	var versions []int
+
+	if err := m.db.SelectContext(ctx, &versions, findAppliedVersions); err != nil {
+		return nil, fmt.Errorf("failed to find applied migrations. Cause: %w", err)
+	}
+
+	applied := make(map[int]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+// Check returns ErrPendingMigrations if any migration of this build wasn't applied yet
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.AppliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := []string{}
+	for _, migration := range migrations {
+		if !applied[migration.version] {
+			pending = append(pending, strconv.Itoa(migration.version))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPendingMigrations, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// apply runs the migration statements and registers its version
+func (m *Migrator) apply(ctx context.Context, migration migration) error {
+	sqlite := m.db.DriverName() == "sqlite3"
+
+	for _, statement := range migration.statements {
+		if replacement, ok := sqliteStatements[statement]; ok && sqlite {
+			statement = replacement
+		}
+
+		if _, err := m.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	stm, err := m.db.PrepareNamedContext(ctx, registerMigration)
+	if err != nil {
+		return err
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"version":    migration.version,
+		"name":       migration.name,
+		"applied_at": time.Now().UTC(),
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrCantRegisterMigration
+	}
+	return nil
+}
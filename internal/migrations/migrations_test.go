@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigratorMigrate(t *testing.T) {
+	t.Run("should apply only pending migrations", func(t *testing.T) {
+		var (
+			executed   []string
+			registered []interface{}
+		)
+
+		migrator := NewMigrator(&godb.DBMock{
+			CallbackExecContext: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+				executed = append(executed, query)
+				return &godb.ResultMock{}, nil
+			},
+			CallbackSelectContext: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+				if versions, ok := dest.(*[]int); ok {
+					*versions = []int{1}
+				}
+				return nil
+			},
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						registered = append(registered, arg.(map[string]interface{})["version"])
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		assert.NoError(t, migrator.Migrate(context.Background()))
+		assert.Equal(t, createSchemaMigrationsTable, executed[0])
+		assert.NotContains(t, executed, createUsersTable)
+		assert.Contains(t, executed, createReviewJobsTable)
+		assert.Len(t, registered, len(migrations)-1)
+		assert.NotContains(t, registered, 1)
+	})
+
+	t.Run("should replace the statements SQLite doesn't support", func(t *testing.T) {
+		executed := []string{}
+		migrator := NewMigrator(&godb.DBMock{
+			CallbackDriverName: func() string {
+				return "sqlite3"
+			},
+			CallbackExecContext: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+				executed = append(executed, query)
+				return &godb.ResultMock{}, nil
+			},
+			CallbackSelectContext: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+				return nil
+			},
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		assert.NoError(t, migrator.Migrate(context.Background()))
+		assert.NotContains(t, executed, replaceUsersEmailIndex)
+		assert.NotContains(t, executed, replaceReviewJobsOrderIndex)
+		assert.Contains(t, executed, sqliteCreateUsersEmailIndex)
+		assert.Contains(t, executed, sqliteCreateReviewJobsOrderIndex)
+	})
+
+	t.Run("should fail to create the migrations table", func(t *testing.T) {
+		errExecContext := errors.New("error to run exec context for tests")
+		migrator := NewMigrator(&godb.DBMock{
+			CallbackExecContext: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+				return nil, errExecContext
+			},
+		})
+
+		err := migrator.Migrate(context.Background())
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, errExecContext)
+	})
+
+	t.Run("should fail when the migration is not registered", func(t *testing.T) {
+		migrator := NewMigrator(&godb.DBMock{
+			CallbackExecContext: func(ctx context.Context, query string, args ...any) (sql.Result, error) {
+				return &godb.ResultMock{}, nil
+			},
+			CallbackSelectContext: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+				return nil
+			},
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := migrator.Migrate(context.Background())
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCantRegisterMigration)
+	})
+}
+
+func TestMigratorCheck(t *testing.T) {
+	t.Run("should pass when every migration was applied", func(t *testing.T) {
+		migrator := NewMigrator(&godb.DBMock{
+			CallbackSelectContext: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+				for _, migration := range migrations {
+					*dest.(*[]int) = append(*dest.(*[]int), migration.version)
+				}
+				return nil
+			},
+		})
+
+		assert.NoError(t, migrator.Check(context.Background()))
+	})
+
+	t.Run("should list the pending migrations", func(t *testing.T) {
+		migrator := NewMigrator(&godb.DBMock{
+			CallbackSelectContext: func(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+				for _, migration := range migrations[:len(migrations)-2] {
+					*dest.(*[]int) = append(*dest.(*[]int), migration.version)
+				}
+				return nil
+			},
+		})
+
+		err := migrator.Check(context.Background())
+		assert.ErrorIs(t, err, ErrPendingMigrations)
+		assert.ErrorContains(t, err, fmt.Sprintf("%d, %d", migrations[len(migrations)-2].version, migrations[len(migrations)-1].version))
+	})
+}
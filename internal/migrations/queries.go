@@ -0,0 +1,279 @@
+package migrations
+
+var createSchemaMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER      NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at DATETIME     NOT NULL
+	);
+`
+
+var findAppliedVersions = `
+	SELECT version FROM schema_migrations ORDER BY version;
+`
+
+var registerMigration = `
+	INSERT INTO schema_migrations (version, name, applied_at) VALUES (:version, :name, :applied_at);
+`
+
+var createUsersTable = `
+	CREATE TABLE IF NOT EXISTS users (
+		id         VARCHAR(36)  NOT NULL PRIMARY KEY,
+		first_name VARCHAR(255) NOT NULL,
+		last_name  VARCHAR(255) NOT NULL,
+		email      VARCHAR(255) NOT NULL UNIQUE
+	);
+`
+
+var createChatsTable = `
+	CREATE TABLE IF NOT EXISTS chats (
+		id      VARCHAR(36) NOT NULL PRIMARY KEY,
+		user_id VARCHAR(36) NOT NULL
+	);
+`
+
+var createMessagesTable = `
+	CREATE TABLE IF NOT EXISTS messages (
+		id      VARCHAR(36) NOT NULL PRIMARY KEY,
+		chat_id VARCHAR(36) NOT NULL,
+		author  VARCHAR(32) NOT NULL,
+		message TEXT        NOT NULL
+	);
+`
+
+var createReviewJobsTable = `
+	CREATE TABLE IF NOT EXISTS review_jobs (
+		id           VARCHAR(36)  NOT NULL PRIMARY KEY,
+		order_id     VARCHAR(255) NOT NULL UNIQUE,
+		user_name    VARCHAR(255) NOT NULL,
+		user_email   VARCHAR(255) NOT NULL,
+		product      VARCHAR(255) NOT NULL,
+		delivered_at DATETIME     NOT NULL,
+		run_at       DATETIME     NOT NULL,
+		status       VARCHAR(32)  NOT NULL,
+		attempts     INTEGER      NOT NULL DEFAULT 0,
+		last_error   TEXT         NOT NULL,
+		created_at   DATETIME     NOT NULL,
+		updated_at   DATETIME     NOT NULL
+	);
+`
+
+var createWebhookEndpointsTable = `
+	CREATE TABLE IF NOT EXISTS webhook_endpoints (
+		id         VARCHAR(36)   NOT NULL PRIMARY KEY,
+		url        VARCHAR(2048) NOT NULL,
+		secret     VARCHAR(255)  NOT NULL,
+		events     VARCHAR(1024) NOT NULL,
+		created_at DATETIME      NOT NULL
+	);
+`
+
+var createWebhookDeliveriesTable = `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id              VARCHAR(36)  NOT NULL PRIMARY KEY,
+		endpoint_id     VARCHAR(36)  NOT NULL,
+		event_id        VARCHAR(36)  NOT NULL,
+		event_type      VARCHAR(64)  NOT NULL,
+		payload         TEXT         NOT NULL,
+		status          VARCHAR(32)  NOT NULL,
+		attempts        INTEGER      NOT NULL DEFAULT 0,
+		response_status INTEGER      NOT NULL DEFAULT 0,
+		last_error      TEXT         NOT NULL,
+		next_attempt_at DATETIME     NOT NULL,
+		created_at      DATETIME     NOT NULL,
+		updated_at      DATETIME     NOT NULL
+	);
+`
+
+var createReviewInvitationsTable = `
+	CREATE TABLE IF NOT EXISTS review_invitations (
+		id         VARCHAR(36)  NOT NULL PRIMARY KEY,
+		user_name  VARCHAR(255) NOT NULL,
+		user_email VARCHAR(255) NOT NULL,
+		product    VARCHAR(255) NOT NULL,
+		status     VARCHAR(32)  NOT NULL,
+		last_error TEXT         NOT NULL,
+		sent_at    DATETIME     NULL,
+		opened_at  DATETIME     NULL,
+		created_at DATETIME     NOT NULL
+	);
+`
+
+var addChatsStatusColumn = `
+	ALTER TABLE chats ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'open';
+`
+
+var addChatsCreatedAtColumn = `
+	ALTER TABLE chats ADD COLUMN created_at DATETIME NULL;
+`
+
+var addChatsClosedAtColumn = `
+	ALTER TABLE chats ADD COLUMN closed_at DATETIME NULL;
+`
+
+var addMessagesCreatedAtColumn = `
+	ALTER TABLE messages ADD COLUMN created_at DATETIME NULL;
+`
+
+var createAuditLogTable = `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id            VARCHAR(36)  NOT NULL PRIMARY KEY,
+		actor         VARCHAR(255) NOT NULL,
+		role          VARCHAR(32)  NOT NULL,
+		action        VARCHAR(64)  NOT NULL,
+		resource_type VARCHAR(32)  NOT NULL,
+		resource_id   VARCHAR(255) NOT NULL,
+		outcome       VARCHAR(16)  NOT NULL,
+		details       TEXT         NOT NULL,
+		created_at    DATETIME     NOT NULL
+	);
+`
+
+var addChatsProductColumn = `
+	ALTER TABLE chats ADD COLUMN product VARCHAR(255) NOT NULL DEFAULT '';
+`
+
+var addChatsReviewedAtColumn = `
+	ALTER TABLE chats ADD COLUMN reviewed_at DATETIME NULL;
+`
+
+var createExportJobsTable = `
+	CREATE TABLE IF NOT EXISTS export_jobs (
+		id           VARCHAR(36)   NOT NULL PRIMARY KEY,
+		requested_by VARCHAR(255)  NOT NULL,
+		user_id      VARCHAR(36)   NOT NULL,
+		email        VARCHAR(255)  NOT NULL,
+		from_date    DATETIME      NOT NULL,
+		to_date      DATETIME      NOT NULL,
+		format       VARCHAR(16)   NOT NULL,
+		status       VARCHAR(32)   NOT NULL,
+		chats        INTEGER       NOT NULL DEFAULT 0,
+		file_path    VARCHAR(1024) NOT NULL,
+		last_error   TEXT          NOT NULL,
+		created_at   DATETIME      NOT NULL,
+		completed_at DATETIME      NULL
+	);
+`
+
+var createMessageAnalysesTable = `
+	CREATE TABLE IF NOT EXISTS message_analyses (
+		message_id VARCHAR(36)  NOT NULL PRIMARY KEY,
+		chat_id    VARCHAR(36)  NOT NULL,
+		sentiment  DOUBLE       NOT NULL,
+		intent     VARCHAR(32)  NOT NULL,
+		language   VARCHAR(16)  NOT NULL,
+		classifier VARCHAR(32)  NOT NULL,
+		created_at DATETIME     NOT NULL
+	);
+`
+
+var addMessagesPIIColumn = `
+	ALTER TABLE messages ADD COLUMN pii TEXT NULL;
+`
+
+var addUsersCreatedAtColumn = `
+	ALTER TABLE users ADD COLUMN created_at DATETIME NULL;
+`
+
+var addUsersVersionColumn = `
+	ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+`
+
+var addUsersUpdatedAtColumn = `
+	ALTER TABLE users ADD COLUMN updated_at DATETIME NULL;
+`
+
+var addUsersDeletedAtColumn = `
+	ALTER TABLE users ADD COLUMN deleted_at DATETIME NULL;
+`
+
+// lowerCaseUsersEmail skips the emails that only differ in case, those must be merged by hand
+var lowerCaseUsersEmail = `
+	UPDATE users SET email = LOWER(email)
+	WHERE LOWER(email) NOT IN (
+		SELECT lowered FROM (
+			SELECT LOWER(email) AS lowered FROM users GROUP BY LOWER(email) HAVING COUNT(*) > 1
+		) AS duplicated
+	);
+`
+
+var createTenantsTable = `
+	CREATE TABLE IF NOT EXISTS tenants (
+		id            VARCHAR(36)  NOT NULL PRIMARY KEY,
+		name          VARCHAR(255) NOT NULL,
+		domain        VARCHAR(255) NOT NULL UNIQUE,
+		support_email VARCHAR(255) NOT NULL,
+		prompt        TEXT         NOT NULL,
+		policies      TEXT         NOT NULL,
+		model         VARCHAR(64)  NOT NULL,
+		temperature   DOUBLE       NULL,
+		api_key_hash  VARCHAR(64)  NOT NULL UNIQUE,
+		created_at    DATETIME     NOT NULL
+	);
+`
+
+// the rows created before the tenants belong to the default tenant, configured by the API
+var addUsersTenantColumn = `
+	ALTER TABLE users ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+// the same email can be a customer of several tenants
+var replaceUsersEmailIndex = `
+	ALTER TABLE users DROP INDEX email, ADD UNIQUE INDEX users_tenant_email (tenant_id, email);
+`
+
+var addChatsTenantColumn = `
+	ALTER TABLE chats ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+var addMessagesTenantColumn = `
+	ALTER TABLE messages ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+var addReviewJobsTenantColumn = `
+	ALTER TABLE review_jobs ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+// the order ids are only unique in the tenant store
+var replaceReviewJobsOrderIndex = `
+	ALTER TABLE review_jobs DROP INDEX order_id, ADD UNIQUE INDEX review_jobs_tenant_order (tenant_id, order_id);
+`
+
+var addReviewInvitationsTenantColumn = `
+	ALTER TABLE review_invitations ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+// the endpoints registered before the tenants receive the events of the default tenant
+var addWebhookEndpointsTenantColumn = `
+	ALTER TABLE webhook_endpoints ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+var addWebhookDeliveriesTenantColumn = `
+	ALTER TABLE webhook_deliveries ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+var addExportJobsTenantColumn = `
+	ALTER TABLE export_jobs ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+var addAuditLogTenantColumn = `
+	ALTER TABLE audit_log ADD COLUMN tenant_id VARCHAR(36) NOT NULL DEFAULT 'default';
+`
+
+// the running jobs that started long ago were left by a stopped instance and are run again
+var addExportJobsStartedAtColumn = `
+	ALTER TABLE export_jobs ADD COLUMN started_at DATETIME NULL;
+`
+
+// the invitation links are single use, the review starts once per invitation
+var addReviewInvitationsUsedAtColumn = `
+	ALTER TABLE review_invitations ADD COLUMN used_at DATETIME NULL;
+`
+
+var sqliteCreateUsersEmailIndex = `
+	CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_email ON users (tenant_id, email);
+`
+
+var sqliteCreateReviewJobsOrderIndex = `
+	CREATE UNIQUE INDEX IF NOT EXISTS review_jobs_tenant_order ON review_jobs (tenant_id, order_id);
+`
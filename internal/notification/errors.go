@@ -0,0 +1,14 @@
+package notification
+
+import "errors"
+
+var (
+	ErrCantSaveInvitation    = errors.New("failed to create new review invitation. Cause: can't save the invitation")
+	ErrCantUpdateInvitation  = errors.New("failed to update review invitation. Cause: can't update the invitation")
+	ErrMissingRequiredFields = errors.New("missing required fields")
+	ErrNotificationsDisabled = errors.New("email notifications are disabled")
+	ErrInvalidSignature      = errors.New("invalid invitation signature")
+	ErrInvitationExpired     = errors.New("the invitation link expired")
+	ErrInvitationUsed        = errors.New("the invitation was already used")
+	ErrSMTPAuthNotSupported  = errors.New("the SMTP server doesn't support AUTH")
+)
@@ -0,0 +1,244 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"strconv"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+)
+
+const (
+	InvitationStatusPending = "pending"
+	InvitationStatusSent    = "sent"
+	InvitationStatusOpened  = "opened"
+	InvitationStatusFailed  = "failed"
+	InvitationStatusUsed    = "used"
+)
+
+type repository interface {
+	CreateInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) (datatypes.ReviewInvitation, error)
+	FindInvitationByID(ctx context.Context, id string) (datatypes.ReviewInvitation, error)
+	UpdateInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) error
+	UseInvitation(ctx context.Context, id string, usedAt time.Time) (bool, error)
+}
+
+type sender interface {
+	Send(ctx context.Context, email Email) error
+}
+
+type NotificationServiceConfig struct {
+	// BaseURL is the public URL of the API used to build the invitation links
+	BaseURL string
+	// LinkSecret is the key used to sign the invitation links
+	LinkSecret string
+	// LinkTTL is how long the invitation links can be opened after the invitation is created
+	LinkTTL time.Duration
+}
+
+type NotificationService struct {
+	repository   repository
+	sender       sender
+	config       NotificationServiceConfig
+	subject      *texttemplate.Template
+	textTemplate *texttemplate.Template
+	htmlTemplate *htmltemplate.Template
+	now          func() time.Time
+}
+
+// NewNotificationService create a new notification service. Emails are disabled when sender is nil.
+func NewNotificationService(
+	repository repository,
+	sender sender,
+	config NotificationServiceConfig,
+) *NotificationService {
+	if config.LinkTTL <= 0 {
+		config.LinkTTL = 7 * 24 * time.Hour
+	}
+
+	return &NotificationService{
+		repository:   repository,
+		sender:       sender,
+		config:       config,
+		subject:      texttemplate.Must(texttemplate.New("subject").Parse(invitationSubjectTemplate)),
+		textTemplate: texttemplate.Must(texttemplate.New("text").Parse(invitationTextTemplate)),
+		htmlTemplate: htmltemplate.Must(htmltemplate.New("html").Parse(invitationHTMLTemplate)),
+		now:          func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// SendReviewInvitation emails the user an invitation to start the review on the web chat
+func (ns *NotificationService) SendReviewInvitation(
+	ctx context.Context,
+	name string,
+	email string,
+	product string,
+) (datatypes.ReviewInvitation, error) {
+	if ns.sender == nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to send review invitation. Cause: %w", ErrNotificationsDisabled)
+	}
+
+	if name == "" || email == "" || product == "" {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to send review invitation. Cause: %w", ErrMissingRequiredFields)
+	}
+
+	invitation, err := ns.repository.CreateInvitation(ctx, datatypes.ReviewInvitation{
+		TenantID:  tenant.IDFromContext(ctx),
+		UserName:  name,
+		UserEmail: email,
+		Product:   product,
+		Status:    InvitationStatusPending,
+	})
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to send review invitation. Cause: %w", err)
+	}
+
+	message, err := ns.renderInvitation(ctx, invitation)
+	if err == nil {
+		err = ns.sender.Send(ctx, message)
+	}
+
+	if err != nil {
+		invitation.Status = InvitationStatusFailed
+		invitation.LastError = err.Error()
+	} else {
+		sentAt := ns.now()
+		invitation.Status = InvitationStatusSent
+		invitation.SentAt = &sentAt
+	}
+
+	if updateErr := ns.repository.UpdateInvitation(ctx, invitation); updateErr != nil && err == nil {
+		err = updateErr
+	}
+
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to send review invitation. Cause: %w", err)
+	}
+	return invitation, nil
+}
+
+// OpenInvitation checks the link signature and expiration and tracks the first time the invitation
+// was opened. Used invitations can't be opened again.
+func (ns *NotificationService) OpenInvitation(
+	ctx context.Context,
+	id string,
+	expires string,
+	signature string,
+) (datatypes.ReviewInvitation, error) {
+	if err := ns.checkLink(id, expires, signature); err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to open review invitation. Cause: %w", err)
+	}
+
+	invitation, err := ns.repository.FindInvitationByID(ctx, id)
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to open review invitation. Cause: %w", err)
+	}
+
+	if invitation.UsedAt != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to open review invitation. Cause: %w", ErrInvitationUsed)
+	}
+
+	if invitation.OpenedAt == nil {
+		openedAt := ns.now()
+		invitation.Status = InvitationStatusOpened
+		invitation.OpenedAt = &openedAt
+
+		if err := ns.repository.UpdateInvitation(ctx, invitation); err != nil {
+			return datatypes.ReviewInvitation{}, fmt.Errorf("failed to open review invitation. Cause: %w", err)
+		}
+	}
+
+	return invitation, nil
+}
+
+// UseInvitation marks the opened invitation as used, so its review starts only once. Returns
+// ErrInvitationUsed when another connection used it first.
+func (ns *NotificationService) UseInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) error {
+	used, err := ns.repository.UseInvitation(ctx, invitation.ID, ns.now())
+	if err != nil {
+		return fmt.Errorf("failed to use review invitation. Cause: %w", err)
+	}
+
+	if !used {
+		return fmt.Errorf("failed to use review invitation. Cause: %w", ErrInvitationUsed)
+	}
+	return nil
+}
+
+// InvitationLink returns the signed link sent to the user. It expires LinkTTL after the invitation was created.
+func (ns *NotificationService) InvitationLink(invitation datatypes.ReviewInvitation) string {
+	expires := strconv.FormatInt(invitation.CreatedAt.Add(ns.config.LinkTTL).Unix(), 10)
+
+	return fmt.Sprintf(
+		"%s/api/invitations/%s/open?exp=%s&signature=%s",
+		ns.config.BaseURL,
+		url.PathEscape(invitation.ID),
+		expires,
+		ns.sign(invitation.ID, expires),
+	)
+}
+
+// checkLink checks the signature of the invitation id and expiration, then the expiration itself
+func (ns *NotificationService) checkLink(id string, expires string, signature string) error {
+	if !hmac.Equal([]byte(ns.sign(id, expires)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !ns.now().Before(time.Unix(expiresAt, 0)) {
+		return ErrInvitationExpired
+	}
+	return nil
+}
+
+// renderInvitation renders the invitation email templates signed by the store of the tenant
+func (ns *NotificationService) renderInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) (Email, error) {
+	var subject, text, html bytes.Buffer
+
+	store, _ := tenant.FromContext(ctx)
+	data := map[string]string{
+		"Store":   store.Name,
+		"Name":    invitation.UserName,
+		"Product": invitation.Product,
+		"Link":    ns.InvitationLink(invitation),
+	}
+
+	if err := ns.subject.Execute(&subject, data); err != nil {
+		return Email{}, err
+	}
+
+	if err := ns.textTemplate.Execute(&text, data); err != nil {
+		return Email{}, err
+	}
+
+	if err := ns.htmlTemplate.Execute(&html, data); err != nil {
+		return Email{}, err
+	}
+
+	return Email{
+		To:      invitation.UserEmail,
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// sign returns the HMAC-SHA256 signature of the invitation id and expiration
+func (ns *NotificationService) sign(id string, expires string) string {
+	mac := hmac.New(sha256.New, []byte(ns.config.LinkSecret))
+	mac.Write([]byte(id + "." + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
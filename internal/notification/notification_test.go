@@ -0,0 +1,234 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repositoryMock struct {
+	Error                      error
+	CallbackCreateInvitation   func(ctx context.Context, invitation datatypes.ReviewInvitation) (datatypes.ReviewInvitation, error)
+	CallbackFindInvitationByID func(ctx context.Context, id string) (datatypes.ReviewInvitation, error)
+	CallbackUpdateInvitation   func(ctx context.Context, invitation datatypes.ReviewInvitation) error
+	CallbackUseInvitation      func(ctx context.Context, id string, usedAt time.Time) (bool, error)
+}
+
+func (rm *repositoryMock) CreateInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) (datatypes.ReviewInvitation, error) {
+	if rm.CallbackCreateInvitation != nil {
+		return rm.CallbackCreateInvitation(ctx, invitation)
+	}
+	return datatypes.ReviewInvitation{}, rm.Error
+}
+
+func (rm *repositoryMock) FindInvitationByID(ctx context.Context, id string) (datatypes.ReviewInvitation, error) {
+	if rm.CallbackFindInvitationByID != nil {
+		return rm.CallbackFindInvitationByID(ctx, id)
+	}
+	return datatypes.ReviewInvitation{}, rm.Error
+}
+
+func (rm *repositoryMock) UpdateInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) error {
+	if rm.CallbackUpdateInvitation != nil {
+		return rm.CallbackUpdateInvitation(ctx, invitation)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) UseInvitation(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	if rm.CallbackUseInvitation != nil {
+		return rm.CallbackUseInvitation(ctx, id, usedAt)
+	}
+	return rm.Error == nil, rm.Error
+}
+
+type senderMock struct {
+	Error        error
+	CallbackSend func(ctx context.Context, email Email) error
+}
+
+func (sm *senderMock) Send(ctx context.Context, email Email) error {
+	if sm.CallbackSend != nil {
+		return sm.CallbackSend(ctx, email)
+	}
+	return sm.Error
+}
+
+func TestServiceSendReviewInvitation(t *testing.T) {
+	config := NotificationServiceConfig{BaseURL: "https://chat.aitechshop.com", LinkSecret: "secret"}
+
+	t.Run("should send the invitation with a signed link", func(t *testing.T) {
+		var (
+			sent    Email
+			updated datatypes.ReviewInvitation
+		)
+
+		service := NewNotificationService(&repositoryMock{
+			CallbackCreateInvitation: func(ctx context.Context, invitation datatypes.ReviewInvitation) (datatypes.ReviewInvitation, error) {
+				invitation.ID = "qwerty"
+				invitation.CreatedAt = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+				return invitation, nil
+			},
+			CallbackUpdateInvitation: func(ctx context.Context, invitation datatypes.ReviewInvitation) error {
+				updated = invitation
+				return nil
+			},
+		}, &senderMock{
+			CallbackSend: func(ctx context.Context, email Email) error {
+				sent = email
+				return nil
+			},
+		}, config)
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "acme", Name: "Acme Store"})
+		invitation, err := service.SendReviewInvitation(ctx, "Mary Ann", "mary@continental.com", "Galaxy S24")
+		assert.NoError(t, err)
+		assert.Equal(t, InvitationStatusSent, invitation.Status)
+		assert.Equal(t, "acme", invitation.TenantID)
+		assert.NotNil(t, updated.SentAt)
+
+		link := service.InvitationLink(invitation)
+		assert.Equal(t, "mary@continental.com", sent.To)
+		assert.Equal(t, "How was your new Galaxy S24?", sent.Subject)
+		assert.Contains(t, sent.Text, link)
+		assert.Contains(t, sent.HTML, "Mary Ann")
+		assert.Contains(t, sent.Text, "Thank you for shopping with Acme Store!")
+
+		parsedLink, err := url.Parse(link)
+		require.NoError(t, err)
+		expires := strconv.FormatInt(time.Date(2024, 3, 8, 10, 0, 0, 0, time.UTC).Unix(), 10)
+		assert.Equal(t, "/api/invitations/qwerty/open", parsedLink.Path)
+		assert.Equal(t, expires, parsedLink.Query().Get("exp"))
+		assert.Equal(t, service.sign("qwerty", expires), parsedLink.Query().Get("signature"))
+	})
+
+	t.Run("should track the failure when the email can't be sent", func(t *testing.T) {
+		var updated datatypes.ReviewInvitation
+		errSend := errors.New("error to send email for tests")
+
+		service := NewNotificationService(&repositoryMock{
+			CallbackCreateInvitation: func(ctx context.Context, invitation datatypes.ReviewInvitation) (datatypes.ReviewInvitation, error) {
+				return invitation, nil
+			},
+			CallbackUpdateInvitation: func(ctx context.Context, invitation datatypes.ReviewInvitation) error {
+				updated = invitation
+				return nil
+			},
+		}, &senderMock{Error: errSend}, config)
+
+		_, err := service.SendReviewInvitation(context.Background(), "john", "john.wick@continental.com", "Galaxy S24")
+		assert.ErrorIs(t, err, errSend)
+		assert.Equal(t, InvitationStatusFailed, updated.Status)
+		assert.Equal(t, errSend.Error(), updated.LastError)
+	})
+
+	t.Run("should fail when emails are disabled", func(t *testing.T) {
+		service := NewNotificationService(&repositoryMock{}, nil, config)
+
+		_, err := service.SendReviewInvitation(context.Background(), "john", "john.wick@continental.com", "Galaxy S24")
+		assert.ErrorIs(t, err, ErrNotificationsDisabled)
+	})
+}
+
+func TestServiceOpenInvitation(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	expires := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+
+	t.Run("should mark the invitation as opened only once", func(t *testing.T) {
+		updates := 0
+		invitation := datatypes.ReviewInvitation{ID: "qwerty", Status: InvitationStatusSent}
+
+		service := NewNotificationService(&repositoryMock{
+			CallbackFindInvitationByID: func(ctx context.Context, id string) (datatypes.ReviewInvitation, error) {
+				return invitation, nil
+			},
+			CallbackUpdateInvitation: func(ctx context.Context, updated datatypes.ReviewInvitation) error {
+				updates++
+				invitation = updated
+				return nil
+			},
+		}, &senderMock{}, NotificationServiceConfig{LinkSecret: "secret"})
+		service.now = func() time.Time { return now }
+
+		signature := service.sign("qwerty", expires)
+
+		opened, err := service.OpenInvitation(context.Background(), "qwerty", expires, signature)
+		assert.NoError(t, err)
+		assert.Equal(t, InvitationStatusOpened, opened.Status)
+
+		_, err = service.OpenInvitation(context.Background(), "qwerty", expires, signature)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updates)
+	})
+
+	t.Run("should fail when the signature is invalid", func(t *testing.T) {
+		service := NewNotificationService(&repositoryMock{
+			Error: errors.New("invalid repository call when testing"),
+		}, &senderMock{}, NotificationServiceConfig{LinkSecret: "secret"})
+		service.now = func() time.Time { return now }
+
+		_, err := service.OpenInvitation(context.Background(), "qwerty", expires, "wrong")
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("should fail when the expiration was changed", func(t *testing.T) {
+		service := NewNotificationService(&repositoryMock{
+			Error: errors.New("invalid repository call when testing"),
+		}, &senderMock{}, NotificationServiceConfig{LinkSecret: "secret"})
+		service.now = func() time.Time { return now }
+
+		later := strconv.FormatInt(now.Add(24*time.Hour).Unix(), 10)
+		_, err := service.OpenInvitation(context.Background(), "qwerty", later, service.sign("qwerty", expires))
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+	})
+
+	t.Run("should fail when the link expired", func(t *testing.T) {
+		service := NewNotificationService(&repositoryMock{
+			Error: errors.New("invalid repository call when testing"),
+		}, &senderMock{}, NotificationServiceConfig{LinkSecret: "secret"})
+		service.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+		_, err := service.OpenInvitation(context.Background(), "qwerty", expires, service.sign("qwerty", expires))
+		assert.ErrorIs(t, err, ErrInvitationExpired)
+	})
+
+	t.Run("should fail when the invitation was used", func(t *testing.T) {
+		usedAt := now.Add(-time.Minute)
+		service := NewNotificationService(&repositoryMock{
+			CallbackFindInvitationByID: func(ctx context.Context, id string) (datatypes.ReviewInvitation, error) {
+				return datatypes.ReviewInvitation{ID: id, Status: InvitationStatusUsed, UsedAt: &usedAt}, nil
+			},
+		}, &senderMock{}, NotificationServiceConfig{LinkSecret: "secret"})
+		service.now = func() time.Time { return now }
+
+		_, err := service.OpenInvitation(context.Background(), "qwerty", expires, service.sign("qwerty", expires))
+		assert.ErrorIs(t, err, ErrInvitationUsed)
+	})
+}
+
+func TestServiceUseInvitation(t *testing.T) {
+	t.Run("should use the invitation once", func(t *testing.T) {
+		used := map[string]bool{}
+		service := NewNotificationService(&repositoryMock{
+			CallbackUseInvitation: func(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+				if used[id] {
+					return false, nil
+				}
+				used[id] = true
+				return true, nil
+			},
+		}, &senderMock{}, NotificationServiceConfig{LinkSecret: "secret"})
+
+		invitation := datatypes.ReviewInvitation{ID: "qwerty"}
+		assert.NoError(t, service.UseInvitation(context.Background(), invitation))
+		assert.ErrorIs(t, service.UseInvitation(context.Background(), invitation), ErrInvitationUsed)
+	})
+}
@@ -0,0 +1,21 @@
+package notification
+
+var createInvitation = `
+	INSERT INTO review_invitations (id, tenant_id, user_name, user_email, product, status, last_error, created_at)
+	VALUES (:id, :tenant_id, :user_name, :user_email, :product, :status, :last_error, :created_at);
+`
+
+var findInvitationByID = `
+	SELECT id, tenant_id, user_name, user_email, product, status, last_error, sent_at, opened_at, used_at, created_at
+	FROM review_invitations WHERE id = :id;
+`
+
+var updateInvitation = `
+	UPDATE review_invitations
+	SET status = :status, last_error = :last_error, sent_at = :sent_at, opened_at = :opened_at
+	WHERE id = :id;
+`
+
+var useInvitation = `
+	UPDATE review_invitations SET status = :status, used_at = :used_at WHERE id = :id AND used_at IS NULL;
+`
@@ -0,0 +1,145 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofrs/uuid/v5"
+)
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// CreateInvitation persists a new review invitation
+func (r *Repository) CreateInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) (datatypes.ReviewInvitation, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, createInvitation)
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to create new review invitation. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to create new review invitation. Cause: %w", err)
+	}
+
+	invitation.ID = id.String()
+	invitation.CreatedAt = time.Now().UTC()
+
+	params := map[string]interface{}{
+		"id":         invitation.ID,
+		"tenant_id":  invitation.TenantID,
+		"user_name":  invitation.UserName,
+		"user_email": invitation.UserEmail,
+		"product":    invitation.Product,
+		"status":     invitation.Status,
+		"last_error": invitation.LastError,
+		"created_at": invitation.CreatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to create new review invitation. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to create new review invitation. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return datatypes.ReviewInvitation{}, ErrCantSaveInvitation
+	}
+
+	return invitation, nil
+}
+
+// FindInvitationByID finds a review invitation by id
+func (r *Repository) FindInvitationByID(ctx context.Context, id string) (datatypes.ReviewInvitation, error) {
+	var invitation datatypes.ReviewInvitation
+
+	stm, err := r.db.PrepareNamedContext(ctx, findInvitationByID)
+	if err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to find review invitation. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id": id,
+	}
+
+	if err = stm.GetContext(ctx, &invitation, params); err != nil {
+		return datatypes.ReviewInvitation{}, fmt.Errorf("failed to find review invitation. Cause: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// UpdateInvitation updates the tracking state of a review invitation
+func (r *Repository) UpdateInvitation(ctx context.Context, invitation datatypes.ReviewInvitation) error {
+	stm, err := r.db.PrepareNamedContext(ctx, updateInvitation)
+	if err != nil {
+		return fmt.Errorf("failed to update review invitation. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":         invitation.ID,
+		"status":     invitation.Status,
+		"last_error": invitation.LastError,
+		"sent_at":    invitation.SentAt,
+		"opened_at":  invitation.OpenedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to update review invitation. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update review invitation. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantUpdateInvitation
+	}
+
+	return nil
+}
+
+// UseInvitation marks the invitation as used. Returns false when it was already used.
+func (r *Repository) UseInvitation(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, useInvitation)
+	if err != nil {
+		return false, fmt.Errorf("failed to use review invitation. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":      id,
+		"status":  InvitationStatusUsed,
+		"used_at": usedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("failed to use review invitation. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use review invitation. Cause: %w", err)
+	}
+
+	return rows > 0, nil
+}
@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateInvitation(t *testing.T) {
+	t.Run("should create a new invitation", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		invitation, err := repository.CreateInvitation(context.Background(), datatypes.ReviewInvitation{UserEmail: "john.wick@continental.com"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, invitation.ID)
+	})
+
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.CreateInvitation(context.Background(), datatypes.ReviewInvitation{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCantSaveInvitation)
+	})
+}
+
+func TestRepositoryFindInvitationByID(t *testing.T) {
+	t.Run("should fail run get context", func(t *testing.T) {
+		errGetContext := errors.New("error to run get context for tests")
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest, arg interface{}) error {
+						return errGetContext
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.FindInvitationByID(context.Background(), "qwerty")
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, errGetContext)
+	})
+}
+
+func TestRepositoryUpdateInvitation(t *testing.T) {
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.UpdateInvitation(context.Background(), datatypes.ReviewInvitation{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCantUpdateInvitation)
+	})
+}
+
+func TestRepositoryUseInvitation(t *testing.T) {
+	for rows, expected := range map[int64]bool{1: true, 0: false} {
+		t.Run(fmt.Sprintf("should report %t when %d rows are used", expected, rows), func(t *testing.T) {
+			repository := NewRepository(&godb.DBMock{
+				CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+					assert.Contains(t, query, "used_at IS NULL")
+					return &godb.NamedStmtMock{
+						CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+							assert.Equal(t, InvitationStatusUsed, arg.(map[string]interface{})["status"])
+							return &godb.ResultMock{
+								CallbackRowsAffected: func() (int64, error) {
+									return rows, nil
+								},
+							}, nil
+						},
+					}, nil
+				},
+			})
+
+			used, err := repository.UseInvitation(context.Background(), "qwerty", time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, expected, used)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// Email is a multipart email with a text and an HTML version
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type SMTPSenderConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// Timeout limits the whole conversation with the server, from the dial to the QUIT
+	Timeout time.Duration
+}
+
+type SMTPSender struct {
+	config SMTPSenderConfig
+}
+
+// NewSMTPSender create a new SMTP sender
+func NewSMTPSender(config SMTPSenderConfig) *SMTPSender {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &SMTPSender{config}
+}
+
+// Send sends the email through the SMTP server. It stops when the context is done or the timeout
+// expires, whichever comes first.
+func (ss *SMTPSender) Send(ctx context.Context, email Email) error {
+	message, err := ss.buildMessage(email)
+	if err != nil {
+		return fmt.Errorf("failed to send email. Cause: %w", err)
+	}
+
+	if err := ss.send(ctx, email.To, message); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to send email. Cause: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to send email. Cause: %w", err)
+	}
+	return nil
+}
+
+// send runs the steps of smtp.SendMail on a connection bounded by the context and the timeout
+func (ss *SMTPSender) send(ctx context.Context, to string, message []byte) error {
+	dialer := net.Dialer{Timeout: ss.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ss.config.Host, ss.config.Port))
+	if err != nil {
+		return err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(ss.config.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	// a done context unblocks the reads and writes in progress
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, ss.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: ss.config.Host}); err != nil {
+			return err
+		}
+	}
+
+	if ss.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return ErrSMTPAuthNotSupported
+		}
+		if err := client.Auth(smtp.PlainAuth("", ss.config.Username, ss.config.Password, ss.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(ss.config.From); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage builds the multipart/alternative MIME message
+func (ss *SMTPSender) buildMessage(email Email) ([]byte, error) {
+	var (
+		body   bytes.Buffer
+		header bytes.Buffer
+	)
+
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=UTF-8", content: email.Text},
+		{contentType: "text/html; charset=UTF-8", content: email.HTML},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qpWriter := quotedprintable.NewWriter(partWriter)
+		if _, err := qpWriter.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qpWriter.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&header, "From: %s\r\n", ss.config.From)
+	fmt.Fprintf(&header, "To: %s\r\n", email.To)
+	fmt.Fprintf(&header, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", email.Subject))
+	fmt.Fprintf(&header, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&header, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&header, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+
+	return append(header.Bytes(), body.Bytes()...), nil
+}
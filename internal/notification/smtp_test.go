@@ -0,0 +1,142 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSMTPStandIn starts a minimal SMTP server that accepts a single email
+func startSMTPStandIn(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		write("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				write("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				write("354 end data with <CR><LF>.<CR><LF>")
+
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				received <- data.String()
+				write("250 OK")
+			case strings.HasPrefix(command, "QUIT"):
+				write("221 bye")
+				return
+			default:
+				write("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+// startSilentSMTPServer starts a server that accepts the connections and never answers
+func startSilentSMTPServer(t *testing.T) (string, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	return host, port
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	t.Run("should send a multipart email", func(t *testing.T) {
+		address, received := startSMTPStandIn(t)
+		host, port, err := net.SplitHostPort(address)
+		require.NoError(t, err)
+
+		sender := NewSMTPSender(SMTPSenderConfig{
+			Host: host,
+			Port: port,
+			From: "support@aitechshop.com",
+		})
+
+		err = sender.Send(context.Background(), Email{
+			To:      "john.wick@continental.com",
+			Subject: "How was your new Galaxy S24?",
+			Text:    "plain version",
+			HTML:    "<p>html version</p>",
+		})
+		require.NoError(t, err)
+
+		message := <-received
+		assert.Contains(t, message, "To: john.wick@continental.com")
+		assert.Contains(t, message, "Content-Type: multipart/alternative")
+		assert.Contains(t, message, "plain version")
+		assert.Contains(t, message, "<p>html version</p>")
+	})
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		host, port := startSilentSMTPServer(t)
+		sender := NewSMTPSender(SMTPSenderConfig{Host: host, Port: port, From: "support@aitechshop.com"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		startedAt := time.Now()
+		err := sender.Send(ctx, Email{To: "john.wick@continental.com"})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(startedAt), 5*time.Second)
+	})
+
+	t.Run("should stop when the server doesn't answer in time", func(t *testing.T) {
+		host, port := startSilentSMTPServer(t)
+		sender := NewSMTPSender(SMTPSenderConfig{
+			Host:    host,
+			Port:    port,
+			From:    "support@aitechshop.com",
+			Timeout: 50 * time.Millisecond,
+		})
+
+		startedAt := time.Now()
+		err := sender.Send(context.Background(), Email{To: "john.wick@continental.com"})
+		assert.Error(t, err)
+		assert.Less(t, time.Since(startedAt), 5*time.Second)
+	})
+}
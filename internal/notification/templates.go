@@ -0,0 +1,26 @@
+package notification
+
+var invitationSubjectTemplate = `How was your new {{.Product}}?`
+
+var invitationTextTemplate = `Hi {{.Name}},
+
+Thank you for shopping with {{.Store}}! We would love to hear about your experience with your new {{.Product}}.
+
+Our assistant Mark is waiting for you. It only takes a couple of minutes:
+{{.Link}}
+
+Best regards,
+{{.Store}}
+`
+
+var invitationHTMLTemplate = `<!DOCTYPE html>
+<html lang="en-US">
+<body style="font-family: sans-serif;">
+    <p>Hi {{.Name}},</p>
+    <p>Thank you for shopping with {{.Store}}! We would love to hear about your experience with your new <strong>{{.Product}}</strong>.</p>
+    <p>Our assistant Mark is waiting for you. It only takes a couple of minutes:</p>
+    <p><a href="{{.Link}}" style="padding: 10px; border-radius: 5px; background-color: #007bff; color: #fff; text-decoration: none;">Start my review</a></p>
+    <p>Best regards,<br>{{.Store}}</p>
+</body>
+</html>
+`
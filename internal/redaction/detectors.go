@@ -0,0 +1,157 @@
+package redaction
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	KindEmail   = "EMAIL"
+	KindPhone   = "PHONE"
+	KindCard    = "CARD"
+	KindAddress = "ADDRESS"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	// phonePattern needs the structure of a phone number, so order numbers and product specs aren't
+	// taken for one: a leading +, an area code in parentheses or digit groups ending in -1234 or .1234
+	phonePattern = regexp.MustCompile(`\+\d{1,3}(?:[\s.\-]?(?:\(\d{1,4}\)|\d{2,5})){1,5}\b` +
+		`|\(\d{2,4}\)[\s.\-]?\d{3,5}[\s.\-]?\d{4}\b` +
+		`|\b\d{2,4}[\s.\-]\d{3,5}[.\-]\d{4}\b`)
+	datePattern = regexp.MustCompile(`^(?:\d{4}[\-/.]\d{1,2}[\-/.]\d{1,2}|\d{1,2}[\-/.]\d{1,2}[\-/.]\d{2,4})$`)
+
+	// addressPattern needs a street name starting with a capital letter or a digit between the number
+	// and the street type, so "a 2 way splitter" or "a 10 min drive" aren't taken for an address
+	addressPattern = regexp.MustCompile(`(?i)\b\d{1,5}\s+(?-i:[\p{Lu}0-9])[\p{L}0-9.'\-]*\s+(?:[\p{L}0-9.'\-]+\s+){0,3}` +
+		`(?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|square|sq)\b\.?` +
+		`|\b(?:rua|avenida|av\.|travessa|alameda|calle|carrera)\s+[\p{L}0-9.'\- ]{2,40},?\s*(?:n[º°o]\.?\s*)?\d{1,5}\b`)
+)
+
+// Detector finds one kind of personal data in a text
+type Detector interface {
+	// Kind is the label used in the tokens, e.g. EMAIL for [EMAIL_1]
+	Kind() string
+	// Find returns the start and end indexes of every match
+	Find(text string) [][]int
+}
+
+// RegexDetector detects the personal data matching a regular expression.
+// Matches rejected by the validate func are ignored.
+type RegexDetector struct {
+	kind     string
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+// NewRegexDetector create a new regex detector. validate is optional.
+func NewRegexDetector(kind string, pattern *regexp.Regexp, validate func(match string) bool) *RegexDetector {
+	return &RegexDetector{
+		kind:     kind,
+		pattern:  pattern,
+		validate: validate,
+	}
+}
+
+// Kind
+func (rd *RegexDetector) Kind() string {
+	return rd.kind
+}
+
+// Find
+func (rd *RegexDetector) Find(text string) [][]int {
+	matches := [][]int{}
+	for _, match := range rd.pattern.FindAllStringIndex(text, -1) {
+		if rd.validate == nil || rd.validate(text[match[0]:match[1]]) {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// NewEmailDetector detects email addresses
+func NewEmailDetector() *RegexDetector {
+	return NewRegexDetector(KindEmail, emailPattern, nil)
+}
+
+// NewCardDetector detects payment card numbers with 13 to 19 digits passing the Luhn check
+func NewCardDetector() *RegexDetector {
+	return NewRegexDetector(KindCard, cardPattern, func(match string) bool {
+		return isValidLuhn(digits(match))
+	})
+}
+
+// NewPhoneDetector detects phone numbers with 7 to 15 digits, like "+55 11 98765-4321",
+// "(212) 555-0199" or "212-555-0199". Plain runs of digits and dates are ignored.
+func NewPhoneDetector() *RegexDetector {
+	return NewRegexDetector(KindPhone, phonePattern, func(match string) bool {
+		count := len(digits(match))
+		return count >= 7 && count <= 15 && !datePattern.MatchString(match)
+	})
+}
+
+// NewAddressDetector detects street addresses like "221 Baker Street", "350 5th Avenue" or "Rua Augusta, 1500"
+func NewAddressDetector() *RegexDetector {
+	return NewRegexDetector(KindAddress, addressPattern, nil)
+}
+
+// NewDetectors creates the detectors by name (email, card, phone or address)
+func NewDetectors(names ...string) ([]Detector, error) {
+	detectors := make([]Detector, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case "email":
+			detectors = append(detectors, NewEmailDetector())
+		case "card":
+			detectors = append(detectors, NewCardDetector())
+		case "phone":
+			detectors = append(detectors, NewPhoneDetector())
+		case "address":
+			detectors = append(detectors, NewAddressDetector())
+		default:
+			return nil, ErrUnknownDetector
+		}
+	}
+	return detectors, nil
+}
+
+// DefaultDetectors returns every built-in detector. Cards come before phones so long
+// card numbers aren't taken as phone numbers.
+func DefaultDetectors() []Detector {
+	return []Detector{NewEmailDetector(), NewCardDetector(), NewPhoneDetector(), NewAddressDetector()}
+}
+
+// digits removes everything but the digits
+func digits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// isValidLuhn validates the card number check digit
+func isValidLuhn(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,72 @@
+package redaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		detector Detector
+		text     string
+		matches  []string
+	}{
+		{NewEmailDetector(), "write to john.wick@continental.com please", []string{"john.wick@continental.com"}},
+		{NewEmailDetector(), "no email here @ all", nil},
+		{NewCardDetector(), "my card is 4111 1111 1111 1111", []string{"4111 1111 1111 1111"}},
+		{NewCardDetector(), "card 4111-1111-1111-1112 failed the check", nil},
+		{NewCardDetector(), "order 1234567890123", nil},
+		{NewPhoneDetector(), "call me at +55 11 98765-4321", []string{"+55 11 98765-4321"}},
+		{NewPhoneDetector(), "my number is (212) 555-0199", []string{"(212) 555-0199"}},
+		{NewPhoneDetector(), "or +1 (212) 555-0199 at night", []string{"+1 (212) 555-0199"}},
+		{NewPhoneDetector(), "whatsapp +442071234567", []string{"+442071234567"}},
+		{NewPhoneDetector(), "call 212-555-0199 or 212.555.0198", []string{"212-555-0199", "212.555.0198"}},
+		{NewPhoneDetector(), "meu celular é 11 98765-4321", []string{"11 98765-4321"}},
+		{NewPhoneDetector(), "it arrived on 2024-05-01", nil},
+		{NewPhoneDetector(), "I give it a 5", nil},
+		{NewPhoneDetector(), "my order 123456789 hasn't arrived", nil},
+		{NewPhoneDetector(), "order #100234567 and order 2024-000123", nil},
+		{NewPhoneDetector(), "tracking code 7701 2345 6789 01", nil},
+		{NewPhoneDetector(), "the TV does 4000 1080 120 Hz", nil},
+		{NewPhoneDetector(), "the Galaxy S24 Ultra 512 GB for 1299.99", nil},
+		{NewAddressDetector(), "ship it to 221B Baker Street, London", nil},
+		{NewAddressDetector(), "ship it to 221 Baker Street, London", []string{"221 Baker Street"}},
+		{NewAddressDetector(), "the store at 350 5th Avenue", []string{"350 5th Avenue"}},
+		{NewAddressDetector(), "I needed a 2 way splitter", nil},
+		{NewAddressDetector(), "it's a 10 min drive from the 4 star place", nil},
+		{NewAddressDetector(), "the 2 way speaker works", nil},
+		{NewAddressDetector(), "moro na Rua Augusta, 1500", []string{"Rua Augusta, 1500"}},
+	}
+
+	for _, test := range tests {
+		t.Run("should detect the "+test.detector.Kind()+" in "+test.text, func(t *testing.T) {
+			var matches []string
+			for _, index := range test.detector.Find(test.text) {
+				matches = append(matches, test.text[index[0]:index[1]])
+			}
+			assert.Equal(t, test.matches, matches)
+		})
+	}
+}
+
+func TestNewDetectors(t *testing.T) {
+	t.Run("should create the detectors by name", func(t *testing.T) {
+		detectors, err := NewDetectors("email", " card", "PHONE", "address", "")
+		assert.NoError(t, err)
+		assert.Len(t, detectors, 4)
+		assert.Equal(t, KindCard, detectors[1].Kind())
+	})
+
+	t.Run("should fail with an unknown detector", func(t *testing.T) {
+		_, err := NewDetectors("email", "passport")
+		assert.ErrorIs(t, err, ErrUnknownDetector)
+	})
+}
+
+func TestIsValidLuhn(t *testing.T) {
+	assert.True(t, isValidLuhn("4111111111111111"))
+	assert.True(t, isValidLuhn("378282246310005"))
+	assert.False(t, isValidLuhn("4111111111111112"))
+	assert.False(t, isValidLuhn("411111111111"))
+}
@@ -0,0 +1,10 @@
+package redaction
+
+import "errors"
+
+var (
+	ErrUnknownDetector  = errors.New("unknown pii detector")
+	ErrInvalidStoreMode = errors.New("invalid redaction store mode")
+	ErrMissingKey       = errors.New("missing redaction encryption key")
+	ErrInvalidSealedPII = errors.New("invalid sealed pii")
+)
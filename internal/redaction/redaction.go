@@ -0,0 +1,304 @@
+package redaction
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// StorePlain saves the messages as the customer wrote them
+	StorePlain = "plain"
+	// StoreRedacted saves the messages with tokens in place of the personal data
+	StoreRedacted = "redacted"
+	// StoreEncrypted saves the redacted messages with the original values encrypted
+	StoreEncrypted = "encrypted"
+)
+
+var tokenPattern = regexp.MustCompile(`\[[A-Z]+_\d+\]`)
+
+// Tokens maps each token, e.g. [EMAIL_1], to the original value
+type Tokens map[string]string
+
+// Redacted is a text with the personal data replaced by tokens
+type Redacted struct {
+	Text   string
+	Tokens Tokens
+}
+
+type RedactionServiceConfig struct {
+	Detectors []Detector
+	// StoreMode is plain (default), redacted or encrypted
+	StoreMode string
+	// Key encrypts the original values in the encrypted store mode
+	Key string
+}
+
+// validate check if configs are valid
+func (rsc RedactionServiceConfig) validate() error {
+	switch rsc.StoreMode {
+	case StorePlain, StoreRedacted:
+		return nil
+	case StoreEncrypted:
+		if rsc.Key == "" {
+			return ErrMissingKey
+		}
+		return nil
+	}
+	return ErrInvalidStoreMode
+}
+
+type RedactionService struct {
+	detectors []Detector
+	storeMode string
+	aead      cipher.AEAD
+}
+
+// NewRedactionService create a new redaction service
+func NewRedactionService(config RedactionServiceConfig) (*RedactionService, error) {
+	baseError := "failed to create redaction service. Cause: %w"
+
+	if config.StoreMode == "" {
+		config.StoreMode = StorePlain
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf(baseError, err)
+	}
+
+	rs := &RedactionService{
+		detectors: config.Detectors,
+		storeMode: config.StoreMode,
+	}
+
+	if config.Key != "" {
+		key := sha256.Sum256([]byte(config.Key))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, fmt.Errorf(baseError, err)
+		}
+
+		if rs.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf(baseError, err)
+		}
+	}
+
+	return rs, nil
+}
+
+// NewSession starts a tokenization session. The same value gets the same token in every
+// message of the session, so the model can still relate them.
+func (rs *RedactionService) NewSession() *Session {
+	return &Session{
+		detectors: rs.detectors,
+		tokens:    Tokens{},
+		values:    map[string]string{},
+		counters:  map[string]int{},
+		mutex:     &sync.Mutex{},
+	}
+}
+
+// RedactText replaces the personal data of a text that is only logged
+func (rs *RedactionService) RedactText(text string) string {
+	return rs.NewSession().Redact(text).Text
+}
+
+// StoredMessage returns the message and the sealed original values to save, according to the store mode
+func (rs *RedactionService) StoredMessage(original string, redacted Redacted) (string, string, error) {
+	switch rs.storeMode {
+	case StoreRedacted:
+		return redacted.Text, "", nil
+	case StoreEncrypted:
+		if len(redacted.Tokens) == 0 {
+			return redacted.Text, "", nil
+		}
+
+		sealed, err := rs.Seal(redacted.Tokens)
+		if err != nil {
+			return "", "", err
+		}
+		return redacted.Text, sealed, nil
+	}
+	return original, "", nil
+}
+
+// Seal encrypts the tokens with AES-GCM
+func (rs *RedactionService) Seal(tokens Tokens) (string, error) {
+	if rs.aead == nil {
+		return "", ErrMissingKey
+	}
+
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return "", fmt.Errorf("failed to seal pii. Cause: %w", err)
+	}
+
+	nonce := make([]byte, rs.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to seal pii. Cause: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(rs.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open decrypts the tokens sealed by Seal
+func (rs *RedactionService) Open(sealed string) (Tokens, error) {
+	if rs.aead == nil {
+		return nil, ErrMissingKey
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < rs.aead.NonceSize() {
+		return nil, ErrInvalidSealedPII
+	}
+
+	nonceSize := rs.aead.NonceSize()
+	plaintext, err := rs.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidSealedPII
+	}
+
+	tokens := Tokens{}
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, ErrInvalidSealedPII
+	}
+	return tokens, nil
+}
+
+// Reveal restores the original values of a stored message
+func (rs *RedactionService) Reveal(message string, sealed string) (string, error) {
+	if sealed == "" {
+		return message, nil
+	}
+
+	tokens, err := rs.Open(sealed)
+	if err != nil {
+		return "", err
+	}
+	return restore(message, tokens), nil
+}
+
+// Session tokenizes the personal data of a single chat
+type Session struct {
+	detectors []Detector
+	// tokens maps the token to the value and values the value to the token
+	tokens   Tokens
+	values   map[string]string
+	counters map[string]int
+	mutex    *sync.Mutex
+}
+
+type match struct {
+	start int
+	end   int
+	kind  string
+}
+
+// Redact replaces the personal data with tokens like [EMAIL_1]. When two detectors match
+// the same text the first one wins.
+func (s *Session) Redact(text string) Redacted {
+	matches := []match{}
+	for _, detector := range s.detectors {
+		for _, index := range detector.Find(text) {
+			if !overlaps(matches, index[0], index[1]) {
+				matches = append(matches, match{start: index[0], end: index[1], kind: detector.Kind()})
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return Redacted{Text: text, Tokens: Tokens{}}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var builder strings.Builder
+	tokens := Tokens{}
+	last := 0
+
+	for _, m := range matches {
+		value := text[m.start:m.end]
+		token := s.token(m.kind, value)
+		tokens[token] = value
+
+		builder.WriteString(text[last:m.start])
+		builder.WriteString(token)
+		last = m.end
+	}
+	builder.WriteString(text[last:])
+
+	return Redacted{Text: builder.String(), Tokens: tokens}
+}
+
+// Restore replaces the tokens of the session with the original values
+func (s *Session) Restore(text string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return restore(text, s.tokens)
+}
+
+// Tokenized returns the text with the session tokens it contains, e.g. a chatbot answer
+func (s *Session) Tokenized(text string) Redacted {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tokens := Tokens{}
+	for _, token := range tokenPattern.FindAllString(text, -1) {
+		if value, ok := s.tokens[token]; ok {
+			tokens[token] = value
+		}
+	}
+	return Redacted{Text: text, Tokens: tokens}
+}
+
+// token returns the token of the value, creating a new one on the first time it's seen
+func (s *Session) token(kind string, value string) string {
+	key := kind + ":" + value
+	if token, ok := s.values[key]; ok {
+		return token
+	}
+
+	s.counters[kind]++
+	token := fmt.Sprintf("[%s_%d]", kind, s.counters[kind])
+	s.values[key] = token
+	s.tokens[token] = value
+	return token
+}
+
+// restore replaces the known tokens with the original values
+func restore(text string, tokens Tokens) string {
+	if len(tokens) == 0 {
+		return text
+	}
+
+	return tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if value, ok := tokens[token]; ok {
+			return value
+		}
+		return token
+	})
+}
+
+// overlaps tells if the range overlaps any of the matches
+func overlaps(matches []match, start int, end int) bool {
+	for _, m := range matches {
+		if start < m.end && m.start < end {
+			return true
+		}
+	}
+	return false
+}
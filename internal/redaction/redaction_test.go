@@ -0,0 +1,118 @@
+package redaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRedact(t *testing.T) {
+	service, err := NewRedactionService(RedactionServiceConfig{Detectors: DefaultDetectors()})
+	require.NoError(t, err)
+
+	t.Run("should replace the personal data with tokens", func(t *testing.T) {
+		session := service.NewSession()
+
+		redacted := session.Redact("I'm john@mail.com, card 4111 1111 1111 1111 and phone (212) 555-0199")
+		assert.Equal(t, "I'm [EMAIL_1], card [CARD_1] and phone [PHONE_1]", redacted.Text)
+		assert.Equal(t, Tokens{
+			"[EMAIL_1]": "john@mail.com",
+			"[CARD_1]":  "4111 1111 1111 1111",
+			"[PHONE_1]": "(212) 555-0199",
+		}, redacted.Tokens)
+	})
+
+	t.Run("should reuse the tokens in the same session", func(t *testing.T) {
+		session := service.NewSession()
+
+		first := session.Redact("my email is john@mail.com")
+		second := session.Redact("not mary@mail.com, it's john@mail.com")
+		assert.Equal(t, "my email is [EMAIL_1]", first.Text)
+		assert.Equal(t, "not [EMAIL_2], it's [EMAIL_1]", second.Text)
+		assert.Len(t, second.Tokens, 2)
+	})
+
+	t.Run("should keep texts without personal data", func(t *testing.T) {
+		redacted := service.NewSession().Redact("The phone is great, 5 stars")
+		assert.Equal(t, "The phone is great, 5 stars", redacted.Text)
+		assert.Empty(t, redacted.Tokens)
+	})
+
+	t.Run("should restore the tokens of the session", func(t *testing.T) {
+		session := service.NewSession()
+		session.Redact("my email is john@mail.com")
+
+		reply := "We will write to [EMAIL_1] about [CARD_7]"
+		assert.Equal(t, "We will write to john@mail.com about [CARD_7]", session.Restore(reply))
+		assert.Equal(t, Tokens{"[EMAIL_1]": "john@mail.com"}, session.Tokenized(reply).Tokens)
+	})
+}
+
+func TestServiceStoredMessage(t *testing.T) {
+	original := "call me at (212) 555-0199"
+
+	t.Run("should store the original message in the plain mode", func(t *testing.T) {
+		service, err := NewRedactionService(RedactionServiceConfig{Detectors: DefaultDetectors()})
+		require.NoError(t, err)
+
+		message, pii, err := service.StoredMessage(original, service.NewSession().Redact(original))
+		assert.NoError(t, err)
+		assert.Equal(t, original, message)
+		assert.Empty(t, pii)
+	})
+
+	t.Run("should store the redacted message in the redacted mode", func(t *testing.T) {
+		service, err := NewRedactionService(RedactionServiceConfig{Detectors: DefaultDetectors(), StoreMode: StoreRedacted})
+		require.NoError(t, err)
+
+		message, pii, err := service.StoredMessage(original, service.NewSession().Redact(original))
+		assert.NoError(t, err)
+		assert.Equal(t, "call me at [PHONE_1]", message)
+		assert.Empty(t, pii)
+	})
+
+	t.Run("should keep the originals encrypted in the encrypted mode", func(t *testing.T) {
+		service, err := NewRedactionService(RedactionServiceConfig{
+			Detectors: DefaultDetectors(),
+			StoreMode: StoreEncrypted,
+			Key:       "qwerty",
+		})
+		require.NoError(t, err)
+
+		message, pii, err := service.StoredMessage(original, service.NewSession().Redact(original))
+		assert.NoError(t, err)
+		assert.Equal(t, "call me at [PHONE_1]", message)
+		assert.NotContains(t, pii, "555")
+
+		revealed, err := service.Reveal(message, pii)
+		assert.NoError(t, err)
+		assert.Equal(t, original, revealed)
+
+		other, err := NewRedactionService(RedactionServiceConfig{StoreMode: StoreEncrypted, Key: "asdfgh"})
+		require.NoError(t, err)
+
+		_, err = other.Reveal(message, pii)
+		assert.ErrorIs(t, err, ErrInvalidSealedPII)
+	})
+
+	t.Run("should fail without the key in the encrypted mode", func(t *testing.T) {
+		_, err := NewRedactionService(RedactionServiceConfig{StoreMode: StoreEncrypted})
+		assert.ErrorIs(t, err, ErrMissingKey)
+	})
+
+	t.Run("should fail with an invalid store mode", func(t *testing.T) {
+		_, err := NewRedactionService(RedactionServiceConfig{StoreMode: "hashed"})
+		assert.ErrorIs(t, err, ErrInvalidStoreMode)
+	})
+}
+
+func TestServiceRedactText(t *testing.T) {
+	service, err := NewRedactionService(RedactionServiceConfig{Detectors: DefaultDetectors()})
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"failed to send message [EMAIL_1] lives at [ADDRESS_1]",
+		service.RedactText("failed to send message john@mail.com lives at 221 Baker Street"),
+	)
+}
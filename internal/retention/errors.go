@@ -0,0 +1,7 @@
+package retention
+
+import "errors"
+
+var (
+	ErrNoRules = errors.New("no retention rules configured")
+)
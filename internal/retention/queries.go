@@ -0,0 +1,44 @@
+package retention
+
+// userLastActivity is the last chat of the user, or its creation when they never chatted. It's
+// NULL for the users created before the creation dates were recorded
+var userLastActivity = `COALESCE((SELECT MAX(c.created_at) FROM chats c WHERE c.user_id = users.id), users.created_at)`
+
+// inactiveUsers matches the users not anonymized yet whose last activity is older than :before
+// or unknown
+var inactiveUsers = `
+	users.email <> users.id
+	AND (` + userLastActivity + ` IS NULL OR ` + userLastActivity + ` < :before)
+`
+
+// the messages saved before the creation dates were recorded have a NULL created_at and count
+// as older than any :before
+
+var countMessageContent = `
+	SELECT COUNT(*) FROM messages WHERE (created_at IS NULL OR created_at < :before) AND message <> '';
+`
+
+var purgeMessageContent = `
+	UPDATE messages SET message = '', pii = NULL WHERE (created_at IS NULL OR created_at < :before) AND message <> '';
+`
+
+var countInactiveUsers = `
+	SELECT COUNT(*) FROM users WHERE ` + inactiveUsers + `;
+`
+
+// the same email can be an active customer of another tenant
+var deleteInactiveUsersInvitations = `
+	DELETE FROM review_invitations
+	WHERE (tenant_id, user_email) IN (SELECT tenant_id, email FROM users WHERE ` + inactiveUsers + `);
+`
+
+var deleteInactiveUsersReviewJobs = `
+	DELETE FROM review_jobs
+	WHERE (tenant_id, user_email) IN (SELECT tenant_id, email FROM users WHERE ` + inactiveUsers + `);
+`
+
+// anonymizeInactiveUsers keeps the user id, so the chats still count on the reports, and
+// replaces the unique email with it
+var anonymizeInactiveUsers = `
+	UPDATE users SET first_name = '', last_name = '', email = id WHERE ` + inactiveUsers + `;
+`
@@ -0,0 +1,104 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+)
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// CountMessageContent counts the messages created before the date that still have content
+func (r *Repository) CountMessageContent(ctx context.Context, before time.Time) (int64, error) {
+	count, err := r.count(ctx, countMessageContent, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count message content. Cause: %w", err)
+	}
+	return count, nil
+}
+
+// PurgeMessageContent removes the content of the messages created before the date
+func (r *Repository) PurgeMessageContent(ctx context.Context, before time.Time) (int64, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, purgeMessageContent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge message content. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	result, err := stm.ExecContext(ctx, map[string]interface{}{"before": before})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge message content. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge message content. Cause: %w", err)
+	}
+	return rows, nil
+}
+
+// CountInactiveUsers counts the users without activity since the date
+func (r *Repository) CountInactiveUsers(ctx context.Context, before time.Time) (int64, error) {
+	count, err := r.count(ctx, countInactiveUsers, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count inactive users. Cause: %w", err)
+	}
+	return count, nil
+}
+
+// AnonymizeInactiveUsers removes the name and email of the users without activity since the date,
+// with their scheduled reviews and invitations
+func (r *Repository) AnonymizeInactiveUsers(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize inactive users. Cause: %w", err)
+	}
+	defer tx.Rollback()
+
+	params := map[string]interface{}{"before": before}
+
+	for _, query := range []string{deleteInactiveUsersInvitations, deleteInactiveUsersReviewJobs} {
+		if _, err := tx.NamedExecContext(ctx, query, params); err != nil {
+			return 0, fmt.Errorf("failed to anonymize inactive users. Cause: %w", err)
+		}
+	}
+
+	result, err := tx.NamedExecContext(ctx, anonymizeInactiveUsers, params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize inactive users. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize inactive users. Cause: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to anonymize inactive users. Cause: %w", err)
+	}
+	return rows, nil
+}
+
+// count runs a count query with the before param
+func (r *Repository) count(ctx context.Context, query string, before time.Time) (int64, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stm.Close()
+
+	var count int64
+	if err := stm.GetContext(ctx, &count, map[string]interface{}{"before": before}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,107 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCountMessageContent(t *testing.T) {
+	t.Run("should count the messages with content", func(t *testing.T) {
+		before := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				assert.Equal(t, countMessageContent, query)
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest, arg interface{}) error {
+						assert.Equal(t, map[string]interface{}{"before": before}, arg)
+						*dest.(*int64) = 7
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		count, err := repository.CountMessageContent(context.Background(), before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), count)
+	})
+
+	t.Run("should count the messages saved before the creation dates were recorded", func(t *testing.T) {
+		assert.Contains(t, countMessageContent, "(created_at IS NULL OR created_at < :before)")
+	})
+}
+
+func TestRepositoryPurgeMessageContent(t *testing.T) {
+	t.Run("should return the purged messages", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				assert.Equal(t, purgeMessageContent, query)
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 12, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		count, err := repository.PurgeMessageContent(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(12), count)
+	})
+
+	t.Run("should purge the messages saved before the creation dates were recorded", func(t *testing.T) {
+		assert.Contains(t, purgeMessageContent, "(created_at IS NULL OR created_at < :before)")
+	})
+}
+
+func TestRepositoryAnonymizeInactiveUsers(t *testing.T) {
+	t.Run("should remove the invitations and review jobs before anonymizing the users", func(t *testing.T) {
+		var (
+			executed  []string
+			committed bool
+		)
+
+		repository := NewRepository(&godb.DBMock{
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						executed = append(executed, query)
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 3, nil
+							},
+						}, nil
+					},
+					CallbackCommit: func() error {
+						committed = true
+						return nil
+					},
+					CallbackRollback: func() error {
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		count, err := repository.AnonymizeInactiveUsers(context.Background(), time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.Equal(t, []string{deleteInactiveUsersInvitations, deleteInactiveUsersReviewJobs, anonymizeInactiveUsers}, executed)
+		assert.True(t, committed)
+	})
+
+	t.Run("should anonymize the users without a last activity date", func(t *testing.T) {
+		for _, query := range []string{countInactiveUsers, deleteInactiveUsersInvitations, deleteInactiveUsersReviewJobs, anonymizeInactiveUsers} {
+			assert.Contains(t, query, "("+userLastActivity+" IS NULL OR "+userLastActivity+" < :before)")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+type repository interface {
+	CountMessageContent(ctx context.Context, before time.Time) (int64, error)
+	PurgeMessageContent(ctx context.Context, before time.Time) (int64, error)
+	CountInactiveUsers(ctx context.Context, before time.Time) (int64, error)
+	AnonymizeInactiveUsers(ctx context.Context, before time.Time) (int64, error)
+}
+
+type RetentionServiceConfig struct {
+	// MessageContentTTL is how long the message content is kept. The chats and the message
+	// authors and dates are kept for the reports. Zero keeps the content forever.
+	MessageContentTTL time.Duration
+	// InactiveUserTTL is how long a user without new chats is kept before being anonymized.
+	// Zero keeps the users forever.
+	InactiveUserTTL time.Duration
+	// Interval is how often the rules run in background
+	Interval time.Duration
+	// DryRun only reports what would be removed
+	DryRun bool
+}
+
+type RetentionService struct {
+	repository repository
+	config     RetentionServiceConfig
+	now        func() time.Time
+}
+
+// NewRetentionService create a new retention service
+func NewRetentionService(repository repository, config RetentionServiceConfig) *RetentionService {
+	if config.Interval <= 0 {
+		config.Interval = 24 * time.Hour
+	}
+
+	return &RetentionService{
+		repository: repository,
+		config:     config,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Enabled tells if any retention rule is configured
+func (rs *RetentionService) Enabled() bool {
+	return rs.config.MessageContentTTL > 0 || rs.config.InactiveUserTTL > 0
+}
+
+// Start applies the retention rules until the context is done
+func (rs *RetentionService) Start(ctx context.Context) {
+	ticker := time.NewTicker(rs.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := rs.Run(ctx, rs.config.DryRun)
+		if err != nil {
+			golog.Log().Error(ctx, err.Error())
+		} else {
+			data, _ := json.Marshal(report)
+			golog.Log().Info(ctx, fmt.Sprintf("retention rules applied: %s", data))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run applies the retention rules. On a dry run nothing is changed and the report
+// tells what would be removed.
+func (rs *RetentionService) Run(ctx context.Context, dryRun bool) (datatypes.RetentionReport, error) {
+	if !rs.Enabled() {
+		return datatypes.RetentionReport{}, ErrNoRules
+	}
+
+	now := rs.now()
+	report := datatypes.RetentionReport{DryRun: dryRun, RanAt: now}
+
+	if rs.config.MessageContentTTL > 0 {
+		before := now.Add(-rs.config.MessageContentTTL)
+		report.MessagesBefore = &before
+
+		count, err := rs.apply(ctx, dryRun, before, rs.repository.CountMessageContent, rs.repository.PurgeMessageContent)
+		if err != nil {
+			return datatypes.RetentionReport{}, err
+		}
+		report.Messages = count
+	}
+
+	if rs.config.InactiveUserTTL > 0 {
+		before := now.Add(-rs.config.InactiveUserTTL)
+		report.UsersBefore = &before
+
+		count, err := rs.apply(ctx, dryRun, before, rs.repository.CountInactiveUsers, rs.repository.AnonymizeInactiveUsers)
+		if err != nil {
+			return datatypes.RetentionReport{}, err
+		}
+		report.Users = count
+	}
+
+	return report, nil
+}
+
+// apply runs the rule, or only counts the affected rows on a dry run
+func (rs *RetentionService) apply(
+	ctx context.Context,
+	dryRun bool,
+	before time.Time,
+	count func(ctx context.Context, before time.Time) (int64, error),
+	remove func(ctx context.Context, before time.Time) (int64, error),
+) (int64, error) {
+	if dryRun {
+		return count(ctx, before)
+	}
+	return remove(ctx, before)
+}
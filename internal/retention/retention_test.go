@@ -0,0 +1,99 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repositoryMock struct {
+	Error   error
+	Counted []string
+	Removed []string
+	Befores []time.Time
+}
+
+func (rm *repositoryMock) CountMessageContent(ctx context.Context, before time.Time) (int64, error) {
+	rm.Counted = append(rm.Counted, "messages")
+	rm.Befores = append(rm.Befores, before)
+	return 10, rm.Error
+}
+
+func (rm *repositoryMock) PurgeMessageContent(ctx context.Context, before time.Time) (int64, error) {
+	rm.Removed = append(rm.Removed, "messages")
+	rm.Befores = append(rm.Befores, before)
+	return 10, rm.Error
+}
+
+func (rm *repositoryMock) CountInactiveUsers(ctx context.Context, before time.Time) (int64, error) {
+	rm.Counted = append(rm.Counted, "users")
+	rm.Befores = append(rm.Befores, before)
+	return 2, rm.Error
+}
+
+func (rm *repositoryMock) AnonymizeInactiveUsers(ctx context.Context, before time.Time) (int64, error) {
+	rm.Removed = append(rm.Removed, "users")
+	rm.Befores = append(rm.Befores, before)
+	return 2, rm.Error
+}
+
+func TestServiceRun(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	config := RetentionServiceConfig{
+		MessageContentTTL: 90 * 24 * time.Hour,
+		InactiveUserTTL:   365 * 24 * time.Hour,
+	}
+
+	t.Run("should apply every rule", func(t *testing.T) {
+		repository := &repositoryMock{}
+		service := NewRetentionService(repository, config)
+		service.now = func() time.Time { return now }
+
+		report, err := service.Run(context.Background(), false)
+		require.NoError(t, err)
+		assert.False(t, report.DryRun)
+		assert.Equal(t, int64(10), report.Messages)
+		assert.Equal(t, int64(2), report.Users)
+		assert.Equal(t, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), *report.MessagesBefore)
+		assert.Equal(t, time.Date(2023, 6, 2, 0, 0, 0, 0, time.UTC), *report.UsersBefore)
+		assert.Equal(t, []string{"messages", "users"}, repository.Removed)
+		assert.Empty(t, repository.Counted)
+	})
+
+	t.Run("should only count on a dry run", func(t *testing.T) {
+		repository := &repositoryMock{}
+		service := NewRetentionService(repository, config)
+
+		report, err := service.Run(context.Background(), true)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, int64(10), report.Messages)
+		assert.Equal(t, []string{"messages", "users"}, repository.Counted)
+		assert.Empty(t, repository.Removed)
+	})
+
+	t.Run("should skip the disabled rules", func(t *testing.T) {
+		repository := &repositoryMock{}
+		service := NewRetentionService(repository, RetentionServiceConfig{InactiveUserTTL: time.Hour})
+
+		report, err := service.Run(context.Background(), false)
+		require.NoError(t, err)
+		assert.Nil(t, report.MessagesBefore)
+		assert.Equal(t, []string{"users"}, repository.Removed)
+	})
+
+	t.Run("should fail without rules", func(t *testing.T) {
+		_, err := NewRetentionService(&repositoryMock{}, RetentionServiceConfig{}).Run(context.Background(), true)
+		assert.ErrorIs(t, err, ErrNoRules)
+	})
+
+	t.Run("should fail when a rule fails", func(t *testing.T) {
+		errRepository := errors.New("error to purge messages for tests")
+		_, err := NewRetentionService(&repositoryMock{Error: errRepository}, config).Run(context.Background(), false)
+		assert.ErrorIs(t, err, errRepository)
+	})
+}
@@ -0,0 +1,10 @@
+package scheduler
+
+import "errors"
+
+var (
+	ErrCantSaveReviewJob      = errors.New("failed to create new review job. Cause: can't save the review job")
+	ErrCantUpdateReviewJob    = errors.New("failed to update review job. Cause: can't update the review job")
+	ErrReviewAlreadyScheduled = errors.New("review already scheduled for this order")
+	ErrMissingRequiredFields  = errors.New("missing required fields")
+)
@@ -0,0 +1,41 @@
+package scheduler
+
+var createReviewJob = `
+	INSERT INTO review_jobs (
+		id, tenant_id, order_id, user_name, user_email, product, delivered_at,
+		run_at, status, attempts, last_error, created_at, updated_at
+	)
+	VALUES (
+		:id, :tenant_id, :order_id, :user_name, :user_email, :product, :delivered_at,
+		:run_at, :status, :attempts, :last_error, :created_at, :updated_at
+	);
+`
+
+var findReviewJobByOrderID = `
+	SELECT
+		id, tenant_id, order_id, user_name, user_email, product, delivered_at,
+		run_at, status, attempts, last_error, created_at, updated_at
+	FROM review_jobs WHERE tenant_id = :tenant_id AND order_id = :order_id;
+`
+
+var findDueReviewJobs = `
+	SELECT
+		id, tenant_id, order_id, user_name, user_email, product, delivered_at,
+		run_at, status, attempts, last_error, created_at, updated_at
+	FROM review_jobs
+	WHERE status = :status AND run_at <= :now
+	ORDER BY run_at
+	LIMIT :limit;
+`
+
+// a job is claimed by moving it out of the due ones, another instance can't claim it until the claim expires
+var claimReviewJob = `
+	UPDATE review_jobs SET run_at = :claimed_until, updated_at = :updated_at
+	WHERE id = :id AND status = :status AND run_at <= :now;
+`
+
+var updateReviewJob = `
+	UPDATE review_jobs
+	SET run_at = :run_at, status = :status, attempts = :attempts, last_error = :last_error, updated_at = :updated_at
+	WHERE id = :id;
+`
@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/sqlutil"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofrs/uuid/v5"
+)
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// CreateJob persists a new review job
+func (r *Repository) CreateJob(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, createReviewJob)
+	if err != nil {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to create new review job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to create new review job. Cause: %w", err)
+	}
+
+	now := time.Now().UTC()
+	job.ID = id.String()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	params := map[string]interface{}{
+		"id":           job.ID,
+		"tenant_id":    job.TenantID,
+		"order_id":     job.OrderID,
+		"user_name":    job.UserName,
+		"user_email":   job.UserEmail,
+		"product":      job.Product,
+		"delivered_at": job.DeliveredAt,
+		"run_at":       job.RunAt,
+		"status":       job.Status,
+		"attempts":     job.Attempts,
+		"last_error":   job.LastError,
+		"created_at":   job.CreatedAt,
+		"updated_at":   job.UpdatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		// the order was scheduled by a concurrent request after FindJobByOrderID
+		if sqlutil.IsDuplicateKey(err) {
+			return datatypes.ReviewJob{}, ErrReviewAlreadyScheduled
+		}
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to create new review job. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to create new review job. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return datatypes.ReviewJob{}, ErrCantSaveReviewJob
+	}
+
+	return job, nil
+}
+
+// FindJobByOrderID finds the review job scheduled for an order
+func (r *Repository) FindJobByOrderID(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+	var job datatypes.ReviewJob
+
+	stm, err := r.db.PrepareNamedContext(ctx, findReviewJobByOrderID)
+	if err != nil {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to find review job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"order_id":  orderID,
+	}
+
+	if err = stm.GetContext(ctx, &job, params); err != nil {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to find review job. Cause: %w", err)
+	}
+
+	return job, nil
+}
+
+// FindDueJobs finds pending review jobs that should run until now
+func (r *Repository) FindDueJobs(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+	jobs := []datatypes.ReviewJob{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findDueReviewJobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find due review jobs. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"status": JobStatusPending,
+		"now":    now,
+		"limit":  limit,
+	}
+
+	if err = stm.SelectContext(ctx, &jobs, params); err != nil {
+		return nil, fmt.Errorf("failed to find due review jobs. Cause: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClaimJob claims the due job for this instance until the given time. It returns false when
+// the job was already claimed by another instance.
+func (r *Repository) ClaimJob(ctx context.Context, job datatypes.ReviewJob, now time.Time, until time.Time) (bool, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, claimReviewJob)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim review job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":            job.ID,
+		"status":        JobStatusPending,
+		"now":           now,
+		"claimed_until": until,
+		"updated_at":    time.Now().UTC(),
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim review job. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim review job. Cause: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// UpdateJob updates the execution state of a review job
+func (r *Repository) UpdateJob(ctx context.Context, job datatypes.ReviewJob) error {
+	stm, err := r.db.PrepareNamedContext(ctx, updateReviewJob)
+	if err != nil {
+		return fmt.Errorf("failed to update review job. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":         job.ID,
+		"run_at":     job.RunAt,
+		"status":     job.Status,
+		"attempts":   job.Attempts,
+		"last_error": job.LastError,
+		"updated_at": time.Now().UTC(),
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to update review job. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update review job. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantUpdateReviewJob
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateJob(t *testing.T) {
+	t.Run("should create a new review job", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		job, err := repository.CreateJob(context.Background(), datatypes.ReviewJob{OrderID: "order-1"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, job.ID)
+		assert.Equal(t, "order-1", job.OrderID)
+		assert.False(t, job.CreatedAt.IsZero())
+	})
+
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.CreateJob(context.Background(), datatypes.ReviewJob{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCantSaveReviewJob)
+	})
+
+	t.Run("should fail when the order is already scheduled", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return nil, errors.New("Error 1062 (23000): Duplicate entry 'default-order-1' for key 'review_jobs_tenant_order'")
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.CreateJob(context.Background(), datatypes.ReviewJob{OrderID: "order-1"})
+		assert.ErrorIs(t, err, ErrReviewAlreadyScheduled)
+	})
+}
+
+func TestRepositoryFindJobByOrderID(t *testing.T) {
+	t.Run("should fail run get context", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest, arg interface{}) error {
+						return sql.ErrNoRows
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.FindJobByOrderID(context.Background(), "order-1")
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+}
+
+func TestRepositoryFindDueJobs(t *testing.T) {
+	t.Run("should find due jobs", func(t *testing.T) {
+		mockedJobs := []datatypes.ReviewJob{{ID: "qwerty"}}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+						if jobs, ok := dest.(*[]datatypes.ReviewJob); ok {
+							*jobs = mockedJobs
+						}
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		jobs, err := repository.FindDueJobs(context.Background(), time.Now(), 10)
+		assert.NoError(t, err)
+		assert.EqualValues(t, mockedJobs, jobs)
+	})
+
+	t.Run("should fail to prepare named context", func(t *testing.T) {
+		errPrepareNamedContext := errors.New("error when preparing named context for tests")
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return nil, errPrepareNamedContext
+			},
+		})
+
+		_, err := repository.FindDueJobs(context.Background(), time.Now(), 10)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, errPrepareNamedContext)
+	})
+}
+
+func TestRepositoryClaimJob(t *testing.T) {
+	t.Run("should not claim a job claimed by another instance", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		claimed, err := repository.ClaimJob(context.Background(), datatypes.ReviewJob{ID: "qwerty"}, time.Now(), time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+}
+
+func TestRepositoryUpdateJob(t *testing.T) {
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.UpdateJob(context.Background(), datatypes.ReviewJob{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCantUpdateReviewJob)
+	})
+}
@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+)
+
+const (
+	JobStatusPending = "pending"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+const (
+	ScheduleStatusScheduled  = "scheduled"
+	ScheduleStatusDuplicated = "duplicated"
+	ScheduleStatusFailed     = "failed"
+)
+
+type repository interface {
+	CreateJob(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error)
+	FindJobByOrderID(ctx context.Context, orderID string) (datatypes.ReviewJob, error)
+	FindDueJobs(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error)
+	ClaimJob(ctx context.Context, job datatypes.ReviewJob, now time.Time, until time.Time) (bool, error)
+	UpdateJob(ctx context.Context, job datatypes.ReviewJob) error
+}
+
+type reviewStarter interface {
+	StartReview(ctx context.Context, name string, email string, product string) error
+}
+
+type tenantFinder interface {
+	FindByID(id string) (datatypes.Tenant, error)
+}
+
+type SchedulerServiceConfig struct {
+	// ReviewDelay is how long after the delivery the review is started
+	ReviewDelay time.Duration
+	// PollInterval is how often the scheduler looks for due jobs
+	PollInterval time.Duration
+	// RetryInterval is the base interval between attempts. It doubles on every failure
+	RetryInterval time.Duration
+	// MaxAttempts is how many times a job runs before being marked as failed
+	MaxAttempts int
+	// BatchSize is the maximum amount of jobs executed on every poll
+	BatchSize int
+	// ClaimTimeout is how long a job is reserved for the instance running it. The jobs of an
+	// instance that stopped before updating them run again after it.
+	ClaimTimeout time.Duration
+}
+
+type SchedulerService struct {
+	repository repository
+	tenants    tenantFinder
+	config     SchedulerServiceConfig
+	now        func() time.Time
+}
+
+// NewSchedulerService create a new scheduler service
+func NewSchedulerService(repository repository, tenants tenantFinder, config SchedulerServiceConfig) *SchedulerService {
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Minute
+	}
+
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = time.Minute
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	if config.ClaimTimeout <= 0 {
+		config.ClaimTimeout = 5 * time.Minute
+	}
+
+	return &SchedulerService{
+		repository: repository,
+		tenants:    tenants,
+		config:     config,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// ScheduleReview schedules a review for a delivered order
+func (ss *SchedulerService) ScheduleReview(ctx context.Context, req datatypes.OrderDeliveredRequest) (datatypes.ReviewJob, error) {
+	if req.OrderID == "" || req.User.Email == "" || req.DeliveredAt.IsZero() {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to schedule review. Cause: %w", ErrMissingRequiredFields)
+	}
+
+	_, err := ss.repository.FindJobByOrderID(ctx, req.OrderID)
+	switch {
+	case err == nil:
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to schedule review. Cause: %w", ErrReviewAlreadyScheduled)
+	case !errors.Is(err, sql.ErrNoRows):
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to schedule review. Cause: %w", err)
+	}
+
+	deliveredAt := req.DeliveredAt.UTC()
+
+	job, err := ss.repository.CreateJob(ctx, datatypes.ReviewJob{
+		TenantID:    tenant.IDFromContext(ctx),
+		OrderID:     req.OrderID,
+		UserName:    req.User.Name,
+		UserEmail:   req.User.Email,
+		Product:     req.Product,
+		DeliveredAt: deliveredAt,
+		RunAt:       deliveredAt.Add(ss.config.ReviewDelay),
+		Status:      JobStatusPending,
+	})
+	if err != nil {
+		return datatypes.ReviewJob{}, fmt.Errorf("failed to schedule review. Cause: %w", err)
+	}
+	return job, nil
+}
+
+// ScheduleReviews schedules a review for every delivered order and returns the result of each one,
+// in the order of the requests. Orders already scheduled are skipped and an order that fails doesn't
+// stop the others.
+func (ss *SchedulerService) ScheduleReviews(
+	ctx context.Context,
+	reqs []datatypes.OrderDeliveredRequest,
+) datatypes.ScheduleReviewsResponse {
+	response := datatypes.ScheduleReviewsResponse{
+		Results: make([]datatypes.ScheduleReviewResult, 0, len(reqs)),
+	}
+
+	for _, req := range reqs {
+		result := datatypes.ScheduleReviewResult{OrderID: req.OrderID}
+
+		job, err := ss.ScheduleReview(ctx, req)
+		switch {
+		case err == nil:
+			result.Status = ScheduleStatusScheduled
+			result.Job = &job
+		case errors.Is(err, ErrReviewAlreadyScheduled):
+			result.Status = ScheduleStatusDuplicated
+		default:
+			golog.Log().Error(ctx, fmt.Sprintf("failed to schedule the review of order %s. Cause: %s", req.OrderID, err))
+			result.Status = ScheduleStatusFailed
+			result.Error = err.Error()
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	return response
+}
+
+// Start runs the due jobs periodically until the context is done
+func (ss *SchedulerService) Start(ctx context.Context, starter reviewStarter) {
+	ticker := time.NewTicker(ss.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ss.RunDueJobs(ctx, starter); err != nil {
+			golog.Log().Error(ctx, err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDueJobs starts the review of every due job in the tenant that scheduled it. Each job is claimed
+// first, so the jobs found by several instances run only once. Failed jobs are retried with an
+// exponential backoff.
+func (ss *SchedulerService) RunDueJobs(ctx context.Context, starter reviewStarter) error {
+	now := ss.now()
+	jobs, err := ss.repository.FindDueJobs(ctx, now, ss.config.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to run due review jobs. Cause: %w", err)
+	}
+
+	for _, job := range jobs {
+		claimed, err := ss.repository.ClaimJob(ctx, job, now, now.Add(ss.config.ClaimTimeout))
+		if err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to run review job %s. Cause: %s", job.ID, err))
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		job.Attempts++
+
+		if err := ss.runJob(ctx, starter, job); err != nil {
+			job.LastError = err.Error()
+
+			if job.Attempts >= ss.config.MaxAttempts {
+				job.Status = JobStatusFailed
+			} else {
+				job.RunAt = ss.now().Add(ss.config.RetryInterval * time.Duration(1<<(job.Attempts-1)))
+			}
+		} else {
+			job.Status = JobStatusDone
+			job.LastError = ""
+		}
+
+		if err := ss.repository.UpdateJob(ctx, job); err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to run review job %s. Cause: %s", job.ID, err))
+		}
+	}
+
+	return nil
+}
+
+// runJob starts the review of the job in its tenant
+func (ss *SchedulerService) runJob(ctx context.Context, starter reviewStarter, job datatypes.ReviewJob) error {
+	jobTenant, err := ss.tenants.FindByID(job.TenantID)
+	if err != nil {
+		return err
+	}
+	return starter.StartReview(tenant.WithTenant(ctx, jobTenant), job.UserName, job.UserEmail, job.Product)
+}
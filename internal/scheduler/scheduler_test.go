@@ -0,0 +1,361 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+type repositoryMock struct {
+	Error                    error
+	CallbackCreateJob        func(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error)
+	CallbackFindJobByOrderID func(ctx context.Context, orderID string) (datatypes.ReviewJob, error)
+	CallbackFindDueJobs      func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error)
+	CallbackClaimJob         func(ctx context.Context, job datatypes.ReviewJob, now time.Time, until time.Time) (bool, error)
+	CallbackUpdateJob        func(ctx context.Context, job datatypes.ReviewJob) error
+}
+
+func (rm *repositoryMock) CreateJob(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+	if rm.CallbackCreateJob != nil {
+		return rm.CallbackCreateJob(ctx, job)
+	}
+	return datatypes.ReviewJob{}, rm.Error
+}
+
+func (rm *repositoryMock) FindJobByOrderID(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+	if rm.CallbackFindJobByOrderID != nil {
+		return rm.CallbackFindJobByOrderID(ctx, orderID)
+	}
+	return datatypes.ReviewJob{}, rm.Error
+}
+
+func (rm *repositoryMock) FindDueJobs(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+	if rm.CallbackFindDueJobs != nil {
+		return rm.CallbackFindDueJobs(ctx, now, limit)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) ClaimJob(ctx context.Context, job datatypes.ReviewJob, now time.Time, until time.Time) (bool, error) {
+	if rm.CallbackClaimJob != nil {
+		return rm.CallbackClaimJob(ctx, job, now, until)
+	}
+	return rm.Error == nil, rm.Error
+}
+
+func (rm *repositoryMock) UpdateJob(ctx context.Context, job datatypes.ReviewJob) error {
+	if rm.CallbackUpdateJob != nil {
+		return rm.CallbackUpdateJob(ctx, job)
+	}
+	return rm.Error
+}
+
+type reviewStarterMock struct {
+	Error               error
+	CallbackStartReview func(ctx context.Context, name string, email string, product string) error
+}
+
+func (rsm *reviewStarterMock) StartReview(ctx context.Context, name string, email string, product string) error {
+	if rsm.CallbackStartReview != nil {
+		return rsm.CallbackStartReview(ctx, name, email, product)
+	}
+	return rsm.Error
+}
+
+type tenantFinderMock struct {
+	Error error
+}
+
+func (tfm *tenantFinderMock) FindByID(id string) (datatypes.Tenant, error) {
+	return datatypes.Tenant{ID: id}, tfm.Error
+}
+
+func TestServiceScheduleReview(t *testing.T) {
+	t.Run("should schedule a review after the configured delay", func(t *testing.T) {
+		request := getMockedOrderDeliveredRequest()
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindJobByOrderID: func(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+				return datatypes.ReviewJob{}, sql.ErrNoRows
+			},
+			CallbackCreateJob: func(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+				return job, nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{ReviewDelay: 72 * time.Hour})
+
+		job, err := service.ScheduleReview(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, request.OrderID, job.OrderID)
+		assert.Equal(t, JobStatusPending, job.Status)
+		assert.Equal(t, request.DeliveredAt.Add(72*time.Hour), job.RunAt)
+	})
+
+	t.Run("should fail when the order was already scheduled", func(t *testing.T) {
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindJobByOrderID: func(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+				return datatypes.ReviewJob{OrderID: orderID}, nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		_, err := service.ScheduleReview(context.Background(), getMockedOrderDeliveredRequest())
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrReviewAlreadyScheduled)
+	})
+
+	t.Run("should fail when the order is scheduled by a concurrent request", func(t *testing.T) {
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindJobByOrderID: func(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+				return datatypes.ReviewJob{}, sql.ErrNoRows
+			},
+			CallbackCreateJob: func(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+				return datatypes.ReviewJob{}, ErrReviewAlreadyScheduled
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		response := service.ScheduleReviews(context.Background(), []datatypes.OrderDeliveredRequest{getMockedOrderDeliveredRequest()})
+		assert.Equal(t, []datatypes.ScheduleReviewResult{{OrderID: "order-1", Status: ScheduleStatusDuplicated}}, response.Results)
+	})
+
+	t.Run("should fail when required fields are missing", func(t *testing.T) {
+		service := NewSchedulerService(&repositoryMock{
+			Error: errors.New("invalid repository call when testing"),
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		_, err := service.ScheduleReview(context.Background(), datatypes.OrderDeliveredRequest{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrMissingRequiredFields)
+	})
+}
+
+func TestServiceScheduleReviews(t *testing.T) {
+	t.Run("should skip duplicated orders", func(t *testing.T) {
+		scheduled := map[string]bool{"order-1": true}
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindJobByOrderID: func(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+				if scheduled[orderID] {
+					return datatypes.ReviewJob{OrderID: orderID}, nil
+				}
+				return datatypes.ReviewJob{}, sql.ErrNoRows
+			},
+			CallbackCreateJob: func(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+				scheduled[job.OrderID] = true
+				return job, nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		first := getMockedOrderDeliveredRequest()
+		second := getMockedOrderDeliveredRequest()
+		second.OrderID = "order-2"
+
+		response := service.ScheduleReviews(context.Background(), []datatypes.OrderDeliveredRequest{first, second, second})
+		statuses := []string{}
+		for _, result := range response.Results {
+			statuses = append(statuses, result.Status)
+		}
+		assert.Equal(t, []string{ScheduleStatusDuplicated, ScheduleStatusScheduled, ScheduleStatusDuplicated}, statuses)
+		assert.Equal(t, "order-2", response.Results[1].Job.OrderID)
+	})
+
+	t.Run("should keep scheduling when an order fails", func(t *testing.T) {
+		errCreateJob := errors.New("error when creating the job for tests")
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindJobByOrderID: func(ctx context.Context, orderID string) (datatypes.ReviewJob, error) {
+				return datatypes.ReviewJob{}, sql.ErrNoRows
+			},
+			CallbackCreateJob: func(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+				if job.OrderID == "order-1" {
+					return datatypes.ReviewJob{}, errCreateJob
+				}
+				return job, nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		first := getMockedOrderDeliveredRequest()
+		second := getMockedOrderDeliveredRequest()
+		second.OrderID = "order-2"
+
+		response := service.ScheduleReviews(context.Background(), []datatypes.OrderDeliveredRequest{first, second})
+		assert.Len(t, response.Results, 2)
+		assert.Equal(t, ScheduleStatusFailed, response.Results[0].Status)
+		assert.Contains(t, response.Results[0].Error, errCreateJob.Error())
+		assert.Equal(t, ScheduleStatusScheduled, response.Results[1].Status)
+	})
+}
+
+func TestServiceRunDueJobs(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should mark the job as done", func(t *testing.T) {
+		var updated datatypes.ReviewJob
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{{ID: "qwerty", Status: JobStatusPending}}, nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				updated = job
+				return nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{MaxAttempts: 3})
+
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{}))
+		assert.Equal(t, JobStatusDone, updated.Status)
+		assert.Equal(t, 1, updated.Attempts)
+	})
+
+	t.Run("should retry the job with backoff", func(t *testing.T) {
+		var updated datatypes.ReviewJob
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{{ID: "qwerty", Status: JobStatusPending, Attempts: 1}}, nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				updated = job
+				return nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{MaxAttempts: 3, RetryInterval: time.Minute})
+		service.now = func() time.Time { return now }
+
+		errStartReview := errors.New("user is offline")
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{Error: errStartReview}))
+		assert.Equal(t, JobStatusPending, updated.Status)
+		assert.Equal(t, 2, updated.Attempts)
+		assert.Equal(t, errStartReview.Error(), updated.LastError)
+		assert.Equal(t, now.Add(2*time.Minute), updated.RunAt)
+	})
+
+	t.Run("should mark the job as failed after the last attempt", func(t *testing.T) {
+		var updated datatypes.ReviewJob
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{{ID: "qwerty", Status: JobStatusPending, Attempts: 2}}, nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				updated = job
+				return nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{MaxAttempts: 3})
+
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{Error: errors.New("user is offline")}))
+		assert.Equal(t, JobStatusFailed, updated.Status)
+		assert.Equal(t, 3, updated.Attempts)
+	})
+
+	t.Run("should skip the jobs claimed by another instance", func(t *testing.T) {
+		var started []string
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{
+					{ID: "claimed", UserEmail: "john@mail.com", Status: JobStatusPending},
+					{ID: "free", UserEmail: "mary@mail.com", Status: JobStatusPending},
+				}, nil
+			},
+			CallbackClaimJob: func(ctx context.Context, job datatypes.ReviewJob, claimedAt time.Time, until time.Time) (bool, error) {
+				assert.Equal(t, now.Add(time.Minute), until)
+				return job.ID == "free", nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				return nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{ClaimTimeout: time.Minute})
+		service.now = func() time.Time { return now }
+
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{
+			CallbackStartReview: func(ctx context.Context, name string, email string, product string) error {
+				started = append(started, email)
+				return nil
+			},
+		}))
+		assert.Equal(t, []string{"mary@mail.com"}, started)
+	})
+
+	t.Run("should keep running the batch when a job can't be updated", func(t *testing.T) {
+		var updated []string
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{{ID: "first", Status: JobStatusPending}, {ID: "second", Status: JobStatusPending}}, nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				updated = append(updated, job.ID)
+				if job.ID == "first" {
+					return ErrCantUpdateReviewJob
+				}
+				return nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{}))
+		assert.Equal(t, []string{"first", "second"}, updated)
+	})
+}
+
+func TestServiceRunDueJobsTenant(t *testing.T) {
+	t.Run("should start the review in the tenant of the job", func(t *testing.T) {
+		var tenantID string
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{{ID: "qwerty", TenantID: "acme", Status: JobStatusPending}}, nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				return nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{MaxAttempts: 3})
+
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{
+			CallbackStartReview: func(ctx context.Context, name string, email string, product string) error {
+				tenantID = tenant.IDFromContext(ctx)
+				return nil
+			},
+		}))
+		assert.Equal(t, "acme", tenantID)
+	})
+
+	t.Run("should fail the job when the tenant is unknown", func(t *testing.T) {
+		var updated datatypes.ReviewJob
+		service := NewSchedulerService(&repositoryMock{
+			CallbackFindDueJobs: func(ctx context.Context, now time.Time, limit int) ([]datatypes.ReviewJob, error) {
+				return []datatypes.ReviewJob{{ID: "qwerty", TenantID: "acme", Status: JobStatusPending}}, nil
+			},
+			CallbackUpdateJob: func(ctx context.Context, job datatypes.ReviewJob) error {
+				updated = job
+				return nil
+			},
+		}, &tenantFinderMock{Error: tenant.ErrTenantNotFound}, SchedulerServiceConfig{MaxAttempts: 1})
+
+		assert.NoError(t, service.RunDueJobs(context.Background(), &reviewStarterMock{}))
+		assert.Equal(t, JobStatusFailed, updated.Status)
+		assert.Equal(t, tenant.ErrTenantNotFound.Error(), updated.LastError)
+	})
+
+	t.Run("should schedule the review in the tenant of the context", func(t *testing.T) {
+		var created datatypes.ReviewJob
+		service := NewSchedulerService(&repositoryMock{
+			Error: sql.ErrNoRows,
+			CallbackCreateJob: func(ctx context.Context, job datatypes.ReviewJob) (datatypes.ReviewJob, error) {
+				created = job
+				return job, nil
+			},
+		}, &tenantFinderMock{}, SchedulerServiceConfig{})
+
+		ctx := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "acme"})
+		_, err := service.ScheduleReview(ctx, getMockedOrderDeliveredRequest())
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", created.TenantID)
+	})
+}
+
+func getMockedOrderDeliveredRequest() datatypes.OrderDeliveredRequest {
+	return datatypes.OrderDeliveredRequest{
+		OrderID: "order-1",
+		User: datatypes.CreateReviewUser{
+			Name:  "john",
+			Email: "john.wick@continental.com",
+		},
+		Product:     "Galaxy S24 Ultra",
+		DeliveredAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
@@ -0,0 +1,18 @@
+package sqlutil
+
+import "strings"
+
+// likeEscaper escapes the LIKE wildcards with "!". The queries declare it with ESCAPE '!', unlike the
+// backslash it's a single character on SQLite and MySQL with or without NO_BACKSLASH_ESCAPES.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// EscapeLike escapes the LIKE wildcards of the value, so they match themselves
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// IsDuplicateKey tells if the error is a unique constraint violation on MySQL or SQLite
+func IsDuplicateKey(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "Duplicate entry") || strings.Contains(message, "UNIQUE constraint failed")
+}
@@ -0,0 +1,29 @@
+package sqlutil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeLike(t *testing.T) {
+	t.Run("should escape the wildcards and the escape character", func(t *testing.T) {
+		assert.Equal(t, "50!% off!_sale!!", EscapeLike("50% off_sale!"))
+	})
+
+	t.Run("should keep the backslashes", func(t *testing.T) {
+		assert.Equal(t, `C:\tv`, EscapeLike(`C:\tv`))
+	})
+}
+
+func TestIsDuplicateKey(t *testing.T) {
+	t.Run("should detect the MySQL and SQLite unique violations", func(t *testing.T) {
+		assert.True(t, IsDuplicateKey(errors.New("Error 1062 (23000): Duplicate entry 'john@wick.com' for key 'users.email'")))
+		assert.True(t, IsDuplicateKey(errors.New("UNIQUE constraint failed: users.email")))
+	})
+
+	t.Run("should ignore the other errors", func(t *testing.T) {
+		assert.False(t, IsDuplicateKey(errors.New("connection refused")))
+	})
+}
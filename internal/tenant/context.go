@@ -0,0 +1,32 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+)
+
+// DefaultID is the tenant of the hosts that don't belong to any tenant, of the tokens
+// and of the data created before the tenants
+const DefaultID = "default"
+
+type contextKey struct{}
+
+// WithTenant returns a copy of the context carrying the tenant
+func WithTenant(ctx context.Context, tenant datatypes.Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenant)
+}
+
+// FromContext returns the tenant carried by the context
+func FromContext(ctx context.Context) (datatypes.Tenant, bool) {
+	tenant, ok := ctx.Value(contextKey{}).(datatypes.Tenant)
+	return tenant, ok
+}
+
+// IDFromContext returns the id of the tenant carried by the context, or the default tenant
+func IDFromContext(ctx context.Context) string {
+	if tenant, ok := FromContext(ctx); ok && tenant.ID != "" {
+		return tenant.ID
+	}
+	return DefaultID
+}
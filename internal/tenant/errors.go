@@ -0,0 +1,10 @@
+package tenant
+
+import "errors"
+
+var (
+	ErrTenantNotFound     = errors.New("tenant not found")
+	ErrDomainAlreadyUsed  = errors.New("the domain already belongs to a tenant")
+	ErrCantSaveTenant     = errors.New("failed to create new tenant. Cause: can't save the tenant")
+	ErrMissingDefaultName = errors.New("missing the default tenant name")
+)
@@ -0,0 +1,16 @@
+package tenant
+
+var createTenant = `
+	INSERT INTO tenants (
+		id, name, domain, support_email, prompt, policies, model, temperature, api_key_hash, created_at
+	)
+	VALUES (
+		:id, :name, :domain, :support_email, :prompt, :policies, :model, :temperature, :api_key_hash, :created_at
+	);
+`
+
+var findTenants = `
+	SELECT id, name, domain, support_email, prompt, policies, model, temperature, api_key_hash, created_at
+	FROM tenants
+	ORDER BY name;
+`
@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/sqlutil"
+)
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// CreateTenant saves a new tenant
+func (r *Repository) CreateTenant(ctx context.Context, tenant datatypes.Tenant) error {
+	stm, err := r.db.PrepareNamedContext(ctx, createTenant)
+	if err != nil {
+		return fmt.Errorf("failed to create new tenant. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":            tenant.ID,
+		"name":          tenant.Name,
+		"domain":        tenant.Domain,
+		"support_email": tenant.SupportEmail,
+		"prompt":        tenant.Prompt,
+		"policies":      tenant.Policies,
+		"model":         tenant.Model,
+		"temperature":   tenant.Temperature,
+		"api_key_hash":  tenant.APIKeyHash,
+		"created_at":    tenant.CreatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		if sqlutil.IsDuplicateKey(err) {
+			return ErrDomainAlreadyUsed
+		}
+		return fmt.Errorf("failed to create new tenant. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create new tenant. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantSaveTenant
+	}
+	return nil
+}
+
+// FindTenants finds every tenant
+func (r *Repository) FindTenants(ctx context.Context) ([]datatypes.Tenant, error) {
+	tenants := []datatypes.Tenant{}
+
+	if err := r.db.SelectContext(ctx, &tenants, findTenants); err != nil {
+		return nil, fmt.Errorf("failed to find tenants. Cause: %w", err)
+	}
+	return tenants, nil
+}
@@ -0,0 +1,45 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateTenant(t *testing.T) {
+	newRepository := func(err error, rows int64) *Repository {
+		return NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return rows, nil
+							},
+						}, err
+					},
+				}, nil
+			},
+		})
+	}
+
+	t.Run("should create a new tenant", func(t *testing.T) {
+		assert.NoError(t, newRepository(nil, 1).CreateTenant(context.Background(), datatypes.Tenant{ID: "acme"}))
+	})
+
+	t.Run("should fail when the domain or the api key already exist", func(t *testing.T) {
+		err := newRepository(errors.New("Error 1062 (23000): Duplicate entry 'acme.com' for key 'domain'"), 0).
+			CreateTenant(context.Background(), datatypes.Tenant{ID: "acme"})
+		assert.ErrorIs(t, err, ErrDomainAlreadyUsed)
+	})
+
+	t.Run("should fail when there are no affected rows", func(t *testing.T) {
+		err := newRepository(nil, 0).CreateTenant(context.Background(), datatypes.Tenant{ID: "acme"})
+		assert.ErrorIs(t, err, ErrCantSaveTenant)
+	})
+}
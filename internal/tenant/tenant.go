@@ -0,0 +1,236 @@
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/gofrs/uuid/v5"
+)
+
+type repository interface {
+	CreateTenant(ctx context.Context, tenant datatypes.Tenant) error
+	FindTenants(ctx context.Context) ([]datatypes.Tenant, error)
+}
+
+type TenantServiceConfig struct {
+	// Default is the tenant of the hosts that don't belong to any tenant and of the data created before the tenants
+	Default datatypes.Tenant
+	// RefreshInterval is how often the tenants created by other instances are loaded
+	RefreshInterval time.Duration
+}
+
+// validate check if configs are valid
+func (tsc TenantServiceConfig) validate() error {
+	if tsc.Default.Name == "" {
+		return ErrMissingDefaultName
+	}
+	return nil
+}
+
+type TenantService struct {
+	repository repository
+	config     TenantServiceConfig
+	mutex      sync.RWMutex
+	byID       map[string]datatypes.Tenant
+	byDomain   map[string]datatypes.Tenant
+	byKeyHash  map[string]datatypes.Tenant
+	now        func() time.Time
+}
+
+// NewTenantService create a new tenant service. The tenants are kept in memory, call Refresh
+// to load them.
+func NewTenantService(repository repository, config TenantServiceConfig) (*TenantService, error) {
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("failed to create tenant service. Cause: %w", err)
+	}
+
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = time.Minute
+	}
+	config.Default.ID = DefaultID
+
+	ts := &TenantService{
+		repository: repository,
+		config:     config,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+	ts.load(nil)
+	return ts, nil
+}
+
+// Default returns the default tenant
+func (ts *TenantService) Default() datatypes.Tenant {
+	return ts.config.Default
+}
+
+// FindByID finds a tenant by id
+func (ts *TenantService) FindByID(id string) (datatypes.Tenant, error) {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	if tenant, ok := ts.byID[id]; ok {
+		return tenant, nil
+	}
+	return datatypes.Tenant{}, ErrTenantNotFound
+}
+
+// FindByAPIKey finds the tenant that owns the api key
+func (ts *TenantService) FindByAPIKey(key string) (datatypes.Tenant, error) {
+	if key == "" {
+		return datatypes.Tenant{}, ErrTenantNotFound
+	}
+
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	if tenant, ok := ts.byKeyHash[hashAPIKey(key)]; ok {
+		return tenant, nil
+	}
+	return datatypes.Tenant{}, ErrTenantNotFound
+}
+
+// FindByHost finds the tenant of the host, ignoring the port and the www prefix. The hosts
+// that don't belong to any tenant belong to the default tenant.
+func (ts *TenantService) FindByHost(host string) datatypes.Tenant {
+	ts.mutex.RLock()
+	defer ts.mutex.RUnlock()
+
+	if tenant, ok := ts.byDomain[normalizeDomain(host)]; ok {
+		return tenant
+	}
+	return ts.config.Default
+}
+
+// List lists the tenants, the default tenant first
+func (ts *TenantService) List(ctx context.Context) ([]datatypes.Tenant, error) {
+	tenants, err := ts.repository.FindTenants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append([]datatypes.Tenant{ts.config.Default}, tenants...), nil
+}
+
+// Create creates a tenant with a new api key. The key is only returned here, just its hash is saved.
+func (ts *TenantService) Create(ctx context.Context, req datatypes.CreateTenantRequest) (datatypes.CreatedTenant, error) {
+	baseError := "failed to create new tenant. Cause: %w"
+
+	if ts.FindByHost(req.Domain).ID != DefaultID || normalizeDomain(req.Domain) == normalizeDomain(ts.config.Default.Domain) {
+		return datatypes.CreatedTenant{}, fmt.Errorf(baseError, ErrDomainAlreadyUsed)
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.CreatedTenant{}, fmt.Errorf(baseError, err)
+	}
+
+	apiKey, err := newAPIKey()
+	if err != nil {
+		return datatypes.CreatedTenant{}, fmt.Errorf(baseError, err)
+	}
+
+	createdAt := ts.now()
+	tenant := datatypes.Tenant{
+		ID:           id.String(),
+		Name:         req.Name,
+		Domain:       normalizeDomain(req.Domain),
+		SupportEmail: req.SupportEmail,
+		Prompt:       req.Prompt,
+		Policies:     req.Policies,
+		Model:        req.Model,
+		Temperature:  req.Temperature,
+		APIKeyHash:   hashAPIKey(apiKey),
+		CreatedAt:    &createdAt,
+	}
+
+	if err := ts.repository.CreateTenant(ctx, tenant); err != nil {
+		return datatypes.CreatedTenant{}, err
+	}
+
+	ts.mutex.Lock()
+	ts.add(tenant)
+	ts.mutex.Unlock()
+
+	return datatypes.CreatedTenant{Tenant: tenant, APIKey: apiKey}, nil
+}
+
+// Refresh loads the tenants from the database
+func (ts *TenantService) Refresh(ctx context.Context) error {
+	tenants, err := ts.repository.FindTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	ts.load(tenants)
+	return nil
+}
+
+// Start loads the tenants created by other instances until the context is done
+func (ts *TenantService) Start(ctx context.Context) {
+	ticker := time.NewTicker(ts.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := ts.Refresh(ctx); err != nil {
+			golog.Log().Error(ctx, err.Error())
+		}
+	}
+}
+
+// load replaces the tenants in memory
+func (ts *TenantService) load(tenants []datatypes.Tenant) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	ts.byID = map[string]datatypes.Tenant{DefaultID: ts.config.Default}
+	ts.byDomain = map[string]datatypes.Tenant{}
+	ts.byKeyHash = map[string]datatypes.Tenant{}
+
+	for _, tenant := range tenants {
+		ts.add(tenant)
+	}
+}
+
+// add must be called holding the mutex
+func (ts *TenantService) add(tenant datatypes.Tenant) {
+	ts.byID[tenant.ID] = tenant
+	ts.byDomain[normalizeDomain(tenant.Domain)] = tenant
+	ts.byKeyHash[tenant.APIKeyHash] = tenant
+}
+
+// normalizeDomain lower cases the domain and removes the port and the www prefix
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if index := strings.LastIndex(domain, ":"); index >= 0 {
+		domain = domain[:index]
+	}
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// newAPIKey returns a random api key
+func newAPIKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// hashAPIKey returns the hex encoded SHA-256 of the api key
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,114 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repositoryMock struct {
+	Error   error
+	Tenants []datatypes.Tenant
+}
+
+func (rm *repositoryMock) CreateTenant(ctx context.Context, tenant datatypes.Tenant) error {
+	if rm.Error != nil {
+		return rm.Error
+	}
+	rm.Tenants = append(rm.Tenants, tenant)
+	return nil
+}
+
+func (rm *repositoryMock) FindTenants(ctx context.Context) ([]datatypes.Tenant, error) {
+	return rm.Tenants, rm.Error
+}
+
+var defaultTenant = datatypes.Tenant{Name: "AI Tech Shop", Domain: "www.aitechshop.com", SupportEmail: "support@aitechshop.com"}
+
+func newTestService(t *testing.T, repository *repositoryMock) *TenantService {
+	service, err := NewTenantService(repository, TenantServiceConfig{Default: defaultTenant})
+	require.NoError(t, err)
+	return service
+}
+
+func TestTenantServiceCreate(t *testing.T) {
+	t.Run("should create a tenant reachable by its api key, host and id", func(t *testing.T) {
+		repository := &repositoryMock{}
+		service := newTestService(t, repository)
+
+		created, err := service.Create(context.Background(), datatypes.CreateTenantRequest{
+			Name:         "Gadget Store",
+			Domain:       "WWW.Gadgets.example",
+			SupportEmail: "help@gadgets.example",
+		})
+		require.NoError(t, err)
+		assert.NotEmpty(t, created.APIKey)
+		assert.Equal(t, "gadgets.example", created.Domain)
+		assert.NotContains(t, repository.Tenants[0].APIKeyHash, created.APIKey)
+
+		byKey, err := service.FindByAPIKey(created.APIKey)
+		require.NoError(t, err)
+		assert.Equal(t, created.ID, byKey.ID)
+
+		assert.Equal(t, created.ID, service.FindByHost("gadgets.example:9000").ID)
+		assert.Equal(t, DefaultID, service.FindByHost("localhost:9000").ID)
+
+		byID, err := service.FindByID(created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Gadget Store", byID.Name)
+	})
+
+	t.Run("should reject a domain already used", func(t *testing.T) {
+		service := newTestService(t, &repositoryMock{})
+
+		_, err := service.Create(context.Background(), datatypes.CreateTenantRequest{Name: "Copy", Domain: "aitechshop.com"})
+		assert.ErrorIs(t, err, ErrDomainAlreadyUsed)
+
+		_, err = service.Create(context.Background(), datatypes.CreateTenantRequest{Name: "Gadget Store", Domain: "gadgets.example"})
+		require.NoError(t, err)
+
+		_, err = service.Create(context.Background(), datatypes.CreateTenantRequest{Name: "Copy", Domain: "www.gadgets.example"})
+		assert.ErrorIs(t, err, ErrDomainAlreadyUsed)
+	})
+}
+
+func TestTenantServiceRefresh(t *testing.T) {
+	t.Run("should load the tenants created by other instances", func(t *testing.T) {
+		repository := &repositoryMock{}
+		service := newTestService(t, repository)
+
+		repository.Tenants = []datatypes.Tenant{{ID: "qwerty", Name: "Gadget Store", Domain: "gadgets.example", APIKeyHash: hashAPIKey("secret")}}
+		require.NoError(t, service.Refresh(context.Background()))
+
+		tenant, err := service.FindByAPIKey("secret")
+		require.NoError(t, err)
+		assert.Equal(t, "qwerty", tenant.ID)
+
+		tenants, err := service.List(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{DefaultID, "qwerty"}, []string{tenants[0].ID, tenants[1].ID})
+	})
+
+	t.Run("should keep the tenants in memory when the database fails", func(t *testing.T) {
+		repository := &repositoryMock{Tenants: []datatypes.Tenant{{ID: "qwerty", APIKeyHash: hashAPIKey("secret")}}}
+		service := newTestService(t, repository)
+		require.NoError(t, service.Refresh(context.Background()))
+
+		repository.Error = errors.New("connection refused")
+		assert.Error(t, service.Refresh(context.Background()))
+
+		_, err := service.FindByAPIKey("secret")
+		assert.NoError(t, err)
+	})
+}
+
+func TestIDFromContext(t *testing.T) {
+	t.Run("should fall back to the default tenant", func(t *testing.T) {
+		assert.Equal(t, DefaultID, IDFromContext(context.Background()))
+		assert.Equal(t, "qwerty", IDFromContext(WithTenant(context.Background(), datatypes.Tenant{ID: "qwerty"})))
+	})
+}
@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentDB starts a span for every query run by a repository. Only the methods used by
+// the repositories are traced: named statements, SelectContext and named executions in transactions.
+func InstrumentDB(db godb.DB, repository string) godb.DB {
+	return &tracedDB{DB: db, repository: repository}
+}
+
+type tracedDB struct {
+	godb.DB
+	repository string
+}
+
+// PrepareNamedContext
+func (tdb *tracedDB) PrepareNamedContext(ctx context.Context, query string) (godb.NamedStmt, error) {
+	stmt, err := tdb.DB.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return stmt, err
+	}
+	return &tracedNamedStmt{NamedStmt: stmt, repository: tdb.repository, query: query}, nil
+}
+
+// SelectContext
+func (tdb *tracedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) (err error) {
+	ctx, span := startQuery(ctx, tdb.repository, query)
+	defer func() { End(span, err) }()
+	return tdb.DB.SelectContext(ctx, dest, query, args...)
+}
+
+// BeginTx
+func (tdb *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+	tx, err := tdb.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return tx, err
+	}
+	return &tracedTx{Tx: tx, repository: tdb.repository}, nil
+}
+
+type tracedNamedStmt struct {
+	godb.NamedStmt
+	repository string
+	query      string
+}
+
+// ExecContext
+func (tns *tracedNamedStmt) ExecContext(ctx context.Context, arg interface{}) (result sql.Result, err error) {
+	ctx, span := startQuery(ctx, tns.repository, tns.query)
+	defer func() { End(span, err) }()
+	return tns.NamedStmt.ExecContext(ctx, arg)
+}
+
+// GetContext
+func (tns *tracedNamedStmt) GetContext(ctx context.Context, dest interface{}, arg interface{}) (err error) {
+	ctx, span := startQuery(ctx, tns.repository, tns.query)
+	defer func() { End(span, err) }()
+	return tns.NamedStmt.GetContext(ctx, dest, arg)
+}
+
+// SelectContext
+func (tns *tracedNamedStmt) SelectContext(ctx context.Context, dest interface{}, arg interface{}) (err error) {
+	ctx, span := startQuery(ctx, tns.repository, tns.query)
+	defer func() { End(span, err) }()
+	return tns.NamedStmt.SelectContext(ctx, dest, arg)
+}
+
+type tracedTx struct {
+	godb.Tx
+	repository string
+}
+
+// NamedExecContext
+func (ttx *tracedTx) NamedExecContext(ctx context.Context, query string, arg interface{}) (result sql.Result, err error) {
+	ctx, span := startQuery(ctx, ttx.repository, query)
+	defer func() { End(span, err) }()
+	return ttx.Tx.NamedExecContext(ctx, query, arg)
+}
+
+// startQuery starts a client span named after the repository. The query is recorded without the arguments.
+func startQuery(ctx context.Context, repository string, query string) (context.Context, trace.Span) {
+	ctx, span := Start(ctx, "db."+repository,
+		semconv.DBSystemMySQL,
+		semconv.DBStatement(query),
+		attribute.String("db.repository", repository),
+	)
+	return ctx, span
+}
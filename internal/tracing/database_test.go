@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+func TestInstrumentDB(t *testing.T) {
+	t.Run("should trace the named statements", func(t *testing.T) {
+		recorder := recordSpans(t)
+		db := InstrumentDB(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest interface{}, arg interface{}) error {
+						return nil
+					},
+				}, nil
+			},
+		}, "user")
+
+		ctx, parent := Start(context.Background(), "request")
+		stmt, err := db.PrepareNamedContext(ctx, "SELECT * FROM users WHERE id = :id;")
+		require.NoError(t, err)
+		assert.NoError(t, stmt.GetContext(ctx, &struct{}{}, map[string]interface{}{"id": "secret"}))
+		parent.End()
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "db.user", spans[0].Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Contains(t, spans[0].Attributes(), semconv.DBStatement("SELECT * FROM users WHERE id = :id;"))
+	})
+
+	t.Run("should record the transaction errors", func(t *testing.T) {
+		recorder := recordSpans(t)
+		errExec := errors.New("error when executing named query for tests")
+		db := InstrumentDB(&godb.DBMock{
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						return nil, errExec
+					},
+				}, nil
+			},
+		}, "chat")
+
+		tx, err := db.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+		_, err = tx.NamedExecContext(context.Background(), "DELETE FROM chats WHERE id = :id;", map[string]interface{}{})
+		assert.ErrorIs(t, err, errExec)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
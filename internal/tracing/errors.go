@@ -0,0 +1,8 @@
+package tracing
+
+import "errors"
+
+var (
+	ErrUnknownExporter    = errors.New("unknown tracing exporter")
+	ErrInvalidSampleRatio = errors.New("tracing sample ratio must be between 0 and 1")
+)
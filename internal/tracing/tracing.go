@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterNone disables the tracing
+	ExporterNone = "none"
+	// ExporterStdout prints the spans, for local runs
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends the spans to an OpenTelemetry collector over HTTP
+	ExporterOTLP = "otlp"
+
+	tracerName = "github.com/JhonatanRSantos/review-chatbot"
+)
+
+type TracingConfig struct {
+	// Exporter is none (default), stdout or otlp
+	Exporter    string
+	ServiceName string
+	// Endpoint of the OTLP collector, e.g. localhost:4318. Empty uses OTEL_EXPORTER_OTLP_ENDPOINT.
+	Endpoint string
+	// Insecure sends the spans to the collector over plain HTTP
+	Insecure bool
+	// SampleRatio of the new traces, from 0 to 1. Traces started by the callers keep their decision.
+	SampleRatio float64
+	// Writer of the stdout exporter. Defaults to os.Stdout.
+	Writer io.Writer
+}
+
+// validate check if configs are valid
+func (tc TracingConfig) validate() error {
+	switch tc.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+	default:
+		return ErrUnknownExporter
+	}
+
+	if tc.SampleRatio < 0 || tc.SampleRatio > 1 {
+		return ErrInvalidSampleRatio
+	}
+	return nil
+}
+
+// Setup registers the global tracer provider and the W3C trace context propagator. The returned
+// func flushes the pending spans and must be called before the process exits.
+func Setup(ctx context.Context, config TracingConfig) (func(context.Context) error, error) {
+	baseError := "failed to setup tracing. Cause: %w"
+
+	if config.Exporter == "" {
+		config.Exporter = ExporterNone
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf(baseError, err)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if config.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf(baseError, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf(baseError, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// newExporter
+func newExporter(ctx context.Context, config TracingConfig) (sdktrace.SpanExporter, error) {
+	if config.Exporter == ExporterStdout {
+		writer := config.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(writer))
+	}
+
+	options := []otlptracehttp.Option{}
+	if config.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+	}
+
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, options...)
+}
+
+// Start starts a span as a child of the span in the context, if any. The returned context keeps
+// the gocontext values of the parent, so it can be passed to the services as any other context.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(gocontext.FromContext(ctx), name, trace.WithAttributes(attributes...))
+}
+
+// Detach returns a new gocontext with the span of the context but without its cancellation, for the
+// work that keeps running in background after the request, e.g. the message analysis.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(gocontext.FromContext(context.Background()), trace.SpanContextFromContext(ctx))
+}
+
+// End records the error, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
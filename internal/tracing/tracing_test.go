@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/JhonatanRSantos/gocore/pkg/gocontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans registers a tracer provider that keeps the ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("should reject unknown exporters", func(t *testing.T) {
+		_, err := Setup(context.Background(), TracingConfig{Exporter: "jaeger"})
+		assert.ErrorIs(t, err, ErrUnknownExporter)
+	})
+
+	t.Run("should reject invalid sample ratios", func(t *testing.T) {
+		_, err := Setup(context.Background(), TracingConfig{Exporter: ExporterStdout, SampleRatio: 1.5})
+		assert.ErrorIs(t, err, ErrInvalidSampleRatio)
+	})
+
+	t.Run("should print the spans with the stdout exporter", func(t *testing.T) {
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		output := &bytes.Buffer{}
+		shutdown, err := Setup(context.Background(), TracingConfig{
+			Exporter:    ExporterStdout,
+			ServiceName: "review-chatbot-test",
+			SampleRatio: 1,
+			Writer:      output,
+		})
+		require.NoError(t, err)
+
+		_, span := Start(context.Background(), "test.Span")
+		span.End()
+
+		require.NoError(t, shutdown(context.Background()))
+		assert.Contains(t, output.String(), `"Name":"test.Span"`)
+		assert.Contains(t, output.String(), "review-chatbot-test")
+	})
+
+	t.Run("should do nothing without exporter", func(t *testing.T) {
+		shutdown, err := Setup(context.Background(), TracingConfig{})
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+}
+
+func TestStart(t *testing.T) {
+	t.Run("should start child spans in a gocontext", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		ctx := gocontext.Add(gocontext.FromContext(context.Background()), "request_id", "abc")
+		parentCtx, parent := Start(ctx, "parent")
+		childCtx, child := Start(parentCtx, "child", attribute.String("chat.id", "chat-1"))
+		End(child, nil)
+		End(parent, nil)
+
+		value, ok := gocontext.Get[string](childCtx, "request_id")
+		assert.True(t, ok)
+		assert.Equal(t, "abc", value)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 2)
+		assert.Equal(t, "child", spans[0].Name())
+		assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+		assert.Contains(t, spans[0].Attributes(), attribute.String("chat.id", "chat-1"))
+	})
+
+	t.Run("should record the errors", func(t *testing.T) {
+		recorder := recordSpans(t)
+
+		_, span := Start(context.Background(), "failed")
+		End(span, errors.New("error when running for tests"))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "error when running for tests", spans[0].Status().Description)
+		assert.Len(t, spans[0].Events(), 1)
+	})
+}
+
+func TestDetach(t *testing.T) {
+	t.Run("should keep the span without the cancellation", func(t *testing.T) {
+		recordSpans(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx, span := Start(ctx, "request")
+		defer span.End()
+		cancel()
+
+		detached := Detach(ctx)
+		assert.NoError(t, detached.Err())
+		assert.Equal(t, span.SpanContext(), trace.SpanContextFromContext(detached))
+	})
+}
@@ -5,4 +5,8 @@ import "errors"
 var (
 	ErrCantSaveUser          = errors.New("failed to create new user. Cause: can't save the user")
 	ErrMissingRequiredFields = errors.New("missing required fields")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrUserAlreadyExists     = errors.New("user already exists")
+	ErrVersionConflict       = errors.New("the user was changed by another request, read it again")
+	ErrCantSaveAuditEntry    = errors.New("can't save audit entry")
 )
@@ -1,10 +1,132 @@
 package user
 
 var createUser = `
-	INSERT INTO users (id, first_name, last_name, email)
-	VALUES (:id, :first_name, :last_name, :email);
+	INSERT INTO users (id, tenant_id, first_name, last_name, email, version, created_at)
+	VALUES (:id, :tenant_id, :first_name, :last_name, :email, 1, :created_at);
 `
 
 var findUserByEmail = `
-	SELECT id, first_name, last_name, email FROM users WHERE email = :email;
+	SELECT id, first_name, last_name, email, version, created_at, updated_at, deleted_at
+	FROM users WHERE email = :email AND tenant_id = :tenant_id;
+`
+
+var findUserByID = `
+	SELECT id, first_name, last_name, email, version, created_at, updated_at, deleted_at
+	FROM users WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+var findUsers = `
+	SELECT id, first_name, last_name, email, version, created_at, updated_at, deleted_at
+	FROM users
+	WHERE tenant_id = :tenant_id
+		AND (
+			:search = ''
+			OR email LIKE :pattern ESCAPE '!'
+			OR first_name LIKE :pattern ESCAPE '!'
+			OR last_name LIKE :pattern ESCAPE '!'
+		)
+		AND (:include_deleted = 1 OR deleted_at IS NULL)
+	ORDER BY email
+	LIMIT :limit OFFSET :offset;
+`
+
+var updateUser = `
+	UPDATE users
+	SET first_name = :first_name, last_name = :last_name, email = :email, version = version + 1, updated_at = :updated_at
+	WHERE id = :id AND tenant_id = :tenant_id AND version = :version AND deleted_at IS NULL;
+`
+
+var deactivateUser = `
+	UPDATE users SET deleted_at = :deleted_at, version = version + 1
+	WHERE id = :id AND tenant_id = :tenant_id AND deleted_at IS NULL;
+`
+
+var findUserChats = `
+	SELECT id, user_id, product, status, created_at, closed_at FROM chats
+	WHERE user_id = :user_id
+	ORDER BY created_at;
+`
+
+var findUserMessages = `
+	SELECT m.id, m.chat_id, m.author, m.message, m.created_at
+	FROM messages m
+	JOIN chats c ON c.id = m.chat_id
+	WHERE c.user_id = :user_id
+	ORDER BY m.created_at;
+`
+
+var findUserAnalyses = `
+	SELECT a.message_id, a.chat_id, a.sentiment, a.intent, a.language, a.classifier, a.created_at
+	FROM message_analyses a
+	JOIN chats c ON c.id = a.chat_id
+	WHERE c.user_id = :user_id
+	ORDER BY a.created_at;
+`
+
+var findUserReviewJobs = `
+	SELECT id, order_id, user_name, user_email, product, delivered_at, run_at, status, attempts, last_error,
+		created_at, updated_at
+	FROM review_jobs
+	WHERE user_email = :email AND tenant_id = :tenant_id
+	ORDER BY created_at;
+`
+
+var findUserInvitations = `
+	SELECT id, user_name, user_email, product, status, last_error, sent_at, opened_at, created_at
+	FROM review_invitations
+	WHERE user_email = :email AND tenant_id = :tenant_id
+	ORDER BY created_at;
+`
+
+var deleteUserAnalyses = `
+	DELETE FROM message_analyses WHERE chat_id IN (SELECT id FROM chats WHERE user_id = :user_id);
+`
+
+var deleteUserMessages = `
+	DELETE FROM messages WHERE chat_id IN (SELECT id FROM chats WHERE user_id = :user_id);
+`
+
+var anonymizeUserChats = `
+	UPDATE chats SET user_id = '' WHERE user_id = :user_id;
+`
+
+var deleteUserInvitations = `
+	DELETE FROM review_invitations WHERE user_email = :email AND tenant_id = :tenant_id;
+`
+
+var deleteUserReviewJobs = `
+	DELETE FROM review_jobs WHERE user_email = :email AND tenant_id = :tenant_id;
+`
+
+var findUserChatIDs = `
+	SELECT id FROM chats WHERE user_id = :user_id AND tenant_id = :tenant_id;
+`
+
+var findUserExportFiles = `
+	SELECT file_path FROM export_jobs
+	WHERE tenant_id = :tenant_id AND (user_id = :user_id OR email = :email) AND file_path <> '';
+`
+
+// the events don't reference the user in a column, the payloads mentioning the user or its chats are matched instead
+var deleteUserPendingDeliveries = `
+	DELETE FROM webhook_deliveries
+	WHERE tenant_id = :tenant_id AND status = 'pending' AND payload LIKE :pattern ESCAPE '!';
+`
+
+var redactUserDeliveries = `
+	UPDATE webhook_deliveries SET payload = ''
+	WHERE tenant_id = :tenant_id AND payload LIKE :pattern ESCAPE '!';
+`
+
+var deleteUserExportJobs = `
+	DELETE FROM export_jobs WHERE tenant_id = :tenant_id AND (user_id = :user_id OR email = :email);
+`
+
+var deleteUser = `
+	DELETE FROM users WHERE id = :user_id AND tenant_id = :tenant_id;
+`
+
+var createAuditEntry = `
+	INSERT INTO audit_log (id, tenant_id, actor, role, action, resource_type, resource_id, outcome, details, created_at)
+	VALUES (:id, :tenant_id, :actor, :role, :action, :resource_type, :resource_id, :outcome, :details, :created_at);
 `
@@ -4,11 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/godb"
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/migrations"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRepositoryCreate(t *testing.T) {
@@ -159,4 +164,286 @@ func TestRepositoryFindByEmail(t *testing.T) {
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, errGetContext)
 	})
+
+	t.Run("should return user not found when the email does not exist", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackGetContext: func(ctx context.Context, dest, arg interface{}) error {
+						return sql.ErrNoRows
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.FindByEmail(context.Background(), "john.wick@continental.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestRepositoryFindUsers(t *testing.T) {
+	t.Run("should escape the LIKE wildcards of the search", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				assert.Equal(t, findUsers, query)
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+						params := arg.(map[string]interface{})
+						assert.Equal(t, "100%_off", params["search"])
+						assert.Equal(t, "%100!%!_off%", params["pattern"])
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		users, err := repository.FindUsers(context.Background(), "100%_off", false, datatypes.Page{Limit: 10})
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+	})
+}
+
+func TestRepositoryFindUsersSQLite(t *testing.T) {
+	ctx := context.Background()
+	database, err := godb.NewDB(godb.DBConfig{
+		User:             "users",
+		Password:         "users",
+		Database:         filepath.Join(t.TempDir(), "users"),
+		DatabaseType:     godb.SQLiteDB,
+		ConnectTimeout:   100 * time.Millisecond,
+		ConnectionParams: godb.DBConnectionParams{"_busy_timeout": "5000"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+	require.NoError(t, migrations.NewMigrator(database).Migrate(ctx))
+
+	repository := NewRepository(database)
+	for _, email := range []string{"john_doe@mail.com", "johnxdoe@mail.com", "sale@100%.com", "1000@mail.com", "wow!@mail.com"} {
+		_, err := repository.Create(ctx, "John", "Doe", email)
+		require.NoError(t, err)
+	}
+
+	t.Run("should match the wildcards of the search literally", func(t *testing.T) {
+		for search, expected := range map[string][]string{
+			"john_":  {"john_doe@mail.com"},
+			"100%":   {"sale@100%.com"},
+			"wow!":   {"wow!@mail.com"},
+			"@mail.": {"1000@mail.com", "john_doe@mail.com", "johnxdoe@mail.com", "wow!@mail.com"},
+		} {
+			users, err := repository.FindUsers(ctx, search, false, datatypes.Page{Limit: 10})
+			require.NoError(t, err)
+
+			emails := []string{}
+			for _, user := range users {
+				emails = append(emails, user.Email)
+			}
+			assert.Equal(t, expected, emails, search)
+		}
+	})
+
+	t.Run("should only erase the deliveries mentioning the user", func(t *testing.T) {
+		found, err := repository.FindByEmail(ctx, "john_doe@mail.com")
+		require.NoError(t, err)
+
+		for id, payload := range map[string]string{
+			"delivery-1": `{"email":"john_doe@mail.com"}`,
+			"delivery-2": `{"email":"johnxdoe@mail.com"}`,
+		} {
+			_, err := database.ExecContext(ctx, `
+				INSERT INTO webhook_deliveries (id, tenant_id, endpoint_id, event_id, event_type, payload, status, last_error, next_attempt_at, created_at, updated_at)
+				VALUES (?, 'default', 'endpoint', 'event', 'chat.created', ?, 'pending', '', ?, ?, ?)`,
+				id, payload, time.Now(), time.Now(), time.Now(),
+			)
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, repository.EraseUser(ctx, found, datatypes.AuditEntry{
+			Actor:        ActorCustomer,
+			Role:         ActorCustomer,
+			Action:       ActionEraseData,
+			ResourceType: "user",
+			ResourceID:   found.ID,
+			Outcome:      "allowed",
+			CreatedAt:    time.Now().UTC(),
+		}))
+
+		var deliveries []string
+		require.NoError(t, database.SelectContext(ctx, &deliveries, "SELECT id FROM webhook_deliveries"))
+		assert.Equal(t, []string{"delivery-2"}, deliveries)
+
+		_, err = repository.FindByEmail(ctx, "john_doe@mail.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestRepositoryEraseUser(t *testing.T) {
+	t.Run("should erase the user data and save the audit entry in a transaction", func(t *testing.T) {
+		var (
+			executed  []string
+			patterns  []string
+			committed bool
+		)
+
+		exportFile := filepath.Join(t.TempDir(), "job-1.json")
+		require.NoError(t, os.WriteFile(exportFile, []byte(`[{"chat":{"userEmail":"john_doe@mail.com"}}]`), 0o600))
+
+		repository := NewRepository(&godb.DBMock{
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+						return &godb.NamedStmtMock{
+							CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+								switch query {
+								case findUserChatIDs:
+									*dest.(*[]string) = []string{"chat-1"}
+								case findUserExportFiles:
+									*dest.(*[]string) = []string{exportFile}
+								}
+								return nil
+							},
+						}, nil
+					},
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						executed = append(executed, query)
+						if query == redactUserDeliveries {
+							patterns = append(patterns, arg.(map[string]interface{})["pattern"].(string))
+						}
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+					CallbackCommit: func() error {
+						committed = true
+						return nil
+					},
+					CallbackRollback: func() error {
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.EraseUser(context.Background(), datatypes.User{ID: "qwerty", Email: "john_doe@mail.com"}, datatypes.AuditEntry{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			deleteUserPendingDeliveries,
+			redactUserDeliveries,
+			deleteUserPendingDeliveries,
+			redactUserDeliveries,
+			deleteUserPendingDeliveries,
+			redactUserDeliveries,
+			deleteUserAnalyses,
+			deleteUserMessages,
+			anonymizeUserChats,
+			deleteUserInvitations,
+			deleteUserReviewJobs,
+			deleteUserExportJobs,
+			deleteUser,
+			createAuditEntry,
+		}, executed)
+		assert.Equal(t, []string{"%qwerty%", "%john!_doe@mail.com%", "%chat-1%"}, patterns)
+		assert.NoFileExists(t, exportFile)
+		assert.True(t, committed)
+	})
+
+	t.Run("should rollback when the user does not exist", func(t *testing.T) {
+		var committed, rolledBack bool
+
+		repository := NewRepository(&godb.DBMock{
+			CallbackBeginTx: func(ctx context.Context, opts *sql.TxOptions) (godb.Tx, error) {
+				return &godb.TxMock{
+					CallbackNamedExecContext: func(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+					CallbackCommit: func() error {
+						committed = true
+						return nil
+					},
+					CallbackRollback: func() error {
+						rolledBack = true
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.EraseUser(context.Background(), datatypes.User{ID: "qwerty"}, datatypes.AuditEntry{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.False(t, committed)
+		assert.True(t, rolledBack)
+	})
+}
+
+func TestRepositoryCreateDuplicated(t *testing.T) {
+	t.Run("should fail when the email is already used", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return nil, errors.New("Error 1062 (23000): Duplicate entry 'john@mail.com' for key 'users.email'")
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.Create(context.Background(), "john", "wick", "john@mail.com")
+		assert.ErrorIs(t, err, ErrUserAlreadyExists)
+	})
+}
+
+func TestRepositoryUpdate(t *testing.T) {
+	newRepository := func(rows int64) *Repository {
+		return NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return rows, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+	}
+
+	t.Run("should increment the version", func(t *testing.T) {
+		user, err := newRepository(1).Update(context.Background(), datatypes.User{ID: "qwerty", Version: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, user.Version)
+		assert.NotNil(t, user.UpdatedAt)
+	})
+
+	t.Run("should fail when the version changed", func(t *testing.T) {
+		_, err := newRepository(0).Update(context.Background(), datatypes.User{ID: "qwerty", Version: 2})
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+}
+
+func TestRepositoryDeactivate(t *testing.T) {
+	t.Run("should fail when the user is not active", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.Deactivate(context.Background(), "qwerty", time.Now())
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
 }
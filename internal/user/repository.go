@@ -2,10 +2,16 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/JhonatanRSantos/gocore/pkg/godb"
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/sqlutil"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
 	"github.com/gofrs/uuid/v5"
 )
 
@@ -31,15 +37,21 @@ func (r *Repository) Create(ctx context.Context, firstName string, lastName stri
 		return datatypes.User{}, fmt.Errorf("failed to create new user. Cause: %w", err)
 	}
 
+	createdAt := time.Now().UTC()
 	params := map[string]interface{}{
 		"id":         id.String(),
+		"tenant_id":  tenant.IDFromContext(ctx),
 		"first_name": firstName,
 		"last_name":  lastName,
 		"email":      email,
+		"created_at": createdAt,
 	}
 
 	result, err := stm.ExecContext(ctx, params)
 	if err != nil {
+		if sqlutil.IsDuplicateKey(err) {
+			return datatypes.User{}, ErrUserAlreadyExists
+		}
 		return datatypes.User{}, fmt.Errorf("failed to create new user. Cause: %w", err)
 	}
 
@@ -57,10 +69,13 @@ func (r *Repository) Create(ctx context.Context, firstName string, lastName stri
 		FirstName: firstName,
 		LastName:  lastName,
 		Email:     email,
+		Version:   1,
+		CreatedAt: &createdAt,
 	}, nil
 }
 
-// FindByEmail finds a user by email
+// FindByEmail finds a user by email, including the deactivated ones. Returns ErrUserNotFound when
+// it doesn't exist
 func (r *Repository) FindByEmail(ctx context.Context, email string) (datatypes.User, error) {
 	var user datatypes.User
 
@@ -71,12 +86,322 @@ func (r *Repository) FindByEmail(ctx context.Context, email string) (datatypes.U
 	defer stm.Close()
 
 	params := map[string]interface{}{
-		"email": email,
+		"tenant_id": tenant.IDFromContext(ctx),
+		"email":     email,
 	}
 
 	if err = stm.GetContext(ctx, &user, params); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return datatypes.User{}, ErrUserNotFound
+		}
 		return datatypes.User{}, fmt.Errorf("failed to find user. Cause: %w", err)
 	}
 
 	return user, nil
 }
+
+// FindByID finds a user by id, including the deactivated ones
+func (r *Repository) FindByID(ctx context.Context, id string) (datatypes.User, error) {
+	var user datatypes.User
+
+	stm, err := r.db.PrepareNamedContext(ctx, findUserByID)
+	if err != nil {
+		return datatypes.User{}, fmt.Errorf("failed to find user. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.GetContext(ctx, &user, map[string]interface{}{"id": id, "tenant_id": tenant.IDFromContext(ctx)}); err != nil {
+		return datatypes.User{}, fmt.Errorf("failed to find user. Cause: %w", err)
+	}
+
+	return user, nil
+}
+
+// FindUsers finds the users whose name or email contains the search. The % and _ in the search
+// match themselves, not any text
+func (r *Repository) FindUsers(
+	ctx context.Context,
+	search string,
+	includeDeleted bool,
+	page datatypes.Page,
+) ([]datatypes.User, error) {
+	users := []datatypes.User{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findUsers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"tenant_id":       tenant.IDFromContext(ctx),
+		"search":          search,
+		"pattern":         "%" + sqlutil.EscapeLike(search) + "%",
+		"include_deleted": includeDeleted,
+		"limit":           page.Limit,
+		"offset":          page.Offset,
+	}
+
+	if err = stm.SelectContext(ctx, &users, params); err != nil {
+		return nil, fmt.Errorf("failed to find users. Cause: %w", err)
+	}
+
+	return users, nil
+}
+
+// Update updates the user names and email when its version is still the same.
+// Returns ErrVersionConflict when nothing was updated.
+func (r *Repository) Update(ctx context.Context, user datatypes.User) (datatypes.User, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, updateUser)
+	if err != nil {
+		return datatypes.User{}, fmt.Errorf("failed to update user. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	updatedAt := time.Now().UTC()
+	params := map[string]interface{}{
+		"id":         user.ID,
+		"tenant_id":  tenant.IDFromContext(ctx),
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"email":      user.Email,
+		"version":    user.Version,
+		"updated_at": updatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		if sqlutil.IsDuplicateKey(err) {
+			return datatypes.User{}, ErrUserAlreadyExists
+		}
+		return datatypes.User{}, fmt.Errorf("failed to update user. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return datatypes.User{}, fmt.Errorf("failed to update user. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return datatypes.User{}, ErrVersionConflict
+	}
+
+	user.Version++
+	user.UpdatedAt = &updatedAt
+	return user, nil
+}
+
+// Deactivate soft deletes the user. The row is kept so its chats are still attributable.
+func (r *Repository) Deactivate(ctx context.Context, id string, deletedAt time.Time) error {
+	stm, err := r.db.PrepareNamedContext(ctx, deactivateUser)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	result, err := stm.ExecContext(ctx, map[string]interface{}{
+		"id":         id,
+		"tenant_id":  tenant.IDFromContext(ctx),
+		"deleted_at": deletedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// FindUserData gathers every row with personal data of the user
+func (r *Repository) FindUserData(ctx context.Context, user datatypes.User) (userData, error) {
+	data := userData{
+		Chats:             []datatypes.Chat{},
+		Messages:          []datatypes.Message{},
+		Analyses:          []datatypes.MessageAnalysis{},
+		ReviewJobs:        []datatypes.ReviewJob{},
+		ReviewInvitations: []datatypes.ReviewInvitation{},
+	}
+
+	params := map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"user_id":   user.ID,
+		"email":     user.Email,
+	}
+
+	queries := []struct {
+		query string
+		dest  interface{}
+	}{
+		{findUserChats, &data.Chats},
+		{findUserMessages, &data.Messages},
+		{findUserAnalyses, &data.Analyses},
+		{findUserReviewJobs, &data.ReviewJobs},
+		{findUserInvitations, &data.ReviewInvitations},
+	}
+
+	for _, q := range queries {
+		if err := r.selectAll(ctx, q.query, q.dest, params); err != nil {
+			return userData{}, fmt.Errorf("failed to find user data. Cause: %w", err)
+		}
+	}
+
+	return data, nil
+}
+
+// EraseUser deletes the user, its messages, analyses, review jobs, invitations, export files and the
+// webhook payloads about it, and anonymizes its chats. The audit entry is saved in the same transaction.
+func (r *Repository) EraseUser(ctx context.Context, user datatypes.User, entry datatypes.AuditEntry) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+	defer tx.Rollback()
+
+	params := map[string]interface{}{
+		"tenant_id": tenant.IDFromContext(ctx),
+		"user_id":   user.ID,
+		"email":     user.Email,
+	}
+
+	var chatIDs, exportFiles []string
+	if err := selectAllTx(ctx, tx, findUserChatIDs, &chatIDs, params); err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+
+	if err := selectAllTx(ctx, tx, findUserExportFiles, &exportFiles, params); err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+
+	for _, reference := range append([]string{user.ID, user.Email}, chatIDs...) {
+		if reference == "" {
+			continue
+		}
+
+		deliveryParams := map[string]interface{}{
+			"tenant_id": params["tenant_id"],
+			"pattern":   "%" + sqlutil.EscapeLike(reference) + "%",
+		}
+
+		for _, query := range []string{deleteUserPendingDeliveries, redactUserDeliveries} {
+			if _, err := tx.NamedExecContext(ctx, query, deliveryParams); err != nil {
+				return fmt.Errorf("failed to erase user. Cause: %w", err)
+			}
+		}
+	}
+
+	queries := []string{
+		deleteUserAnalyses,
+		deleteUserMessages,
+		anonymizeUserChats,
+		deleteUserInvitations,
+		deleteUserReviewJobs,
+		deleteUserExportJobs,
+	}
+
+	for _, query := range queries {
+		if _, err := tx.NamedExecContext(ctx, query, params); err != nil {
+			return fmt.Errorf("failed to erase user. Cause: %w", err)
+		}
+	}
+
+	result, err := tx.NamedExecContext(ctx, deleteUser, params)
+	if err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	if _, err := tx.NamedExecContext(ctx, createAuditEntry, auditEntryParams(ctx, entry)); err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+
+	// the files are removed before the commit, so a failure keeps the jobs pointing to them and the erasure can be retried
+	for _, file := range exportFiles {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to erase user. Cause: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to erase user. Cause: %w", err)
+	}
+	return nil
+}
+
+// CreateAuditEntry saves a new audit entry
+func (r *Repository) CreateAuditEntry(ctx context.Context, entry datatypes.AuditEntry) error {
+	stm, err := r.db.PrepareNamedContext(ctx, createAuditEntry)
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	result, err := stm.ExecContext(ctx, auditEntryParams(ctx, entry))
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to create new audit entry. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantSaveAuditEntry
+	}
+	return nil
+}
+
+// selectAll runs a select query into dest
+func (r *Repository) selectAll(ctx context.Context, query string, dest interface{}, params map[string]interface{}) error {
+	stm, err := r.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stm.Close()
+
+	return stm.SelectContext(ctx, dest, params)
+}
+
+// selectAllTx runs a select query into dest inside the transaction
+func selectAllTx(ctx context.Context, tx godb.Tx, query string, dest interface{}, params map[string]interface{}) error {
+	stm, err := tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stm.Close()
+
+	return stm.SelectContext(ctx, dest, params)
+}
+
+// auditEntryParams maps the audit entry of the tenant to the createAuditEntry params with a new id
+func auditEntryParams(ctx context.Context, entry datatypes.AuditEntry) map[string]interface{} {
+	id := uuid.Must(uuid.NewV4())
+
+	return map[string]interface{}{
+		"id":            id.String(),
+		"tenant_id":     tenant.IDFromContext(ctx),
+		"actor":         entry.Actor,
+		"role":          entry.Role,
+		"action":        entry.Action,
+		"resource_type": entry.ResourceType,
+		"resource_id":   entry.ResourceID,
+		"outcome":       entry.Outcome,
+		"details":       entry.Details,
+		"created_at":    entry.CreatedAt,
+	}
+}
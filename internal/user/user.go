@@ -2,24 +2,62 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/validation"
 )
 
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+const (
+	ActionExportData = "users.data_export"
+	ActionEraseData  = "users.erase"
+)
+
+// ActorCustomer is the audit actor of the requests made by the customers about their own data.
+// The email is not used so the audit log doesn't keep personal data after the erasure.
+const ActorCustomer = "customer"
+
 type repository interface {
 	Create(ctx context.Context, firstName string, lastName string, email string) (datatypes.User, error)
 	FindByEmail(ctx context.Context, email string) (datatypes.User, error)
+	FindByID(ctx context.Context, id string) (datatypes.User, error)
+	FindUsers(ctx context.Context, search string, includeDeleted bool, page datatypes.Page) ([]datatypes.User, error)
+	Update(ctx context.Context, user datatypes.User) (datatypes.User, error)
+	Deactivate(ctx context.Context, id string, deletedAt time.Time) error
+	FindUserData(ctx context.Context, user datatypes.User) (userData, error)
+	EraseUser(ctx context.Context, user datatypes.User, entry datatypes.AuditEntry) error
+	CreateAuditEntry(ctx context.Context, entry datatypes.AuditEntry) error
+}
+
+// userData holds every row with personal data of a user
+type userData struct {
+	Chats             []datatypes.Chat
+	Messages          []datatypes.Message
+	Analyses          []datatypes.MessageAnalysis
+	ReviewJobs        []datatypes.ReviewJob
+	ReviewInvitations []datatypes.ReviewInvitation
 }
 
 type UserService struct {
 	repository repository
+	now        func() time.Time
 }
 
 // NewUserService create a new user service
 func NewUserService(repository repository) *UserService {
-	return &UserService{repository}
+	return &UserService{
+		repository: repository,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
 }
 
 // Create create a new user
@@ -35,12 +73,153 @@ func (us *UserService) Create(
 	return us.repository.Create(ctx, firstName, lastName, email)
 }
 
-// FindByEmail finds a user by email
+// FindByEmail finds an active user by email
 func (us *UserService) FindByEmail(ctx context.Context, email string) (datatypes.User, error) {
 	if sliceHasEmptyStrings(email) {
 		return datatypes.User{}, fmt.Errorf("failed to find user. Cause: %w", ErrMissingRequiredFields)
 	}
-	return us.repository.FindByEmail(ctx, email)
+
+	user, err := us.repository.FindByEmail(ctx, validation.LookupEmail(email))
+	if err != nil {
+		return datatypes.User{}, err
+	}
+
+	if user.DeletedAt != nil {
+		return datatypes.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// FindByID finds a user by id, including the deactivated ones
+func (us *UserService) FindByID(ctx context.Context, id string) (datatypes.User, error) {
+	user, err := us.repository.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return datatypes.User{}, ErrUserNotFound
+		}
+		return datatypes.User{}, err
+	}
+	return user, nil
+}
+
+// List lists the users whose name or email contains the search
+func (us *UserService) List(
+	ctx context.Context,
+	search string,
+	includeDeleted bool,
+	page datatypes.Page,
+) ([]datatypes.User, error) {
+	if page.Limit <= 0 || page.Limit > maxPageLimit {
+		page.Limit = defaultPageLimit
+	}
+
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+	return us.repository.FindUsers(ctx, search, includeDeleted, page)
+}
+
+// Update updates the user names and email. The request must have the version of the user it
+// read, otherwise ErrVersionConflict is returned and the user must be read again.
+func (us *UserService) Update(ctx context.Context, id string, req datatypes.UpdateUserRequest) (datatypes.User, error) {
+	user, err := us.FindByID(ctx, id)
+	if err != nil {
+		return datatypes.User{}, err
+	}
+
+	if user.DeletedAt != nil {
+		return datatypes.User{}, ErrUserNotFound
+	}
+
+	if user.Version != req.Version {
+		return datatypes.User{}, ErrVersionConflict
+	}
+
+	user.FirstName = req.FirstName
+	user.LastName = req.LastName
+	user.Email = req.Email
+	return us.repository.Update(ctx, user)
+}
+
+// Deactivate soft deletes the user. The chats are kept and the user can't connect anymore.
+func (us *UserService) Deactivate(ctx context.Context, id string) error {
+	return us.repository.Deactivate(ctx, id, us.now())
+}
+
+// ExportData gathers every personal data of the user: profile, chats with messages,
+// message analyses, scheduled reviews and invitations
+func (us *UserService) ExportData(ctx context.Context, email string) (datatypes.UserDataExport, error) {
+	user, err := us.findUser(ctx, email)
+	if err != nil {
+		return datatypes.UserDataExport{}, err
+	}
+
+	data, err := us.repository.FindUserData(ctx, user)
+	if err != nil {
+		return datatypes.UserDataExport{}, err
+	}
+
+	if err := us.repository.CreateAuditEntry(ctx, us.auditEntry(user, ActionExportData)); err != nil {
+		return datatypes.UserDataExport{}, err
+	}
+
+	transcripts := make([]datatypes.Transcript, 0, len(data.Chats))
+	indexes := make(map[string]int, len(data.Chats))
+	for _, chat := range data.Chats {
+		chat.UserEmail = user.Email
+		indexes[chat.ID] = len(transcripts)
+		transcripts = append(transcripts, datatypes.Transcript{Chat: chat, Messages: []datatypes.Message{}})
+	}
+
+	for _, message := range data.Messages {
+		if index, ok := indexes[message.ChatID]; ok {
+			transcripts[index].Messages = append(transcripts[index].Messages, message)
+		}
+	}
+
+	return datatypes.UserDataExport{
+		User:              user,
+		Chats:             transcripts,
+		Analyses:          data.Analyses,
+		ReviewJobs:        data.ReviewJobs,
+		ReviewInvitations: data.ReviewInvitations,
+		ExportedAt:        us.now(),
+	}, nil
+}
+
+// Forget erases the user with its messages, analyses, scheduled reviews, invitations, exports and webhook payloads.
+// The chats are kept without the user so the reports don't change.
+func (us *UserService) Forget(ctx context.Context, email string) error {
+	user, err := us.findUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	return us.repository.EraseUser(ctx, user, us.auditEntry(user, ActionEraseData))
+}
+
+// findUser finds the user by email, including the deactivated ones, returning ErrUserNotFound when it doesn't exist
+func (us *UserService) findUser(ctx context.Context, email string) (datatypes.User, error) {
+	user, err := us.repository.FindByEmail(ctx, validation.LookupEmail(email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return datatypes.User{}, ErrUserNotFound
+		}
+		return datatypes.User{}, err
+	}
+	return user, nil
+}
+
+// auditEntry creates the audit entry of a customer request about the user data
+func (us *UserService) auditEntry(user datatypes.User, action string) datatypes.AuditEntry {
+	return datatypes.AuditEntry{
+		Actor:        ActorCustomer,
+		Role:         ActorCustomer,
+		Action:       action,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		Outcome:      "allowed",
+		CreatedAt:    us.now(),
+	}
 }
 
 // sliceHasEmptyStrings
@@ -2,8 +2,10 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
 	"github.com/gofrs/uuid/v5"
@@ -14,6 +16,61 @@ type repositoryMock struct {
 	Error               error
 	CallbackCreate      func(ctx context.Context, firstName string, lastName string, email string) (datatypes.User, error)
 	CallbackFindByEmail func(ctx context.Context, email string) (datatypes.User, error)
+	CallbackFindByID    func(ctx context.Context, id string) (datatypes.User, error)
+	CallbackFindUsers   func(ctx context.Context, search string, includeDeleted bool, page datatypes.Page) ([]datatypes.User, error)
+	CallbackUpdate      func(ctx context.Context, user datatypes.User) (datatypes.User, error)
+	CallbackDeactivate  func(ctx context.Context, id string, deletedAt time.Time) error
+	UserData            userData
+	AuditEntries        []datatypes.AuditEntry
+	ErasedUsers         []datatypes.User
+}
+
+func (rm *repositoryMock) FindByID(ctx context.Context, id string) (datatypes.User, error) {
+	if rm.CallbackFindByID != nil {
+		return rm.CallbackFindByID(ctx, id)
+	}
+	return datatypes.User{}, rm.Error
+}
+
+func (rm *repositoryMock) FindUsers(
+	ctx context.Context,
+	search string,
+	includeDeleted bool,
+	page datatypes.Page,
+) ([]datatypes.User, error) {
+	if rm.CallbackFindUsers != nil {
+		return rm.CallbackFindUsers(ctx, search, includeDeleted, page)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) Update(ctx context.Context, user datatypes.User) (datatypes.User, error) {
+	if rm.CallbackUpdate != nil {
+		return rm.CallbackUpdate(ctx, user)
+	}
+	return datatypes.User{}, rm.Error
+}
+
+func (rm *repositoryMock) Deactivate(ctx context.Context, id string, deletedAt time.Time) error {
+	if rm.CallbackDeactivate != nil {
+		return rm.CallbackDeactivate(ctx, id, deletedAt)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) FindUserData(ctx context.Context, user datatypes.User) (userData, error) {
+	return rm.UserData, rm.Error
+}
+
+func (rm *repositoryMock) EraseUser(ctx context.Context, user datatypes.User, entry datatypes.AuditEntry) error {
+	rm.ErasedUsers = append(rm.ErasedUsers, user)
+	rm.AuditEntries = append(rm.AuditEntries, entry)
+	return rm.Error
+}
+
+func (rm *repositoryMock) CreateAuditEntry(ctx context.Context, entry datatypes.AuditEntry) error {
+	rm.AuditEntries = append(rm.AuditEntries, entry)
+	return rm.Error
 }
 
 func (rm *repositoryMock) Create(ctx context.Context, firstName string, lastName string, email string) (datatypes.User, error) {
@@ -78,6 +135,109 @@ func TestServiceFindByEmail(t *testing.T) {
 		assert.Error(t, err)
 		assert.ErrorIs(t, err, ErrMissingRequiredFields)
 	})
+
+	t.Run("should not find deactivated users", func(t *testing.T) {
+		deletedAt := time.Now()
+		service := NewUserService(&repositoryMock{
+			CallbackFindByEmail: func(ctx context.Context, email string) (datatypes.User, error) {
+				return datatypes.User{ID: "qwerty", Email: email, DeletedAt: &deletedAt}, nil
+			},
+		})
+
+		_, err := service.FindByEmail(context.Background(), "john.wick@continental.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestServiceFindByID(t *testing.T) {
+	t.Run("should fail when the user does not exist", func(t *testing.T) {
+		_, err := NewUserService(&repositoryMock{Error: sql.ErrNoRows}).FindByID(context.Background(), "qwerty")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestServiceList(t *testing.T) {
+	t.Run("should apply the default page limit", func(t *testing.T) {
+		var usedPage datatypes.Page
+		service := NewUserService(&repositoryMock{
+			CallbackFindUsers: func(ctx context.Context, search string, includeDeleted bool, page datatypes.Page) ([]datatypes.User, error) {
+				usedPage = page
+				return []datatypes.User{}, nil
+			},
+		})
+
+		_, err := service.List(context.Background(), "john", false, datatypes.Page{Limit: 1000, Offset: -1})
+		assert.NoError(t, err)
+		assert.Equal(t, datatypes.Page{Limit: defaultPageLimit, Offset: 0}, usedPage)
+	})
+}
+
+func TestServiceUpdate(t *testing.T) {
+	request := datatypes.UpdateUserRequest{FirstName: "jonathan", LastName: "wick", Email: "john@continental.com", Version: 2}
+
+	t.Run("should update the user with the version it read", func(t *testing.T) {
+		var updated datatypes.User
+		service := NewUserService(&repositoryMock{
+			CallbackFindByID: func(ctx context.Context, id string) (datatypes.User, error) {
+				return datatypes.User{ID: id, FirstName: "john", LastName: "wick", Email: "john.wick@continental.com", Version: 2}, nil
+			},
+			CallbackUpdate: func(ctx context.Context, user datatypes.User) (datatypes.User, error) {
+				updated = user
+				user.Version++
+				return user, nil
+			},
+		})
+
+		user, err := service.Update(context.Background(), "qwerty", request)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, user.Version)
+		assert.Equal(t, datatypes.User{
+			ID:        "qwerty",
+			FirstName: "jonathan",
+			LastName:  "wick",
+			Email:     "john@continental.com",
+			Version:   2,
+		}, updated)
+	})
+
+	t.Run("should fail when the user changed after it was read", func(t *testing.T) {
+		service := NewUserService(&repositoryMock{
+			CallbackFindByID: func(ctx context.Context, id string) (datatypes.User, error) {
+				return datatypes.User{ID: id, Version: 3}, nil
+			},
+		})
+
+		_, err := service.Update(context.Background(), "qwerty", request)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("should not update deactivated users", func(t *testing.T) {
+		deletedAt := time.Now()
+		service := NewUserService(&repositoryMock{
+			CallbackFindByID: func(ctx context.Context, id string) (datatypes.User, error) {
+				return datatypes.User{ID: id, Version: 2, DeletedAt: &deletedAt}, nil
+			},
+		})
+
+		_, err := service.Update(context.Background(), "qwerty", request)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestServiceDeactivate(t *testing.T) {
+	t.Run("should soft delete the user", func(t *testing.T) {
+		var deactivated string
+		service := NewUserService(&repositoryMock{
+			CallbackDeactivate: func(ctx context.Context, id string, deletedAt time.Time) error {
+				deactivated = id
+				assert.False(t, deletedAt.IsZero())
+				return nil
+			},
+		})
+
+		assert.NoError(t, service.Deactivate(context.Background(), "qwerty"))
+		assert.Equal(t, "qwerty", deactivated)
+	})
 }
 
 func getMockedUser(t *testing.T) datatypes.User {
@@ -93,3 +253,60 @@ func getMockedUser(t *testing.T) datatypes.User {
 		Email:     "john.wick@continental.com",
 	}
 }
+
+func TestServiceExportData(t *testing.T) {
+	t.Run("should group the messages by chat and audit the export", func(t *testing.T) {
+		mockedUser := datatypes.User{ID: "user-1", Email: "john@mail.com"}
+		repository := &repositoryMock{
+			CallbackFindByEmail: func(ctx context.Context, email string) (datatypes.User, error) {
+				return mockedUser, nil
+			},
+			UserData: userData{
+				Chats: []datatypes.Chat{{ID: "chat-1"}, {ID: "chat-2"}},
+				Messages: []datatypes.Message{
+					{ID: "1", ChatID: "chat-1"},
+					{ID: "2", ChatID: "chat-2"},
+					{ID: "3", ChatID: "chat-1"},
+				},
+			},
+		}
+
+		export, err := NewUserService(repository).ExportData(context.Background(), mockedUser.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, mockedUser, export.User)
+		assert.Len(t, export.Chats, 2)
+		assert.Len(t, export.Chats[0].Messages, 2)
+		assert.Len(t, export.Chats[1].Messages, 1)
+		assert.Equal(t, "john@mail.com", export.Chats[0].Chat.UserEmail)
+
+		assert.Len(t, repository.AuditEntries, 1)
+		assert.Equal(t, ActionExportData, repository.AuditEntries[0].Action)
+		assert.Equal(t, "user-1", repository.AuditEntries[0].ResourceID)
+		assert.Equal(t, ActorCustomer, repository.AuditEntries[0].Actor)
+	})
+
+	t.Run("should fail when the user does not exist", func(t *testing.T) {
+		_, err := NewUserService(&repositoryMock{Error: sql.ErrNoRows}).ExportData(context.Background(), "john@mail.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestServiceForget(t *testing.T) {
+	t.Run("should erase the user with an audit entry", func(t *testing.T) {
+		repository := &repositoryMock{
+			CallbackFindByEmail: func(ctx context.Context, email string) (datatypes.User, error) {
+				return datatypes.User{ID: "user-1", Email: email}, nil
+			},
+		}
+
+		assert.NoError(t, NewUserService(repository).Forget(context.Background(), "john@mail.com"))
+		assert.Equal(t, "user-1", repository.ErasedUsers[0].ID)
+		assert.Equal(t, ActionEraseData, repository.AuditEntries[0].Action)
+		assert.NotContains(t, repository.AuditEntries[0].Actor, "john@mail.com")
+	})
+
+	t.Run("should fail when the user does not exist", func(t *testing.T) {
+		err := NewUserService(&repositoryMock{Error: sql.ErrNoRows}).Forget(context.Background(), "john@mail.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
@@ -0,0 +1,152 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	maxEmailLength     = 254
+	maxLocalPartLength = 64
+	maxLabelLength     = 63
+)
+
+// disposableDomains are well known throwaway email providers. They're only rejected after BlockDomains.
+var disposableDomains = []string{
+	"10minutemail.com",
+	"discard.email",
+	"dispostable.com",
+	"emailondeck.com",
+	"fakeinbox.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"maildrop.cc",
+	"mailinator.com",
+	"mailnesia.com",
+	"mintemail.com",
+	"mohmal.com",
+	"sharklasers.com",
+	"temp-mail.org",
+	"tempmail.com",
+	"tempmailo.com",
+	"throwawaymail.com",
+	"trashmail.com",
+	"yopmail.com",
+}
+
+var blocklist = struct {
+	mutex   sync.RWMutex
+	domains map[string]struct{}
+}{domains: map[string]struct{}{}}
+
+// DisposableDomains returns the built-in list of disposable email domains
+func DisposableDomains() []string {
+	return append([]string{}, disposableDomains...)
+}
+
+// BlockDomains replaces the domains rejected by IsBlocked. Their subdomains are rejected as well.
+// The blocklist is a plain domain match, no DNS lookups are made.
+func BlockDomains(domains ...string) {
+	blocked := make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		if domain, err := normalizeDomain(strings.TrimSpace(domain)); err == nil {
+			blocked[domain] = struct{}{}
+		}
+	}
+
+	blocklist.mutex.Lock()
+	defer blocklist.mutex.Unlock()
+	blocklist.domains = blocked
+}
+
+// NormalizeEmail parses a bare RFC 5322 address, e.g. john@mail.com, and returns it lower cased with
+// the domain in its ASCII (punycode) form, so the same mailbox always has the same spelling.
+// Display names, comments and quoted local parts are rejected.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || len(email) > maxEmailLength {
+		return "", ErrInvalidEmail
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return "", ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	localPart, domain := email[:at], email[at+1:]
+	if len(localPart) > maxLocalPartLength {
+		return "", ErrInvalidEmail
+	}
+
+	if domain, err = normalizeDomain(domain); err != nil {
+		return "", err
+	}
+	return strings.ToLower(localPart) + "@" + domain, nil
+}
+
+// LookupEmail normalizes the email used to find a user, so the lookups are case insensitive.
+// Emails saved before the validation may not parse, those are only lower cased.
+func LookupEmail(email string) string {
+	if normalized, err := NormalizeEmail(email); err == nil {
+		return normalized
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizeDomain converts the domain to lower case ASCII and requires at least two labels
+// and an alphabetic top level domain
+func normalizeDomain(domain string) (string, error) {
+	domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil {
+		return "", ErrInvalidEmail
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return "", ErrInvalidEmail
+	}
+
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+			return "", ErrInvalidEmail
+		}
+
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return "", ErrInvalidEmail
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if !strings.HasPrefix(tld, "xn--") {
+		if len(tld) < 2 || strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") != "" {
+			return "", ErrInvalidEmail
+		}
+	}
+	return domain, nil
+}
+
+// IsBlocked tells if the domain of a normalized email, or one of its parents, is in the blocklist
+func IsBlocked(email string) bool {
+	domain := email[strings.LastIndex(email, "@")+1:]
+
+	blocklist.mutex.RLock()
+	defer blocklist.mutex.RUnlock()
+
+	for {
+		if _, ok := blocklist.domains[domain]; ok {
+			return true
+		}
+
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
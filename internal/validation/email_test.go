@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	t.Run("should lower case the email", func(t *testing.T) {
+		email, err := NormalizeEmail(" John.Wick@Continental.COM ")
+		require.NoError(t, err)
+		assert.Equal(t, "john.wick@continental.com", email)
+	})
+
+	t.Run("should convert international domains to punycode", func(t *testing.T) {
+		email, err := NormalizeEmail("hans@München.de")
+		require.NoError(t, err)
+		assert.Equal(t, "hans@xn--mnchen-3ya.de", email)
+	})
+
+	t.Run("should accept plus addressing and subdomains", func(t *testing.T) {
+		email, err := NormalizeEmail("mary+reviews@mail.shop.co.uk")
+		require.NoError(t, err)
+		assert.Equal(t, "mary+reviews@mail.shop.co.uk", email)
+	})
+
+	t.Run("should reject invalid emails", func(t *testing.T) {
+		for _, email := range []string{
+			"",
+			"abc",
+			"john@",
+			"@continental.com",
+			"john@localhost",
+			"john wick@continental.com",
+			"John Wick <john@continental.com>",
+			`"john"@continental.com`,
+			"john@-continental.com",
+			"john@continental..com",
+			"john@continental.c0m",
+			"john@continental.c",
+		} {
+			_, err := NormalizeEmail(email)
+			assert.ErrorIs(t, err, ErrInvalidEmail, email)
+		}
+	})
+}
+
+func TestLookupEmail(t *testing.T) {
+	t.Run("should lower case emails that don't parse", func(t *testing.T) {
+		assert.Equal(t, "john@continental.com", LookupEmail("John@Continental.com"))
+		assert.Equal(t, "legacy", LookupEmail(" Legacy "))
+	})
+}
+
+func TestIsBlocked(t *testing.T) {
+	BlockDomains(append(DisposableDomains(), " Spam.Example.com ", "not a domain")...)
+	defer BlockDomains()
+
+	t.Run("should block the domains and their subdomains", func(t *testing.T) {
+		assert.True(t, IsBlocked("john@mailinator.com"))
+		assert.True(t, IsBlocked("john@eu.mailinator.com"))
+		assert.True(t, IsBlocked("john@spam.example.com"))
+	})
+
+	t.Run("should accept the other domains", func(t *testing.T) {
+		assert.False(t, IsBlocked("john@continental.com"))
+		assert.False(t, IsBlocked("john@example.com"))
+		assert.False(t, IsBlocked("john@notmailinator.com"))
+	})
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	MsgRequired          = "is required"
+	MsgInvalidEmail      = "must be a valid email address"
+	MsgDisposableEmail   = "disposable email addresses aren't accepted"
+	MsgInvalidCharacters = "has invalid characters"
+)
+
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// Errors maps each invalid field of a request to its message
+type Errors map[string]string
+
+// Add records the first problem of the field
+func (e Errors) Add(field string, message string) {
+	if _, ok := e[field]; !ok {
+		e[field] = message
+	}
+}
+
+// Err returns the errors, or nil when every field is valid
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error lists the fields in alphabetical order, e.g. "invalid fields: email must be a valid email address"
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	problems := make([]string, 0, len(fields))
+	for _, field := range fields {
+		problems = append(problems, fmt.Sprintf("%s %s", field, e[field]))
+	}
+	return "invalid fields: " + strings.Join(problems, "; ")
+}
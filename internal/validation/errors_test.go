@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("should be nil without errors", func(t *testing.T) {
+		assert.NoError(t, Errors{}.Err())
+	})
+
+	t.Run("should keep the first problem of each field", func(t *testing.T) {
+		errs := Errors{}
+		errs.Add("name", MsgRequired)
+		errs.Add("name", MsgInvalidCharacters)
+		errs.Add("email", MsgInvalidEmail)
+
+		assert.Equal(t, Errors{"name": MsgRequired, "email": MsgInvalidEmail}, errs)
+		assert.EqualError(t, errs.Err(), "invalid fields: email must be a valid email address; name is required")
+	})
+}
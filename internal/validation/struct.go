@@ -0,0 +1,246 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// CharsetName allows letters of any language, spaces, apostrophes, hyphens and periods
+	CharsetName = "name"
+	// CharsetText allows any printable character, but no line breaks or other control characters
+	CharsetText = "text"
+	// CharsetIdentifier allows ASCII letters, digits and ._:-
+	CharsetIdentifier = "identifier"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// rules of a single field, parsed from its validate tag
+type rules struct {
+	required  bool
+	min       *int
+	max       *int
+	charset   string
+	email     bool
+	blocklist bool
+}
+
+// Struct trims the string fields of the request, a pointer to a struct, and checks the rules of
+// their validate tags:
+//
+//	required       the field can't be empty, zero or without items
+//	min=N, max=N   the number of characters of a string, items of a slice or the value of a number
+//	charset=X      the characters of a string or of each string of a slice: name, text or identifier
+//	email          the string must be an email address, it's normalized by NormalizeEmail
+//	blocklist      the email domain can't be blocked by BlockDomains
+//
+// Nested structs and slices of structs are validated as well. The errors are keyed by the JSON path
+// of the field, e.g. user.email or orders[0].product.
+func Struct(request any) error {
+	value := reflect.ValueOf(request)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: Struct needs a pointer to a struct, got %T", request))
+	}
+
+	errs := Errors{}
+	validateStruct(errs, "", value.Elem())
+	return errs.Err()
+}
+
+// validateStruct validates every field of the struct
+func validateStruct(errs Errors, prefix string, value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		validateField(errs, prefix+fieldName(field), value.Field(i), parseRules(field))
+	}
+}
+
+// validateField checks the rules of a single field
+func validateField(errs Errors, path string, value reflect.Value, fieldRules rules) {
+	switch {
+	case value.Kind() == reflect.String:
+		validateString(errs, path, value, fieldRules)
+	case value.Kind() == reflect.Slice:
+		validateSlice(errs, path, value, fieldRules)
+	case value.Type() == timeType:
+		if fieldRules.required && value.Interface().(time.Time).IsZero() {
+			errs.Add(path, MsgRequired)
+		}
+	case value.Kind() == reflect.Struct:
+		if fieldRules.required && value.IsZero() {
+			errs.Add(path, MsgRequired)
+		}
+		validateStruct(errs, path+".", value)
+	case value.CanInt():
+		validateNumber(errs, path, value.Int(), fieldRules)
+	}
+}
+
+// validateString trims the string and checks its rules. Emails are replaced by the normalized address.
+func validateString(errs Errors, path string, value reflect.Value, fieldRules rules) {
+	text := strings.TrimSpace(value.String())
+	value.SetString(text)
+
+	if text == "" {
+		if fieldRules.required {
+			errs.Add(path, MsgRequired)
+		}
+		return
+	}
+
+	length := utf8.RuneCountInString(text)
+	if fieldRules.min != nil && length < *fieldRules.min {
+		errs.Add(path, fmt.Sprintf("must have at least %d characters", *fieldRules.min))
+	}
+
+	if fieldRules.max != nil && length > *fieldRules.max {
+		errs.Add(path, fmt.Sprintf("must have at most %d characters", *fieldRules.max))
+	}
+
+	if fieldRules.charset != "" && !matchesCharset(text, fieldRules.charset) {
+		errs.Add(path, MsgInvalidCharacters)
+	}
+
+	if fieldRules.email {
+		email, err := NormalizeEmail(text)
+		if err != nil {
+			errs.Add(path, MsgInvalidEmail)
+			return
+		}
+
+		if fieldRules.blocklist && IsBlocked(email) {
+			errs.Add(path, MsgDisposableEmail)
+		}
+		value.SetString(email)
+	}
+}
+
+// validateSlice checks the number of items. Strings are trimmed and checked against the charset,
+// structs are validated with their own rules.
+func validateSlice(errs Errors, path string, value reflect.Value, fieldRules rules) {
+	count := value.Len()
+	if fieldRules.required && count == 0 {
+		errs.Add(path, MsgRequired)
+	}
+
+	if fieldRules.min != nil && count < *fieldRules.min {
+		errs.Add(path, fmt.Sprintf("must have at least %d items", *fieldRules.min))
+	}
+
+	if fieldRules.max != nil && count > *fieldRules.max {
+		errs.Add(path, fmt.Sprintf("must have at most %d items", *fieldRules.max))
+	}
+
+	for i := 0; i < count; i++ {
+		item := value.Index(i)
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+
+		switch {
+		case item.Kind() == reflect.String:
+			validateString(errs, itemPath, item, rules{required: true, charset: fieldRules.charset})
+		case item.Kind() == reflect.Struct && item.Type() != timeType:
+			validateStruct(errs, itemPath+".", item)
+		}
+	}
+}
+
+// validateNumber checks the value of a number. Required numbers can't be zero.
+func validateNumber(errs Errors, path string, number int64, fieldRules rules) {
+	if fieldRules.required && number == 0 {
+		errs.Add(path, MsgRequired)
+		return
+	}
+
+	if fieldRules.min != nil && number < int64(*fieldRules.min) {
+		errs.Add(path, fmt.Sprintf("must be at least %d", *fieldRules.min))
+	}
+
+	if fieldRules.max != nil && number > int64(*fieldRules.max) {
+		errs.Add(path, fmt.Sprintf("must be at most %d", *fieldRules.max))
+	}
+}
+
+// parseRules parses a validate tag like "required,max=100,charset=name". Invalid tags are a bug, so it panics.
+func parseRules(field reflect.StructField) rules {
+	parsed := rules{}
+	tag := field.Tag.Get("validate")
+	if tag == "" {
+		return parsed
+	}
+
+	invalid := func() {
+		panic(fmt.Sprintf("validation: invalid rule %q of field %s", tag, field.Name))
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, argument, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		switch name {
+		case "required":
+			parsed.required = true
+		case "email":
+			parsed.email = true
+		case "blocklist":
+			parsed.blocklist = true
+		case "charset":
+			if argument != CharsetName && argument != CharsetText && argument != CharsetIdentifier {
+				invalid()
+			}
+			parsed.charset = argument
+		case "min", "max":
+			limit, err := strconv.Atoi(argument)
+			if err != nil {
+				invalid()
+			}
+
+			if name == "min" {
+				parsed.min = &limit
+			} else {
+				parsed.max = &limit
+			}
+		default:
+			invalid()
+		}
+	}
+	return parsed
+}
+
+// fieldName returns the JSON name of the field
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// matchesCharset tells if every character of the text belongs to the charset
+func matchesCharset(text string, charset string) bool {
+	for _, r := range text {
+		switch charset {
+		case CharsetName:
+			if !unicode.IsLetter(r) && !unicode.IsMark(r) && !strings.ContainsRune(" '’-.", r) {
+				return false
+			}
+		case CharsetText:
+			if !unicode.IsGraphic(r) {
+				return false
+			}
+		case CharsetIdentifier:
+			if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._:-", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCustomer struct {
+	Name  string `json:"name"  validate:"required,max=20,charset=name"`
+	Email string `json:"email" validate:"required,email,blocklist"`
+}
+
+type testOrder struct {
+	ID          string         `json:"id"          validate:"required,charset=identifier"`
+	Customer    testCustomer   `json:"customer"`
+	Product     string         `json:"product"     validate:"required,min=2,max=30,charset=text"`
+	Quantity    int            `json:"quantity"    validate:"min=1,max=10"`
+	Tags        []string       `json:"tags"        validate:"max=2,charset=identifier"`
+	Gifts       []testCustomer `json:"gifts"`
+	DeliveredAt time.Time      `json:"deliveredAt" validate:"required"`
+	Notes       string
+}
+
+func TestStruct(t *testing.T) {
+	validOrder := func() testOrder {
+		return testOrder{
+			ID:          "order-1",
+			Customer:    testCustomer{Name: "Mary Ann", Email: "mary@mail.com"},
+			Product:     "Galaxy S24 Ultra",
+			Quantity:    1,
+			DeliveredAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+
+	t.Run("should trim the strings and keep the inner spaces", func(t *testing.T) {
+		order := validOrder()
+		order.Customer.Name = "  Mary Ann "
+		order.Product = " Galaxy S24 Ultra\t"
+		order.Notes = " leave at the door "
+
+		require.NoError(t, Struct(&order))
+		assert.Equal(t, "Mary Ann", order.Customer.Name)
+		assert.Equal(t, "Galaxy S24 Ultra", order.Product)
+		assert.Equal(t, "leave at the door", order.Notes)
+	})
+
+	t.Run("should accept unicode names", func(t *testing.T) {
+		for _, name := range []string{"José Müller", "Zoë O'Brien", "Anne-Marie", "Dr. Søren", "山田 太郎", "Nguyễn Văn An"} {
+			order := validOrder()
+			order.Customer.Name = name
+			assert.NoError(t, Struct(&order), name)
+		}
+	})
+
+	t.Run("should normalize the emails", func(t *testing.T) {
+		order := validOrder()
+		order.Customer.Email = " Mary@Mail.COM "
+
+		require.NoError(t, Struct(&order))
+		assert.Equal(t, "mary@mail.com", order.Customer.Email)
+	})
+
+	t.Run("should return every invalid field by its json path", func(t *testing.T) {
+		order := testOrder{
+			ID:       "order 1",
+			Customer: testCustomer{Name: "R2-D2", Email: "mary"},
+			Product:  "Galaxy\nS24",
+			Quantity: 11,
+			Tags:     []string{"a", "b", "c d"},
+			Gifts:    []testCustomer{{Name: strings.Repeat("a", 21), Email: "john@mail.com"}},
+		}
+
+		err := Struct(&order)
+		require.Error(t, err)
+		assert.Equal(t, Errors{
+			"id":             MsgInvalidCharacters,
+			"customer.name":  MsgInvalidCharacters,
+			"customer.email": MsgInvalidEmail,
+			"product":        MsgInvalidCharacters,
+			"quantity":       "must be at most 10",
+			"tags":           "must have at most 2 items",
+			"tags[2]":        MsgInvalidCharacters,
+			"gifts[0].name":  "must have at most 20 characters",
+			"deliveredAt":    MsgRequired,
+		}, err)
+	})
+
+	t.Run("should require the fields", func(t *testing.T) {
+		err := Struct(&testOrder{})
+		assert.Equal(t, Errors{
+			"id":             MsgRequired,
+			"customer.name":  MsgRequired,
+			"customer.email": MsgRequired,
+			"product":        MsgRequired,
+			"quantity":       "must be at least 1",
+			"deliveredAt":    MsgRequired,
+		}, err)
+	})
+
+	t.Run("should count characters instead of bytes", func(t *testing.T) {
+		order := validOrder()
+		order.Customer.Name = strings.Repeat("é", 20)
+		assert.NoError(t, Struct(&order))
+	})
+
+	t.Run("should reject blocked domains", func(t *testing.T) {
+		BlockDomains("mailinator.com")
+		defer BlockDomains()
+
+		order := validOrder()
+		order.Customer.Email = "mary@mailinator.com"
+		assert.Equal(t, Errors{"customer.email": MsgDisposableEmail}, Struct(&order))
+	})
+
+	t.Run("should panic on invalid rules", func(t *testing.T) {
+		invalid := struct {
+			Name string `validate:"required,charset=emoji"`
+		}{}
+		assert.Panics(t, func() { _ = Struct(&invalid) })
+		assert.Panics(t, func() { _ = Struct(invalid) })
+	})
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// allowedIP tells if the webhooks can be sent to the address. The loopback, link-local, private and
+// unspecified addresses are refused, so an endpoint can't reach the network of the API.
+func allowedIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified()
+}
+
+// checkHost fails when the host is, or resolves to, an address the webhooks can't be sent to
+func checkHost(ctx context.Context, lookupIP lookupIPFunc, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !allowedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+		}
+		return nil
+	}
+
+	addresses, err := lookupIP(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		if !allowedIP(address.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, address.IP)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient creates the client that sends the webhooks. The addresses refused on registration
+// are checked again when connecting, the name of an endpoint may resolve to another address later.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, conn syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !allowedIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the endpoint
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,14 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrCantSaveEndpoint      = errors.New("failed to create new webhook endpoint. Cause: can't save the endpoint")
+	ErrEndpointNotFound      = errors.New("webhook endpoint not found")
+	ErrCantSaveDelivery      = errors.New("failed to create new webhook delivery. Cause: can't save the delivery")
+	ErrCantUpdateDelivery    = errors.New("failed to update webhook delivery. Cause: can't update the delivery")
+	ErrMissingRequiredFields = errors.New("missing required fields")
+	ErrInvalidEndpointURL    = errors.New("invalid webhook endpoint url")
+	ErrUnexpectedStatusCode  = errors.New("unexpected webhook response status code")
+	ErrForbiddenAddress      = errors.New("webhooks can't be sent to loopback, link-local or private addresses")
+)
@@ -0,0 +1,63 @@
+package webhook
+
+var createEndpoint = `
+	INSERT INTO webhook_endpoints (id, tenant_id, url, secret, events, created_at)
+	VALUES (:id, :tenant_id, :url, :secret, :events, :created_at);
+`
+
+var findEndpoints = `
+	SELECT id, tenant_id, url, secret, events, created_at FROM webhook_endpoints
+	WHERE tenant_id = :tenant_id
+	ORDER BY created_at;
+`
+
+var deleteEndpoint = `
+	DELETE FROM webhook_endpoints WHERE id = :id AND tenant_id = :tenant_id;
+`
+
+var createDelivery = `
+	INSERT INTO webhook_deliveries (
+		id, tenant_id, endpoint_id, event_id, event_type, payload, status, attempts,
+		response_status, last_error, next_attempt_at, created_at, updated_at
+	)
+	VALUES (
+		:id, :tenant_id, :endpoint_id, :event_id, :event_type, :payload, :status, :attempts,
+		:response_status, :last_error, :next_attempt_at, :created_at, :updated_at
+	);
+`
+
+var findDeliveriesByEndpoint = `
+	SELECT
+		id, tenant_id, endpoint_id, event_id, event_type, payload, status, attempts,
+		response_status, last_error, next_attempt_at, created_at, updated_at
+	FROM webhook_deliveries
+	WHERE endpoint_id = :endpoint_id AND tenant_id = :tenant_id
+	ORDER BY created_at DESC
+	LIMIT :limit;
+`
+
+// the worker sends the deliveries of every tenant, each one only reaches the endpoints of its own tenant
+var findPendingDeliveries = `
+	SELECT
+		d.id, d.tenant_id, d.endpoint_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+		d.response_status, d.last_error, d.next_attempt_at, d.created_at, d.updated_at,
+		e.url, e.secret
+	FROM webhook_deliveries d
+	INNER JOIN webhook_endpoints e ON e.id = d.endpoint_id AND e.tenant_id = d.tenant_id
+	WHERE d.status = :status AND d.next_attempt_at <= :now
+	ORDER BY d.next_attempt_at
+	LIMIT :limit;
+`
+
+// a delivery is claimed by moving its next attempt forward, another instance can't claim it until the claim expires
+var claimDelivery = `
+	UPDATE webhook_deliveries SET next_attempt_at = :claimed_until, updated_at = :updated_at
+	WHERE id = :id AND status = :status AND next_attempt_at <= :now;
+`
+
+var updateDelivery = `
+	UPDATE webhook_deliveries
+	SET status = :status, attempts = :attempts, response_status = :response_status,
+		last_error = :last_error, next_attempt_at = :next_attempt_at, updated_at = :updated_at
+	WHERE id = :id;
+`
@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofrs/uuid/v5"
+)
+
+// pendingDelivery is a delivery joined with the endpoint it must be sent to
+type pendingDelivery struct {
+	datatypes.WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type Repository struct {
+	db godb.DB
+}
+
+// NewRepository create a new repository
+func NewRepository(db godb.DB) *Repository {
+	return &Repository{db}
+}
+
+// CreateEndpoint registers a new webhook endpoint
+func (r *Repository) CreateEndpoint(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, createEndpoint)
+	if err != nil {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to create new webhook endpoint. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to create new webhook endpoint. Cause: %w", err)
+	}
+
+	endpoint.ID = id.String()
+	endpoint.CreatedAt = time.Now().UTC()
+
+	params := map[string]interface{}{
+		"id":         endpoint.ID,
+		"tenant_id":  endpoint.TenantID,
+		"url":        endpoint.URL,
+		"secret":     endpoint.Secret,
+		"events":     endpoint.Events,
+		"created_at": endpoint.CreatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to create new webhook endpoint. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to create new webhook endpoint. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return datatypes.WebhookEndpoint{}, ErrCantSaveEndpoint
+	}
+
+	return endpoint, nil
+}
+
+// FindEndpoints finds every webhook endpoint registered by the tenant
+func (r *Repository) FindEndpoints(ctx context.Context) ([]datatypes.WebhookEndpoint, error) {
+	endpoints := []datatypes.WebhookEndpoint{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findEndpoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook endpoints. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	if err = stm.SelectContext(ctx, &endpoints, map[string]interface{}{"tenant_id": tenant.IDFromContext(ctx)}); err != nil {
+		return nil, fmt.Errorf("failed to find webhook endpoints. Cause: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// DeleteEndpoint removes a webhook endpoint of the tenant
+func (r *Repository) DeleteEndpoint(ctx context.Context, id string) error {
+	stm, err := r.db.PrepareNamedContext(ctx, deleteEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":        id,
+		"tenant_id": tenant.IDFromContext(ctx),
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrEndpointNotFound
+	}
+
+	return nil
+}
+
+// CreateDelivery writes a new delivery into the outbox
+func (r *Repository) CreateDelivery(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, createDelivery)
+	if err != nil {
+		return datatypes.WebhookDelivery{}, fmt.Errorf("failed to create new webhook delivery. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return datatypes.WebhookDelivery{}, fmt.Errorf("failed to create new webhook delivery. Cause: %w", err)
+	}
+
+	now := time.Now().UTC()
+	delivery.ID = id.String()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	params := map[string]interface{}{
+		"id":              delivery.ID,
+		"tenant_id":       delivery.TenantID,
+		"endpoint_id":     delivery.EndpointID,
+		"event_id":        delivery.EventID,
+		"event_type":      delivery.EventType,
+		"payload":         delivery.Payload,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"created_at":      delivery.CreatedAt,
+		"updated_at":      delivery.UpdatedAt,
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return datatypes.WebhookDelivery{}, fmt.Errorf("failed to create new webhook delivery. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return datatypes.WebhookDelivery{}, fmt.Errorf("failed to create new webhook delivery. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return datatypes.WebhookDelivery{}, ErrCantSaveDelivery
+	}
+
+	return delivery, nil
+}
+
+// FindDeliveriesByEndpoint finds the latest deliveries of an endpoint of the tenant
+func (r *Repository) FindDeliveriesByEndpoint(ctx context.Context, endpointID string, limit int) ([]datatypes.WebhookDelivery, error) {
+	deliveries := []datatypes.WebhookDelivery{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findDeliveriesByEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook deliveries. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"tenant_id":   tenant.IDFromContext(ctx),
+		"endpoint_id": endpointID,
+		"limit":       limit,
+	}
+
+	if err = stm.SelectContext(ctx, &deliveries, params); err != nil {
+		return nil, fmt.Errorf("failed to find webhook deliveries. Cause: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// FindPendingDeliveries finds the deliveries that must be sent until now
+func (r *Repository) FindPendingDeliveries(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+	deliveries := []pendingDelivery{}
+
+	stm, err := r.db.PrepareNamedContext(ctx, findPendingDeliveries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending webhook deliveries. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"status": DeliveryStatusPending,
+		"now":    now,
+		"limit":  limit,
+	}
+
+	if err = stm.SelectContext(ctx, &deliveries, params); err != nil {
+		return nil, fmt.Errorf("failed to find pending webhook deliveries. Cause: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDelivery reserves the pending delivery until the given time for the instance sending it.
+// Returns false when another instance claimed it first.
+func (r *Repository) ClaimDelivery(ctx context.Context, delivery datatypes.WebhookDelivery, now time.Time, until time.Time) (bool, error) {
+	stm, err := r.db.PrepareNamedContext(ctx, claimDelivery)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":            delivery.ID,
+		"status":        DeliveryStatusPending,
+		"now":           now,
+		"claimed_until": until,
+		"updated_at":    time.Now().UTC(),
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery. Cause: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// UpdateDelivery updates the state of a delivery after an attempt
+func (r *Repository) UpdateDelivery(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+	stm, err := r.db.PrepareNamedContext(ctx, updateDelivery)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery. Cause: %w", err)
+	}
+	defer stm.Close()
+
+	params := map[string]interface{}{
+		"id":              delivery.ID,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"updated_at":      time.Now().UTC(),
+	}
+
+	result, err := stm.ExecContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery. Cause: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery. Cause: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrCantUpdateDelivery
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/godb"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateEndpoint(t *testing.T) {
+	t.Run("should create a new endpoint", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		endpoint, err := repository.CreateEndpoint(context.Background(), datatypes.WebhookEndpoint{URL: "https://crm.example.com"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, endpoint.ID)
+	})
+
+	t.Run("should fail when there ara no affected rows", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.CreateEndpoint(context.Background(), datatypes.WebhookEndpoint{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrCantSaveEndpoint)
+	})
+}
+
+func TestRepositoryDeleteEndpoint(t *testing.T) {
+	t.Run("should fail when the endpoint does not exist", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		err := repository.DeleteEndpoint(context.Background(), "qwerty")
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrEndpointNotFound)
+	})
+}
+
+func TestRepositoryFindEndpoints(t *testing.T) {
+	t.Run("should find only the endpoints of the tenant", func(t *testing.T) {
+		var params []interface{}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+						params = append(params, arg)
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.FindEndpoints(tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "store-a"}))
+		assert.NoError(t, err)
+		_, err = repository.FindEndpoints(tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "store-b"}))
+		assert.NoError(t, err)
+
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"tenant_id": "store-a"},
+			map[string]interface{}{"tenant_id": "store-b"},
+		}, params)
+	})
+}
+
+func TestRepositoryCreateDelivery(t *testing.T) {
+	t.Run("should fail to run exec context", func(t *testing.T) {
+		errExecContext := errors.New("error to run exec context for tests")
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return nil, errExecContext
+					},
+				}, nil
+			},
+		})
+
+		_, err := repository.CreateDelivery(context.Background(), datatypes.WebhookDelivery{})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, errExecContext)
+	})
+}
+
+func TestRepositoryFindPendingDeliveries(t *testing.T) {
+	t.Run("should find pending deliveries", func(t *testing.T) {
+		mockedDeliveries := []pendingDelivery{{URL: "https://crm.example.com"}}
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackSelectContext: func(ctx context.Context, dest, arg interface{}) error {
+						if deliveries, ok := dest.(*[]pendingDelivery); ok {
+							*deliveries = mockedDeliveries
+						}
+						return nil
+					},
+				}, nil
+			},
+		})
+
+		deliveries, err := repository.FindPendingDeliveries(context.Background(), time.Now(), 10)
+		assert.NoError(t, err)
+		assert.EqualValues(t, mockedDeliveries, deliveries)
+	})
+}
+
+func TestRepositoryClaimDelivery(t *testing.T) {
+	t.Run("should claim the pending delivery until the given time", func(t *testing.T) {
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				assert.Equal(t, claimDelivery, query)
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						params := arg.(map[string]interface{})
+						assert.Equal(t, "qwerty", params["id"])
+						assert.Equal(t, DeliveryStatusPending, params["status"])
+						assert.Equal(t, now, params["now"])
+						assert.Equal(t, now.Add(time.Minute), params["claimed_until"])
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 1, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		claimed, err := repository.ClaimDelivery(context.Background(), datatypes.WebhookDelivery{ID: "qwerty"}, now, now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+	})
+
+	t.Run("should not claim a delivery claimed by another instance", func(t *testing.T) {
+		repository := NewRepository(&godb.DBMock{
+			CallbackPrepareNamedContext: func(ctx context.Context, query string) (godb.NamedStmt, error) {
+				return &godb.NamedStmtMock{
+					CallbackExecContext: func(ctx context.Context, arg interface{}) (sql.Result, error) {
+						return &godb.ResultMock{
+							CallbackRowsAffected: func() (int64, error) {
+								return 0, nil
+							},
+						}, nil
+					},
+				}, nil
+			},
+		})
+
+		claimed, err := repository.ClaimDelivery(context.Background(), datatypes.WebhookDelivery{ID: "qwerty"}, time.Now(), time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+}
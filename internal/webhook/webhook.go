@@ -0,0 +1,357 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JhonatanRSantos/gocore/pkg/golog"
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+
+	// AllEvents subscribes an endpoint to every event
+	AllEvents = "*"
+
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+type repository interface {
+	CreateEndpoint(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error)
+	FindEndpoints(ctx context.Context) ([]datatypes.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, id string) error
+	CreateDelivery(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error)
+	FindDeliveriesByEndpoint(ctx context.Context, endpointID string, limit int) ([]datatypes.WebhookDelivery, error)
+	FindPendingDeliveries(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error)
+	ClaimDelivery(ctx context.Context, delivery datatypes.WebhookDelivery, now time.Time, until time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery datatypes.WebhookDelivery) error
+}
+
+// lookupIPFunc resolves the host of an endpoint
+type lookupIPFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Event is the JSON body sent to the webhook endpoints
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookServiceConfig struct {
+	// DeliveryInterval is how often the outbox is checked for pending deliveries
+	DeliveryInterval time.Duration
+	// RetryInterval is the base interval between attempts. It doubles on every failure
+	RetryInterval time.Duration
+	// MaxAttempts is how many times a delivery is sent before being marked as failed
+	MaxAttempts int
+	// BatchSize is the maximum amount of deliveries sent on every check
+	BatchSize int
+	// ClaimTimeout is how long a delivery is reserved for the instance sending it. The deliveries of
+	// an instance that stopped before updating them are sent again after it.
+	ClaimTimeout time.Duration
+}
+
+type WebhookService struct {
+	repository repository
+	client     httpClient
+	config     WebhookServiceConfig
+	now        func() time.Time
+	lookupIP   lookupIPFunc
+}
+
+// NewWebhookService create a new webhook service
+func NewWebhookService(repository repository, client httpClient, config WebhookServiceConfig) *WebhookService {
+	if config.DeliveryInterval <= 0 {
+		config.DeliveryInterval = 5 * time.Second
+	}
+
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = 30 * time.Second
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 1
+	}
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+
+	if config.ClaimTimeout <= 0 {
+		config.ClaimTimeout = 5 * time.Minute
+	}
+
+	return &WebhookService{
+		repository: repository,
+		client:     client,
+		config:     config,
+		now:        func() time.Time { return time.Now().UTC() },
+		lookupIP:   net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+// RegisterEndpoint registers a new endpoint. A secret is generated when none is given. The endpoints
+// on loopback, link-local or private addresses are refused.
+func (ws *WebhookService) RegisterEndpoint(ctx context.Context, req datatypes.CreateWebhookEndpointRequest) (datatypes.WebhookEndpoint, error) {
+	if req.URL == "" || len(req.Events) == 0 {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to register webhook endpoint. Cause: %w", ErrMissingRequiredFields)
+	}
+
+	endpointURL, err := url.Parse(req.URL)
+	if err != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to register webhook endpoint. Cause: %w", ErrInvalidEndpointURL)
+	}
+
+	if err := checkHost(ctx, ws.lookupIP, endpointURL.Hostname()); err != nil {
+		return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to register webhook endpoint. Cause: %w: %w", ErrInvalidEndpointURL, err)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return datatypes.WebhookEndpoint{}, fmt.Errorf("failed to register webhook endpoint. Cause: %w", err)
+		}
+	}
+
+	return ws.repository.CreateEndpoint(ctx, datatypes.WebhookEndpoint{
+		TenantID: tenant.IDFromContext(ctx),
+		URL:      req.URL,
+		Secret:   secret,
+		Events:   strings.Join(req.Events, ","),
+	})
+}
+
+// ListEndpoints lists the registered endpoints without their secrets
+func (ws *WebhookService) ListEndpoints(ctx context.Context) ([]datatypes.WebhookEndpoint, error) {
+	endpoints, err := ws.repository.FindEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+	return endpoints, nil
+}
+
+// DeleteEndpoint removes an endpoint
+func (ws *WebhookService) DeleteEndpoint(ctx context.Context, id string) error {
+	return ws.repository.DeleteEndpoint(ctx, id)
+}
+
+// ListDeliveries lists the latest deliveries of an endpoint
+func (ws *WebhookService) ListDeliveries(ctx context.Context, endpointID string, limit int) ([]datatypes.WebhookDelivery, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return ws.repository.FindDeliveriesByEndpoint(ctx, endpointID, limit)
+}
+
+// Publish writes the event into the outbox of every endpoint of the tenant subscribed to it
+func (ws *WebhookService) Publish(ctx context.Context, eventType string, data interface{}) error {
+	deliveries, err := ws.Deliveries(ctx, eventType, data)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if _, err := ws.repository.CreateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to publish webhook event. Cause: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Deliveries builds the pending deliveries of the event to every endpoint of the tenant subscribed
+// to it, without saving them. Callers write them in the transaction of the change the event is about.
+func (ws *WebhookService) Deliveries(ctx context.Context, eventType string, data interface{}) ([]datatypes.WebhookDelivery, error) {
+	endpoints, err := ws.repository.FindEndpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish webhook event. Cause: %w", err)
+	}
+
+	eventID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish webhook event. Cause: %w", err)
+	}
+
+	now := ws.now()
+	payload, err := json.Marshal(Event{
+		ID:        eventID.String(),
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish webhook event. Cause: %w", err)
+	}
+
+	tenantID := tenant.IDFromContext(ctx)
+	deliveries := []datatypes.WebhookDelivery{}
+	for _, endpoint := range endpoints {
+		if endpoint.TenantID != tenantID || !isSubscribed(endpoint, eventType) {
+			continue
+		}
+
+		deliveries = append(deliveries, datatypes.WebhookDelivery{
+			TenantID:      tenantID,
+			EndpointID:    endpoint.ID,
+			EventID:       eventID.String(),
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        DeliveryStatusPending,
+			NextAttemptAt: now,
+		})
+	}
+
+	return deliveries, nil
+}
+
+// Start sends the pending deliveries periodically until the context is done
+func (ws *WebhookService) Start(ctx context.Context) {
+	ticker := time.NewTicker(ws.config.DeliveryInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ws.DeliverPending(ctx); err != nil {
+			golog.Log().Error(ctx, err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending sends every pending delivery. Each delivery is claimed first, so the deliveries found
+// by several instances are sent only once. Failed deliveries are retried with an exponential backoff.
+func (ws *WebhookService) DeliverPending(ctx context.Context) error {
+	now := ws.now()
+	deliveries, err := ws.repository.FindPendingDeliveries(ctx, now, ws.config.BatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhooks. Cause: %w", err)
+	}
+
+	for _, pending := range deliveries {
+		delivery := pending.WebhookDelivery
+
+		claimed, err := ws.repository.ClaimDelivery(ctx, delivery, now, now.Add(ws.config.ClaimTimeout))
+		if err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to deliver webhook %s. Cause: %s", delivery.ID, err))
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		delivery.Attempts++
+
+		statusCode, err := ws.send(ctx, pending)
+		delivery.ResponseStatus = statusCode
+
+		if err != nil {
+			delivery.LastError = err.Error()
+
+			if delivery.Attempts >= ws.config.MaxAttempts {
+				delivery.Status = DeliveryStatusFailed
+			} else {
+				delivery.NextAttemptAt = ws.now().Add(ws.config.RetryInterval * time.Duration(1<<(delivery.Attempts-1)))
+			}
+		} else {
+			delivery.Status = DeliveryStatusDelivered
+			delivery.LastError = ""
+		}
+
+		if err := ws.repository.UpdateDelivery(ctx, delivery); err != nil {
+			golog.Log().Error(ctx, fmt.Sprintf("failed to deliver webhook %s. Cause: %s", delivery.ID, err))
+		}
+	}
+
+	return nil
+}
+
+// send posts the signed payload to the endpoint
+func (ws *WebhookService) send(ctx context.Context, delivery pendingDelivery) (int, error) {
+	timestamp := strconv.FormatInt(ws.now().Unix(), 10)
+	payload := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, payload))
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%w: %d", ErrUnexpectedStatusCode, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature sent on the X-Webhook-Signature header.
+// Receivers must compute HMAC-SHA256 of "<timestamp>.<body>" with the endpoint secret and compare both values.
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// isSubscribed check if the endpoint wants to receive the event
+func isSubscribed(endpoint datatypes.WebhookEndpoint, eventType string) bool {
+	for _, event := range strings.Split(endpoint.Events, ",") {
+		if event == AllEvents || event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// newSecret generates a random endpoint secret
+func newSecret() (string, error) {
+	bs := make([]byte, 32)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bs), nil
+}
@@ -0,0 +1,426 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JhonatanRSantos/review-chatbot/internal/datatypes"
+	"github.com/JhonatanRSantos/review-chatbot/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repositoryMock struct {
+	Error                            error
+	CallbackCreateEndpoint           func(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error)
+	CallbackFindEndpoints            func(ctx context.Context) ([]datatypes.WebhookEndpoint, error)
+	CallbackDeleteEndpoint           func(ctx context.Context, id string) error
+	CallbackCreateDelivery           func(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error)
+	CallbackFindDeliveriesByEndpoint func(ctx context.Context, endpointID string, limit int) ([]datatypes.WebhookDelivery, error)
+	CallbackFindPendingDeliveries    func(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error)
+	CallbackClaimDelivery            func(ctx context.Context, delivery datatypes.WebhookDelivery, now time.Time, until time.Time) (bool, error)
+	CallbackUpdateDelivery           func(ctx context.Context, delivery datatypes.WebhookDelivery) error
+}
+
+func (rm *repositoryMock) CreateEndpoint(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error) {
+	if rm.CallbackCreateEndpoint != nil {
+		return rm.CallbackCreateEndpoint(ctx, endpoint)
+	}
+	return datatypes.WebhookEndpoint{}, rm.Error
+}
+
+func (rm *repositoryMock) FindEndpoints(ctx context.Context) ([]datatypes.WebhookEndpoint, error) {
+	if rm.CallbackFindEndpoints != nil {
+		return rm.CallbackFindEndpoints(ctx)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) DeleteEndpoint(ctx context.Context, id string) error {
+	if rm.CallbackDeleteEndpoint != nil {
+		return rm.CallbackDeleteEndpoint(ctx, id)
+	}
+	return rm.Error
+}
+
+func (rm *repositoryMock) CreateDelivery(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error) {
+	if rm.CallbackCreateDelivery != nil {
+		return rm.CallbackCreateDelivery(ctx, delivery)
+	}
+	return datatypes.WebhookDelivery{}, rm.Error
+}
+
+func (rm *repositoryMock) FindDeliveriesByEndpoint(ctx context.Context, endpointID string, limit int) ([]datatypes.WebhookDelivery, error) {
+	if rm.CallbackFindDeliveriesByEndpoint != nil {
+		return rm.CallbackFindDeliveriesByEndpoint(ctx, endpointID, limit)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) FindPendingDeliveries(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+	if rm.CallbackFindPendingDeliveries != nil {
+		return rm.CallbackFindPendingDeliveries(ctx, now, limit)
+	}
+	return nil, rm.Error
+}
+
+func (rm *repositoryMock) ClaimDelivery(ctx context.Context, delivery datatypes.WebhookDelivery, now time.Time, until time.Time) (bool, error) {
+	if rm.CallbackClaimDelivery != nil {
+		return rm.CallbackClaimDelivery(ctx, delivery, now, until)
+	}
+	return rm.Error == nil, rm.Error
+}
+
+func (rm *repositoryMock) UpdateDelivery(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+	if rm.CallbackUpdateDelivery != nil {
+		return rm.CallbackUpdateDelivery(ctx, delivery)
+	}
+	return rm.Error
+}
+
+type httpClientMock struct {
+	Error      error
+	CallbackDo func(req *http.Request) (*http.Response, error)
+}
+
+func (hcm *httpClientMock) Do(req *http.Request) (*http.Response, error) {
+	if hcm.CallbackDo != nil {
+		return hcm.CallbackDo(req)
+	}
+	return nil, hcm.Error
+}
+
+// lookupPublicIP resolves every host to a public address
+func lookupPublicIP(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return []net.IPAddr{{IP: net.ParseIP("203.0.113.10")}}, nil
+}
+
+func TestServiceRegisterEndpoint(t *testing.T) {
+	t.Run("should register an endpoint with a generated secret", func(t *testing.T) {
+		service := NewWebhookService(&repositoryMock{
+			CallbackCreateEndpoint: func(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error) {
+				return endpoint, nil
+			},
+		}, &httpClientMock{}, WebhookServiceConfig{})
+		service.lookupIP = lookupPublicIP
+
+		endpoint, err := service.RegisterEndpoint(context.Background(), datatypes.CreateWebhookEndpointRequest{
+			URL:    "https://crm.example.com/hooks",
+			Events: []string{"chat.started", "message.created"},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, endpoint.Secret, 64)
+		assert.Equal(t, "chat.started,message.created", endpoint.Events)
+	})
+
+	t.Run("should fail when the url is invalid", func(t *testing.T) {
+		service := NewWebhookService(&repositoryMock{}, &httpClientMock{}, WebhookServiceConfig{})
+
+		_, err := service.RegisterEndpoint(context.Background(), datatypes.CreateWebhookEndpointRequest{
+			URL:    "ftp://crm.example.com",
+			Events: []string{"*"},
+		})
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrInvalidEndpointURL)
+	})
+
+	t.Run("should refuse loopback, link-local and private addresses", func(t *testing.T) {
+		service := NewWebhookService(&repositoryMock{
+			CallbackCreateEndpoint: func(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error) {
+				t.Fatal("endpoint must not be saved")
+				return endpoint, nil
+			},
+		}, &httpClientMock{}, WebhookServiceConfig{})
+		service.lookupIP = lookupPublicIP
+
+		urls := []string{
+			"http://127.0.0.1:8080/hooks",
+			"http://[::1]/hooks",
+			"http://169.254.169.254/latest/meta-data",
+			"http://10.0.0.5/hooks",
+			"http://192.168.1.20/hooks",
+			"http://0.0.0.0/hooks",
+		}
+
+		for _, endpointURL := range urls {
+			_, err := service.RegisterEndpoint(context.Background(), datatypes.CreateWebhookEndpointRequest{URL: endpointURL, Events: []string{AllEvents}})
+			assert.ErrorIs(t, err, ErrInvalidEndpointURL, endpointURL)
+			assert.ErrorIs(t, err, ErrForbiddenAddress, endpointURL)
+		}
+	})
+
+	t.Run("should refuse the names resolved to private addresses", func(t *testing.T) {
+		service := NewWebhookService(&repositoryMock{}, &httpClientMock{}, WebhookServiceConfig{})
+		service.lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			assert.Equal(t, "crm.internal", host)
+			return []net.IPAddr{{IP: net.ParseIP("203.0.113.10")}, {IP: net.ParseIP("172.16.0.3")}}, nil
+		}
+
+		_, err := service.RegisterEndpoint(context.Background(), datatypes.CreateWebhookEndpointRequest{
+			URL:    "https://crm.internal/hooks",
+			Events: []string{AllEvents},
+		})
+		assert.ErrorIs(t, err, ErrForbiddenAddress)
+	})
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	t.Run("should refuse to connect to a loopback address", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("request must not be sent")
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPClient(time.Second).Post(server.URL, "application/json", strings.NewReader("{}"))
+		assert.ErrorIs(t, err, ErrForbiddenAddress)
+	})
+}
+
+func TestServicePublish(t *testing.T) {
+	t.Run("should write a delivery only for subscribed endpoints", func(t *testing.T) {
+		var deliveries []datatypes.WebhookDelivery
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindEndpoints: func(ctx context.Context) ([]datatypes.WebhookEndpoint, error) {
+				return []datatypes.WebhookEndpoint{
+					{ID: "all", TenantID: tenant.DefaultID, Events: AllEvents},
+					{ID: "messages", TenantID: tenant.DefaultID, Events: "message.created"},
+					{ID: "chats", TenantID: tenant.DefaultID, Events: "chat.started"},
+				}, nil
+			},
+			CallbackCreateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error) {
+				deliveries = append(deliveries, delivery)
+				return delivery, nil
+			},
+		}, &httpClientMock{}, WebhookServiceConfig{})
+
+		err := service.Publish(context.Background(), "message.created", map[string]string{"message": "Hi"})
+		assert.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, "all", deliveries[0].EndpointID)
+		assert.Equal(t, "messages", deliveries[1].EndpointID)
+		assert.Equal(t, deliveries[0].EventID, deliveries[1].EventID)
+
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &event))
+		assert.Equal(t, "message.created", event.Type)
+	})
+
+	t.Run("should write deliveries only for the endpoints of the event tenant", func(t *testing.T) {
+		var endpoints []datatypes.WebhookEndpoint
+		var deliveries []datatypes.WebhookDelivery
+		service := NewWebhookService(&repositoryMock{
+			CallbackCreateEndpoint: func(ctx context.Context, endpoint datatypes.WebhookEndpoint) (datatypes.WebhookEndpoint, error) {
+				endpoint.ID = fmt.Sprintf("endpoint-%d", len(endpoints))
+				endpoints = append(endpoints, endpoint)
+				return endpoint, nil
+			},
+			CallbackFindEndpoints: func(ctx context.Context) ([]datatypes.WebhookEndpoint, error) {
+				found := []datatypes.WebhookEndpoint{}
+				for _, endpoint := range endpoints {
+					if endpoint.TenantID == tenant.IDFromContext(ctx) {
+						found = append(found, endpoint)
+					}
+				}
+				return found, nil
+			},
+			CallbackCreateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error) {
+				deliveries = append(deliveries, delivery)
+				return delivery, nil
+			},
+		}, &httpClientMock{}, WebhookServiceConfig{})
+		service.lookupIP = lookupPublicIP
+
+		storeA := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "store-a"})
+		storeB := tenant.WithTenant(context.Background(), datatypes.Tenant{ID: "store-b"})
+
+		endpointA, err := service.RegisterEndpoint(storeA, datatypes.CreateWebhookEndpointRequest{URL: "https://a.example.com/hooks", Events: []string{AllEvents}})
+		require.NoError(t, err)
+		assert.Equal(t, "store-a", endpointA.TenantID)
+
+		endpointB, err := service.RegisterEndpoint(storeB, datatypes.CreateWebhookEndpointRequest{URL: "https://b.example.com/hooks", Events: []string{AllEvents}})
+		require.NoError(t, err)
+		assert.Equal(t, "store-b", endpointB.TenantID)
+
+		require.NoError(t, service.Publish(storeA, "chat.started", map[string]string{"chatId": "1"}))
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, endpointA.ID, deliveries[0].EndpointID)
+		assert.Equal(t, "store-a", deliveries[0].TenantID)
+
+		listed, err := service.ListEndpoints(storeB)
+		require.NoError(t, err)
+		require.Len(t, listed, 1)
+		assert.Equal(t, endpointB.ID, listed[0].ID)
+	})
+}
+
+func TestServiceDeliveries(t *testing.T) {
+	t.Run("should build the deliveries of the subscribed endpoints without saving them", func(t *testing.T) {
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindEndpoints: func(ctx context.Context) ([]datatypes.WebhookEndpoint, error) {
+				return []datatypes.WebhookEndpoint{
+					{ID: "messages", TenantID: tenant.DefaultID, Events: "message.created"},
+					{ID: "chats", TenantID: tenant.DefaultID, Events: "chat.started"},
+				}, nil
+			},
+			CallbackCreateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) (datatypes.WebhookDelivery, error) {
+				t.Fatal("delivery must not be saved")
+				return delivery, nil
+			},
+		}, &httpClientMock{}, WebhookServiceConfig{})
+
+		deliveries, err := service.Deliveries(context.Background(), "chat.started", map[string]string{"chatId": "1"})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, "chats", deliveries[0].EndpointID)
+		assert.Equal(t, tenant.DefaultID, deliveries[0].TenantID)
+		assert.Equal(t, DeliveryStatusPending, deliveries[0].Status)
+	})
+}
+
+func TestServiceDeliverPending(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("should send a signed payload", func(t *testing.T) {
+		var updated datatypes.WebhookDelivery
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindPendingDeliveries: func(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+				return []pendingDelivery{{
+					WebhookDelivery: datatypes.WebhookDelivery{ID: "qwerty", EventType: "chat.started", Payload: `{"id":"1"}`},
+					URL:             "https://crm.example.com/hooks",
+					Secret:          "secret",
+				}}, nil
+			},
+			CallbackUpdateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+				updated = delivery
+				return nil
+			},
+		}, &httpClientMock{
+			CallbackDo: func(req *http.Request) (*http.Response, error) {
+				body, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				assert.Equal(t, Sign("secret", req.Header.Get(HeaderTimestamp), body), req.Header.Get(HeaderSignature))
+				assert.Equal(t, "chat.started", req.Header.Get(HeaderEvent))
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+			},
+		}, WebhookServiceConfig{MaxAttempts: 3})
+
+		assert.NoError(t, service.DeliverPending(context.Background()))
+		assert.Equal(t, DeliveryStatusDelivered, updated.Status)
+		assert.Equal(t, http.StatusOK, updated.ResponseStatus)
+		assert.Equal(t, 1, updated.Attempts)
+	})
+
+	t.Run("should retry with backoff when the endpoint fails", func(t *testing.T) {
+		var updated datatypes.WebhookDelivery
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindPendingDeliveries: func(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+				return []pendingDelivery{{
+					WebhookDelivery: datatypes.WebhookDelivery{ID: "qwerty", Status: DeliveryStatusPending, Attempts: 2},
+					URL:             "https://crm.example.com/hooks",
+				}}, nil
+			},
+			CallbackUpdateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+				updated = delivery
+				return nil
+			},
+		}, &httpClientMock{
+			CallbackDo: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader(""))}, nil
+			},
+		}, WebhookServiceConfig{MaxAttempts: 5, RetryInterval: time.Second})
+		service.now = func() time.Time { return now }
+
+		assert.NoError(t, service.DeliverPending(context.Background()))
+		assert.Equal(t, DeliveryStatusPending, updated.Status)
+		assert.Equal(t, 3, updated.Attempts)
+		assert.Equal(t, now.Add(4*time.Second), updated.NextAttemptAt)
+		assert.Contains(t, updated.LastError, ErrUnexpectedStatusCode.Error())
+	})
+
+	t.Run("should mark the delivery as failed after the last attempt", func(t *testing.T) {
+		var updated datatypes.WebhookDelivery
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindPendingDeliveries: func(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+				return []pendingDelivery{{
+					WebhookDelivery: datatypes.WebhookDelivery{ID: "qwerty"},
+					URL:             "https://crm.example.com/hooks",
+				}}, nil
+			},
+			CallbackUpdateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+				updated = delivery
+				return nil
+			},
+		}, &httpClientMock{Error: errors.New("connection refused")}, WebhookServiceConfig{MaxAttempts: 1})
+
+		assert.NoError(t, service.DeliverPending(context.Background()))
+		assert.Equal(t, DeliveryStatusFailed, updated.Status)
+		assert.Equal(t, "connection refused", updated.LastError)
+	})
+
+	t.Run("should skip the deliveries claimed by another instance", func(t *testing.T) {
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindPendingDeliveries: func(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+				return []pendingDelivery{{WebhookDelivery: datatypes.WebhookDelivery{ID: "qwerty"}, URL: "https://crm.example.com/hooks"}}, nil
+			},
+			CallbackClaimDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery, claimedAt time.Time, until time.Time) (bool, error) {
+				assert.Equal(t, now, claimedAt)
+				assert.Equal(t, now.Add(time.Minute), until)
+				return false, nil
+			},
+			CallbackUpdateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+				t.Fatal("delivery must not be updated")
+				return nil
+			},
+		}, &httpClientMock{
+			CallbackDo: func(req *http.Request) (*http.Response, error) {
+				t.Fatal("delivery must not be sent")
+				return nil, nil
+			},
+		}, WebhookServiceConfig{ClaimTimeout: time.Minute})
+		service.now = func() time.Time { return now }
+
+		assert.NoError(t, service.DeliverPending(context.Background()))
+	})
+
+	t.Run("should keep sending the batch when a delivery can't be claimed or updated", func(t *testing.T) {
+		var sent []string
+		service := NewWebhookService(&repositoryMock{
+			CallbackFindPendingDeliveries: func(ctx context.Context, now time.Time, limit int) ([]pendingDelivery, error) {
+				return []pendingDelivery{
+					{WebhookDelivery: datatypes.WebhookDelivery{ID: "claim-fails"}, URL: "https://crm.example.com/hooks"},
+					{WebhookDelivery: datatypes.WebhookDelivery{ID: "update-fails"}, URL: "https://crm.example.com/hooks"},
+					{WebhookDelivery: datatypes.WebhookDelivery{ID: "qwerty"}, URL: "https://crm.example.com/hooks"},
+				}, nil
+			},
+			CallbackClaimDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery, now time.Time, until time.Time) (bool, error) {
+				if delivery.ID == "claim-fails" {
+					return false, errors.New("error to claim the delivery for tests")
+				}
+				return true, nil
+			},
+			CallbackUpdateDelivery: func(ctx context.Context, delivery datatypes.WebhookDelivery) error {
+				if delivery.ID == "update-fails" {
+					return errors.New("error to update the delivery for tests")
+				}
+				return nil
+			},
+		}, &httpClientMock{
+			CallbackDo: func(req *http.Request) (*http.Response, error) {
+				sent = append(sent, req.Header.Get(HeaderDeliveryID))
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+			},
+		}, WebhookServiceConfig{})
+
+		assert.NoError(t, service.DeliverPending(context.Background()))
+		assert.Equal(t, []string{"update-fails", "qwerty"}, sent)
+	})
+}